@@ -0,0 +1,119 @@
+package pidpool
+
+import (
+	"errors"
+	"math"
+	"sync"
+	"time"
+)
+
+// ActuatorFault describes an actuator that does not respond: the output
+// moved by OutputChange and was held there for Duration while the process
+// value changed by only ValueChange. It is distinct from sensor faults,
+// which surface as Source read errors.
+type ActuatorFault struct {
+	Time         time.Time
+	OutputChange float64
+	ValueChange  float64
+	Duration     time.Duration
+}
+
+// ActuatorMonitorConfig configures an ActuatorMonitor.
+type ActuatorMonitorConfig struct {
+	// MinOutputChange is the output move, away from where the process last
+	// responded, that is expected to move the process value.
+	MinOutputChange float64
+	// MinValueChange is the process value change that counts as a response.
+	MinValueChange float64
+	// Window is how long the output move must be held without a response
+	// before a fault is raised.
+	Window time.Duration
+	// OnFault is called once when a fault is detected.
+	OnFault func(ActuatorFault)
+	// OnRecover is called when the process responds again after a fault.
+	OnRecover func()
+}
+
+// ActuatorMonitor detects an actuator that accepts outputs but has no effect
+// on the process, e.g. a seized valve or a disconnected heater, from the
+// outputs written and the process values read afterwards.
+type ActuatorMonitor struct {
+	cfg ActuatorMonitorConfig
+
+	mu       sync.Mutex
+	primed   bool
+	refOut   float64
+	refValue float64
+	moved    bool
+	movedAt  time.Time
+	faulted  bool
+}
+
+// NewActuatorMonitor returns a monitor for the given configuration.
+func NewActuatorMonitor(cfg ActuatorMonitorConfig) (*ActuatorMonitor, error) {
+	if cfg.MinOutputChange <= 0 || cfg.MinValueChange <= 0 {
+		return nil, errors.New("minimum output and value changes must be positive")
+	}
+	if cfg.Window <= 0 {
+		return nil, errors.New("window must be positive")
+	}
+	return &ActuatorMonitor{cfg: cfg}, nil
+}
+
+// Observe records that output was in effect when value was measured at t.
+func (m *ActuatorMonitor) Observe(t time.Time, output, value float64) {
+	m.mu.Lock()
+	var notify func()
+	switch {
+	case !m.primed:
+		m.primed = true
+		m.rebase(output, value)
+	case math.Abs(value-m.refValue) >= m.cfg.MinValueChange:
+		// the process responded.
+		if m.faulted && m.cfg.OnRecover != nil {
+			notify = m.cfg.OnRecover
+		}
+		m.faulted = false
+		m.rebase(output, value)
+	case math.Abs(output-m.refOut) < m.cfg.MinOutputChange:
+		// no significant move yet. The reference stays anchored, so a ramp
+		// of small moves adds up.
+		m.moved = false
+	case !m.moved:
+		m.moved, m.movedAt = true, t
+	case !m.faulted && t.Sub(m.movedAt) >= m.cfg.Window:
+		m.faulted = true
+		if m.cfg.OnFault != nil {
+			f := ActuatorFault{
+				Time:         t,
+				OutputChange: output - m.refOut,
+				ValueChange:  value - m.refValue,
+				Duration:     t.Sub(m.movedAt),
+			}
+			notify = func() { m.cfg.OnFault(f) }
+		}
+	}
+	m.mu.Unlock()
+
+	if notify != nil {
+		notify()
+	}
+}
+
+func (m *ActuatorMonitor) rebase(output, value float64) {
+	m.refOut, m.refValue, m.moved = output, value, false
+}
+
+// Faulted reports whether a fault is active.
+func (m *ActuatorMonitor) Faulted() bool {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.faulted
+}
+
+// Reset forgets the observed history and clears any active fault.
+func (m *ActuatorMonitor) Reset() {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.primed, m.faulted = false, false
+}
@@ -0,0 +1,101 @@
+package pidpool_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/ankur-anand/go-pidpool"
+)
+
+func TestActuatorMonitor(t *testing.T) {
+	var faults []pidpool.ActuatorFault
+	recovered := 0
+	m, err := pidpool.NewActuatorMonitor(pidpool.ActuatorMonitorConfig{
+		MinOutputChange: 10,
+		MinValueChange:  0.5,
+		Window:          5 * time.Second,
+		OnFault:         func(f pidpool.ActuatorFault) { faults = append(faults, f) },
+		OnRecover:       func() { recovered++ },
+	})
+	if err != nil {
+		t.Fatalf("NewActuatorMonitor err: %v", err)
+	}
+
+	t0 := time.Unix(0, 0)
+	at := func(s int) time.Time { return t0.Add(time.Duration(s) * time.Second) }
+	m.Observe(at(0), 20, 50)
+	m.Observe(at(1), 25, 50.1) // small move, no response expected yet.
+	for s := 2; s <= 6; s++ {
+		m.Observe(at(s), 60, 50.1) // large move, process insensitive.
+	}
+	if m.Faulted() {
+		t.Fatalf("fault raised before the window elapsed")
+	}
+	m.Observe(at(7), 60, 50.2)
+	m.Observe(at(8), 60, 50.3)
+	if !m.Faulted() || len(faults) != 1 {
+		t.Fatalf("expected exactly one fault, got %v", faults)
+	}
+	if f := faults[0]; f.OutputChange != 40 || f.Duration != 5*time.Second {
+		t.Fatalf("unexpected fault %+v", f)
+	}
+
+	m.Observe(at(9), 60, 51)
+	if m.Faulted() || recovered != 1 {
+		t.Fatalf("expected recovery once the process responds")
+	}
+}
+
+func TestActuatorMonitor_SlowRamp(t *testing.T) {
+	m, err := pidpool.NewActuatorMonitor(pidpool.ActuatorMonitorConfig{
+		MinOutputChange: 10,
+		MinValueChange:  0.5,
+		Window:          5 * time.Second,
+	})
+	if err != nil {
+		t.Fatalf("NewActuatorMonitor err: %v", err)
+	}
+
+	// every step is below MinOutputChange, the ramp as a whole is not.
+	t0 := time.Unix(0, 0)
+	for s := 0; s <= 30; s++ {
+		m.Observe(t0.Add(time.Duration(s)*time.Second), 20+float64(s), 50)
+	}
+	if !m.Faulted() {
+		t.Fatalf("expected a ramp of small moves to reveal the stuck actuator")
+	}
+}
+
+func TestRunner_ActuatorFault(t *testing.T) {
+	p := pidpool.NewP(10)
+	p.SetSetPoint(100)
+	source := pidpool.SourceFunc(func(context.Context) (float64, error) { return 20, nil })
+	sink := pidpool.SinkFunc(func(context.Context, float64) error { return nil })
+
+	fault := make(chan pidpool.ActuatorFault, 1)
+	m, _ := pidpool.NewActuatorMonitor(pidpool.ActuatorMonitorConfig{
+		MinOutputChange: 1,
+		MinValueChange:  1,
+		Window:          5 * time.Millisecond,
+		OnFault:         func(f pidpool.ActuatorFault) { fault <- f },
+	})
+	r := pidpool.NewRunner(p, time.Millisecond, source, sink)
+	r.SetActuatorMonitor(m)
+	if err := r.Start(); err != nil {
+		t.Fatalf("Start err: %v", err)
+	}
+	defer r.Stop()
+
+	// the output is constant, so no fault until it moves.
+	time.Sleep(20 * time.Millisecond)
+	if m.Faulted() {
+		t.Fatalf("fault without an output move")
+	}
+	p.SetSetPoint(200)
+	select {
+	case <-fault:
+	case <-time.After(2 * time.Second):
+		t.Fatalf("actuator fault not detected")
+	}
+}
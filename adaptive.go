@@ -0,0 +1,220 @@
+package pidpool
+
+import (
+	"errors"
+	"math"
+	"sync"
+	"time"
+)
+
+// AdaptiveConfig configures an Adaptive gain adapter.
+type AdaptiveConfig struct {
+	// ModelTimeConstant is the time constant of the first-order reference
+	// model: the response the closed loop should have to a setpoint
+	// change.
+	ModelTimeConstant time.Duration
+	// Model replaces the first-order reference model, e.g. with a
+	// SecondOrderModel. It must not be shared between adapters.
+	Model ReferenceModel
+	// Rate is the adaptation gain. Keep it small: the gains should drift
+	// with the plant, not follow every disturbance.
+	Rate float64
+	// Normalization is added to the squared regressor norm that divides
+	// every adaptation step, bounding the step for large signals. Defaults
+	// to 1.
+	Normalization float64
+
+	// Min and Max bound every gain. A gain whose bounds are equal is not
+	// adapted. For a reverse-acting loop with negative gains both bounds
+	// of Kp are negative and the adaptation direction flips with them.
+	Min Gains
+	Max Gains
+
+	// AdaptWhileSaturated keeps adapting while the output is saturated or
+	// anti-windup holds the integral. By default adaptation freezes then:
+	// the loop is not acting on its gains, so the model error says nothing
+	// about them.
+	AdaptWhileSaturated bool
+}
+
+// Adaptive adjusts the gains of a controller online with the normalized
+// MIT rule, so the loop keeps following a reference model while the plant
+// drifts slowly, e.g. a heat exchanger fouling over weeks.
+//
+// The reference model ym follows the setpoint, by default with
+// ModelTimeConstant. Every
+// update moves each gain against the gradient of the squared model error
+// (y - ym)², approximating the sensitivity of y to the gain by its
+// regressor: the error for Kp, its integral for Ki and the negated rate of
+// change of the value for Kd.
+type Adaptive struct {
+	cfg  AdaptiveConfig
+	sign float64
+
+	mu          sync.Mutex
+	model       ReferenceModel
+	primed      bool
+	modelOut    float64
+	modelErr    float64
+	prevValue   float64
+	integral    float64
+	frozen      bool
+	adaptations int64
+}
+
+// AdaptationState is a snapshot of an adapter for monitoring.
+type AdaptationState struct {
+	// ModelOutput is the output of the reference model and ModelError the
+	// value minus it, at the last update.
+	ModelOutput float64
+	ModelError  float64
+	Frozen      bool
+	// Adaptations counts the updates that adapted the gains.
+	Adaptations int64
+}
+
+// NewAdaptive returns an adapter for the given configuration.
+func NewAdaptive(cfg AdaptiveConfig) (*Adaptive, error) {
+	model := cfg.Model
+	if model == nil {
+		var err error
+		if model, err = NewFirstOrderModel(cfg.ModelTimeConstant); err != nil {
+			return nil, err
+		}
+	}
+	if !(cfg.Rate > 0) || math.IsInf(cfg.Rate, 0) {
+		return nil, errors.New("adaptation rate must be positive")
+	}
+	if cfg.Normalization < 0 {
+		return nil, errors.New("normalization must not be negative")
+	}
+	if cfg.Normalization == 0 {
+		cfg.Normalization = 1
+	}
+	if err := cfg.Min.Validate(); err != nil {
+		return nil, err
+	}
+	if err := cfg.Max.Validate(); err != nil {
+		return nil, err
+	}
+	if cfg.Min.Kp > cfg.Max.Kp || cfg.Min.Ki > cfg.Max.Ki || cfg.Min.Kd > cfg.Max.Kd {
+		return nil, errors.New("min gains greater than max gains")
+	}
+	sign := 1.0
+	if cfg.Max.Kp <= 0 && cfg.Min.Kp < 0 {
+		sign = -1
+	}
+
+	return &Adaptive{cfg: cfg, sign: sign, model: model}, nil
+}
+
+// Observe feeds an update made with gains g to the adapter and returns the
+// adapted gains, within the bounds.
+func (a *Adaptive) Observe(ev UpdateEvent, g Gains) Gains {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	if ev.Quality.IsBad() || ev.DT <= 0 {
+		return a.clampLocked(g)
+	}
+	if !a.primed {
+		a.primed = true
+		a.model.Reset(ev.Value)
+		a.modelOut, a.prevValue = ev.Value, ev.Value
+		return a.clampLocked(g)
+	}
+
+	dt := ev.DT
+	y, r := ev.Value, ev.SetPoint
+	a.modelOut = a.model.Step(r, dt)
+	a.modelErr = y - a.modelOut
+
+	e := r - y
+	a.integral += float64(e * dt)
+	rate := -(y - a.prevValue) / dt
+	a.prevValue = y
+
+	st := ev.Status()
+	if a.frozen || !a.cfg.AdaptWhileSaturated && (st.Saturated || st.WindupClamped) {
+		return a.clampLocked(g)
+	}
+
+	phi := [3]float64{e, a.integral, rate}
+	norm := a.cfg.Normalization + phi[0]*phi[0] + phi[1]*phi[1] + phi[2]*phi[2]
+	step := -a.sign * a.cfg.Rate * a.modelErr * dt / norm
+	a.adaptations++
+	g.Kp += step * phi[0]
+	g.Ki += step * phi[1]
+	g.Kd += step * phi[2]
+
+	return a.clampLocked(g)
+}
+
+func (a *Adaptive) clampLocked(g Gains) Gains {
+	return Gains{
+		Kp: clamp(g.Kp, a.cfg.Min.Kp, a.cfg.Max.Kp),
+		Ki: clamp(g.Ki, a.cfg.Min.Ki, a.cfg.Max.Ki),
+		Kd: clamp(g.Kd, a.cfg.Min.Kd, a.cfg.Max.Kd),
+	}
+}
+
+// ModelOutput returns the current output of the reference model.
+func (a *Adaptive) ModelOutput() float64 {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	return a.modelOut
+}
+
+// State returns a snapshot of the adapter.
+func (a *Adaptive) State() AdaptationState {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	return AdaptationState{
+		ModelOutput: a.modelOut,
+		ModelError:  a.modelErr,
+		Frozen:      a.frozen,
+		Adaptations: a.adaptations,
+	}
+}
+
+// Freeze stops the adaptation until Unfreeze, e.g. during commissioning
+// or a known upset. The reference model keeps tracking.
+func (a *Adaptive) Freeze() {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	a.frozen = true
+}
+
+// Unfreeze resumes the adaptation.
+func (a *Adaptive) Unfreeze() {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	a.frozen = false
+}
+
+// Frozen reports whether the adaptation is frozen.
+func (a *Adaptive) Frozen() bool {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	return a.frozen
+}
+
+// Reset restarts the reference model from the next value.
+func (a *Adaptive) Reset() {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	a.primed, a.integral, a.modelErr = false, 0, 0
+}
+
+// Attach adapts the gains of pid after every update in Auto mode. The
+// returned function detaches the adapter.
+func (a *Adaptive) Attach(pid *PID) (remove func()) {
+	return pid.OnUpdateWith(func(ev UpdateEvent) {
+		if pid.GetMode() != Auto {
+			return
+		}
+		g := pid.Gains()
+		if next := a.Observe(ev, g); next != g {
+			pid.SetPID(next.Kp, next.Ki, next.Kd)
+		}
+	}, HookOptions{Name: "adaptive"})
+}
@@ -0,0 +1,73 @@
+package pidpool_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/ankur-anand/go-pidpool"
+)
+
+func TestAdaptive_TracksGainDrop(t *testing.T) {
+	pid := pidpool.NewPI(0.5, 0.1)
+	pid.SetOutputLimits(-100, 100)
+	a, err := pidpool.NewAdaptive(pidpool.AdaptiveConfig{
+		ModelTimeConstant: 5 * time.Second,
+		Rate:              0.5,
+		Min:               pidpool.Gains{Kp: 0.1, Ki: 0.01},
+		Max:               pidpool.Gains{Kp: 5, Ki: 1},
+	})
+	if err != nil {
+		t.Fatalf("NewAdaptive err: %v", err)
+	}
+	defer a.Attach(pid)()
+
+	// the plant gain has dropped to a fifth of what the gains were tuned
+	// for, so the loop is sluggish against the reference model.
+	const dt, tau, gain = 0.1, 4.0, 0.2
+	y, u := 0.0, 0.0
+	for i := 0; i < 20000; i++ {
+		if i%1000 == 0 {
+			pid.SetSetPoint(float64((i / 1000) % 2 * 10))
+		}
+		y += dt / tau * (gain*u - y)
+		u = pid.UpdateDuration(y, dt)
+	}
+
+	g := pid.Gains()
+	if g.Kp <= 0.5 || g.Ki <= 0.1 {
+		t.Fatalf("expected the gains to grow, got %+v", g)
+	}
+	if g.Kp > 5 || g.Ki > 1 || g.Kd != 0 {
+		t.Fatalf("gains left their bounds: %+v", g)
+	}
+}
+
+func TestAdaptive_Freeze(t *testing.T) {
+	a, err := pidpool.NewAdaptive(pidpool.AdaptiveConfig{
+		ModelTimeConstant: time.Second,
+		Rate:              1,
+		Max:               pidpool.Gains{Kp: 10, Ki: 10},
+	})
+	if err != nil {
+		t.Fatalf("NewAdaptive err: %v", err)
+	}
+	g := pidpool.Gains{Kp: 1, Ki: 1}
+	a.Observe(pidpool.UpdateEvent{DT: 1, SetPoint: 10, Value: 0, Output: 10, RawOutput: 10}, g)
+
+	// a saturated update does not adapt.
+	if got := a.Observe(pidpool.UpdateEvent{DT: 1, SetPoint: 10, Value: 0, Output: 10, RawOutput: 50}, g); got != g {
+		t.Fatalf("adapted while saturated: %+v", got)
+	}
+	a.Freeze()
+	if got := a.Observe(pidpool.UpdateEvent{DT: 1, SetPoint: 10, Value: 0, Output: 10, RawOutput: 10}, g); got != g {
+		t.Fatalf("adapted while frozen: %+v", got)
+	}
+	a.Unfreeze()
+	if got := a.Observe(pidpool.UpdateEvent{DT: 1, SetPoint: 10, Value: 0, Output: 10, RawOutput: 10}, g); got == g {
+		t.Fatalf("expected adaptation after Unfreeze")
+	}
+
+	if _, err := pidpool.NewAdaptive(pidpool.AdaptiveConfig{ModelTimeConstant: time.Second, Rate: 1, Min: pidpool.Gains{Kp: 2}, Max: pidpool.Gains{Kp: 1}}); err == nil {
+		t.Fatalf("expected error for inverted bounds")
+	}
+}
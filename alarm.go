@@ -0,0 +1,224 @@
+package pidpool
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// AlarmSignal selects the signal an alarm limit watches.
+type AlarmSignal int
+
+const (
+	// AlarmValue watches the process value.
+	AlarmValue AlarmSignal = iota
+	// AlarmDeviation watches the absolute control error, |setpoint - value|.
+	AlarmDeviation
+)
+
+// String implements fmt.Stringer.
+func (s AlarmSignal) String() string {
+	switch s {
+	case AlarmValue:
+		return "value"
+	case AlarmDeviation:
+		return "deviation"
+	}
+	return fmt.Sprintf("AlarmSignal(%d)", int(s))
+}
+
+// AlarmLevel is the level of an alarm limit.
+type AlarmLevel int
+
+const (
+	// AlarmHighHigh is the upper trip or interlock level.
+	AlarmHighHigh AlarmLevel = iota
+	// AlarmHigh is the upper warning level.
+	AlarmHigh
+	// AlarmLow is the lower warning level.
+	AlarmLow
+	// AlarmLowLow is the lower trip or interlock level.
+	AlarmLowLow
+)
+
+// String implements fmt.Stringer.
+func (l AlarmLevel) String() string {
+	switch l {
+	case AlarmHighHigh:
+		return "high-high"
+	case AlarmHigh:
+		return "high"
+	case AlarmLow:
+		return "low"
+	case AlarmLowLow:
+		return "low-low"
+	}
+	return fmt.Sprintf("AlarmLevel(%d)", int(l))
+}
+
+func (l AlarmLevel) high() bool { return l == AlarmHighHigh || l == AlarmHigh }
+
+// AlarmLimit is one alarm threshold.
+type AlarmLimit struct {
+	Signal AlarmSignal
+	Level  AlarmLevel
+	// Limit is the threshold: a high alarm is raised above it, a low alarm
+	// below it.
+	Limit float64
+	// Deadband is the hysteresis: a high alarm clears once the signal is
+	// back below Limit - Deadband, a low alarm above Limit + Deadband.
+	Deadband float64
+	// Delay is how long the limit must be exceeded before the alarm is
+	// raised, so short excursions do not alarm.
+	Delay time.Duration
+}
+
+// AlarmEvent reports an alarm being raised or cleared.
+type AlarmEvent struct {
+	Time  time.Time
+	Limit AlarmLimit
+	// Value is the watched signal at the time of the event.
+	Value float64
+	// Active is true when the alarm was raised, false when it cleared.
+	Active bool
+}
+
+// AlarmConfig configures Alarms.
+type AlarmConfig struct {
+	Limits []AlarmLimit
+	// OnAlarm is called with every event, on the goroutine that observed
+	// the update.
+	OnAlarm func(AlarmEvent)
+	// Events, if not nil, also receives every event. Sends never block:
+	// an event is dropped while the channel is full.
+	Events chan<- AlarmEvent
+}
+
+type alarmState struct {
+	active   bool
+	pending  bool
+	exceeded time.Time
+}
+
+// Alarms watches the process value and the control error against a set of
+// high-high, high, low and low-low limits with hysteresis and delay, the
+// way a plant alarm system does.
+type Alarms struct {
+	cfg     AlarmConfig
+	dropped atomic.Int64
+
+	mu     sync.Mutex
+	states []alarmState
+}
+
+// NewAlarms returns alarms for the given configuration.
+func NewAlarms(cfg AlarmConfig) (*Alarms, error) {
+	for _, l := range cfg.Limits {
+		if l.Signal != AlarmValue && l.Signal != AlarmDeviation {
+			return nil, fmt.Errorf("unknown alarm signal %d", int(l.Signal))
+		}
+		if l.Level < AlarmHighHigh || l.Level > AlarmLowLow {
+			return nil, fmt.Errorf("unknown alarm level %d", int(l.Level))
+		}
+		if math.IsNaN(l.Limit) || math.IsInf(l.Limit, 0) {
+			return nil, errors.New("alarm limit must be finite")
+		}
+		if l.Deadband < 0 || l.Delay < 0 {
+			return nil, errors.New("alarm deadband and delay must not be negative")
+		}
+	}
+	cfg.Limits = append([]AlarmLimit(nil), cfg.Limits...)
+	return &Alarms{cfg: cfg, states: make([]alarmState, len(cfg.Limits))}, nil
+}
+
+// Observe feeds an update to the alarms and delivers the events it
+// causes. Updates of bad quality are ignored.
+func (a *Alarms) Observe(ev UpdateEvent) {
+	if ev.Quality.IsBad() {
+		return
+	}
+	a.mu.Lock()
+	events := a.observeLocked(ev)
+	a.mu.Unlock()
+
+	for _, e := range events {
+		if a.cfg.OnAlarm != nil {
+			a.cfg.OnAlarm(e)
+		}
+		if a.cfg.Events != nil {
+			select {
+			case a.cfg.Events <- e:
+			default:
+				a.dropped.Add(1)
+			}
+		}
+	}
+}
+
+func (a *Alarms) observeLocked(ev UpdateEvent) []AlarmEvent {
+	var events []AlarmEvent
+	for i, l := range a.cfg.Limits {
+		x := ev.Value
+		if l.Signal == AlarmDeviation {
+			x = math.Abs(ev.SetPoint - ev.Value)
+		}
+		exceeded := x > l.Limit
+		cleared := x < l.Limit-l.Deadband
+		if !l.Level.high() {
+			exceeded, cleared = x < l.Limit, x > l.Limit+l.Deadband
+		}
+
+		s := &a.states[i]
+		switch {
+		case s.active && cleared:
+			s.active = false
+			events = append(events, AlarmEvent{Time: ev.Time, Limit: l, Value: x})
+		case s.active:
+		case !exceeded:
+			s.pending = false
+		case !s.pending:
+			s.pending, s.exceeded = true, ev.Time
+			fallthrough
+		default:
+			if ev.Time.Sub(s.exceeded) >= l.Delay {
+				s.active, s.pending = true, false
+				events = append(events, AlarmEvent{Time: ev.Time, Limit: l, Value: x, Active: true})
+			}
+		}
+	}
+	return events
+}
+
+// Active returns the limits whose alarm is raised.
+func (a *Alarms) Active() []AlarmLimit {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	var active []AlarmLimit
+	for i, s := range a.states {
+		if s.active {
+			active = append(active, a.cfg.Limits[i])
+		}
+	}
+	return active
+}
+
+// Dropped returns the number of events dropped because Events was full.
+func (a *Alarms) Dropped() int64 {
+	return a.dropped.Load()
+}
+
+// Reset clears every alarm without reporting it.
+func (a *Alarms) Reset() {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	clear(a.states)
+}
+
+// Attach observes every update of pid. The returned function detaches the
+// alarms.
+func (a *Alarms) Attach(pid *PID) (remove func()) {
+	return pid.OnUpdateWith(a.Observe, HookOptions{Name: "alarms"})
+}
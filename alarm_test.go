@@ -0,0 +1,68 @@
+package pidpool_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/ankur-anand/go-pidpool"
+)
+
+func TestAlarms(t *testing.T) {
+	events := make(chan pidpool.AlarmEvent, 1)
+	var got []pidpool.AlarmEvent
+	a, err := pidpool.NewAlarms(pidpool.AlarmConfig{
+		Limits: []pidpool.AlarmLimit{
+			{Signal: pidpool.AlarmValue, Level: pidpool.AlarmHigh, Limit: 100, Deadband: 5, Delay: 2 * time.Second},
+			{Signal: pidpool.AlarmValue, Level: pidpool.AlarmLowLow, Limit: 0},
+			{Signal: pidpool.AlarmDeviation, Level: pidpool.AlarmHigh, Limit: 20},
+		},
+		OnAlarm: func(e pidpool.AlarmEvent) { got = append(got, e) },
+		Events:  events,
+	})
+	if err != nil {
+		t.Fatalf("NewAlarms err: %v", err)
+	}
+
+	t0 := time.Unix(0, 0)
+	obs := func(sec int, value float64) {
+		a.Observe(pidpool.UpdateEvent{Time: t0.Add(time.Duration(sec) * time.Second), SetPoint: 90, Value: value})
+	}
+
+	// a short excursion does not qualify.
+	obs(0, 101)
+	obs(1, 99)
+	obs(2, 101)
+	obs(3, 102)
+	if len(got) != 0 {
+		t.Fatalf("alarm raised before the delay: %+v", got)
+	}
+	obs(4, 102)
+	if len(got) != 1 || !got[0].Active || got[0].Limit.Level != pidpool.AlarmHigh {
+		t.Fatalf("expected high alarm, got %+v", got)
+	}
+	// inside the deadband the alarm stays active.
+	obs(5, 97)
+	if len(got) != 1 || len(a.Active()) != 1 {
+		t.Fatalf("alarm cleared inside the deadband")
+	}
+	obs(6, 94)
+	if len(got) != 2 || got[1].Active {
+		t.Fatalf("expected the high alarm to clear, got %+v", got)
+	}
+
+	// a low-low trip and a deviation alarm at once.
+	obs(7, -1)
+	if len(got) != 4 || len(a.Active()) != 2 {
+		t.Fatalf("expected low-low and deviation alarms, got %+v", got)
+	}
+	if got[3].Limit.Signal != pidpool.AlarmDeviation || got[3].Value != 91 {
+		t.Fatalf("unexpected deviation event %+v", got[3])
+	}
+	if len(events) != 1 || a.Dropped() != 3 {
+		t.Fatalf("expected 1 queued and 3 dropped events, got %d and %d", len(events), a.Dropped())
+	}
+
+	if _, err := pidpool.NewAlarms(pidpool.AlarmConfig{Limits: []pidpool.AlarmLimit{{Deadband: -1}}}); err == nil {
+		t.Fatalf("expected error for negative deadband")
+	}
+}
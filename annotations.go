@@ -0,0 +1,55 @@
+package pidpool
+
+import (
+	"errors"
+	"maps"
+)
+
+// SetAnnotation attaches a key/value annotation to the controller, e.g. its
+// location or asset ID. Annotations are carried through State and exported
+// by the telemetry and admin packages so fleet tooling can correlate loops
+// with physical assets. They have no effect on control.
+func (pid *PID) SetAnnotation(key, value string) error {
+	if key == "" {
+		return errors.New("annotation key must not be empty")
+	}
+	defer pid.notifyChange()
+	pid.mu.Lock()
+	defer pid.mu.Unlock()
+	if pid.annotations == nil {
+		pid.annotations = make(map[string]string)
+	}
+	pid.annotations[key] = value
+
+	return nil
+}
+
+// DeleteAnnotation removes the annotation with the given key.
+func (pid *PID) DeleteAnnotation(key string) {
+	defer pid.notifyChange()
+	pid.mu.Lock()
+	defer pid.mu.Unlock()
+	delete(pid.annotations, key)
+}
+
+// Annotation returns the annotation with the given key.
+func (pid *PID) Annotation(key string) (string, bool) {
+	pid.mu.Lock()
+	defer pid.mu.Unlock()
+	v, ok := pid.annotations[key]
+	return v, ok
+}
+
+// Annotations returns a copy of all annotations, or nil if there are none.
+func (pid *PID) Annotations() map[string]string {
+	pid.mu.Lock()
+	defer pid.mu.Unlock()
+	return cloneAnnotations(pid.annotations)
+}
+
+func cloneAnnotations(m map[string]string) map[string]string {
+	if len(m) == 0 {
+		return nil
+	}
+	return maps.Clone(m)
+}
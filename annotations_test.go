@@ -0,0 +1,36 @@
+package pidpool_test
+
+import (
+	"testing"
+
+	"github.com/ankur-anand/go-pidpool"
+)
+
+func TestAnnotations(t *testing.T) {
+	p := pidpool.NewPID(1, 0, 0, 0)
+	if err := p.SetAnnotation("", "x"); err == nil {
+		t.Fatalf("expected error for empty key")
+	}
+	if err := p.SetAnnotation("location", "hall B"); err != nil {
+		t.Fatalf("SetAnnotation err: %v", err)
+	}
+
+	a := p.Annotations()
+	a["location"] = "mutated"
+	if v, _ := p.Annotation("location"); v != "hall B" {
+		t.Fatalf("Annotations must return a copy, got %q", v)
+	}
+
+	q := pidpool.NewPID(0, 0, 0, 0)
+	if err := q.RestoreState(p.State()); err != nil {
+		t.Fatalf("RestoreState err: %v", err)
+	}
+	if v, ok := q.Annotation("location"); !ok || v != "hall B" {
+		t.Fatalf("annotation not carried through state, got %q", v)
+	}
+
+	p.DeleteAnnotation("location")
+	if p.Annotations() != nil {
+		t.Fatalf("expected no annotations, got %v", p.Annotations())
+	}
+}
@@ -0,0 +1,176 @@
+package pidpool
+
+import (
+	"encoding/json"
+	"errors"
+	"math"
+)
+
+// DirectionalParams are the gains and output limits used for one direction
+// of the error, see SetNegativeErrorParams.
+type DirectionalParams struct {
+	Kp float64
+	Ki float64
+	Kd float64
+
+	OutputMin float64
+	OutputMax float64
+}
+
+// Validate reports whether the parameters are well formed.
+func (p DirectionalParams) Validate() error {
+	for _, g := range []float64{p.Kp, p.Ki, p.Kd} {
+		if math.IsNaN(g) || math.IsInf(g, 0) {
+			return errors.New("gains must be finite")
+		}
+	}
+	if !(p.OutputMin <= p.OutputMax) {
+		return errors.New("min output greater than max output")
+	}
+	return nil
+}
+
+type directionalJSON struct {
+	Kp        float64  `json:"kp"`
+	Ki        float64  `json:"ki"`
+	Kd        float64  `json:"kd"`
+	OutputMin *float64 `json:"outputMin"`
+	OutputMax *float64 `json:"outputMax"`
+}
+
+// MarshalJSON implements json.Marshaler. Unbounded limits are encoded as
+// null.
+func (p DirectionalParams) MarshalJSON() ([]byte, error) {
+	return json.Marshal(directionalJSON{
+		Kp:        p.Kp,
+		Ki:        p.Ki,
+		Kd:        p.Kd,
+		OutputMin: limitToJSON(p.OutputMin),
+		OutputMax: limitToJSON(p.OutputMax),
+	})
+}
+
+// UnmarshalJSON implements json.Unmarshaler.
+func (p *DirectionalParams) UnmarshalJSON(data []byte) error {
+	var js directionalJSON
+	if err := json.Unmarshal(data, &js); err != nil {
+		return err
+	}
+	*p = DirectionalParams{
+		Kp:        js.Kp,
+		Ki:        js.Ki,
+		Kd:        js.Kd,
+		OutputMin: limitFromJSON(js.OutputMin, math.Inf(-1)),
+		OutputMax: limitFromJSON(js.OutputMax, math.Inf(1)),
+	}
+	return nil
+}
+
+// SetNegativeErrorParams makes the controller use p while the error is
+// negative, that is while the value is above the setpoint, and its regular
+// gains and output limits while the error is positive. A process that
+// heats fast but cools slowly can so be driven harder in one direction
+// than in the other.
+//
+// The switch is bumpless: the proportional term is zero where the error
+// changes sign, and the integral is rescaled by the ratio of the integral
+// gains so the integral term carries over unchanged. Only a change of kd
+// while the measurement is moving, or a switch to a zero integral gain,
+// can still step the output. An error of exactly zero keeps the current
+// direction. Integral term limits set via SetIntegralTermLimits apply to
+// both directions; raw limits set via SetIntegralLimits bound the
+// accumulator, so the integral term they allow differs per direction.
+func (pid *PID) SetNegativeErrorParams(p DirectionalParams) error {
+	if err := p.Validate(); err != nil {
+		return err
+	}
+	defer pid.notifyChange()
+	pid.mu.Lock()
+	defer pid.mu.Unlock()
+	pid.negative = &p
+
+	return nil
+}
+
+// ClearNegativeErrorParams returns the controller to the same gains and
+// output limits in both directions.
+func (pid *PID) ClearNegativeErrorParams() {
+	defer pid.notifyChange()
+	pid.mu.Lock()
+	defer pid.mu.Unlock()
+	if pid.negativeActive {
+		pid.rescaleIntegralLocked(pid.negative.Ki, pid.ki)
+	}
+	pid.negative = nil
+	pid.negativeActive = false
+}
+
+// GetNegativeErrorParams returns the parameters used for a negative error.
+// The boolean is false when none are set.
+func (pid *PID) GetNegativeErrorParams() (DirectionalParams, bool) {
+	pid.mu.Lock()
+	defer pid.mu.Unlock()
+	if pid.negative == nil {
+		return DirectionalParams{}, false
+	}
+	return *pid.negative, true
+}
+
+func cloneDirectional(p *DirectionalParams) *DirectionalParams {
+	if p == nil {
+		return nil
+	}
+	c := *p
+	return &c
+}
+
+// gainSet is the set of parameters in effect for one update.
+type gainSet struct {
+	kp, ki, kd               float64
+	outputMin, outputMax     float64
+	integralMin, integralMax float64
+}
+
+// gainsLocked returns the parameters for err, switching direction first
+// when err changed sign.
+func (pid *PID) gainsLocked(err float64) gainSet {
+	if pid.negative != nil {
+		if err < 0 && !pid.negativeActive {
+			pid.rescaleIntegralLocked(pid.ki, pid.negative.Ki)
+			pid.negativeActive = true
+		} else if err > 0 && pid.negativeActive {
+			pid.rescaleIntegralLocked(pid.negative.Ki, pid.ki)
+			pid.negativeActive = false
+		}
+	}
+	return pid.activeGainsLocked()
+}
+
+// activeGainsLocked returns the parameters of the current direction.
+func (pid *PID) activeGainsLocked() gainSet {
+	scale := pid.gainScaleLocked()
+	g := gainSet{
+		kp: pid.kp * scale, ki: pid.ki * scale, kd: pid.kd * scale,
+		outputMin: pid.outputMin, outputMax: pid.outputMax,
+		integralMin: pid.integralMin, integralMax: pid.integralMax,
+	}
+	if !pid.negativeActive {
+		return g
+	}
+
+	n := pid.negative
+	g.kp, g.ki, g.kd = n.Kp*scale, n.Ki*scale, n.Kd*scale
+	g.outputMin, g.outputMax = n.OutputMin, n.OutputMax
+	if pid.termLimits && n.Ki != 0 {
+		g.integralMin, g.integralMax = termToAccumulator(pid.termMin, pid.termMax, g.ki)
+	}
+	return g
+}
+
+// rescaleIntegralLocked keeps the integral term unchanged across a switch
+// of the integral gain from ki to next.
+func (pid *PID) rescaleIntegralLocked(ki, next float64) {
+	if ki != 0 && next != 0 {
+		pid.integral = float64(pid.integral * ki / next)
+	}
+}
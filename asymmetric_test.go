@@ -0,0 +1,72 @@
+package pidpool_test
+
+import (
+	"encoding/json"
+	"math"
+	"reflect"
+	"testing"
+
+	"github.com/ankur-anand/go-pidpool"
+)
+
+func TestNegativeErrorParams(t *testing.T) {
+	pid := pidpool.NewPI(2, 1)
+	pid.SetOutputLimits(0, 100)
+	pid.SetIntegralTermLimits(-200, 200)
+	pid.SetSetPoint(50)
+	if err := pid.SetNegativeErrorParams(pidpool.DirectionalParams{Kp: 8, Ki: 4, OutputMin: -100, OutputMax: 100}); err != nil {
+		t.Fatalf("SetNegativeErrorParams err: %v", err)
+	}
+
+	// positive error: regular gains.
+	if out := pid.UpdateDuration(40, 1); out != 2*10+1*10 {
+		t.Fatalf("expected 30, got %v", out)
+	}
+	// crossing exactly onto the setpoint keeps the I term.
+	if out := pid.UpdateDuration(50, 1); out != 10 {
+		t.Fatalf("expected 10, got %v", out)
+	}
+	// negative error: the I term carries over and the new gains apply.
+	out := pid.UpdateDuration(51, 1)
+	if want := 10 + 8*-1.0 + 4*-1.0; math.Abs(out-want) > 1e-9 {
+		t.Fatalf("expected %v, got %v", want, out)
+	}
+	// and the negative output limits.
+	if out := pid.UpdateDuration(80, 1); out >= 0 {
+		t.Fatalf("expected negative output, got %v", out)
+	}
+
+	st := pid.State()
+	if !st.NegativeErrorActive || st.NegativeError == nil || st.NegativeError.Kp != 8 {
+		t.Fatalf("unexpected state %+v", st)
+	}
+	b, err := json.Marshal(st)
+	if err != nil {
+		t.Fatalf("marshal err: %v", err)
+	}
+	var got pidpool.State
+	if err := json.Unmarshal(b, &got); err != nil {
+		t.Fatalf("unmarshal err: %v", err)
+	}
+	if !reflect.DeepEqual(got.NegativeError, st.NegativeError) || !got.NegativeErrorActive {
+		t.Fatalf("round trip lost the negative error params: %s", b)
+	}
+
+	// back to a positive error: the I term is still continuous.
+	iTerm := 4 * pid.State().Integral
+	var ev pidpool.UpdateEvent
+	remove := pid.OnUpdate(func(e pidpool.UpdateEvent) { ev = e })
+	pid.UpdateDuration(49, 0)
+	remove()
+	if math.Abs(ev.I-iTerm) > 1e-9 || ev.P != 2 {
+		t.Fatalf("expected I %v and P 2, got %+v", iTerm, ev)
+	}
+
+	if err := pid.SetNegativeErrorParams(pidpool.DirectionalParams{OutputMin: 1, OutputMax: 0}); err == nil {
+		t.Fatalf("expected error for inverted limits")
+	}
+	pid.ClearNegativeErrorParams()
+	if _, ok := pid.GetNegativeErrorParams(); ok {
+		t.Fatalf("expected params cleared")
+	}
+}
@@ -0,0 +1,163 @@
+package pidpool
+
+import (
+	"context"
+	"errors"
+	"math"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// BatcherConfig configures a Batcher.
+type BatcherConfig[T any] struct {
+	// PID computes the batch size from the latency of each batch, in
+	// seconds, from its first item to the end of its flush. Time spent
+	// queued behind an earlier flush is not counted: it depends on
+	// throughput, which larger batches raise. A latency above Target should
+	// shrink the batches, so the gains are positive. The batcher governs
+	// it over [MinSize, MaxSize], see PID.Govern.
+	PID    *PID
+	Target time.Duration
+
+	MinSize int
+	MaxSize int
+	// MaxWait flushes a partial batch this long after its first item.
+	// Defaults to Target.
+	MaxWait time.Duration
+
+	// Flush writes a batch. Batches are flushed one at a time, in order.
+	Flush func(batch []T) error
+	// OnError is called with the errors returned by Flush.
+	OnError func(error)
+}
+
+// ErrBatcherClosed is returned by Add after Close.
+var ErrBatcherClosed = errors.New("batcher closed")
+
+// Batcher groups items into batches whose size is adjusted by a PID loop
+// after every flush, holding the batch latency at a target: batches grow
+// while latency allows, maximizing throughput, and shrink when it does not.
+type Batcher[T any] struct {
+	cfg BatcherConfig[T]
+	in  chan T
+
+	mu     sync.RWMutex
+	closed bool
+	// size is kept apart from mu, which Add holds while it blocks.
+	size atomic.Int64
+
+	done chan struct{}
+}
+
+// NewBatcher returns a batcher starting at MinSize and starts its flush
+// goroutine.
+func NewBatcher[T any](cfg BatcherConfig[T]) (*Batcher[T], error) {
+	if cfg.PID == nil || cfg.Flush == nil {
+		return nil, errors.New("pid and flush are required")
+	}
+	if cfg.MinSize < 1 || cfg.MaxSize < cfg.MinSize {
+		return nil, errors.New("sizes must satisfy 1 <= min <= max")
+	}
+	if cfg.Target <= 0 || cfg.MaxWait < 0 {
+		return nil, errors.New("target must be positive and max wait not negative")
+	}
+	if cfg.MaxWait == 0 {
+		cfg.MaxWait = cfg.Target
+	}
+	if err := cfg.PID.Govern(cfg.Target.Seconds(), float64(cfg.MinSize), float64(cfg.MaxSize)); err != nil {
+		return nil, err
+	}
+
+	b := &Batcher[T]{
+		cfg:  cfg,
+		in:   make(chan T, cfg.MaxSize),
+		done: make(chan struct{}),
+	}
+	b.size.Store(int64(cfg.MinSize))
+	go b.loop()
+
+	return b, nil
+}
+
+// Add queues an item, blocking while a full batch is waiting to be
+// flushed.
+func (b *Batcher[T]) Add(ctx context.Context, v T) error {
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+	if b.closed {
+		return ErrBatcherClosed
+	}
+	select {
+	case b.in <- v:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// Size returns the current batch size.
+func (b *Batcher[T]) Size() int {
+	return int(b.size.Load())
+}
+
+// Close stops accepting items, flushes what is queued and waits for the
+// last flush to finish.
+func (b *Batcher[T]) Close() {
+	b.mu.Lock()
+	if !b.closed {
+		b.closed = true
+		close(b.in)
+	}
+	b.mu.Unlock()
+	<-b.done
+}
+
+func (b *Batcher[T]) loop() {
+	defer close(b.done)
+	var (
+		batch   []T
+		started time.Time
+		timer   = time.NewTimer(0)
+		waiting <-chan time.Time
+	)
+	<-timer.C
+	last := time.Now()
+
+	flush := func() {
+		if err := b.cfg.Flush(batch); err != nil && b.cfg.OnError != nil {
+			b.cfg.OnError(err)
+		}
+		now := time.Now()
+		out := b.cfg.PID.UpdateDuration(now.Sub(started).Seconds(), now.Sub(last).Seconds())
+		last = now
+
+		b.size.Store(int64(math.Round(out)))
+		batch, waiting = nil, nil
+		timer.Stop()
+	}
+
+	for {
+		select {
+		case v, ok := <-b.in:
+			if !ok {
+				if len(batch) > 0 {
+					flush()
+				}
+				return
+			}
+			if len(batch) == 0 {
+				started = time.Now()
+				timer.Reset(b.cfg.MaxWait)
+				waiting = timer.C
+			}
+			batch = append(batch, v)
+			if len(batch) >= b.Size() {
+				flush()
+			}
+		case <-waiting:
+			waiting = nil
+			flush()
+		}
+	}
+}
@@ -0,0 +1,50 @@
+package pidpool_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/ankur-anand/go-pidpool"
+)
+
+func TestBatcher_GrowsWithinLatencyTarget(t *testing.T) {
+	var got []int
+	b, err := pidpool.NewBatcher(pidpool.BatcherConfig[int]{
+		PID:     pidpool.NewPI(2000, 20000),
+		Target:  20 * time.Millisecond,
+		MinSize: 1,
+		MaxSize: 500,
+		Flush: func(batch []int) error {
+			// a fixed cost per write makes small batches expensive.
+			time.Sleep(time.Millisecond + time.Duration(len(batch))*10*time.Microsecond)
+			got = append(got, batch...)
+			return nil
+		},
+	})
+	if err != nil {
+		t.Fatalf("NewBatcher err: %v", err)
+	}
+
+	for i := 0; i < 5000; i++ {
+		if err := b.Add(context.Background(), i); err != nil {
+			t.Fatalf("Add err: %v", err)
+		}
+	}
+	if n := b.Size(); n <= 10 {
+		t.Fatalf("batch size did not grow: %d", n)
+	}
+	b.Close()
+
+	if len(got) != 5000 {
+		t.Fatalf("expected 5000 items flushed, got %d", len(got))
+	}
+	for i, v := range got {
+		if v != i {
+			t.Fatalf("item %d out of order: %d", i, v)
+		}
+	}
+	if err := b.Add(context.Background(), 0); err != pidpool.ErrBatcherClosed {
+		t.Fatalf("expected ErrBatcherClosed, got %v", err)
+	}
+}
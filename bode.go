@@ -0,0 +1,115 @@
+package pidpool
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"io"
+	"math"
+	"math/cmplx"
+	"strconv"
+	"time"
+)
+
+// FrequencyPoint is the response of a transfer function at one frequency.
+type FrequencyPoint struct {
+	// Freq is in rad/s.
+	Freq        float64 `json:"freq"`
+	Magnitude   float64 `json:"magnitude"`
+	MagnitudeDB float64 `json:"magnitudeDB"`
+	// Phase is in degrees, unwrapped so it is continuous over frequency.
+	Phase float64 `json:"phase"`
+}
+
+// FrequencyResponse returns the Bode data of tf at points frequencies
+// spaced logarithmically from min to max rad/s.
+func (tf TransferFunction) FrequencyResponse(min, max float64, points int) ([]FrequencyPoint, error) {
+	if err := tf.Validate(); err != nil {
+		return nil, err
+	}
+	if !(min > 0) || !(max > min) || math.IsInf(max, 0) {
+		return nil, errors.New("frequency range must be positive and increasing")
+	}
+	if points < 2 {
+		return nil, errors.New("at least two points required")
+	}
+
+	r := phaseTracker{tf: tf}
+	out := make([]FrequencyPoint, points)
+	w, phase := min, r.startPhase(min)
+	step := math.Pow(10, 1.0/marginPerDecade)
+	for i := range out {
+		target := min * math.Pow(max/min, float64(i)/float64(points-1))
+		// unwrap through the dense scan between the requested points.
+		for w*step < target {
+			next := w * step
+			phase = r.phase(next, w, phase)
+			w = next
+		}
+		phase = r.phase(target, w, phase)
+		w = target
+
+		mag := cmplx.Abs(tf.Eval(target))
+		out[i] = FrequencyPoint{
+			Freq:        target,
+			Magnitude:   mag,
+			MagnitudeDB: 20 * math.Log10(mag),
+			Phase:       phase * 180 / math.Pi,
+		}
+	}
+
+	return out, nil
+}
+
+// Bode is the frequency response of a controller alone and in series with
+// a plant, sampled at the same frequencies.
+type Bode struct {
+	Controller []FrequencyPoint `json:"controller"`
+	Loop       []FrequencyPoint `json:"loop"`
+}
+
+// NewBode returns the Bode data of the controller with gains g and
+// derivative filter time constant filter, and of the open loop with the
+// plant, at points frequencies from min to max rad/s.
+func NewBode(g Gains, filter time.Duration, plant TransferFunction, min, max float64, points int) (Bode, error) {
+	if err := g.Validate(); err != nil {
+		return Bode{}, err
+	}
+	c := g.TransferFunction(filter)
+	ctrl, err := c.FrequencyResponse(min, max, points)
+	if err != nil {
+		return Bode{}, err
+	}
+	loop, err := c.Mul(plant).FrequencyResponse(min, max, points)
+	if err != nil {
+		return Bode{}, err
+	}
+	return Bode{Controller: ctrl, Loop: loop}, nil
+}
+
+var bodeHeader = []string{
+	"freq", "controller_magnitude_db", "controller_phase", "loop_magnitude_db", "loop_phase",
+}
+
+// WriteCSV writes the data as CSV with a header, one row per frequency.
+func (b Bode) WriteCSV(w io.Writer) error {
+	cw := csv.NewWriter(w)
+	if err := cw.Write(bodeHeader); err != nil {
+		return err
+	}
+	f := func(v float64) string { return strconv.FormatFloat(v, 'g', -1, 64) }
+	for i, c := range b.Controller {
+		l := b.Loop[i]
+		if err := cw.Write([]string{f(c.Freq), f(c.MagnitudeDB), f(c.Phase), f(l.MagnitudeDB), f(l.Phase)}); err != nil {
+			return err
+		}
+	}
+	cw.Flush()
+
+	return cw.Error()
+}
+
+// WriteJSON writes the data as a JSON object.
+func (b Bode) WriteJSON(w io.Writer) error {
+	return json.NewEncoder(w).Encode(b)
+}
@@ -0,0 +1,66 @@
+package pidpool_test
+
+import (
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"math"
+	"testing"
+
+	"github.com/ankur-anand/go-pidpool"
+)
+
+func TestFrequencyResponse_UnwrapsDeadTime(t *testing.T) {
+	tf := pidpool.FOPDT{Gain: 1, TimeConstant: 1, DeadTime: 1}.TransferFunction()
+	pts, err := tf.FrequencyResponse(0.01, 100, 5)
+	if err != nil {
+		t.Fatalf("FrequencyResponse err: %v", err)
+	}
+	if len(pts) != 5 || pts[0].Freq != 0.01 || math.Abs(pts[4].Freq-100) > 1e-9 {
+		t.Fatalf("unexpected frequencies: %+v", pts)
+	}
+	// at 100 rad/s the lag is atan(100) plus 100 rad of dead time.
+	want := -(math.Atan(100) + 100) * 180 / math.Pi
+	if math.Abs(pts[4].Phase-want) > 1e-6 {
+		t.Fatalf("expected the unwrapped phase %v, got %v", want, pts[4].Phase)
+	}
+	if math.Abs(pts[2].MagnitudeDB-20*math.Log10(1/math.Sqrt2)) > 1e-9 {
+		t.Fatalf("unexpected magnitude at the corner frequency: %+v", pts[2])
+	}
+	if _, err := tf.FrequencyResponse(1, 1, 10); err == nil {
+		t.Fatalf("expected error for an empty range")
+	}
+}
+
+func TestBode_Export(t *testing.T) {
+	plant := pidpool.FOPDT{Gain: 2, TimeConstant: 30, DeadTime: 5}.TransferFunction()
+	b, err := pidpool.NewBode(pidpool.Gains{Kp: 1.5, Ki: 0.05}, 0, plant, 0.001, 10, 50)
+	if err != nil {
+		t.Fatalf("NewBode err: %v", err)
+	}
+	// the PI controller is an integrator at low frequency.
+	if p := b.Controller[0].Phase; math.Abs(p+90) > 2 {
+		t.Fatalf("expected about -90° at low frequency, got %v", p)
+	}
+	if math.Abs(b.Loop[10].MagnitudeDB-b.Controller[10].MagnitudeDB-20*math.Log10(2/math.Hypot(1, 30*b.Loop[10].Freq))) > 1e-9 {
+		t.Fatalf("loop magnitude is not controller times plant")
+	}
+
+	var buf bytes.Buffer
+	if err := b.WriteCSV(&buf); err != nil {
+		t.Fatalf("WriteCSV err: %v", err)
+	}
+	rows, err := csv.NewReader(&buf).ReadAll()
+	if err != nil || len(rows) != 51 || rows[0][0] != "freq" {
+		t.Fatalf("unexpected CSV: %d rows, %v", len(rows), err)
+	}
+
+	buf.Reset()
+	if err := b.WriteJSON(&buf); err != nil {
+		t.Fatalf("WriteJSON err: %v", err)
+	}
+	var decoded pidpool.Bode
+	if err := json.Unmarshal(buf.Bytes(), &decoded); err != nil || len(decoded.Loop) != 50 {
+		t.Fatalf("unexpected JSON: %v", err)
+	}
+}
@@ -0,0 +1,127 @@
+package pidpool
+
+import (
+	"context"
+	"errors"
+	"math"
+)
+
+// Calibration maps raw sensor readings to measurement units as
+// Scale*raw + Offset. A zero Scale means 1, so the zero value passes
+// readings through unchanged.
+type Calibration struct {
+	Scale  float64 `json:"scale,omitempty"`
+	Offset float64 `json:"offset,omitempty"`
+}
+
+func (c Calibration) scale() float64 {
+	if c.Scale == 0 {
+		return 1
+	}
+	return c.Scale
+}
+
+// Apply returns the calibrated value of raw.
+func (c Calibration) Apply(raw float64) float64 {
+	return float64(c.scale()*raw) + c.Offset
+}
+
+// Validate reports whether the calibration is usable.
+func (c Calibration) Validate() error {
+	if math.IsNaN(c.Scale) || math.IsInf(c.Scale, 0) || math.IsNaN(c.Offset) || math.IsInf(c.Offset, 0) {
+		return errors.New("calibration must be finite")
+	}
+	return nil
+}
+
+// GetCalibration returns the calibration applied to measurements.
+func (pid *PID) GetCalibration() Calibration {
+	pid.mu.Lock()
+	defer pid.mu.Unlock()
+	return pid.calibration
+}
+
+// Recalibrate replaces the calibration applied to measurements, e.g. after
+// a sensor offset update, without the loop seeing a step disturbance.
+//
+// The previous measurement, the derivative filter state and the
+// measurement filter state are converted to the new calibration, so neither
+// the derivative nor the filter reacts to the change. In Auto mode the integral absorbs the change of the proportional
+// term, so the output is continuous and the corrected error is worked off
+// by the integral action. The noise estimate restarts.
+func (pid *PID) Recalibrate(c Calibration) error {
+	if err := c.Validate(); err != nil {
+		return err
+	}
+	defer pid.notifyChange()
+	pid.mu.Lock()
+	defer pid.mu.Unlock()
+
+	// a value x in the old units becomes k*x + b in the new ones.
+	old := pid.calibration
+	k := c.scale() / old.scale()
+	b := c.Offset - float64(k*old.Offset)
+	remap := func(x float64) float64 { return float64(k*x) + b }
+
+	shift := remap(pid.prevValue) - pid.prevValue
+	pid.prevValue = remap(pid.prevValue)
+	// the filtered derivative is a rate, which only the scale changes.
+	pid.derivativeState *= k
+	h := pid.filterHistory
+	switch pid.filter.Kind {
+	case FilterEMA, FilterSMA:
+		for i := range h {
+			h[i] = remap(h[i])
+		}
+	case FilterAlphaBeta, FilterKalman:
+		if len(h) > 0 {
+			h[0] = remap(h[0])
+			h[1] *= k
+		}
+		for i := 2; i < len(h); i++ {
+			// covariances.
+			h[i] *= float64(k * k)
+		}
+	}
+	if pid.noise != nil {
+		pid.noise.Reset()
+	}
+
+	// the error moves by -shift, so does the P term by -kp*shift.
+	if g := pid.activeGainsLocked(); pid.mode == Auto && g.ki != 0 {
+		pid.integral += float64(g.kp*shift) / g.ki
+		pid.integral = math.Max(g.integralMin, math.Min(g.integralMax, pid.integral))
+	}
+	pid.calibration = c
+
+	return nil
+}
+
+// Calibrator reports the current sensor calibration, e.g. from a
+// calibration database or a transmitter's configuration registers.
+type Calibrator interface {
+	Calibration(ctx context.Context) (Calibration, error)
+}
+
+// SetCalibrator makes the runner poll c before every tick and recalibrate
+// the controller with PID.Recalibrate when the calibration changes. A
+// failing Calibrator leaves the calibration unchanged. Nil disables polling.
+func (r *Runner) SetCalibrator(c Calibrator) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.calibrator = c
+}
+
+func (r *Runner) recalibrate(ctx context.Context) {
+	r.mu.Lock()
+	c := r.calibrator
+	r.mu.Unlock()
+	if c == nil {
+		return
+	}
+	cal, err := c.Calibration(ctx)
+	if err != nil || cal == r.pid.GetCalibration() {
+		return
+	}
+	_ = r.pid.Recalibrate(cal)
+}
@@ -0,0 +1,92 @@
+package pidpool_test
+
+import (
+	"context"
+	"math"
+	"testing"
+	"time"
+
+	"github.com/ankur-anand/go-pidpool"
+)
+
+func TestRecalibrate_Bumpless(t *testing.T) {
+	p := pidpool.NewPID(2, 0.5, 1, 0)
+	p.SetSetPoint(50)
+	if err := p.SetMeasurementFilter(pidpool.MeasurementFilter{Kind: pidpool.FilterEMA, Alpha: 0.5}); err != nil {
+		t.Fatalf("SetMeasurementFilter err: %v", err)
+	}
+	// the sensor has drifted: it reads 3 high, i.e. the raw value 43 is
+	// really 40.
+	for i := 0; i < 20; i++ {
+		p.UpdateDuration(43, 0.1)
+	}
+	before := p.UpdateDuration(43, 0.1)
+
+	if err := p.Recalibrate(pidpool.Calibration{Offset: -3}); err != nil {
+		t.Fatalf("Recalibrate err: %v", err)
+	}
+	if p.GetCalibration().Offset != -3 || p.State().Calibration.Offset != -3 {
+		t.Fatalf("calibration not stored")
+	}
+	after := p.UpdateDuration(43, 0.1)
+	if math.Abs(after-before) > 1 { // a P kick would be 6.
+		t.Fatalf("recalibration bumped the output: %v -> %v", before, after)
+	}
+	if v := p.State().PrevValue; v != 40 {
+		t.Fatalf("calibration not applied: %v", v)
+	}
+
+	if err := p.Recalibrate(pidpool.Calibration{Scale: math.NaN()}); err == nil {
+		t.Fatalf("expected error for NaN scale")
+	}
+}
+
+func TestRecalibrate_FilteredDerivative(t *testing.T) {
+	// a controller recalibrated mid-ramp must continue exactly like one
+	// that had the new calibration from the start.
+	d := pidpool.Discretization{DerivativeFilter: time.Second}
+	p, ref := pidpool.NewPID(0, 0, 1, 0), pidpool.NewPID(0, 0, 1, 0)
+	for _, c := range []*pidpool.PID{p, ref} {
+		if err := c.SetDiscretization(d); err != nil {
+			t.Fatalf("SetDiscretization err: %v", err)
+		}
+	}
+	if err := ref.Recalibrate(pidpool.Calibration{Scale: 2}); err != nil {
+		t.Fatalf("Recalibrate err: %v", err)
+	}
+	for i := 0; i < 5; i++ {
+		p.UpdateDuration(float64(i), 0.1)
+		ref.UpdateDuration(float64(i), 0.1)
+	}
+
+	if err := p.Recalibrate(pidpool.Calibration{Scale: 2}); err != nil {
+		t.Fatalf("Recalibrate err: %v", err)
+	}
+	got, want := p.UpdateDuration(5, 0.1), ref.UpdateDuration(5, 0.1)
+	if math.Abs(got-want) > 1e-9 {
+		t.Fatalf("derivative bumped on recalibration: got %v, want %v", got, want)
+	}
+}
+
+type fixedCalibrator pidpool.Calibration
+
+func (c fixedCalibrator) Calibration(context.Context) (pidpool.Calibration, error) {
+	return pidpool.Calibration(c), nil
+}
+
+func TestRunner_Calibrator(t *testing.T) {
+	p := pidpool.NewP(1)
+	src := pidpool.SourceFunc(func(context.Context) (float64, error) { return 10, nil })
+	sink := pidpool.SinkFunc(func(context.Context, float64) error { return nil })
+	r := pidpool.NewRunner(p, time.Millisecond, src, sink)
+	r.SetCalibrator(fixedCalibrator{Scale: 2, Offset: 1})
+	if err := r.RunOnce(context.Background()); err != nil {
+		t.Fatalf("RunOnce err: %v", err)
+	}
+	if c := p.GetCalibration(); c.Scale != 2 || c.Offset != 1 {
+		t.Fatalf("calibration not polled: %+v", c)
+	}
+	if out := p.LastOutput(); out != -21 {
+		t.Fatalf("unexpected output %v", out)
+	}
+}
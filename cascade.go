@@ -0,0 +1,189 @@
+package pidpool
+
+import (
+	"errors"
+	"sync"
+	"time"
+)
+
+// CascadeEvent describes one update of a Cascade.
+type CascadeEvent struct {
+	// Outer is the most recent update of the outer loop. Between outer
+	// updates it is repeated unchanged.
+	Outer UpdateEvent
+	// Inner is the update of the inner loop.
+	Inner UpdateEvent
+	// OuterUpdated reports whether the outer loop ran in this update.
+	OuterUpdated bool
+}
+
+// Cascade nests two loops: the output of the outer loop, e.g. a
+// temperature controller, is the setpoint of the inner loop, e.g. a flow
+// controller driving the valve. The inner loop usually runs several times
+// faster than the outer one; every update runs the inner loop and the
+// outer loop runs once per outer period, with dt the time elapsed since
+// its previous run. In between, its output stays latched as the inner
+// setpoint.
+//
+// While the inner loop is saturated, moving its setpoint further does not
+// move the process, so the outer integral is held until the inner output
+// leaves its limits.
+type Cascade struct {
+	// stepMu serializes steps; mu guards the fields below and is never
+	// held while a loop steps, so hooks may call back into the Cascade.
+	stepMu sync.Mutex
+	mu     sync.Mutex
+
+	outer, inner *PID
+	period       float64
+
+	elapsed    float64
+	started    bool
+	lastUpdate time.Time
+	last       CascadeEvent
+
+	hooks []*func(CascadeEvent)
+}
+
+// NewCascade returns a cascade of outer driving the setpoint of inner. The
+// outer loop runs once per outerPeriod; zero runs it on every update.
+// Bound the inner setpoint with the output limits of outer.
+func NewCascade(outer, inner *PID, outerPeriod time.Duration) (*Cascade, error) {
+	if outer == nil || inner == nil {
+		return nil, errors.New("outer and inner loops are required")
+	}
+	if outer == inner {
+		return nil, errors.New("outer and inner loops must differ")
+	}
+	if outerPeriod < 0 {
+		return nil, errors.New("outer period must not be negative")
+	}
+	return &Cascade{
+		outer:      outer,
+		inner:      inner,
+		period:     outerPeriod.Seconds(),
+		lastUpdate: time.Now(),
+	}, nil
+}
+
+// Outer returns the outer loop.
+func (c *Cascade) Outer() *PID {
+	return c.outer
+}
+
+// Inner returns the inner loop.
+func (c *Cascade) Inner() *PID {
+	return c.inner
+}
+
+// SetSetPoint sets the setpoint of the outer loop.
+func (c *Cascade) SetSetPoint(val float64) {
+	c.outer.SetSetPoint(val)
+}
+
+// Update runs one inner step for the measured values of both loops and
+// returns the inner output. Uses wall time for dt.
+func (c *Cascade) Update(outerValue, innerValue float64) float64 {
+	c.mu.Lock()
+	now := time.Now()
+	dt := now.Sub(c.lastUpdate).Seconds()
+	c.lastUpdate = now
+	c.mu.Unlock()
+
+	return c.Step(outerValue, innerValue, dt).Inner.Output
+}
+
+// UpdateDuration allows custom duration between inner updates.
+func (c *Cascade) UpdateDuration(outerValue, innerValue, dt float64) float64 {
+	return c.Step(outerValue, innerValue, dt).Inner.Output
+}
+
+// Step runs one inner step dt seconds after the previous one and reports
+// both loops. The outer loop runs first on the first step and then
+// whenever the time accumulated since its last run reaches the outer
+// period.
+func (c *Cascade) Step(outerValue, innerValue, dt float64) CascadeEvent {
+	c.stepMu.Lock()
+	c.mu.Lock()
+	c.elapsed += dt
+	elapsed := c.elapsed
+	// tolerate the rounding of summed inner steps.
+	due := !c.started || elapsed >= c.period*(1-1e-9)
+	ev := CascadeEvent{Outer: c.last.Outer, OuterUpdated: due}
+	hold := c.last.Inner.Status().Saturated
+	c.mu.Unlock()
+
+	if due {
+		ev.Outer = c.outer.step(func() UpdateEvent {
+			c.outer.holdIntegral = hold
+			e := c.outer.updateInternal(outerValue, elapsed)
+			c.outer.holdIntegral = false
+			e.Time = time.Now()
+			return e
+		})
+		c.inner.SetSetPoint(ev.Outer.Output)
+	}
+	ev.Inner = c.inner.step(func() UpdateEvent {
+		e := c.inner.updateInternal(innerValue, dt)
+		e.Time = time.Now()
+		return e
+	})
+
+	c.mu.Lock()
+	if due {
+		c.elapsed = 0
+		c.started = true
+	}
+	c.last = ev
+	hooks := c.hooks
+	c.mu.Unlock()
+	c.stepMu.Unlock()
+
+	for _, fn := range hooks {
+		(*fn)(ev)
+	}
+	return ev
+}
+
+// Last returns the most recent update of the cascade.
+func (c *Cascade) Last() CascadeEvent {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.last
+}
+
+// OnUpdate registers fn to be called after every update with both loops'
+// events. The returned function removes it.
+func (c *Cascade) OnUpdate(fn func(CascadeEvent)) (remove func()) {
+	h := &fn
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.hooks = append(c.hooks[:len(c.hooks):len(c.hooks)], h)
+
+	return func() {
+		c.mu.Lock()
+		defer c.mu.Unlock()
+		// copy on write, so in-flight notifications keep their own slice.
+		hooks := make([]*func(CascadeEvent), 0, len(c.hooks))
+		for _, cur := range c.hooks {
+			if cur != h {
+				hooks = append(hooks, cur)
+			}
+		}
+		c.hooks = hooks
+	}
+}
+
+// Reset resets both loops; the outer loop runs again on the next update.
+func (c *Cascade) Reset() {
+	c.stepMu.Lock()
+	defer c.stepMu.Unlock()
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.outer.Reset()
+	c.inner.Reset()
+	c.elapsed = 0
+	c.started = false
+	c.lastUpdate = time.Now()
+	c.last = CascadeEvent{}
+}
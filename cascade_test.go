@@ -0,0 +1,119 @@
+package pidpool_test
+
+import (
+	"math"
+	"testing"
+	"time"
+
+	"github.com/ankur-anand/go-pidpool"
+)
+
+func TestCascade_MultiRate(t *testing.T) {
+	outer := pidpool.NewPI(2, 0.5)
+	outer.SetOutputLimits(0, 10)
+	inner := pidpool.NewPI(1, 5)
+	inner.SetOutputLimits(0, 100)
+	c, err := pidpool.NewCascade(outer, inner, 500*time.Millisecond)
+	if err != nil {
+		t.Fatalf("NewCascade err: %v", err)
+	}
+	c.SetSetPoint(50)
+
+	var outerRuns int
+	remove := c.OnUpdate(func(ev pidpool.CascadeEvent) {
+		if ev.OuterUpdated {
+			outerRuns++
+		}
+	})
+
+	// the inner loop runs every 100ms, the outer one every 500ms.
+	temp, flow, valve := 20.0, 0.0, 0.0
+	for i := 0; i < 3000; i++ {
+		flow += (valve/10 - flow) * 0.1 / 0.5
+		temp += (2*flow - 0.1*(temp-20)) * 0.1 / 10
+		ev := c.Step(temp, flow, 0.1)
+		valve = ev.Inner.Output
+
+		if ev.OuterUpdated != (i%5 == 0) {
+			t.Fatalf("step %d: outer updated %v", i, ev.OuterUpdated)
+		}
+		if i > 0 && ev.OuterUpdated && math.Abs(ev.Outer.DT-0.5) > 1e-9 {
+			t.Fatalf("step %d: expected the outer dt to span 5 inner steps, got %v", i, ev.Outer.DT)
+		}
+		if ev.Inner.DT != 0.1 {
+			t.Fatalf("step %d: inner dt %v", i, ev.Inner.DT)
+		}
+		if ev.Inner.SetPoint != ev.Outer.Output {
+			t.Fatalf("step %d: inner setpoint %v does not follow the latched outer output %v", i, ev.Inner.SetPoint, ev.Outer.Output)
+		}
+	}
+	remove()
+
+	if outerRuns != 600 {
+		t.Fatalf("expected 600 outer runs, got %d", outerRuns)
+	}
+	if math.Abs(temp-50) > 0.5 {
+		t.Fatalf("cascade did not settle at the setpoint: %v", temp)
+	}
+	last := c.Last()
+	if last.Inner.Output != valve {
+		t.Fatalf("Last does not report the final update")
+	}
+
+	c.Step(temp, flow, 0.1)
+	if outerRuns != 600 {
+		t.Fatalf("removed hook still called")
+	}
+
+	c.Reset()
+	if ev := c.Step(temp, flow, 0.1); !ev.OuterUpdated {
+		t.Fatalf("expected the outer loop to run after a reset")
+	}
+}
+
+func TestNewCascade_Validation(t *testing.T) {
+	p := pidpool.NewP(1)
+	if _, err := pidpool.NewCascade(p, p, time.Second); err == nil {
+		t.Fatalf("expected an error for the same loop twice")
+	}
+	if _, err := pidpool.NewCascade(p, nil, time.Second); err == nil {
+		t.Fatalf("expected an error for a missing loop")
+	}
+	if _, err := pidpool.NewCascade(p, pidpool.NewP(1), -time.Second); err == nil {
+		t.Fatalf("expected an error for a negative period")
+	}
+}
+
+func TestCascade_InnerSaturation(t *testing.T) {
+	outer := pidpool.NewPI(1, 1)
+	outer.SetOutputLimits(0, 100)
+	outer.SetIntegralLimits(-1000, 1000)
+	inner := pidpool.NewP(1)
+	inner.SetOutputLimits(0, 10)
+	c, err := pidpool.NewCascade(outer, inner, 0)
+	if err != nil {
+		t.Fatalf("NewCascade err: %v", err)
+	}
+	c.SetSetPoint(50)
+
+	// hooks may read the cascade back while it steps.
+	var seen int
+	outer.OnUpdate(func(pidpool.UpdateEvent) { c.Last(); seen++ })
+	c.OnUpdate(func(pidpool.CascadeEvent) { c.Last() })
+
+	// the inner loop is stuck at its limit and the process does not move.
+	for i := 0; i < 50; i++ {
+		c.Step(0, 0, 1)
+	}
+	if seen != 50 {
+		t.Fatalf("expected 50 outer hook calls, got %d", seen)
+	}
+	// only the first outer step, before the inner loop saturated,
+	// integrates.
+	if i := outer.State().Integral; i != 50 {
+		t.Fatalf("expected the outer integral held at 50, got %v", i)
+	}
+	if ev := c.Last(); !ev.Outer.IntegralClamped {
+		t.Fatalf("expected the held integral reported as clamped")
+	}
+}
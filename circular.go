@@ -0,0 +1,101 @@
+package pidpool
+
+import (
+	"errors"
+	"math"
+)
+
+// SetInputRangeCircular makes the measurement and setpoint angular, wrapping
+// at max back to min, e.g. -180 and 180 for a heading in degrees. The error
+// is taken the short way around, so a setpoint of 170 seen from -170 is an
+// error of -20, not 340. The measurement is unwrapped before filtering and
+// differentiation, so crossing the wrap point causes no derivative kick.
+func (pid *PID) SetInputRangeCircular(min, max float64) error {
+	r := Limits{Min: min, Max: max}
+	if err := validateCircular(r); err != nil {
+		return err
+	}
+	defer pid.notifyChange()
+	pid.mu.Lock()
+	defer pid.mu.Unlock()
+	pid.circular = &r
+
+	return nil
+}
+
+// ClearInputRangeCircular returns to a linear measurement.
+func (pid *PID) ClearInputRangeCircular() {
+	defer pid.notifyChange()
+	pid.mu.Lock()
+	defer pid.mu.Unlock()
+	pid.circular = nil
+}
+
+// GetInputRangeCircular returns the circular input range. The boolean is
+// false when the measurement is linear.
+func (pid *PID) GetInputRangeCircular() (min, max float64, ok bool) {
+	pid.mu.Lock()
+	defer pid.mu.Unlock()
+	if pid.circular == nil {
+		return 0, 0, false
+	}
+	return pid.circular.Min, pid.circular.Max, true
+}
+
+func validateCircular(r Limits) error {
+	if math.IsNaN(r.Min) || math.IsNaN(r.Max) || math.IsInf(r.Min, 0) || math.IsInf(r.Max, 0) {
+		return errors.New("circular range must be finite")
+	}
+	if r.Min >= r.Max {
+		return errors.New("circular range min must be less than max")
+	}
+	return nil
+}
+
+// wrapDelta wraps the difference d into [-span/2, span/2).
+func wrapDelta(d, span float64) float64 {
+	return d - span*math.Floor(d/span+0.5)
+}
+
+// wrapValue wraps v into [r.Min, r.Max).
+func wrapValue(v float64, r *Limits) float64 {
+	span := r.Max - r.Min
+	return v - span*math.Floor((v-r.Min)/span)
+}
+
+// unwrapLocked maps a circular measurement onto the continuous track of
+// the previous one.
+func (pid *PID) unwrapLocked(value float64) float64 {
+	if pid.circular == nil {
+		return value
+	}
+	return pid.prevValue + wrapDelta(value-pid.prevValue, pid.circular.Max-pid.circular.Min)
+}
+
+// reportedValueLocked returns the measurement as reported in UpdateEvent,
+// wrapped back into the circular range.
+func (pid *PID) reportedValueLocked(value float64) float64 {
+	if pid.circular == nil {
+		return value
+	}
+	return wrapValue(value, pid.circular)
+}
+
+// errorLocked returns the control error for the measurement value.
+func (pid *PID) errorLocked(value float64) float64 {
+	if pid.errorFunc != nil {
+		return pid.errorFunc(pid.setPoint, value)
+	}
+	if pid.circular == nil {
+		return pid.setPoint - value
+	}
+	return wrapDelta(pid.setPoint-value, pid.circular.Max-pid.circular.Min)
+}
+
+func cloneLimits(l *Limits) *Limits {
+	if l == nil {
+		return nil
+	}
+	c := *l
+	return &c
+}
@@ -0,0 +1,65 @@
+package pidpool_test
+
+import (
+	"encoding/json"
+	"math"
+	"testing"
+
+	"github.com/ankur-anand/go-pidpool"
+)
+
+func TestInputRangeCircular_ShortWayAround(t *testing.T) {
+	pid := pidpool.NewP(1)
+	if err := pid.SetInputRangeCircular(-180, 180); err != nil {
+		t.Fatalf("SetInputRangeCircular err: %v", err)
+	}
+	pid.SetSetPoint(170)
+	if out := pid.UpdateDuration(-170, 1); math.Abs(out+20) > 1e-9 {
+		t.Fatalf("expected an error of -20 the short way around, got %v", out)
+	}
+
+	pid.ClearInputRangeCircular()
+	if out := pid.UpdateDuration(-170, 1); out != 340 {
+		t.Fatalf("expected a linear error of 340, got %v", out)
+	}
+	if err := pid.SetInputRangeCircular(10, 10); err == nil {
+		t.Fatalf("expected error for an empty range")
+	}
+}
+
+func TestInputRangeCircular_NoDerivativeKickAtWrap(t *testing.T) {
+	pid := pidpool.NewPD(0, 1)
+	pid.SetInputRangeCircular(0, 360)
+	pid.SetSetPoint(0)
+
+	var ev pidpool.UpdateEvent
+	pid.OnUpdate(func(e pidpool.UpdateEvent) { ev = e })
+	pid.UpdateDuration(358, 1)
+	if out := pid.UpdateDuration(1, 1); math.Abs(out+3) > 1e-9 {
+		t.Fatalf("expected a derivative of 3 degrees per second, got %v", out)
+	}
+	if ev.Value != 1 || ev.Error != -1 {
+		t.Fatalf("expected the wrapped value and error in the event, got %v and %v", ev.Value, ev.Error)
+	}
+}
+
+func TestInputRangeCircular_State(t *testing.T) {
+	pid := pidpool.NewP(1)
+	pid.SetInputRangeCircular(0, 360)
+
+	data, err := json.Marshal(pid.State())
+	if err != nil {
+		t.Fatalf("Marshal err: %v", err)
+	}
+	var st pidpool.State
+	if err := json.Unmarshal(data, &st); err != nil {
+		t.Fatalf("Unmarshal err: %v", err)
+	}
+	restored := pidpool.NewP(0)
+	if err := restored.RestoreState(st); err != nil {
+		t.Fatalf("RestoreState err: %v", err)
+	}
+	if min, max, ok := restored.GetInputRangeCircular(); !ok || min != 0 || max != 360 {
+		t.Fatalf("circular range not restored: %v %v %v", min, max, ok)
+	}
+}
@@ -0,0 +1,140 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/ankur-anand/go-pidpool/pidhttp"
+)
+
+// errNoLeases is returned by acquire when the handler does not serve
+// leases.
+var errNoLeases = errors.New("leases not enabled")
+
+// client talks to a pidhttp admin endpoint.
+type client struct {
+	base string
+	name string
+	http *http.Client
+
+	// lease is the lease held on the controller, if any; its token is sent
+	// with every modifying request.
+	lease   pidhttp.Lease
+	renewAt time.Time
+}
+
+func (c *client) url() string {
+	return strings.TrimSuffix(c.base, "/") + "/" + url.PathEscape(c.name)
+}
+
+func (c *client) get(ctx context.Context) (pidhttp.Controller, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, c.url(), nil)
+	if err != nil {
+		return pidhttp.Controller{}, err
+	}
+	return c.do(req)
+}
+
+func (c *client) post(ctx context.Context, u pidhttp.Update) (pidhttp.Controller, error) {
+	body, err := json.Marshal(u)
+	if err != nil {
+		return pidhttp.Controller{}, err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, c.url(), bytes.NewReader(body))
+	if err != nil {
+		return pidhttp.Controller{}, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	return c.do(req)
+}
+
+// acquire takes a lease on the controller for ttl, or renews the one held.
+// Renewal is due halfway through the granted lease, which the handler may
+// have shortened to its maximum.
+func (c *client) acquire(ctx context.Context, holder string, ttl time.Duration) error {
+	body, err := json.Marshal(pidhttp.LeaseRequest{Holder: holder, TTL: ttl.String(), Token: c.lease.Token})
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, c.url()+"/lease", bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	now := time.Now()
+	resp, err := c.http.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	var lease pidhttp.Lease
+	switch resp.StatusCode {
+	case http.StatusOK:
+		if err := json.NewDecoder(resp.Body).Decode(&lease); err != nil {
+			return err
+		}
+		c.lease = lease
+		c.renewAt = now.Add(lease.Expires.Sub(now) / 2)
+		return nil
+	case http.StatusNotFound, http.StatusMethodNotAllowed:
+		return errNoLeases
+	case http.StatusConflict:
+		c.lease = pidhttp.Lease{}
+		_ = json.NewDecoder(resp.Body).Decode(&lease)
+		return fmt.Errorf("%s is leased by %s until %s", c.name, lease.Holder, lease.Expires.Format(time.TimeOnly))
+	}
+	return fmt.Errorf("%s %s: %s", req.Method, req.URL, resp.Status)
+}
+
+// renewDue reports whether a held lease should be renewed.
+func (c *client) renewDue(now time.Time) bool {
+	return c.lease.Token != "" && !now.Before(c.renewAt)
+}
+
+// release gives up the lease, if one is held.
+func (c *client) release(ctx context.Context) error {
+	if c.lease.Token == "" {
+		return nil
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodDelete, c.url()+"/lease", nil)
+	if err != nil {
+		return err
+	}
+	req.Header.Set(pidhttp.LeaseHeader, c.lease.Token)
+	resp, err := c.http.Do(req)
+	if err != nil {
+		return err
+	}
+	resp.Body.Close()
+	c.lease = pidhttp.Lease{}
+	return nil
+}
+
+func (c *client) do(req *http.Request) (pidhttp.Controller, error) {
+	if c.lease.Token != "" {
+		req.Header.Set(pidhttp.LeaseHeader, c.lease.Token)
+	}
+	resp, err := c.http.Do(req)
+	if err != nil {
+		return pidhttp.Controller{}, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		var e struct {
+			Error string `json:"error"`
+		}
+		_ = json.NewDecoder(resp.Body).Decode(&e)
+		return pidhttp.Controller{}, fmt.Errorf("%s %s: %s %s", req.Method, req.URL, resp.Status, e.Error)
+	}
+	var ctl pidhttp.Controller
+	err = json.NewDecoder(resp.Body).Decode(&ctl)
+	return ctl, err
+}
@@ -0,0 +1,164 @@
+// Command pidmon is a terminal UI for live tuning. It polls a controller
+// served by a pidhttp admin handler, shows a scrolling plot of the setpoint,
+// process value and output, and nudges gains and setpoint on key presses.
+//
+// Usage:
+//
+//	pidmon -addr http://localhost:8080/pid -name oven
+//
+// Keys: p/P, i/I and d/D lower or raise a gain by 10%, -/+ move the
+// setpoint by -step, q quits. The plot samples every -interval, however
+// often keys are pressed. The terminal is switched to unbuffered input
+// with stty, so pidmon runs on Unix-like systems only.
+//
+// When the handler serves leases, pidmon takes one on the controller for
+// -lease, renews it halfway through and releases it on exit, so its
+// changes do not fight another operator's. It refuses to start while
+// someone else holds the lease.
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"net/http"
+	"os"
+	"os/exec"
+	"os/signal"
+	"strings"
+	"time"
+)
+
+func main() {
+	if err := run(); err != nil {
+		fmt.Fprintln(os.Stderr, "pidmon:", err)
+		os.Exit(1)
+	}
+}
+
+func run() error {
+	var (
+		addr     = flag.String("addr", "http://localhost:8080", "base URL of the pidhttp handler")
+		name     = flag.String("name", "", "controller name")
+		interval = flag.Duration("interval", 500*time.Millisecond, "poll interval")
+		spStep   = flag.Float64("step", 1, "setpoint change per key press")
+		width    = flag.Int("width", 72, "plot width in samples")
+		height   = flag.Int("height", 18, "plot height in lines")
+		leaseTTL = flag.Duration("lease", 30*time.Second, "lease duration; 0 does not take a lease")
+		holder   = flag.String("holder", defaultHolder(), "lease holder shown to other operators")
+	)
+	flag.Parse()
+	if *name == "" {
+		return errors.New("-name is required")
+	}
+	if *leaseTTL < 0 || *leaseTTL > 0 && *leaseTTL <= 2**interval {
+		return errors.New("-lease must be 0 or longer than two intervals")
+	}
+
+	c := &client{base: *addr, name: *name, http: &http.Client{Timeout: 5 * time.Second}}
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+	if _, err := c.get(ctx); err != nil {
+		return err
+	}
+	if *leaseTTL > 0 {
+		if err := c.acquire(ctx, *holder, *leaseTTL); err != nil && !errors.Is(err, errNoLeases) {
+			return err
+		}
+		defer func() { _ = c.release(context.Background()) }()
+	}
+
+	restore, err := rawTerminal()
+	if err != nil {
+		return err
+	}
+	defer restore()
+
+	keys := make(chan byte)
+	go func() {
+		buf := make([]byte, 1)
+		for {
+			if n, err := os.Stdin.Read(buf); err != nil {
+				close(keys)
+				return
+			} else if n == 1 {
+				keys <- buf[0]
+			}
+		}
+	}()
+
+	s := &screen{width: *width, height: *height}
+	sample := func() {
+		if ctl, err := c.get(ctx); err != nil {
+			s.status = err.Error()
+		} else {
+			s.add(ctl)
+		}
+	}
+	sample()
+	tick := time.NewTicker(*interval)
+	defer tick.Stop()
+	for {
+		s.render(os.Stdout)
+
+		select {
+		case <-ctx.Done():
+			return nil
+		case now := <-tick.C:
+			if c.renewDue(now) {
+				if err := c.acquire(ctx, *holder, *leaseTTL); err != nil {
+					s.status = "lease: " + err.Error()
+				}
+			}
+			sample()
+		case key, ok := <-keys:
+			if !ok || key == 'q' || key == 3 {
+				return nil
+			}
+			if len(s.points) == 0 {
+				continue
+			}
+			u, ok := keyUpdate(key, s.points[len(s.points)-1], *spStep)
+			if !ok {
+				continue
+			}
+			if _, err := c.post(ctx, u); err != nil {
+				s.status = err.Error()
+			} else {
+				s.status = ""
+			}
+		}
+	}
+}
+
+// rawTerminal switches the terminal to unbuffered input without echo and
+// returns a function that restores it.
+func rawTerminal() (func(), error) {
+	saved, err := stty("-g")
+	if err != nil {
+		return nil, fmt.Errorf("stdin is not a terminal: %w", err)
+	}
+	if _, err := stty("-icanon", "-echo", "min", "1"); err != nil {
+		return nil, err
+	}
+	return func() {
+		_, _ = stty(strings.TrimSpace(saved))
+		fmt.Print("\x1b[0m\r\n")
+	}, nil
+}
+
+func stty(args ...string) (string, error) {
+	cmd := exec.Command("stty", args...)
+	cmd.Stdin = os.Stdin
+	out, err := cmd.Output()
+	return string(out), err
+}
+
+func defaultHolder() string {
+	host, err := os.Hostname()
+	if err != nil {
+		return "pidmon"
+	}
+	return "pidmon@" + host
+}
@@ -0,0 +1,109 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/ankur-anand/go-pidpool"
+	"github.com/ankur-anand/go-pidpool/pidhttp"
+)
+
+func TestClientAndScreen(t *testing.T) {
+	reg := pidpool.NewRegistry()
+	pid := pidpool.NewPID(2, 0.5, 0, 0)
+	pid.SetSetPoint(50)
+	if err := reg.Register("oven", pid); err != nil {
+		t.Fatalf("Register err: %v", err)
+	}
+	srv := httptest.NewServer(pidhttp.NewHandler(reg))
+	defer srv.Close()
+	c := &client{base: srv.URL, name: "oven", http: http.DefaultClient}
+
+	s := &screen{width: 20, height: 9}
+	for _, v := range []float64{40, 45, 48} {
+		pid.UpdateDuration(v, 1)
+		ctl, err := c.get(context.Background())
+		if err != nil {
+			t.Fatalf("get err: %v", err)
+		}
+		s.add(ctl)
+	}
+	var out strings.Builder
+	s.render(&out)
+	if !strings.Contains(out.String(), "sp 50  pv 48") || strings.Count(out.String(), "*") != 3 {
+		t.Fatalf("unexpected screen:\n%s", out.String())
+	}
+
+	u, ok := keyUpdate('P', s.points[2], 1)
+	if !ok {
+		t.Fatalf("P not bound")
+	}
+	if _, err := c.post(context.Background(), u); err != nil {
+		t.Fatalf("post err: %v", err)
+	}
+	u, _ = keyUpdate('+', s.points[2], 5)
+	if _, err := c.post(context.Background(), u); err != nil {
+		t.Fatalf("post err: %v", err)
+	}
+	if kp, _, _ := pid.GetPID(); kp != 2.2 || pid.GetSetPoint() != 55 {
+		t.Fatalf("updates not applied: kp %v, sp %v", kp, pid.GetSetPoint())
+	}
+
+	c.name = "missing"
+	if _, err := c.get(context.Background()); err == nil || !strings.Contains(err.Error(), "404") {
+		t.Fatalf("expected 404, got %v", err)
+	}
+}
+
+func TestClientLease(t *testing.T) {
+	reg := pidpool.NewRegistry()
+	pid := pidpool.NewPID(2, 0.5, 0, 0)
+	if err := reg.Register("oven", pid); err != nil {
+		t.Fatalf("Register err: %v", err)
+	}
+	srv := httptest.NewServer(pidhttp.NewHandler(reg, pidhttp.WithLeases(time.Minute)))
+	defer srv.Close()
+	ctx := context.Background()
+	c := &client{base: srv.URL, name: "oven", http: http.DefaultClient}
+	other := &client{base: srv.URL, name: "oven", http: http.DefaultClient}
+
+	if _, err := c.post(ctx, pidhttp.Update{SetPoint: ptr(10.0)}); err == nil {
+		t.Fatalf("expected a modification without a lease to be refused")
+	}
+	if err := c.acquire(ctx, "alice", time.Hour); err != nil {
+		t.Fatalf("acquire err: %v", err)
+	}
+	if c.renewDue(time.Now()) || !c.renewDue(time.Now().Add(31*time.Second)) {
+		t.Fatalf("expected renewal halfway through the granted minute")
+	}
+	if err := other.acquire(ctx, "bob", time.Minute); err == nil || !strings.Contains(err.Error(), "alice") {
+		t.Fatalf("expected the lease held by alice, got %v", err)
+	}
+	if _, err := c.post(ctx, pidhttp.Update{SetPoint: ptr(10.0)}); err != nil || pid.GetSetPoint() != 10 {
+		t.Fatalf("post with lease err: %v", err)
+	}
+	token := c.lease.Token
+	if err := c.acquire(ctx, "alice", time.Minute); err != nil || c.lease.Token != token {
+		t.Fatalf("expected renewal to keep the token, got %v", err)
+	}
+	if err := c.release(ctx); err != nil {
+		t.Fatalf("release err: %v", err)
+	}
+	if err := other.acquire(ctx, "bob", time.Minute); err != nil {
+		t.Fatalf("acquire after release err: %v", err)
+	}
+
+	plain := httptest.NewServer(pidhttp.NewHandler(reg))
+	defer plain.Close()
+	c = &client{base: plain.URL, name: "oven", http: http.DefaultClient}
+	if err := c.acquire(ctx, "alice", time.Minute); !errors.Is(err, errNoLeases) {
+		t.Fatalf("expected errNoLeases, got %v", err)
+	}
+}
+
+func ptr[T any](v T) *T { return &v }
@@ -0,0 +1,129 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"math"
+	"strings"
+
+	"github.com/ankur-anand/go-pidpool/pidhttp"
+)
+
+// gainStep is the relative change of a gain per key press.
+const gainStep = 0.1
+
+const help = "p/P kp  i/I ki  d/D kd  -/+ setpoint  q quit"
+
+// keyUpdate returns the update a key press makes to ctl. Lower case keys
+// decrease, upper case keys increase.
+func keyUpdate(key byte, ctl pidhttp.Controller, spStep float64) (pidhttp.Update, bool) {
+	g := ctl.Gains
+	scale := func(v float64, up bool) float64 {
+		if up {
+			return v * (1 + gainStep)
+		}
+		return v * (1 - gainStep)
+	}
+	switch key {
+	case 'p', 'P':
+		g.Kp = scale(g.Kp, key == 'P')
+	case 'i', 'I':
+		g.Ki = scale(g.Ki, key == 'I')
+	case 'd', 'D':
+		g.Kd = scale(g.Kd, key == 'D')
+	case '+', '=', '-', '_':
+		sp := ctl.SetPoint + spStep
+		if key == '-' || key == '_' {
+			sp = ctl.SetPoint - spStep
+		}
+		return pidhttp.Update{SetPoint: &sp}, true
+	default:
+		return pidhttp.Update{}, false
+	}
+	return pidhttp.Update{Gains: &g}, true
+}
+
+// screen is the scrolling view of a controller.
+type screen struct {
+	width  int
+	height int
+
+	points []pidhttp.Controller
+	status string
+}
+
+func (s *screen) add(ctl pidhttp.Controller) {
+	s.points = append(s.points, ctl)
+	if len(s.points) > s.width {
+		s.points = s.points[len(s.points)-s.width:]
+	}
+}
+
+// render draws the screen: a plot of the setpoint ('-') and process value
+// ('*'), a plot of the output ('+'), and a status block.
+func (s *screen) render(w io.Writer) {
+	var b strings.Builder
+	b.WriteString("\x1b[H\x1b[2J")
+	if len(s.points) == 0 {
+		fmt.Fprintf(&b, "waiting for data...\r\n%s\r\n", s.status)
+		io.WriteString(w, b.String())
+		return
+	}
+
+	top := s.height * 2 / 3
+	s.plot(&b, top, func(c pidhttp.Controller) []plotted {
+		return []plotted{{c.SetPoint, '-'}, {c.Value, '*'}}
+	})
+	b.WriteString("\r\n")
+	s.plot(&b, s.height-top, func(c pidhttp.Controller) []plotted {
+		return []plotted{{c.Output, '+'}}
+	})
+
+	last := s.points[len(s.points)-1]
+	fmt.Fprintf(&b, "\r\n%s  mode %s\r\n", last.Name, last.Mode)
+	fmt.Fprintf(&b, "sp %.4g  pv %.4g  out %.4g\r\n", last.SetPoint, last.Value, last.Output)
+	fmt.Fprintf(&b, "kp %.4g  ki %.4g  kd %.4g\r\n", last.Gains.Kp, last.Gains.Ki, last.Gains.Kd)
+	fmt.Fprintf(&b, "%s\r\n%s\r\n", help, s.status)
+	io.WriteString(w, b.String())
+}
+
+type plotted struct {
+	v  float64
+	ch byte
+}
+
+func (s *screen) plot(b *strings.Builder, height int, series func(pidhttp.Controller) []plotted) {
+	if height < 2 {
+		height = 2
+	}
+	lo, hi := math.Inf(1), math.Inf(-1)
+	for _, p := range s.points {
+		for _, v := range series(p) {
+			lo, hi = math.Min(lo, v.v), math.Max(hi, v.v)
+		}
+	}
+	if hi == lo {
+		lo, hi = lo-1, hi+1
+	}
+
+	grid := make([][]byte, height)
+	for i := range grid {
+		grid[i] = []byte(strings.Repeat(" ", s.width))
+	}
+	for x, p := range s.points {
+		for _, v := range series(p) {
+			row := height - 1 - int(math.Round((v.v-lo)/(hi-lo)*float64(height-1)))
+			grid[row][x] = v.ch
+		}
+	}
+	for i, line := range grid {
+		label := ""
+		switch i {
+		case 0:
+			label = fmt.Sprintf("%.4g", hi)
+		case height - 1:
+			label = fmt.Sprintf("%.4g", lo)
+		}
+		fmt.Fprintf(b, "%10s |%s\r\n", label, line)
+	}
+}
@@ -0,0 +1,87 @@
+package main
+
+import (
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/ankur-anand/go-pidpool"
+)
+
+// identify fits a model to the recorded trace at path.
+func identify(path string, maxDelay int) (pidpool.FOPDT, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return pidpool.FOPDT{}, err
+	}
+	defer f.Close()
+	recs, err := csv.NewReader(f).ReadAll()
+	if err != nil {
+		return pidpool.FOPDT{}, err
+	}
+	data, dt, err := parseTrace(recs)
+	if err != nil {
+		return pidpool.FOPDT{}, fmt.Errorf("%s: %w", path, err)
+	}
+
+	return pidpool.IdentifyFOPDT(data, dt, maxDelay)
+}
+
+// parseTrace returns the samples of a trace with a time, value and output
+// header, and the sample period in seconds.
+func parseTrace(recs [][]string) ([]pidpool.IOSample, float64, error) {
+	if len(recs) < 3 {
+		return nil, 0, errors.New("trace too short")
+	}
+	col := map[string]int{}
+	for i, name := range recs[0] {
+		col[strings.ToLower(strings.TrimSpace(name))] = i
+	}
+	ti, ok1 := col["time"]
+	vi, ok2 := col["value"]
+	oi, ok3 := col["output"]
+	if !ok1 || !ok2 || !ok3 {
+		return nil, 0, errors.New("header must name time, value and output columns")
+	}
+
+	data := make([]pidpool.IOSample, 0, len(recs)-1)
+	var first, last time.Time
+	for n, rec := range recs[1:] {
+		t, err := parseTime(rec[ti])
+		if err != nil {
+			return nil, 0, fmt.Errorf("line %d: %w", n+2, err)
+		}
+		v, err := strconv.ParseFloat(strings.TrimSpace(rec[vi]), 64)
+		if err != nil {
+			return nil, 0, fmt.Errorf("line %d: %w", n+2, err)
+		}
+		u, err := strconv.ParseFloat(strings.TrimSpace(rec[oi]), 64)
+		if err != nil {
+			return nil, 0, fmt.Errorf("line %d: %w", n+2, err)
+		}
+		if n == 0 {
+			first = t
+		}
+		last = t
+		data = append(data, pidpool.IOSample{Output: u, Value: v})
+	}
+
+	dt := last.Sub(first).Seconds() / float64(len(data)-1)
+	if dt <= 0 {
+		return nil, 0, errors.New("timestamps must increase")
+	}
+
+	return data, dt, nil
+}
+
+func parseTime(s string) (time.Time, error) {
+	s = strings.TrimSpace(s)
+	if secs, err := strconv.ParseFloat(s, 64); err == nil {
+		return time.Unix(0, int64(secs*1e9)), nil
+	}
+	return time.Parse(time.RFC3339Nano, s)
+}
@@ -0,0 +1,203 @@
+// Command pidtune decides controller gains offline. It takes a process model,
+// either given directly or identified from a recorded CSV trace, picks gains
+// with the SIMC rule unless they are given, simulates a setpoint step and
+// prints the response with its performance indices.
+//
+// Usage:
+//
+//	pidtune -model 2,30,5 [-kp 1 -ki 0.05 -kd 0] [-step 10] [-out trace.csv]
+//	pidtune -csv production.csv [-tc 10] [-record tuning.json]
+//
+// A model is gain,time-constant,dead-time with times in seconds. A CSV trace
+// has a header with time, value and output columns, as written by pidtrace;
+// time is RFC 3339 or Unix seconds and samples must be evenly spaced.
+//
+// With -record the gains are saved with the model and the simulated
+// performance indices as a pidpool.TuningRecord.
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"math"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/ankur-anand/go-pidpool"
+	"github.com/ankur-anand/go-pidpool/pidtrace"
+	"github.com/ankur-anand/go-pidpool/plant"
+	"github.com/ankur-anand/go-pidpool/sim"
+)
+
+func main() {
+	if err := run(os.Args[1:], os.Stdout); err != nil {
+		fmt.Fprintln(os.Stderr, "pidtune:", err)
+		os.Exit(1)
+	}
+}
+
+func run(args []string, stdout io.Writer) error {
+	fs := flag.NewFlagSet("pidtune", flag.ContinueOnError)
+	var (
+		modelFlag = fs.String("model", "", "process model `gain,tau,deadtime`")
+		csvFlag   = fs.String("csv", "", "identify the model from a recorded trace")
+		maxDelay  = fs.Int("maxdelay", 50, "longest dead time searched by -csv, in samples")
+		tc        = fs.Float64("tc", 0, "SIMC closed-loop time constant in seconds, 0 for the default")
+		kp        = fs.Float64("kp", math.NaN(), "proportional gain, autotuned if unset")
+		ki        = fs.Float64("ki", 0, "integral gain")
+		kd        = fs.Float64("kd", 0, "derivative gain")
+		step      = fs.Float64("step", 1, "setpoint step size")
+		duration  = fs.Duration("duration", 0, "simulated time, 0 for 5 time constants plus 10 dead times")
+		dt        = fs.Duration("dt", 0, "controller period, 0 for a 100th of the time constant")
+		noise     = fs.Float64("noise", 0, "measurement noise standard deviation")
+		outFlag   = fs.String("out", "", "write the simulated trace to this CSV file")
+		record    = fs.String("record", "", "save the gains as a tuning record to this JSON file")
+		plot      = fs.Bool("plot", true, "print a plot of the response")
+	)
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	var model pidpool.FOPDT
+	var err error
+	switch {
+	case *modelFlag != "" && *csvFlag != "":
+		return errors.New("-model and -csv are mutually exclusive")
+	case *modelFlag != "":
+		model, err = parseModel(*modelFlag)
+	case *csvFlag != "":
+		model, err = identify(*csvFlag, *maxDelay)
+	default:
+		return errors.New("one of -model or -csv is required")
+	}
+	if err != nil {
+		return err
+	}
+	fmt.Fprintf(stdout, "model: gain %.4g, time constant %.4gs, dead time %.4gs\n",
+		model.Gain, model.TimeConstant, model.DeadTime)
+
+	gains := pidpool.Gains{Kp: *kp, Ki: *ki, Kd: *kd}
+	method := "manual"
+	if math.IsNaN(gains.Kp) {
+		if gains.Kp, gains.Ki, gains.Kd, err = model.SIMC(*tc); err != nil {
+			return err
+		}
+		method = "simc"
+		fmt.Fprint(stdout, "SIMC ")
+	}
+	fmt.Fprintf(stdout, "gains: kp %.4g, ki %.4g, kd %.4g\n", gains.Kp, gains.Ki, gains.Kd)
+
+	if *dt <= 0 {
+		*dt = seconds(model.TimeConstant / 100)
+	}
+	if *duration <= 0 {
+		*duration = seconds(5*model.TimeConstant + 10*model.DeadTime)
+	}
+	trace, rep, err := simulate(model, gains, *step, *noise, *duration, *dt)
+	if err != nil {
+		return err
+	}
+
+	if *plot {
+		fmt.Fprintln(stdout)
+		writePlot(stdout, trace, 72, 16)
+		fmt.Fprintln(stdout)
+	}
+	fmt.Fprintf(stdout, "IAE %.4g  ISE %.4g  ITAE %.4g\n", rep.IAE, rep.ISE, rep.ITAE)
+	fmt.Fprintf(stdout, "overshoot %.4g (%.1f%%)\n", rep.Overshoot, 100*rep.OvershootRatio)
+	if rep.Settled {
+		fmt.Fprintf(stdout, "settling time %v\n", rep.SettlingTime.Round(time.Millisecond))
+	} else {
+		fmt.Fprintln(stdout, "not settled")
+	}
+
+	if *record != "" {
+		r := pidpool.TuningRecord{
+			Time:        time.Now(),
+			Method:      method,
+			Gains:       gains,
+			Model:       &model,
+			Performance: &rep,
+		}
+		if err := r.Save(*record); err != nil {
+			return err
+		}
+	}
+
+	if *outFlag != "" {
+		f, err := os.Create(*outFlag)
+		if err != nil {
+			return err
+		}
+		if err := pidtrace.WriteCSV(f, trace); err != nil {
+			f.Close()
+			return err
+		}
+		return f.Close()
+	}
+
+	return nil
+}
+
+func seconds(s float64) time.Duration {
+	return time.Duration(s * float64(time.Second))
+}
+
+func parseModel(s string) (pidpool.FOPDT, error) {
+	parts := strings.Split(s, ",")
+	if len(parts) != 3 {
+		return pidpool.FOPDT{}, fmt.Errorf("model %q: want gain,tau,deadtime", s)
+	}
+	var v [3]float64
+	for i, p := range parts {
+		f, err := strconv.ParseFloat(strings.TrimSpace(p), 64)
+		if err != nil {
+			return pidpool.FOPDT{}, fmt.Errorf("model %q: %w", s, err)
+		}
+		v[i] = f
+	}
+	m := pidpool.FOPDT{Gain: v[0], TimeConstant: v[1], DeadTime: v[2]}
+	if m.Gain == 0 || m.TimeConstant <= 0 || m.DeadTime < 0 {
+		return m, fmt.Errorf("model %q: want non-zero gain, positive time constant and non-negative dead time", s)
+	}
+
+	return m, nil
+}
+
+// simulate runs a setpoint step against the model and returns the trace
+// with the performance indices of the response.
+func simulate(model pidpool.FOPDT, gains pidpool.Gains, step, noise float64, duration, dt time.Duration) ([]pidtrace.Point, pidpool.PerformanceReport, error) {
+	pid, err := pidpool.New(pidpool.Params{Gains: gains})
+	if err != nil {
+		return nil, pidpool.PerformanceReport{}, err
+	}
+	perf, err := pidpool.NewPerformance(pidpool.PerformanceConfig{})
+	if err != nil {
+		return nil, pidpool.PerformanceReport{}, err
+	}
+
+	start := time.Unix(0, 0).UTC()
+	var trace []pidtrace.Point
+	pid.OnUpdate(func(ev pidpool.UpdateEvent) {
+		perf.Observe(ev)
+		// stamp the points with simulated time.
+		ev.Time = start.Add(time.Duration(len(trace)) * dt)
+		trace = append(trace, pidtrace.FromEvent(ev))
+	})
+
+	var p plant.Model = plant.NewFOPDT(model)
+	if noise > 0 {
+		p = &plant.Noisy{Model: p, StdDev: noise}
+	}
+	_, err = sim.Simulate(pid, p, sim.Scenario{
+		Duration: duration,
+		DT:       dt,
+		SetPoint: step,
+	})
+
+	return trace, perf.Report(), err
+}
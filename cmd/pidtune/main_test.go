@@ -0,0 +1,43 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/ankur-anand/go-pidpool"
+)
+
+func TestRun_ModelAndTrace(t *testing.T) {
+	out := filepath.Join(t.TempDir(), "trace.csv")
+	record := filepath.Join(t.TempDir(), "tuning.json")
+	var buf strings.Builder
+	if err := run([]string{"-model", "2,30,5", "-step", "10", "-out", out, "-record", record}, &buf); err != nil {
+		t.Fatalf("run err: %v", err)
+	}
+	if rec, err := pidpool.LoadTuningRecord(record); err != nil || rec.Method != "simc" || rec.Model == nil || rec.Performance == nil {
+		t.Fatalf("tuning record not written: %+v, %v", rec, err)
+	}
+	for _, want := range []string{"SIMC gains: kp 1.5", "IAE", "settling time"} {
+		if !strings.Contains(buf.String(), want) {
+			t.Fatalf("output misses %q:\n%s", want, buf.String())
+		}
+	}
+
+	// identify the model back from the simulated trace.
+	if _, err := os.Stat(out); err != nil {
+		t.Fatalf("trace not written: %v", err)
+	}
+	buf.Reset()
+	if err := run([]string{"-csv", out, "-plot=false"}, &buf); err != nil {
+		t.Fatalf("run -csv err: %v", err)
+	}
+	if !strings.Contains(buf.String(), "model: gain 2, time constant 30s") {
+		t.Fatalf("unexpected identification:\n%s", buf.String())
+	}
+
+	if err := run(nil, &buf); err == nil {
+		t.Fatalf("expected error without a model")
+	}
+}
@@ -0,0 +1,53 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"math"
+	"strings"
+
+	"github.com/ankur-anand/go-pidpool/pidtrace"
+)
+
+// writePlot draws the setpoint ('-') and the process value ('*') of trace
+// as a width x height character plot.
+func writePlot(w io.Writer, trace []pidtrace.Point, width, height int) {
+	if len(trace) == 0 || width < 2 || height < 2 {
+		return
+	}
+	lo, hi := math.Inf(1), math.Inf(-1)
+	for _, p := range trace {
+		lo = math.Min(lo, math.Min(p.Value, p.SetPoint))
+		hi = math.Max(hi, math.Max(p.Value, p.SetPoint))
+	}
+	if hi == lo {
+		hi = lo + 1
+	}
+
+	grid := make([][]byte, height)
+	for i := range grid {
+		grid[i] = []byte(strings.Repeat(" ", width))
+	}
+	row := func(v float64) int {
+		return height - 1 - int(math.Round((v-lo)/(hi-lo)*float64(height-1)))
+	}
+	for x := 0; x < width; x++ {
+		p := trace[x*(len(trace)-1)/(width-1)]
+		grid[row(p.SetPoint)][x] = '-'
+		grid[row(p.Value)][x] = '*'
+	}
+
+	for i, line := range grid {
+		label := ""
+		switch i {
+		case 0:
+			label = fmt.Sprintf("%.4g", hi)
+		case height - 1:
+			label = fmt.Sprintf("%.4g", lo)
+		}
+		fmt.Fprintf(w, "%10s |%s\n", label, line)
+	}
+	start, end := trace[0].Time, trace[len(trace)-1].Time
+	fmt.Fprintf(w, "%10s +%s\n", "", strings.Repeat("-", width))
+	fmt.Fprintf(w, "%10s  0%*v\n", "", width-1, end.Sub(start))
+}
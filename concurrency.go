@@ -0,0 +1,85 @@
+package pidpool
+
+import (
+	"errors"
+	"sync"
+)
+
+// ConcurrencyPolicy defines what happens when several goroutines call
+// Update on the same controller at the same time.
+//
+// Interleaved updates are always free of data races, but each update
+// derives dt and the derivative from the previous one, so the order in
+// which concurrent updates are applied matters.
+type ConcurrencyPolicy int
+
+const (
+	// ConcurrencySerialize applies concurrent updates one at a time in
+	// arrival (FIFO) order, including their hooks. This is the default.
+	ConcurrencySerialize ConcurrencyPolicy = iota
+	// ConcurrencyReject refuses an update while another one is in
+	// progress. TryUpdate reports ErrConcurrentUpdate; Update returns the
+	// last output unchanged.
+	ConcurrencyReject
+)
+
+// ErrConcurrentUpdate is returned by TryUpdate when the controller rejects
+// an update because another one is in progress.
+var ErrConcurrentUpdate = errors.New("concurrent update rejected")
+
+// updateGate is a ticket lock that admits updates in FIFO order.
+type updateGate struct {
+	mu      sync.Mutex
+	cond    *sync.Cond
+	policy  ConcurrencyPolicy
+	next    uint64
+	serving uint64
+}
+
+func (g *updateGate) enter() error {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	if g.policy == ConcurrencyReject {
+		if g.next != g.serving {
+			return ErrConcurrentUpdate
+		}
+		g.next++
+		return nil
+	}
+
+	if g.cond == nil {
+		g.cond = sync.NewCond(&g.mu)
+	}
+	ticket := g.next
+	g.next++
+	for g.serving != ticket {
+		g.cond.Wait()
+	}
+
+	return nil
+}
+
+func (g *updateGate) leave() {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	g.serving++
+	if g.cond != nil {
+		g.cond.Broadcast()
+	}
+}
+
+// SetConcurrencyPolicy sets how concurrent updates are handled.
+func (pid *PID) SetConcurrencyPolicy(p ConcurrencyPolicy) {
+	pid.gate.mu.Lock()
+	defer pid.gate.mu.Unlock()
+	pid.gate.policy = p
+}
+
+// TryUpdate is like Update but reports ErrConcurrentUpdate instead of
+// silently returning the last output when the update is rejected. It also
+// reports ErrInvalidInput and ErrFaulted, along with the output produced
+// under the invalid input policy.
+func (pid *PID) TryUpdate(value float64) (float64, error) {
+	ev, err := pid.tryStep(func() UpdateEvent { return pid.wallClockStep(value) })
+	return ev.Output, err
+}
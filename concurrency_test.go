@@ -0,0 +1,62 @@
+package pidpool_test
+
+import (
+	"sync"
+	"testing"
+
+	"github.com/ankur-anand/go-pidpool"
+)
+
+func TestConcurrencyReject(t *testing.T) {
+	p := pidpool.NewPID(1, 0, 0, 0)
+	p.SetSetPoint(10)
+	p.SetConcurrencyPolicy(pidpool.ConcurrencyReject)
+
+	entered := make(chan struct{})
+	release := make(chan struct{})
+	var once sync.Once
+	p.OnUpdate(func(pidpool.UpdateEvent) {
+		once.Do(func() {
+			close(entered)
+			<-release
+		})
+	})
+
+	done := make(chan float64)
+	go func() { done <- p.UpdateDuration(4, 1) }()
+	<-entered
+
+	if _, err := p.TryUpdate(0); err != pidpool.ErrConcurrentUpdate {
+		t.Fatalf("expected ErrConcurrentUpdate, got %v", err)
+	}
+	close(release)
+	if out := <-done; out != 6 {
+		t.Fatalf("unexpected output of the admitted update: %v", out)
+	}
+
+	if out, err := p.TryUpdate(4); err != nil || out != 6 {
+		t.Fatalf("TryUpdate after release: %v, %v", out, err)
+	}
+}
+
+func TestConcurrencySerialize_AllUpdatesApplied(t *testing.T) {
+	p := pidpool.NewPID(0, 1, 0, 0)
+	if err := p.SetIntegralLimits(-1e9, 1e9); err != nil {
+		t.Fatalf("SetIntegralLimits err: %v", err)
+	}
+	p.SetSetPoint(1)
+
+	var wg sync.WaitGroup
+	for i := 0; i < 50; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			p.UpdateDuration(0, 1)
+		}()
+	}
+	wg.Wait()
+
+	if st := p.State(); st.Integral != 50 {
+		t.Fatalf("expected all 50 updates applied, integral %v", st.Integral)
+	}
+}
@@ -0,0 +1,126 @@
+package pidpool
+
+import (
+	"errors"
+	"sync"
+	"time"
+)
+
+// Controller is the common interface of the controllers in this package,
+// so callers can swap control strategies.
+type Controller interface {
+	// Update runs one control step for the measured value and returns the
+	// new output.
+	Update(value float64) float64
+	SetSetPoint(val float64)
+	// Reset clears the dynamic state, keeping the configuration.
+	Reset()
+}
+
+var (
+	_ Controller = (*PID)(nil)
+	_ Controller = (*OnOff)(nil)
+)
+
+// Reset clears the integral, the error and measurement history, the
+// measurement filter, the last output and the limit counters, keeping
+// gains, limits and setpoint. The next Update measures dt from the time of
+// the reset.
+func (pid *PID) Reset() {
+	pid.mu.Lock()
+	defer pid.mu.Unlock()
+	pid.integral = 0
+	pid.prevError = 0
+	pid.prevValue = 0
+	pid.derivativeState = 0
+	pid.lastOutput = 0
+	pid.lastStatus = Status{}
+	pid.filterHistory = nil
+	pid.filterQuality = nil
+	pid.hasLastGood = false
+	pid.negativeActive = false
+	pid.lastUpdate = time.Now()
+	pid.counters = LimitCounters{Since: pid.lastUpdate}
+	if pid.noise != nil {
+		pid.noise.Reset()
+	}
+	if pid.deadBandNoise != nil {
+		pid.deadBandNoise.Reset()
+	}
+}
+
+// NewP returns a proportional-only controller.
+func NewP(kp float64) *PID {
+	return NewPID(kp, 0, 0, 0)
+}
+
+// NewPI returns a proportional-integral controller.
+func NewPI(kp, ki float64) *PID {
+	return NewPID(kp, ki, 0, 0)
+}
+
+// NewPD returns a proportional-derivative controller.
+func NewPD(kp, kd float64) *PID {
+	return NewPID(kp, 0, kd, 0)
+}
+
+// OnOff is a bang-bang controller with hysteresis. It outputs high while
+// the value is below the band around the setpoint and low once it rises
+// above the band; inside the band it keeps its previous output. Swapping
+// low and high turns a heating controller into a cooling one.
+type OnOff struct {
+	mu sync.Mutex
+
+	setPoint   float64
+	hysteresis float64
+	low        float64
+	high       float64
+	on         bool
+}
+
+// NewOnOff returns an on/off controller with a band of width hysteresis
+// centered on the setpoint. It starts in the low state.
+func NewOnOff(hysteresis, low, high float64) (*OnOff, error) {
+	if hysteresis < 0 {
+		return nil, errors.New("hysteresis must not be negative")
+	}
+	return &OnOff{hysteresis: hysteresis, low: low, high: high}, nil
+}
+
+// Update implements Controller.
+func (c *OnOff) Update(value float64) float64 {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	half := c.hysteresis / 2
+	switch {
+	case value < c.setPoint-half:
+		c.on = true
+	case value > c.setPoint+half:
+		c.on = false
+	}
+	if c.on {
+		return c.high
+	}
+	return c.low
+}
+
+// SetSetPoint implements Controller.
+func (c *OnOff) SetSetPoint(val float64) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.setPoint = val
+}
+
+// GetSetPoint returns the current setpoint.
+func (c *OnOff) GetSetPoint() float64 {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.setPoint
+}
+
+// Reset implements Controller. The controller returns to the low state.
+func (c *OnOff) Reset() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.on = false
+}
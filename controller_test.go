@@ -0,0 +1,58 @@
+package pidpool_test
+
+import (
+	"testing"
+
+	"github.com/ankur-anand/go-pidpool"
+)
+
+func TestOnOff_Hysteresis(t *testing.T) {
+	c, err := pidpool.NewOnOff(2, 0, 100)
+	if err != nil {
+		t.Fatalf("NewOnOff err: %v", err)
+	}
+	c.SetSetPoint(20)
+
+	steps := []struct{ value, want float64 }{
+		{20, 0}, // inside the band, starts low.
+		{18.9, 100},
+		{20.5, 100}, // inside the band, holds.
+		{21.1, 0},
+		{19.5, 0},
+	}
+	for i, s := range steps {
+		if got := c.Update(s.value); got != s.want {
+			t.Fatalf("step %d: value %v expected %v, got %v", i, s.value, s.want, got)
+		}
+	}
+}
+
+func TestPID_Reset(t *testing.T) {
+	var c pidpool.Controller = pidpool.NewPI(1, 1)
+	c.SetSetPoint(10)
+	c.Update(0)
+	c.Reset()
+
+	p := c.(*pidpool.PID)
+	if st := p.State(); st.Integral != 0 || st.PrevValue != 0 || st.SetPoint != 10 || st.Kp != 1 {
+		t.Fatalf("unexpected state after reset: %+v", st)
+	}
+	if p.LastOutput() != 0 {
+		t.Fatalf("last output not cleared")
+	}
+}
+
+func TestNewP_PI_PD(t *testing.T) {
+	for _, tc := range []struct {
+		p          *pidpool.PID
+		kp, ki, kd float64
+	}{
+		{pidpool.NewP(2), 2, 0, 0},
+		{pidpool.NewPI(2, 3), 2, 3, 0},
+		{pidpool.NewPD(2, 4), 2, 0, 4},
+	} {
+		if kp, ki, kd := tc.p.GetPID(); kp != tc.kp || ki != tc.ki || kd != tc.kd {
+			t.Fatalf("unexpected gains (%v,%v,%v)", kp, ki, kd)
+		}
+	}
+}
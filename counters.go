@@ -0,0 +1,55 @@
+package pidpool
+
+import "time"
+
+// LimitCounters counts how often and how long a controller was pegged at
+// its limits. Durations are the sum of the dt of the updates concerned.
+type LimitCounters struct {
+	// Saturations is the number of times the output became clamped to the
+	// output limits, and SaturatedTime the time it spent clamped.
+	Saturations   uint64
+	SaturatedTime time.Duration
+	// Windups is the number of times anti-windup started holding the
+	// integral back, and WindupTime the time it held it.
+	Windups    uint64
+	WindupTime time.Duration
+	// Since is when the counters were last reset.
+	Since time.Time
+}
+
+// LimitCounters returns the saturation and windup counters.
+func (pid *PID) LimitCounters() LimitCounters {
+	pid.mu.Lock()
+	defer pid.mu.Unlock()
+	c := pid.counters
+	if c.Since.IsZero() {
+		c.Since = pid.created
+	}
+	return c
+}
+
+// ResetLimitCounters zeroes the saturation and windup counters. Reset
+// zeroes them too.
+func (pid *PID) ResetLimitCounters() {
+	pid.mu.Lock()
+	defer pid.mu.Unlock()
+	pid.counters = LimitCounters{Since: time.Now()}
+}
+
+// countLimitsLocked accounts the update with status st; pid.lastStatus is
+// still the status of the previous update.
+func (pid *PID) countLimitsLocked(st Status, dt float64) {
+	d := time.Duration(dt * float64(time.Second))
+	if st.Saturated {
+		if !pid.lastStatus.Saturated {
+			pid.counters.Saturations++
+		}
+		pid.counters.SaturatedTime += d
+	}
+	if st.WindupClamped {
+		if !pid.lastStatus.WindupClamped {
+			pid.counters.Windups++
+		}
+		pid.counters.WindupTime += d
+	}
+}
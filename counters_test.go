@@ -0,0 +1,40 @@
+package pidpool_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/ankur-anand/go-pidpool"
+)
+
+func TestLimitCounters(t *testing.T) {
+	pid := pidpool.NewPI(1, 1)
+	pid.SetOutputLimits(0, 10)
+	pid.SetIntegralLimits(-5, 5)
+	pid.SetSetPoint(20)
+
+	for i := 0; i < 10; i++ {
+		pid.UpdateDuration(0, 0.5)
+	}
+	pid.UpdateDuration(20, 0.5)
+	for i := 0; i < 4; i++ {
+		pid.UpdateDuration(0, 0.5)
+	}
+
+	c := pid.LimitCounters()
+	if c.Saturations != 2 || c.SaturatedTime != 7*time.Second {
+		t.Fatalf("unexpected saturation counters: %+v", c)
+	}
+	// anti-windup holds the integral back while the output is pegged.
+	if c.Windups != 2 || c.WindupTime != 7*time.Second {
+		t.Fatalf("unexpected windup counters: %+v", c)
+	}
+	if c.Since.IsZero() {
+		t.Fatalf("expected the counters to report their start")
+	}
+
+	pid.ResetLimitCounters()
+	if c := pid.LimitCounters(); c.Saturations != 0 || c.SaturatedTime != 0 || c.Windups != 0 || c.WindupTime != 0 {
+		t.Fatalf("expected zero counters after reset, got %+v", c)
+	}
+}
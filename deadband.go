@@ -0,0 +1,155 @@
+package pidpool
+
+import (
+	"errors"
+	"math"
+)
+
+// AdaptiveDeadBand sizes the dead-band from the measured sensor noise: it
+// follows Multiplier times the estimated noise standard deviation, so the
+// controller ignores errors the sensor cannot resolve.
+type AdaptiveDeadBand struct {
+	// Multiplier scales the noise standard deviation. Zero selects the
+	// default of 3; for no dead-band use SetDeadBand(0) instead.
+	Multiplier float64 `json:"multiplier,omitempty"`
+	// Alpha is the EWMA smoothing factor of the noise estimate, in
+	// (0, 1]; smaller values average over a longer window. Defaults to
+	// 0.05.
+	Alpha float64 `json:"alpha,omitempty"`
+	// Min and Max bound the dead-band. Zero Max leaves it unbounded.
+	Min float64 `json:"min,omitempty"`
+	Max float64 `json:"max,omitempty"`
+	// Warmup is the number of measurements seen before the estimate
+	// replaces the dead-band in effect. Defaults to 20.
+	Warmup int `json:"warmup,omitempty"`
+}
+
+// Validate reports whether the configuration is well formed.
+func (a AdaptiveDeadBand) Validate() error {
+	if !(a.Multiplier >= 0) || math.IsInf(a.Multiplier, 0) {
+		return errors.New("dead-band multiplier must be finite and not negative")
+	}
+	if !(a.Alpha >= 0 && a.Alpha <= 1) {
+		return errors.New("alpha must not be negative or exceed 1")
+	}
+	if !(a.Min >= 0) || !(a.Max >= 0) || math.IsInf(a.Min, 0) {
+		return errors.New("dead-band bounds must not be negative")
+	}
+	if a.Max != 0 && a.Min > a.Max {
+		return errors.New("min dead-band greater than max dead-band")
+	}
+	if a.Warmup < 0 {
+		return errors.New("warmup must not be negative")
+	}
+	return nil
+}
+
+func (a AdaptiveDeadBand) withDefaults() AdaptiveDeadBand {
+	if a.Multiplier == 0 {
+		a.Multiplier = 3
+	}
+	if a.Alpha == 0 {
+		a.Alpha = 0.05
+	}
+	if a.Warmup == 0 {
+		a.Warmup = 20
+	}
+	return a
+}
+
+// SetDeadBand sets a fixed dead-band: errors smaller in magnitude are
+// treated as zero. It turns the adaptive dead-band off.
+func (pid *PID) SetDeadBand(v float64) error {
+	if !(v >= 0) || math.IsInf(v, 0) {
+		return errors.New("dead-band must be finite and not negative")
+	}
+	defer pid.notifyChange()
+	pid.mu.Lock()
+	defer pid.mu.Unlock()
+	pid.deadBand = v
+	pid.adaptiveDeadBand = nil
+	pid.deadBandNoise = nil
+
+	return nil
+}
+
+// GetDeadBand returns the dead-band in effect, the latest estimate when
+// the adaptive dead-band is on.
+func (pid *PID) GetDeadBand() float64 {
+	pid.mu.Lock()
+	defer pid.mu.Unlock()
+	return pid.deadBand
+}
+
+// SetAdaptiveDeadBand sizes the dead-band from the noise of the calibrated
+// measurement after the measurement filter, estimated on every update, as
+// the dead-band applies to the error of the filtered value. Until Warmup
+// measurements have been seen the current dead-band stays in effect.
+func (pid *PID) SetAdaptiveDeadBand(a AdaptiveDeadBand) error {
+	if err := a.Validate(); err != nil {
+		return err
+	}
+	defer pid.notifyChange()
+	pid.mu.Lock()
+	defer pid.mu.Unlock()
+	pid.setAdaptiveDeadBandLocked(&a)
+
+	return nil
+}
+
+// ClearAdaptiveDeadBand turns the adaptive dead-band off, keeping the last
+// estimate as a fixed dead-band.
+func (pid *PID) ClearAdaptiveDeadBand() {
+	defer pid.notifyChange()
+	pid.mu.Lock()
+	defer pid.mu.Unlock()
+	pid.setAdaptiveDeadBandLocked(nil)
+}
+
+// GetAdaptiveDeadBand returns the adaptive dead-band configuration. The
+// boolean is false when the dead-band is fixed.
+func (pid *PID) GetAdaptiveDeadBand() (AdaptiveDeadBand, bool) {
+	pid.mu.Lock()
+	defer pid.mu.Unlock()
+	if pid.adaptiveDeadBand == nil {
+		return AdaptiveDeadBand{}, false
+	}
+	return *pid.adaptiveDeadBand, true
+}
+
+func (pid *PID) setAdaptiveDeadBandLocked(a *AdaptiveDeadBand) {
+	pid.adaptiveDeadBand, pid.deadBandNoise = nil, nil
+	if a == nil {
+		return
+	}
+	cfg := a.withDefaults()
+	pid.adaptiveDeadBand = &cfg
+	// alpha was validated, so the estimator cannot fail.
+	pid.deadBandNoise, _ = NewNoiseEstimator(cfg.Alpha)
+}
+
+// adaptDeadBandLocked feeds value to the dead-band noise estimator and,
+// once warmed up, moves the dead-band to the new estimate.
+func (pid *PID) adaptDeadBandLocked(value float64) {
+	if pid.adaptiveDeadBand == nil {
+		return
+	}
+	pid.deadBandNoise.Add(value)
+	st := pid.deadBandNoise.Stats()
+	if st.Samples < pid.adaptiveDeadBand.Warmup {
+		return
+	}
+	db := math.Max(pid.adaptiveDeadBand.Multiplier*math.Sqrt(st.Variance), pid.adaptiveDeadBand.Min)
+	if pid.adaptiveDeadBand.Max > 0 {
+		db = math.Min(db, pid.adaptiveDeadBand.Max)
+	}
+	pid.deadBand = db
+}
+
+func cloneAdaptiveDeadBand(a *AdaptiveDeadBand) *AdaptiveDeadBand {
+	if a == nil {
+		return nil
+	}
+	c := *a
+	return &c
+}
@@ -0,0 +1,117 @@
+package pidpool_test
+
+import (
+	"encoding/json"
+	"math"
+	"math/rand"
+	"testing"
+
+	"github.com/ankur-anand/go-pidpool"
+)
+
+func TestSetDeadBand(t *testing.T) {
+	pid := pidpool.NewP(1)
+	pid.SetSetPoint(10)
+	if err := pid.SetDeadBand(0.5); err != nil {
+		t.Fatalf("SetDeadBand err: %v", err)
+	}
+	if got := pid.GetDeadBand(); got != 0.5 {
+		t.Fatalf("GetDeadBand: expected 0.5, got %v", got)
+	}
+	if out := pid.UpdateDuration(9.7, 1); out != 0 {
+		t.Fatalf("expected an error inside the dead-band to be ignored, got %v", out)
+	}
+	if out := pid.UpdateDuration(9, 1); out != 1 {
+		t.Fatalf("expected an error outside the dead-band to act, got %v", out)
+	}
+
+	for _, v := range []float64{-1, math.NaN(), math.Inf(1)} {
+		if err := pid.SetDeadBand(v); err == nil {
+			t.Fatalf("expected an error for dead-band %v", v)
+		}
+	}
+}
+
+func TestAdaptiveDeadBand(t *testing.T) {
+	pid := pidpool.NewPI(1, 0.1)
+	pid.SetSetPoint(20)
+	if err := pid.SetAdaptiveDeadBand(pidpool.AdaptiveDeadBand{Multiplier: 3, Alpha: 0.02, Min: 0.01}); err != nil {
+		t.Fatalf("SetAdaptiveDeadBand err: %v", err)
+	}
+	cfg, ok := pid.GetAdaptiveDeadBand()
+	if !ok || cfg.Warmup != 20 || cfg.Multiplier != 3 {
+		t.Fatalf("unexpected configuration %+v, %v", cfg, ok)
+	}
+
+	rng := rand.New(rand.NewSource(1))
+	const sigma = 0.2
+	for i := 0; i < 19; i++ {
+		pid.UpdateDuration(20+rng.NormFloat64()*sigma, 0.1)
+	}
+	if got := pid.GetDeadBand(); got != 0 {
+		t.Fatalf("expected the dead-band to hold during warmup, got %v", got)
+	}
+	for i := 0; i < 2000; i++ {
+		pid.UpdateDuration(20+rng.NormFloat64()*sigma, 0.1)
+	}
+	if got := pid.GetDeadBand(); math.Abs(got-3*sigma) > 0.15 {
+		t.Fatalf("expected a dead-band near 3 sigma = %v, got %v", 3*sigma, got)
+	}
+	// noise alone no longer winds up the integral.
+	if i := pid.State().Integral; math.Abs(i) > 1 {
+		t.Fatalf("integral driven by noise: %v", i)
+	}
+
+	// the configuration survives a JSON round trip.
+	data, err := json.Marshal(pid.State())
+	if err != nil {
+		t.Fatalf("Marshal err: %v", err)
+	}
+	var st pidpool.State
+	if err := json.Unmarshal(data, &st); err != nil {
+		t.Fatalf("Unmarshal err: %v", err)
+	}
+	restored := pidpool.NewPI(1, 0.1)
+	if err := restored.RestoreState(st); err != nil {
+		t.Fatalf("RestoreState err: %v", err)
+	}
+	if got, ok := restored.GetAdaptiveDeadBand(); !ok || got != cfg {
+		t.Fatalf("adaptive dead-band not restored: %+v, %v", got, ok)
+	}
+
+	db := pid.GetDeadBand()
+	pid.ClearAdaptiveDeadBand()
+	if _, ok := pid.GetAdaptiveDeadBand(); ok {
+		t.Fatalf("expected the adaptive dead-band to be off")
+	}
+	pid.UpdateDuration(25, 0.1)
+	if got := pid.GetDeadBand(); got != db {
+		t.Fatalf("expected the last estimate to stay fixed, got %v", got)
+	}
+
+	if err := pid.SetAdaptiveDeadBand(pidpool.AdaptiveDeadBand{Min: 2, Max: 1}); err == nil {
+		t.Fatalf("expected an error for min above max")
+	}
+}
+
+func TestAdaptiveDeadBand_Filtered(t *testing.T) {
+	pid := pidpool.NewPI(1, 0.1)
+	pid.SetSetPoint(20)
+	if err := pid.SetMeasurementFilter(pidpool.MeasurementFilter{Kind: pidpool.FilterEMA, Alpha: 0.1}); err != nil {
+		t.Fatalf("SetMeasurementFilter err: %v", err)
+	}
+	if err := pid.SetAdaptiveDeadBand(pidpool.AdaptiveDeadBand{Alpha: 0.02}); err != nil {
+		t.Fatalf("SetAdaptiveDeadBand err: %v", err)
+	}
+
+	// the filter removes most of the noise, so the dead-band must follow
+	// the filtered measurement, not 3 sigma of the raw one.
+	rng := rand.New(rand.NewSource(1))
+	const sigma = 0.2
+	for i := 0; i < 2000; i++ {
+		pid.UpdateDuration(20+rng.NormFloat64()*sigma, 0.1)
+	}
+	if got := pid.GetDeadBand(); got > 1.5*sigma {
+		t.Fatalf("dead-band sized on the raw noise: %v", got)
+	}
+}
@@ -0,0 +1,117 @@
+package pidpool
+
+import (
+	"errors"
+	"fmt"
+	"time"
+)
+
+// IntegralMethod selects how the integral is discretized.
+type IntegralMethod int
+
+const (
+	// IntegralRectangular adds error*dt every update (backward Euler). This
+	// is the default.
+	IntegralRectangular IntegralMethod = iota
+	// IntegralTrapezoidal adds the mean of the previous and the current
+	// error times dt, which is exact for errors varying linearly between
+	// samples and much more accurate at low sample rates.
+	IntegralTrapezoidal
+)
+
+// DerivativeMethod selects how the derivative is discretized.
+type DerivativeMethod int
+
+const (
+	// DerivativeBackward differentiates with the backward difference. This
+	// is the default.
+	DerivativeBackward DerivativeMethod = iota
+	// DerivativeTustin differentiates with the bilinear (Tustin)
+	// transform of the filtered derivative s/(1+Tf*s), which keeps its
+	// phase at low sample rates. It requires a DerivativeFilter.
+	DerivativeTustin
+)
+
+// Discretization selects how the integral and derivative are discretized.
+// The zero value is the rectangular integral and the unfiltered backward
+// difference.
+type Discretization struct {
+	Integral   IntegralMethod   `json:"integral,omitempty"`
+	Derivative DerivativeMethod `json:"derivative,omitempty"`
+	// DerivativeFilter is the time constant Tf of the first-order filter
+	// on the derivative, s/(1+Tf*s). Zero differentiates unfiltered.
+	DerivativeFilter time.Duration `json:"derivativeFilter,omitempty"`
+}
+
+// Validate reports whether the discretization is well formed.
+func (d Discretization) Validate() error {
+	if d.Integral != IntegralRectangular && d.Integral != IntegralTrapezoidal {
+		return fmt.Errorf("unknown integral method %d", int(d.Integral))
+	}
+	if d.Derivative != DerivativeBackward && d.Derivative != DerivativeTustin {
+		return fmt.Errorf("unknown derivative method %d", int(d.Derivative))
+	}
+	if d.DerivativeFilter < 0 {
+		return errors.New("derivative filter time constant must not be negative")
+	}
+	if d.Derivative == DerivativeTustin && d.DerivativeFilter == 0 {
+		return errors.New("tustin derivative requires a derivative filter")
+	}
+	return nil
+}
+
+// SetDiscretization selects how the integral and derivative are
+// discretized. The derivative filter state restarts from zero. A measurement
+// filter that estimates the rate, such as FilterAlphaBeta, supplies the
+// derivative itself and takes precedence over the derivative method.
+func (pid *PID) SetDiscretization(d Discretization) error {
+	if err := d.Validate(); err != nil {
+		return err
+	}
+	defer pid.notifyChange()
+	pid.mu.Lock()
+	defer pid.mu.Unlock()
+	pid.discretization = d
+	pid.derivativeState = 0
+
+	return nil
+}
+
+// GetDiscretization returns the discretization.
+func (pid *PID) GetDiscretization() Discretization {
+	pid.mu.Lock()
+	defer pid.mu.Unlock()
+	return pid.discretization
+}
+
+// integralStepLocked returns the integral increment for err over dt.
+func (pid *PID) integralStepLocked(err, dt float64) float64 {
+	if pid.integralMaxDT > 0 && dt > pid.integralMaxDT {
+		dt = pid.integralMaxDT
+	}
+	if pid.discretization.Integral == IntegralTrapezoidal {
+		return float64(float64(err+pid.prevError) * dt / 2)
+	}
+	return float64(err * dt)
+}
+
+// derivativeLocked returns the derivative on measurement, -dy/dt, of value
+// since the previous update dt seconds ago.
+func (pid *PID) derivativeLocked(value, dt float64) float64 {
+	if dt <= 0 {
+		return 0
+	}
+	d := pid.discretization
+	delta := value - pid.prevValue
+	if d.DerivativeFilter == 0 {
+		return -delta / dt
+	}
+
+	tf := d.DerivativeFilter.Seconds()
+	if d.Derivative == DerivativeTustin {
+		pid.derivativeState = float64((2*tf-dt)/(2*tf+dt)*pid.derivativeState) - float64(2*delta/(2*tf+dt))
+	} else {
+		pid.derivativeState = float64(tf/(tf+dt)*pid.derivativeState) - float64(delta/(tf+dt))
+	}
+	return pid.derivativeState
+}
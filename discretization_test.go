@@ -0,0 +1,95 @@
+package pidpool_test
+
+import (
+	"math"
+	"testing"
+	"time"
+
+	"github.com/ankur-anand/go-pidpool"
+)
+
+// maxDeviation drives pid with the measurement y(t) sampled every dt for
+// 10 s and returns the largest deviation of the output from the continuous
+// reference want(t).
+func maxDeviation(pid *pidpool.PID, dt float64, y, want func(t float64) float64) float64 {
+	pid.UpdateDuration(y(0), 0)
+	worst := 0.0
+	for k := 1; float64(k)*dt <= 10; k++ {
+		ts := float64(k) * dt
+		out := pid.UpdateDuration(y(ts), dt)
+		worst = math.Max(worst, math.Abs(out-want(ts)))
+	}
+	return worst
+}
+
+func TestDiscretization_TrapezoidalIntegral(t *testing.T) {
+	newPID := func(m pidpool.IntegralMethod) *pidpool.PID {
+		pid := pidpool.NewPID(0, 1, 0, 0)
+		pid.SetIntegralLimits(math.Inf(-1), math.Inf(1))
+		if err := pid.SetDiscretization(pidpool.Discretization{Integral: m}); err != nil {
+			t.Fatalf("SetDiscretization err: %v", err)
+		}
+		return pid
+	}
+	// error sin(t) integrates to 1 - cos(t).
+	y := func(t float64) float64 { return -math.Sin(t) }
+	want := func(t float64) float64 { return 1 - math.Cos(t) }
+
+	euler := maxDeviation(newPID(pidpool.IntegralRectangular), 0.5, y, want)
+	trap := maxDeviation(newPID(pidpool.IntegralTrapezoidal), 0.5, y, want)
+	if trap > 0.05 || trap > euler/5 {
+		t.Fatalf("trapezoidal deviation %v not well below rectangular %v", trap, euler)
+	}
+}
+
+func TestDiscretization_TustinDerivative(t *testing.T) {
+	const tf = 0.2
+	newPID := func(m pidpool.DerivativeMethod) *pidpool.PID {
+		pid := pidpool.NewPID(0, 0, 1, 0)
+		d := pidpool.Discretization{Derivative: m, DerivativeFilter: 200 * time.Millisecond}
+		if err := pid.SetDiscretization(d); err != nil {
+			t.Fatalf("SetDiscretization err: %v", err)
+		}
+		return pid
+	}
+	// the steady-state response of -s/(1+tf*s) to sin(t), ignoring the
+	// decaying start-up transient.
+	y := func(t float64) float64 { return math.Sin(t) }
+	want := func(t float64) float64 {
+		if t < 3 {
+			return math.NaN()
+		}
+		return -math.Cos(t-math.Atan(tf)) / math.Sqrt(1+tf*tf)
+	}
+	dev := func(pid *pidpool.PID) float64 {
+		pid.UpdateDuration(y(0), 0)
+		worst := 0.0
+		for k := 1; k <= 40; k++ {
+			ts := float64(k) * 0.25
+			out := pid.UpdateDuration(y(ts), 0.25)
+			if w := want(ts); !math.IsNaN(w) {
+				worst = math.Max(worst, math.Abs(out-w))
+			}
+		}
+		return worst
+	}
+
+	backward := dev(newPID(pidpool.DerivativeBackward))
+	tustin := dev(newPID(pidpool.DerivativeTustin))
+	if tustin > 0.02 || tustin > backward/3 {
+		t.Fatalf("tustin deviation %v not well below backward %v", tustin, backward)
+	}
+
+	if err := pidpool.NewP(1).SetDiscretization(pidpool.Discretization{Derivative: pidpool.DerivativeTustin}); err == nil {
+		t.Fatalf("expected error for tustin without a filter")
+	}
+}
+
+func TestDiscretization_DefaultUnchanged(t *testing.T) {
+	pid := pidpool.NewPID(1, 0.5, 0.2, 0)
+	pid.SetSetPoint(10)
+	pid.UpdateDuration(2, 0.1)
+	if out := pid.UpdateDuration(3, 0.1); out != 7+0.5*(0.8+0.7)-0.2*10 {
+		t.Fatalf("default discretization changed: %v", out)
+	}
+}
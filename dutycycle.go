@@ -0,0 +1,164 @@
+package pidpool
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// ErrNoState is returned by a StateStore that holds no state yet.
+var ErrNoState = errors.New("no saved state")
+
+// StateStore persists controller state across restarts and deep sleep.
+type StateStore interface {
+	// Load returns the saved state, or ErrNoState.
+	Load() (State, error)
+	Save(State) error
+}
+
+// FileStore is a StateStore keeping the state as JSON in a file. Saves
+// write and sync a temporary file, rename it and sync the directory, so a
+// power cut mid-write leaves the previous state intact.
+type FileStore struct {
+	Path string
+}
+
+// Load implements StateStore.
+func (f FileStore) Load() (State, error) {
+	data, err := os.ReadFile(f.Path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return State{}, ErrNoState
+	}
+	if err != nil {
+		return State{}, err
+	}
+	var st State
+	if err := json.Unmarshal(data, &st); err != nil {
+		return State{}, err
+	}
+
+	return st, nil
+}
+
+// Save implements StateStore.
+func (f FileStore) Save(st State) error {
+	data, err := json.Marshal(st)
+	if err != nil {
+		return err
+	}
+	tmp := f.Path + ".tmp"
+	if err := writeSynced(tmp, data); err != nil {
+		return err
+	}
+	if err := os.Rename(tmp, f.Path); err != nil {
+		return err
+	}
+	// the rename is durable once the directory entry is on disk.
+	dir, err := os.Open(filepath.Dir(f.Path))
+	if err != nil {
+		return err
+	}
+	defer dir.Close()
+	return dir.Sync()
+}
+
+func writeSynced(path string, data []byte) error {
+	f, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0o600)
+	if err != nil {
+		return err
+	}
+	if _, err := f.Write(data); err != nil {
+		f.Close()
+		return err
+	}
+	if err := f.Sync(); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
+
+// DutyCycle configures a Runner for battery powered devices that sleep
+// between widely spaced updates.
+type DutyCycle struct {
+	// Store receives the controller state after every tick and provides it
+	// on Start and RunOnce, so the loop resumes after deep sleep or a
+	// restart as if it had never stopped.
+	Store StateStore
+	// MaxDT caps the dt the integral is advanced by in a single update.
+	// After a long sleep the wall clock gap can be hours, which would
+	// otherwise dump a huge error-time product into the integral in one
+	// step. The derivative and the measurement filter still see the real
+	// gap, so the change of the value over the sleep causes no derivative
+	// kick. Zero disables the cap.
+	MaxDT time.Duration
+	// OnError is called when loading or saving state fails in the
+	// background loop. RunOnce returns such errors instead.
+	OnError func(error)
+}
+
+// SetDutyCycle enables duty-cycling. It must be called before Start.
+func (r *Runner) SetDutyCycle(d DutyCycle) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.duty = d
+}
+
+// RunOnce restores the controller from the duty-cycle store, runs a single
+// tick and saves the state again. A device that powers down between
+// updates calls it once per wake-up instead of using Start.
+func (r *Runner) RunOnce(ctx context.Context) error {
+	r.mu.Lock()
+	duty := r.duty
+	r.mu.Unlock()
+
+	if err := r.restore(duty); err != nil {
+		return err
+	}
+	if err := r.tick(ctx); err != nil {
+		return err
+	}
+	return r.save(duty)
+}
+
+func (r *Runner) restore(duty DutyCycle) error {
+	if duty.Store == nil {
+		return nil
+	}
+	st, err := duty.Store.Load()
+	if errors.Is(err, ErrNoState) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	return r.pid.RestoreState(st)
+}
+
+func (r *Runner) save(duty DutyCycle) error {
+	if duty.Store == nil {
+		return nil
+	}
+	return duty.Store.Save(r.pid.State())
+}
+
+// updateCapped is UpdateWithQuality with the integral step limited to
+// maxDT. Zero means no limit.
+func (pid *PID) updateCapped(value float64, q Quality, maxDT time.Duration) float64 {
+	return pid.step(func() UpdateEvent {
+		now := time.Now()
+		dt := now.Sub(pid.lastUpdate)
+		pid.lastUpdate = now
+
+		pid.integralMaxDT = maxDT.Seconds()
+		ev := pid.qualityStepLocked(value, q, dt.Seconds())
+		pid.integralMaxDT = 0
+		ev.Time = now
+
+		return ev
+	}).Output
+}
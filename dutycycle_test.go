@@ -0,0 +1,78 @@
+package pidpool_test
+
+import (
+	"context"
+	"errors"
+	"math"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/ankur-anand/go-pidpool"
+)
+
+func TestRunner_RunOnceAcrossSleep(t *testing.T) {
+	store := pidpool.FileStore{Path: filepath.Join(t.TempDir(), "loop.json")}
+	if _, err := store.Load(); !errors.Is(err, pidpool.ErrNoState) {
+		t.Fatalf("expected ErrNoState, got %v", err)
+	}
+
+	// the state saved before the device went to sleep an hour ago.
+	before := pidpool.NewPI(0, 1)
+	before.SetSetPoint(20)
+	st := before.State()
+	st.LastUpdate = time.Now().Add(-time.Hour)
+	if err := store.Save(st); err != nil {
+		t.Fatalf("Save err: %v", err)
+	}
+
+	// after waking the process starts from scratch.
+	p := pidpool.NewPID(0, 0, 0, 0)
+	source := pidpool.SourceFunc(func(context.Context) (float64, error) { return 18, nil })
+	var written float64
+	sink := pidpool.SinkFunc(func(_ context.Context, out float64) error { written = out; return nil })
+	r := pidpool.NewRunner(p, time.Minute, source, sink)
+	r.SetDutyCycle(pidpool.DutyCycle{Store: store, MaxDT: 30 * time.Second})
+	if err := r.RunOnce(context.Background()); err != nil {
+		t.Fatalf("RunOnce err: %v", err)
+	}
+
+	// error 2 integrated over the capped 30s, not the hour asleep.
+	if written != 60 {
+		t.Fatalf("expected output 60, got %v", written)
+	}
+	saved, err := store.Load()
+	if err != nil {
+		t.Fatalf("Load err: %v", err)
+	}
+	if saved.Integral != 60 || time.Since(saved.LastUpdate) > time.Minute {
+		t.Fatalf("state not saved after tick: %+v", saved)
+	}
+}
+
+func TestRunner_MaxDTKeepsRealDerivative(t *testing.T) {
+	store := pidpool.FileStore{Path: filepath.Join(t.TempDir(), "loop.json")}
+	before := pidpool.NewPID(0, 0, 1, 0)
+	before.SetOutputLimits(-100, 100)
+	before.UpdateDuration(18, 1)
+	st := before.State()
+	st.LastUpdate = time.Now().Add(-time.Hour)
+	if err := store.Save(st); err != nil {
+		t.Fatalf("Save err: %v", err)
+	}
+
+	p := pidpool.NewPID(0, 0, 0, 0)
+	source := pidpool.SourceFunc(func(context.Context) (float64, error) { return 19, nil })
+	var written float64
+	sink := pidpool.SinkFunc(func(_ context.Context, out float64) error { written = out; return nil })
+	r := pidpool.NewRunner(p, time.Minute, source, sink)
+	r.SetDutyCycle(pidpool.DutyCycle{Store: store, MaxDT: 30 * time.Second})
+	if err := r.RunOnce(context.Background()); err != nil {
+		t.Fatalf("RunOnce err: %v", err)
+	}
+
+	// the value rose by 1 over the hour asleep, not over the capped 30s.
+	if want := -1.0 / 3600; math.Abs(written-want) > 1e-6 {
+		t.Fatalf("expected derivative output %v, got %v", want, written)
+	}
+}
@@ -0,0 +1,265 @@
+package pidpool
+
+import (
+	"fmt"
+	"sync"
+	"time"
+)
+
+// EventKind classifies a controller lifecycle event.
+type EventKind int
+
+const (
+	// EventSetPointChanged is emitted when the setpoint changes.
+	EventSetPointChanged EventKind = iota
+	// EventGainsChanged is emitted when Kp, Ki or Kd change.
+	EventGainsChanged
+	// EventModeChanged is emitted when the mode changes.
+	EventModeChanged
+	// EventSaturationEntered is emitted by the first update whose output
+	// is clamped to the output limits.
+	EventSaturationEntered
+	// EventSaturationLeft is emitted by the first update that is no longer
+	// clamped.
+	EventSaturationLeft
+	// EventFaultRaised is emitted when the controller enters the fault
+	// state.
+	EventFaultRaised
+	// EventFaultCleared is emitted when it leaves it.
+	EventFaultCleared
+	// EventUpdated is emitted by every update, with Update set and State
+	// left zero. Only subscribers registered with Updates receive it.
+	EventUpdated
+	// EventConfigChanged is emitted by every configuration change, with
+	// State and Previous set, alongside any of the kinds above. Only
+	// subscribers registered with Config receive it.
+	EventConfigChanged
+)
+
+// String implements fmt.Stringer.
+func (k EventKind) String() string {
+	switch k {
+	case EventSetPointChanged:
+		return "setpoint-changed"
+	case EventGainsChanged:
+		return "gains-changed"
+	case EventModeChanged:
+		return "mode-changed"
+	case EventSaturationEntered:
+		return "saturation-entered"
+	case EventSaturationLeft:
+		return "saturation-left"
+	case EventFaultRaised:
+		return "fault-raised"
+	case EventFaultCleared:
+		return "fault-cleared"
+	case EventUpdated:
+		return "updated"
+	case EventConfigChanged:
+		return "config-changed"
+	}
+	return fmt.Sprintf("EventKind(%d)", int(k))
+}
+
+// Event is a controller lifecycle or state-change event.
+type Event struct {
+	Kind EventKind
+	Time time.Time
+	// State is the controller state after the event and Previous the state
+	// before a configuration change.
+	State    State
+	Previous State
+	// Update is the update that ran, or that entered or left saturation.
+	Update UpdateEvent
+	// Fault is the fault raised.
+	Fault Fault
+}
+
+// Subscribe registers fn to be called with every event of the controller,
+// on the goroutine that caused it. Configuration changes are emitted by
+// the setter, saturation by the update, and faults by Trip, ClearFault or
+// the update that tripped. fn must not block. The returned function
+// removes the subscriber.
+//
+// The integrations of this package and its subpackages, such as
+// WithLogger, build on Subscribe and SubscribeWith.
+func (pid *PID) Subscribe(fn func(Event)) (remove func()) {
+	return pid.SubscribeWith(fn, SubscribeOptions{})
+}
+
+// SubscribeOptions selects the high-volume events a subscriber receives on
+// top of the lifecycle events.
+type SubscribeOptions struct {
+	// Updates delivers EventUpdated for every update.
+	Updates bool
+	// Config delivers EventConfigChanged for every configuration change.
+	Config bool
+}
+
+// SubscribeWith is Subscribe with the events selected by opts.
+func (pid *PID) SubscribeWith(fn func(Event), opts SubscribeOptions) (remove func()) {
+	return pid.eventBus().subscribe(&eventSubscriber{fn: fn, opts: opts})
+}
+
+// Events returns a channel receiving the events of the controller,
+// buffered to size. Events are dropped while the buffer is full. cancel
+// unsubscribes; the channel is not closed.
+func (pid *PID) Events(size int) (events <-chan Event, cancel func()) {
+	ch := make(chan Event, size)
+	cancel = pid.Subscribe(func(ev Event) {
+		select {
+		case ch <- ev:
+		default:
+		}
+	})
+	return ch, cancel
+}
+
+type eventSubscriber struct {
+	fn   func(Event)
+	opts SubscribeOptions
+}
+
+func (s *eventSubscriber) wants(k EventKind) bool {
+	switch k {
+	case EventUpdated:
+		return s.opts.Updates
+	case EventConfigChanged:
+		return s.opts.Config
+	}
+	return true
+}
+
+// eventBus derives the events from the configuration watchers and the
+// update hooks. It is created with the first subscriber and lives as long
+// as the controller.
+type eventBus struct {
+	mu        sync.Mutex
+	subs      []*eventSubscriber
+	prev      State
+	saturated bool
+	faulted   bool
+}
+
+func (pid *PID) eventBus() *eventBus {
+	pid.mu.Lock()
+	b := pid.bus
+	created := b == nil
+	if created {
+		b = &eventBus{
+			prev:      pid.stateLocked(),
+			saturated: pid.lastStatus.Saturated,
+			faulted:   pid.faulted,
+		}
+		pid.bus = b
+	}
+	pid.mu.Unlock()
+
+	if created {
+		pid.watch(b.observeState)
+		pid.OnUpdateWith(func(ev UpdateEvent) {
+			b.observeUpdate(pid, ev)
+		}, HookOptions{Name: "events"})
+	}
+	return b
+}
+
+func (b *eventBus) subscribe(s *eventSubscriber) (remove func()) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.subs = append(b.subs[:len(b.subs):len(b.subs)], s)
+
+	return func() {
+		b.mu.Lock()
+		defer b.mu.Unlock()
+		// copy on write, so in-flight events keep their own slice.
+		subs := make([]*eventSubscriber, 0, len(b.subs))
+		for _, cur := range b.subs {
+			if cur != s {
+				subs = append(subs, cur)
+			}
+		}
+		b.subs = subs
+	}
+}
+
+func (b *eventBus) emit(subs []*eventSubscriber, events []Event) {
+	for _, ev := range events {
+		for _, s := range subs {
+			if s.wants(ev.Kind) {
+				s.fn(ev)
+			}
+		}
+	}
+}
+
+func (b *eventBus) observeState(st State) {
+	now := time.Now()
+	b.mu.Lock()
+	prev := b.prev
+	b.prev = st
+	subs := b.subs
+	b.mu.Unlock()
+
+	var events []Event
+	add := func(kind EventKind) {
+		events = append(events, Event{Kind: kind, Time: now, State: st, Previous: prev})
+	}
+	if st.SetPoint != prev.SetPoint {
+		add(EventSetPointChanged)
+	}
+	if st.Kp != prev.Kp || st.Ki != prev.Ki || st.Kd != prev.Kd {
+		add(EventGainsChanged)
+	}
+	if st.Mode != prev.Mode {
+		add(EventModeChanged)
+	}
+	add(EventConfigChanged)
+	b.emit(subs, events)
+}
+
+func (b *eventBus) observeUpdate(pid *PID, ev UpdateEvent) {
+	saturated := ev.Status().Saturated
+	b.mu.Lock()
+	changed := saturated != b.saturated
+	b.saturated = saturated
+	subs := b.subs
+	b.mu.Unlock()
+
+	b.emit(subs, []Event{{Kind: EventUpdated, Time: ev.Time, Update: ev}})
+	if changed {
+		kind := EventSaturationLeft
+		if saturated {
+			kind = EventSaturationEntered
+		}
+		b.emit(subs, []Event{{Kind: kind, Time: ev.Time, State: pid.State(), Update: ev}})
+	}
+	b.syncFault(pid)
+}
+
+// syncFault emits the fault transition since the last call, if any. b may
+// be nil when nobody subscribed. It runs after every update, so the state
+// is only copied on a transition.
+func (b *eventBus) syncFault(pid *PID) {
+	if b == nil {
+		return
+	}
+	pid.mu.Lock()
+	fault, faulted := pid.fault, pid.faulted
+	pid.mu.Unlock()
+
+	b.mu.Lock()
+	changed := faulted != b.faulted
+	b.faulted = faulted
+	subs := b.subs
+	b.mu.Unlock()
+	if !changed {
+		return
+	}
+
+	ev := Event{Kind: EventFaultCleared, Time: time.Now(), State: pid.State()}
+	if faulted {
+		ev.Kind, ev.Time, ev.Fault = EventFaultRaised, fault.Time, fault
+	}
+	b.emit(subs, []Event{ev})
+}
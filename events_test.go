@@ -0,0 +1,168 @@
+package pidpool_test
+
+import (
+	"math"
+	"sync"
+	"testing"
+
+	"github.com/ankur-anand/go-pidpool"
+)
+
+func TestSubscribe_Events(t *testing.T) {
+	pid := pidpool.NewP(1)
+	pid.SetOutputLimits(0, 10)
+	pid.SetInvalidInputPolicy(pidpool.InvalidFault)
+
+	var got []pidpool.Event
+	remove := pid.Subscribe(func(ev pidpool.Event) { got = append(got, ev) })
+	kinds := func() []pidpool.EventKind {
+		var k []pidpool.EventKind
+		for _, ev := range got {
+			k = append(k, ev.Kind)
+		}
+		got = nil
+		return k
+	}
+	expect := func(want ...pidpool.EventKind) {
+		t.Helper()
+		k := kinds()
+		if len(k) != len(want) {
+			t.Fatalf("expected %v, got %v", want, k)
+		}
+		for i := range k {
+			if k[i] != want[i] {
+				t.Fatalf("expected %v, got %v", want, k)
+			}
+		}
+	}
+
+	pid.SetSetPoint(50)
+	if len(got) != 1 || got[0].Previous.SetPoint != 0 || got[0].State.SetPoint != 50 {
+		t.Fatalf("unexpected setpoint event: %+v", got)
+	}
+	expect(pidpool.EventSetPointChanged)
+
+	pid.SetPID(2, 0, 0)
+	pid.SetPID(2, 0, 0)
+	expect(pidpool.EventGainsChanged)
+
+	pid.UpdateDuration(0, 1)
+	pid.UpdateDuration(0, 1)
+	expect(pidpool.EventSaturationEntered)
+	pid.UpdateDuration(48, 1)
+	expect(pidpool.EventSaturationLeft)
+
+	pid.SetMode(pidpool.Manual)
+	pid.SetMode(pidpool.Auto)
+	expect(pidpool.EventModeChanged, pidpool.EventModeChanged)
+
+	// an invalid measurement trips the controller inside the update.
+	pid.UpdateDuration(math.NaN(), 1)
+	if len(got) != 1 || got[0].Kind != pidpool.EventFaultRaised || got[0].Fault.Reason == "" {
+		t.Fatalf("expected a fault event, got %+v", got)
+	}
+	got = nil
+	pid.Trip("again")
+	pid.ClearFault()
+	expect(pidpool.EventFaultCleared)
+
+	remove()
+	pid.SetSetPoint(1)
+	expect()
+}
+
+func TestEvents_Channel(t *testing.T) {
+	pid := pidpool.NewP(1)
+	events, cancel := pid.Events(1)
+	defer cancel()
+
+	pid.SetSetPoint(1)
+	pid.SetSetPoint(2) // dropped, the buffer is full.
+	if ev := <-events; ev.Kind != pidpool.EventSetPointChanged || ev.State.SetPoint != 1 {
+		t.Fatalf("unexpected event: %+v", ev)
+	}
+	select {
+	case ev := <-events:
+		t.Fatalf("expected the second event to be dropped, got %+v", ev)
+	default:
+	}
+}
+
+func TestSubscribeWith_UpdatesAndConfig(t *testing.T) {
+	pid := pidpool.NewP(1)
+	var plain, all []pidpool.EventKind
+	pid.Subscribe(func(ev pidpool.Event) { plain = append(plain, ev.Kind) })
+	pid.SubscribeWith(func(ev pidpool.Event) { all = append(all, ev.Kind) },
+		pidpool.SubscribeOptions{Updates: true, Config: true})
+
+	pid.SetSetPoint(5)
+	pid.SetOutputLimits(0, 10)
+	pid.UpdateDuration(0, 1)
+
+	if len(plain) != 1 || plain[0] != pidpool.EventSetPointChanged {
+		t.Fatalf("expected only the setpoint event for Subscribe, got %v", plain)
+	}
+	want := []pidpool.EventKind{
+		pidpool.EventSetPointChanged, pidpool.EventConfigChanged,
+		pidpool.EventConfigChanged,
+		pidpool.EventUpdated,
+	}
+	if len(all) != len(want) {
+		t.Fatalf("expected %v, got %v", want, all)
+	}
+	for i := range want {
+		if all[i] != want[i] {
+			t.Fatalf("expected %v, got %v", want, all)
+		}
+	}
+}
+
+func TestSubscribe_ConcurrentSettersInOrder(t *testing.T) {
+	for run := 0; run < 50; run++ {
+		pid := pidpool.NewP(1)
+		var mu sync.Mutex
+		last := 0.0
+		pid.Subscribe(func(e pidpool.Event) {
+			if e.Kind == pidpool.EventSetPointChanged {
+				mu.Lock()
+				last = e.State.SetPoint
+				mu.Unlock()
+			}
+		})
+
+		var wg sync.WaitGroup
+		for i := 1; i <= 8; i++ {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				pid.SetSetPoint(float64(i))
+			}()
+		}
+		wg.Wait()
+		mu.Lock()
+		got := last
+		mu.Unlock()
+		if want := pid.GetSetPoint(); got != want {
+			t.Fatalf("run %d: subscribers ended on setpoint %v, controller has %v", run, got, want)
+		}
+	}
+}
+
+func TestSubscribe_SetterInSubscriber(t *testing.T) {
+	pid := pidpool.NewP(1)
+	var seen []float64
+	pid.Subscribe(func(e pidpool.Event) {
+		if e.Kind != pidpool.EventSetPointChanged {
+			return
+		}
+		seen = append(seen, e.State.SetPoint)
+		if e.State.SetPoint > 10 {
+			// clamp the setpoint from inside the subscriber.
+			pid.SetSetPoint(10)
+		}
+	})
+	pid.SetSetPoint(20)
+	if len(seen) != 2 || seen[0] != 20 || seen[1] != 10 {
+		t.Fatalf("expected setpoints [20 10] in order, got %v", seen)
+	}
+}
@@ -0,0 +1,157 @@
+package pidpool
+
+import (
+	"errors"
+	"math"
+	"sort"
+	"sync"
+	"time"
+)
+
+// FairShare governs a shared resource, e.g. a global rate or concurrency
+// limit, across tenants. Every tenant has its own controller driven by its
+// SLO signal, whose output is that tenant's demand. When the demands fit
+// under the capacity every tenant gets its demand; otherwise the capacity is
+// divided by weighted max-min fairness: no tenant gets more than it asked
+// for, and the rest is split in proportion to the weights.
+//
+// A tenant whose allocation is capped below its demand does not integrate
+// its demand any higher, so its controller does not wind up on capacity it
+// cannot get and backs off as soon as its signal recovers.
+type FairShare struct {
+	pids *Manager
+
+	mu       sync.Mutex
+	capacity float64
+	weights  map[string]float64
+}
+
+// NewFairShare returns a FairShare for the given capacity that creates a
+// controller for each tenant with newPID on first use.
+func NewFairShare(capacity float64, newPID func(tenant string) *PID) (*FairShare, error) {
+	if capacity < 0 || math.IsNaN(capacity) {
+		return nil, errors.New("capacity must not be negative")
+	}
+	return &FairShare{
+		pids:     NewManager(newPID),
+		capacity: capacity,
+		weights:  make(map[string]float64),
+	}, nil
+}
+
+// Controllers returns the manager holding the per-tenant controllers.
+func (f *FairShare) Controllers() *Manager {
+	return f.pids
+}
+
+// SetCapacity sets the global cap.
+func (f *FairShare) SetCapacity(capacity float64) error {
+	if capacity < 0 || math.IsNaN(capacity) {
+		return errors.New("capacity must not be negative")
+	}
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.capacity = capacity
+
+	return nil
+}
+
+// SetWeight sets the fairness weight of a tenant. Tenants default to 1.
+func (f *FairShare) SetWeight(tenant string, weight float64) error {
+	if !(weight > 0) || math.IsInf(weight, 1) {
+		return errors.New("weight must be positive and finite")
+	}
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.weights[tenant] = weight
+
+	return nil
+}
+
+// Remove forgets a tenant and its controller.
+func (f *FairShare) Remove(tenant string) {
+	f.pids.Delete(tenant)
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	delete(f.weights, tenant)
+}
+
+// Update runs each tenant's controller on its signal and returns the
+// allocations by tenant. Negative demands count as zero. The allocations of
+// the tenants in signals never sum to more than the capacity.
+func (f *FairShare) Update(signals map[string]float64, dt float64) map[string]float64 {
+	demands := make(map[string]float64, len(signals))
+	before := make(map[string]float64, len(signals))
+	for tenant, v := range signals {
+		pid := f.pids.Get(tenant)
+		out := pid.step(func() UpdateEvent {
+			before[tenant] = pid.integral
+			ev := pid.updateInternal(v, dt)
+			ev.Time = time.Now()
+			return ev
+		}).Output
+		demands[tenant] = math.Max(0, out)
+	}
+
+	f.mu.Lock()
+	capacity := f.capacity
+	weights := make(map[string]float64, len(demands))
+	for tenant := range demands {
+		w, ok := f.weights[tenant]
+		if !ok {
+			w = 1
+		}
+		weights[tenant] = w
+	}
+	f.mu.Unlock()
+
+	alloc := fairAllocate(capacity, demands, weights)
+	for tenant, a := range alloc {
+		if a < demands[tenant] {
+			f.pids.Get(tenant).holdIntegralRise(before[tenant])
+		}
+	}
+	return alloc
+}
+
+// holdIntegralRise restores the integral to before if the last update
+// integrated it in the direction that raises the output.
+func (pid *PID) holdIntegralRise(before float64) {
+	pid.mu.Lock()
+	defer pid.mu.Unlock()
+	if pid.activeGainsLocked().ki*(pid.integral-before) > 0 {
+		pid.integral = before
+	}
+}
+
+// fairAllocate computes the weighted max-min fair allocation by water
+// filling: tenants are satisfied in order of demand per unit of weight, and
+// whatever a satisfied tenant leaves is shared among the others.
+func fairAllocate(capacity float64, demands, weights map[string]float64) map[string]float64 {
+	tenants := make([]string, 0, len(demands))
+	totalWeight := 0.0
+	for t := range demands {
+		tenants = append(tenants, t)
+		totalWeight += weights[t]
+	}
+	sort.Slice(tenants, func(i, j int) bool {
+		ri := demands[tenants[i]] / weights[tenants[i]]
+		rj := demands[tenants[j]] / weights[tenants[j]]
+		if ri != rj {
+			return ri < rj
+		}
+		return tenants[i] < tenants[j]
+	})
+
+	alloc := make(map[string]float64, len(tenants))
+	remaining := capacity
+	for _, t := range tenants {
+		share := remaining * weights[t] / totalWeight
+		a := math.Min(demands[t], share)
+		alloc[t] = a
+		remaining -= a
+		totalWeight -= weights[t]
+	}
+
+	return alloc
+}
@@ -0,0 +1,73 @@
+package pidpool_test
+
+import (
+	"math"
+	"testing"
+
+	"github.com/ankur-anand/go-pidpool"
+)
+
+func TestFairShare_WeightedCap(t *testing.T) {
+	// the controllers are pure P on the shortfall to each tenant's target,
+	// so a tenant's demand equals setpoint-signal.
+	f, err := pidpool.NewFairShare(100, func(tenant string) *pidpool.PID {
+		p := pidpool.NewP(1)
+		p.SetSetPoint(100)
+		return p
+	})
+	if err != nil {
+		t.Fatalf("NewFairShare err: %v", err)
+	}
+
+	// demands: a=10, b=80, c=90, total 180 > 100.
+	if err := f.SetWeight("c", 2); err != nil {
+		t.Fatalf("SetWeight err: %v", err)
+	}
+	got := f.Update(map[string]float64{"a": 90, "b": 20, "c": 10}, 1)
+
+	// a is satisfied with 10; b and c split the remaining 90 at 1:2.
+	want := map[string]float64{"a": 10, "b": 30, "c": 60}
+	sum := 0.0
+	for k, v := range want {
+		if math.Abs(got[k]-v) > 1e-9 {
+			t.Fatalf("tenant %s: expected %v, got %v (all %v)", k, v, got[k], got)
+		}
+		sum += got[k]
+	}
+	if sum > 100+1e-9 {
+		t.Fatalf("allocations exceed capacity: %v", sum)
+	}
+
+	// under the cap everyone gets their demand.
+	if err := f.SetCapacity(1000); err != nil {
+		t.Fatalf("SetCapacity err: %v", err)
+	}
+	got = f.Update(map[string]float64{"a": 90, "b": 20, "c": 10}, 1)
+	if got["b"] != 80 || got["c"] != 90 {
+		t.Fatalf("expected full demands under the cap, got %v", got)
+	}
+	if f.Controllers().Len() != 3 {
+		t.Fatalf("expected one controller per tenant")
+	}
+}
+
+func TestFairShare_CappedTenantsDoNotWindUp(t *testing.T) {
+	f, _ := pidpool.NewFairShare(100, func(tenant string) *pidpool.PID {
+		p := pidpool.NewPI(0, 1)
+		p.SetSetPoint(100)
+		return p
+	})
+
+	// each tenant asks for 50 more every step but gets 50 at most.
+	for i := 0; i < 20; i++ {
+		f.Update(map[string]float64{"a": 50, "b": 50}, 1)
+	}
+	if got := f.Controllers().Get("a").State().Integral; got != 50 {
+		t.Fatalf("expected the integral held at the capped demand 50, got %v", got)
+	}
+
+	got := f.Update(map[string]float64{"a": 150, "b": 50}, 1)
+	if got["a"] != 0 {
+		t.Fatalf("expected a to back off at once when its signal recovers, got %v", got)
+	}
+}
@@ -0,0 +1,181 @@
+package pidpool
+
+import (
+	"errors"
+	"math"
+	"sync/atomic"
+	"time"
+
+	"github.com/ankur-anand/go-pidpool/internal/locking"
+)
+
+// FastPID is a PID controller without a lock on the update path, for loops
+// running at rates where the mutex of PID is measurable.
+//
+// The caller must guarantee that Update, UpdateDuration and Reset are only
+// ever called from one goroutine. Everything else, reading or changing the
+// configuration and reading the last output, is safe from any goroutine:
+// the configuration lives in the store internal/locking picks for the
+// build target, which the update loop loads once per step.
+//
+// FastPID has no hooks, history, modes or feedforward; use PID for those.
+type FastPID struct {
+	cfg locking.Store[fastConfig]
+
+	// owned by the updating goroutine.
+	integral   float64
+	prevValue  float64
+	lastUpdate time.Time
+
+	lastOutput atomic.Uint64
+}
+
+type fastConfig struct {
+	kp, ki, kd  float64
+	setPoint    float64
+	deadBand    float64
+	outputMin   float64
+	outputMax   float64
+	integralMin float64
+	integralMax float64
+}
+
+var _ Controller = (*FastPID)(nil)
+
+// NewFastPID returns a new FastPID with the given gains and dead-band and
+// the same default limits as NewPID.
+func NewFastPID(kp, ki, kd, deadBand float64) *FastPID {
+	return &FastPID{
+		cfg: locking.New(fastConfig{
+			kp:          kp,
+			ki:          ki,
+			kd:          kd,
+			deadBand:    deadBand,
+			outputMin:   math.Inf(-1),
+			outputMax:   math.Inf(1),
+			integralMin: -100,
+			integralMax: 100,
+		}),
+		lastUpdate: time.Now(),
+	}
+}
+
+// SetOutputLimits sets min and max output.
+func (pid *FastPID) SetOutputLimits(min, max float64) error {
+	if min > max {
+		return errors.New("min output greater than max output")
+	}
+	pid.cfg.Update(func(c fastConfig) fastConfig {
+		c.outputMin, c.outputMax = min, max
+		return c
+	})
+
+	return nil
+}
+
+// SetIntegralLimits clamps the running sum (anti-windup). The accumulator
+// is clamped on the next update.
+func (pid *FastPID) SetIntegralLimits(min, max float64) error {
+	if min > max {
+		return errors.New("min integral greater than max integral")
+	}
+	pid.cfg.Update(func(c fastConfig) fastConfig {
+		c.integralMin, c.integralMax = min, max
+		return c
+	})
+
+	return nil
+}
+
+// SetSetPoint sets the setPoint.
+func (pid *FastPID) SetSetPoint(val float64) {
+	pid.cfg.Update(func(c fastConfig) fastConfig {
+		c.setPoint = val
+		return c
+	})
+}
+
+// GetSetPoint returns the current setPoint.
+func (pid *FastPID) GetSetPoint() float64 {
+	return pid.cfg.Load().setPoint
+}
+
+// SetPID sets the PID gains.
+func (pid *FastPID) SetPID(kp, ki, kd float64) {
+	pid.cfg.Update(func(c fastConfig) fastConfig {
+		c.kp, c.ki, c.kd = kp, ki, kd
+		return c
+	})
+}
+
+// GetPID returns the PID gains.
+func (pid *FastPID) GetPID() (float64, float64, float64) {
+	c := pid.cfg.Load()
+	return c.kp, c.ki, c.kd
+}
+
+// LastOutput returns the output of the most recent update.
+func (pid *FastPID) LastOutput() float64 {
+	return math.Float64frombits(pid.lastOutput.Load())
+}
+
+// Update runs the PID calculation. Uses wall time for dt. Must only be
+// called from the updating goroutine.
+func (pid *FastPID) Update(value float64) float64 {
+	now := time.Now()
+	dt := now.Sub(pid.lastUpdate).Seconds()
+	pid.lastUpdate = now
+
+	return pid.UpdateDuration(value, dt)
+}
+
+// UpdateDuration allows custom duration between updates. Must only be
+// called from the updating goroutine.
+//
+// The arithmetic matches PID.UpdateDuration, so both produce identical
+// outputs for the same configuration and inputs.
+func (pid *FastPID) UpdateDuration(value float64, dt float64) float64 {
+	c := pid.cfg.Load()
+
+	err := c.setPoint - value
+	if math.Abs(err) < c.deadBand {
+		err = 0
+	}
+
+	pid.integral += float64(err * dt)
+	if pid.integral > c.integralMax {
+		pid.integral = c.integralMax
+	} else if pid.integral < c.integralMin {
+		pid.integral = c.integralMin
+	}
+
+	derivative := 0.0
+	if dt > 0 {
+		derivative = -(value - pid.prevValue) / dt
+	}
+	pid.prevValue = value
+
+	pTerm := float64(c.kp * err)
+	iTerm := float64(c.ki * pid.integral)
+	dTerm := float64(c.kd * derivative)
+	output := pTerm + iTerm
+	output += dTerm
+	if output > c.outputMax {
+		output = c.outputMax
+	} else if output < c.outputMin {
+		output = c.outputMin
+	}
+
+	pid.lastOutput.Store(math.Float64bits(output))
+
+	return output
+}
+
+// Reset clears the integral, the measurement history and the last output.
+// Must only be called from the updating goroutine.
+func (pid *FastPID) Reset() {
+	pid.integral = 0
+	pid.prevValue = 0
+	pid.lastUpdate = time.Now()
+	pid.lastOutput.Store(0)
+}
@@ -0,0 +1,65 @@
+package pidpool_test
+
+import (
+	"sync"
+	"testing"
+
+	"github.com/ankur-anand/go-pidpool"
+)
+
+func TestFastPID_MatchesPID(t *testing.T) {
+	slow := pidpool.NewPID(1.2, 0.4, 0.05, 0.1)
+	fast := pidpool.NewFastPID(1.2, 0.4, 0.05, 0.1)
+	for _, c := range []interface {
+		SetSetPoint(float64)
+		SetOutputLimits(float64, float64) error
+	}{slow, fast} {
+		c.SetSetPoint(50)
+		_ = c.SetOutputLimits(-20, 20)
+	}
+
+	for i := 0; i < 200; i++ {
+		v := float64(i%37) * 1.7
+		if a, b := slow.UpdateDuration(v, 0.01), fast.UpdateDuration(v, 0.01); a != b {
+			t.Fatalf("step %d: PID %v != FastPID %v", i, a, b)
+		}
+	}
+	if fast.LastOutput() != slow.LastOutput() {
+		t.Fatalf("last output mismatch")
+	}
+}
+
+func TestFastPID_ConcurrentConfig(t *testing.T) {
+	p := pidpool.NewFastPID(1, 0, 0, 0)
+	var wg sync.WaitGroup
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		for i := 0; i < 1000; i++ {
+			p.SetSetPoint(float64(i))
+			_, _, _ = p.GetPID()
+			_ = p.LastOutput()
+		}
+	}()
+	for i := 0; i < 1000; i++ {
+		p.UpdateDuration(0, 0.001)
+	}
+	wg.Wait()
+	if p.GetSetPoint() != 999 {
+		t.Fatalf("expected setpoint 999, got %v", p.GetSetPoint())
+	}
+}
+
+func BenchmarkPID_UpdateDuration(b *testing.B) {
+	p := pidpool.NewPID(1, 0.1, 0.01, 0)
+	for i := 0; i < b.N; i++ {
+		p.UpdateDuration(float64(i&63), 0.0001)
+	}
+}
+
+func BenchmarkFastPID_UpdateDuration(b *testing.B) {
+	p := pidpool.NewFastPID(1, 0.1, 0.01, 0)
+	for i := 0; i < b.N; i++ {
+		p.UpdateDuration(float64(i&63), 0.0001)
+	}
+}
@@ -0,0 +1,93 @@
+package pidpool
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"time"
+)
+
+// Fault describes why a controller is in the fault state.
+type Fault struct {
+	Time   time.Time
+	Reason string
+}
+
+// FailSafeMode selects the output of a faulted controller.
+type FailSafeMode int
+
+const (
+	// FailSafeHold holds the last output before the fault. This is the
+	// default.
+	FailSafeHold FailSafeMode = iota
+	// FailSafeOutput drives FailSafe.Output, e.g. 0% for a heater.
+	FailSafeOutput
+)
+
+// FailSafe configures the output latched while the controller is faulted.
+type FailSafe struct {
+	Mode FailSafeMode
+	// Output is the output of FailSafeOutput, clamped to the output
+	// limits.
+	Output float64
+}
+
+// SetFailSafe sets the output latched while the controller is faulted.
+func (pid *PID) SetFailSafe(f FailSafe) error {
+	if f.Mode != FailSafeHold && f.Mode != FailSafeOutput {
+		return fmt.Errorf("unknown fail-safe mode %d", int(f.Mode))
+	}
+	if math.IsNaN(f.Output) || math.IsInf(f.Output, 0) {
+		return errors.New("fail-safe output must be finite")
+	}
+	pid.mu.Lock()
+	defer pid.mu.Unlock()
+	pid.failSafe = f
+
+	return nil
+}
+
+// GetFailSafe returns the fail-safe configuration.
+func (pid *PID) GetFailSafe() FailSafe {
+	pid.mu.Lock()
+	defer pid.mu.Unlock()
+	return pid.failSafe
+}
+
+// Trip puts the controller in the fault state for reason, e.g. from an
+// external interlock. Every update from now on is rejected with ErrFaulted
+// and outputs the fail-safe value until ClearFault. Tripping a faulted
+// controller keeps the original reason.
+func (pid *PID) Trip(reason string) {
+	pid.mu.Lock()
+	pid.tripLocked(reason)
+	bus := pid.bus
+	pid.mu.Unlock()
+	bus.syncFault(pid)
+}
+
+func (pid *PID) tripLocked(reason string) {
+	if pid.faulted {
+		return
+	}
+	pid.faulted = true
+	pid.fault = Fault{Time: time.Now(), Reason: reason}
+}
+
+// Fault returns the active fault. The boolean is false when the controller
+// is not faulted.
+func (pid *PID) Fault() (Fault, bool) {
+	pid.mu.Lock()
+	defer pid.mu.Unlock()
+	return pid.fault, pid.faulted
+}
+
+// faultLocked returns the event of an update rejected by the fault state.
+func (pid *PID) faultLocked(value, dt float64) UpdateEvent {
+	ev := pid.holdLocked(value, dt)
+	if pid.failSafe.Mode == FailSafeOutput {
+		ev.RawOutput = pid.failSafe.Output
+		ev.Output = math.Max(pid.outputMin, math.Min(pid.outputMax, pid.failSafe.Output))
+	}
+	return ev
+}
@@ -0,0 +1,75 @@
+package pidpool_test
+
+import (
+	"errors"
+	"math"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/ankur-anand/go-pidpool"
+)
+
+func TestTripAndFailSafe(t *testing.T) {
+	p := pidpool.NewP(1)
+	p.SetSetPoint(10)
+	p.SetOutputLimits(0, 100)
+	if err := p.SetFailSafe(pidpool.FailSafe{Mode: pidpool.FailSafeOutput, Output: -5}); err != nil {
+		t.Fatalf("SetFailSafe err: %v", err)
+	}
+	p.UpdateDuration(4, 1)
+
+	p.Trip("door open")
+	p.Trip("second trip")
+	f, ok := p.Fault()
+	if !ok || f.Reason != "door open" {
+		t.Fatalf("expected door open fault, got %+v (%v)", f, ok)
+	}
+	out, err := p.TryUpdate(0)
+	if !errors.Is(err, pidpool.ErrFaulted) || !strings.Contains(err.Error(), "door open") || out != 0 {
+		t.Fatalf("expected fail-safe 0 with ErrFaulted, got %v %v", out, err)
+	}
+	// the fault overrides the quality policy too.
+	if out, _ := p.UpdateWithQuality(0, pidpool.QualityBad); out != 0 {
+		t.Fatalf("expected fail-safe 0, got %v", out)
+	}
+
+	p.ClearFault()
+	if _, ok := p.Fault(); ok {
+		t.Fatalf("expected fault cleared")
+	}
+	if out := p.UpdateDuration(4, 1); out != 6 {
+		t.Fatalf("expected normal output 6, got %v", out)
+	}
+}
+
+func TestInvalidFault_Reason(t *testing.T) {
+	p := pidpool.NewP(1)
+	p.SetInvalidInputPolicy(pidpool.InvalidFault)
+	p.TryUpdate(math.NaN())
+	if f, ok := p.Fault(); !ok || !strings.HasPrefix(f.Reason, "invalid input") {
+		t.Fatalf("expected invalid input fault, got %+v (%v)", f, ok)
+	}
+}
+
+func TestStallDetector_Trip(t *testing.T) {
+	d, err := pidpool.NewStallDetector(pidpool.StallDetectorConfig{
+		MinOutputChange: 1,
+		Window:          300 * time.Millisecond,
+		Trip:            true,
+	})
+	if err != nil {
+		t.Fatalf("NewStallDetector err: %v", err)
+	}
+	pid := pidpool.NewPI(1, 1)
+	pid.SetSetPoint(10)
+	defer d.Attach(pid)()
+
+	for i := 0; i < 10 && !pid.Faulted(); i++ {
+		time.Sleep(60 * time.Millisecond)
+		pid.Update(0)
+	}
+	if f, ok := pid.Fault(); !ok || !strings.Contains(f.Reason, "stalled") {
+		t.Fatalf("expected a stall fault, got %+v (%v)", f, ok)
+	}
+}
@@ -0,0 +1,198 @@
+package pidpool
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+)
+
+// Delta is a single sequence-numbered change published on a ChangeFeed. It
+// carries the full state of the affected controller, so applying the same
+// delta twice is harmless.
+type Delta struct {
+	Seq     uint64
+	Time    time.Time
+	Key     string
+	Removed bool
+	State   State
+}
+
+// ChangeFeed is a bounded, sequence-numbered log of controller changes.
+// Consumers such as dashboards and remote clients remember the last
+// sequence number they applied and ask for everything after it, instead of
+// polling full snapshots.
+type ChangeFeed struct {
+	mu sync.Mutex
+
+	seq    uint64
+	buf    []Delta
+	start  int
+	notify chan struct{}
+}
+
+// NewChangeFeed returns a feed that retains the last capacity deltas.
+func NewChangeFeed(capacity int) (*ChangeFeed, error) {
+	if capacity <= 0 {
+		return nil, errors.New("capacity must be positive")
+	}
+
+	return &ChangeFeed{
+		buf:    make([]Delta, 0, capacity),
+		notify: make(chan struct{}),
+	}, nil
+}
+
+// Publish appends a delta for key and returns its sequence number.
+func (f *ChangeFeed) Publish(key string, st State) uint64 {
+	return f.publish(Delta{Key: key, State: st})
+}
+
+// PublishRemoved appends a delta recording that key was removed.
+func (f *ChangeFeed) PublishRemoved(key string) uint64 {
+	return f.publish(Delta{Key: key, Removed: true})
+}
+
+func (f *ChangeFeed) publish(d Delta) uint64 {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.seq++
+	d.Seq = f.seq
+	d.Time = time.Now()
+
+	if len(f.buf) < cap(f.buf) {
+		f.buf = append(f.buf, d)
+	} else {
+		f.buf[f.start] = d
+		f.start = (f.start + 1) % len(f.buf)
+	}
+
+	// wake up waiters.
+	close(f.notify)
+	f.notify = make(chan struct{})
+
+	return d.Seq
+}
+
+// Seq returns the sequence number of the latest delta.
+func (f *ChangeFeed) Seq() uint64 {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.seq
+}
+
+// Since returns the deltas published after seq. The boolean is false when
+// some of those deltas have already been evicted; the consumer must then
+// resync from a full snapshot.
+func (f *ChangeFeed) Since(seq uint64) ([]Delta, bool) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if seq >= f.seq {
+		return nil, true
+	}
+	n := int(f.seq - seq)
+	if n > len(f.buf) {
+		return nil, false
+	}
+
+	out := make([]Delta, 0, n)
+	for i := len(f.buf) - n; i < len(f.buf); i++ {
+		out = append(out, f.buf[(f.start+i)%len(f.buf)])
+	}
+
+	return out, true
+}
+
+// Wait blocks until a delta newer than seq is published or ctx is done.
+func (f *ChangeFeed) Wait(ctx context.Context, seq uint64) error {
+	for {
+		f.mu.Lock()
+		if f.seq > seq {
+			f.mu.Unlock()
+			return nil
+		}
+		ch := f.notify
+		f.mu.Unlock()
+
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-ch:
+		}
+	}
+}
+
+// stateWatcher is a registered watch callback; its address identifies it
+// for removal.
+type stateWatcher struct {
+	fn func(State)
+}
+
+// watch registers fn to be called with the new state after every
+// configuration change. The returned function removes it.
+func (pid *PID) watch(fn func(State)) (remove func()) {
+	w := &stateWatcher{fn: fn}
+	pid.mu.Lock()
+	defer pid.mu.Unlock()
+	pid.watchers = append(pid.watchers[:len(pid.watchers):len(pid.watchers)], w)
+
+	return func() {
+		pid.mu.Lock()
+		defer pid.mu.Unlock()
+		// copy on write, so in-flight notifications keep their own slice.
+		watchers := make([]*stateWatcher, 0, len(pid.watchers))
+		for _, cur := range pid.watchers {
+			if cur != w {
+				watchers = append(watchers, cur)
+			}
+		}
+		pid.watchers = watchers
+	}
+}
+
+// stateChange is a state copy waiting to be delivered to watchers.
+type stateChange struct {
+	st       State
+	watchers []*stateWatcher
+}
+
+// notifyChange must be called without pid.mu held. The state is copied
+// under the lock and queued, and one caller at a time delivers the queue,
+// so watchers see concurrent changes in the order they were made. A
+// change made while another caller delivers, including one made by a
+// watcher, is delivered by that caller.
+func (pid *PID) notifyChange() {
+	pid.mu.Lock()
+	if len(pid.watchers) == 0 {
+		pid.mu.Unlock()
+		return
+	}
+	pid.changes = append(pid.changes, stateChange{st: pid.stateLocked(), watchers: pid.watchers})
+	if pid.notifying {
+		pid.mu.Unlock()
+		return
+	}
+	pid.notifying = true
+	done := false
+	defer func() {
+		if !done {
+			// a watcher panicked; let the next change deliver again.
+			pid.mu.Lock()
+			pid.changes, pid.notifying = nil, false
+			pid.mu.Unlock()
+		}
+	}()
+	for len(pid.changes) > 0 {
+		c := pid.changes[0]
+		pid.changes = pid.changes[1:]
+		pid.mu.Unlock()
+		for _, w := range c.watchers {
+			w.fn(c.st)
+		}
+		pid.mu.Lock()
+	}
+	pid.changes = nil
+	pid.notifying = false
+	done = true
+	pid.mu.Unlock()
+}
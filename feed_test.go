@@ -0,0 +1,61 @@
+package pidpool_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/ankur-anand/go-pidpool"
+)
+
+func TestChangeFeed_SinceAndEviction(t *testing.T) {
+	f, err := pidpool.NewChangeFeed(3)
+	if err != nil {
+		t.Fatalf("NewChangeFeed err: %v", err)
+	}
+	for i := 0; i < 5; i++ {
+		f.Publish("k", pidpool.State{SetPoint: float64(i)})
+	}
+
+	ds, ok := f.Since(2)
+	if !ok || len(ds) != 3 || ds[0].Seq != 3 || ds[2].State.SetPoint != 4 {
+		t.Fatalf("unexpected deltas: ok=%v %+v", ok, ds)
+	}
+	if _, ok := f.Since(1); ok {
+		t.Fatalf("expected resync for evicted deltas")
+	}
+	if ds, ok := f.Since(5); !ok || len(ds) != 0 {
+		t.Fatalf("expected no deltas when up to date")
+	}
+}
+
+func TestManager_FeedTracksChanges(t *testing.T) {
+	m := pidpool.NewManager(newTenantPID)
+	m.Get("a")
+	seq, states := m.Snapshot()
+	if len(states) != 1 {
+		t.Fatalf("expected 1 state in snapshot, got %d", len(states))
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	done := make(chan error, 1)
+	go func() { done <- m.Feed().Wait(ctx, seq) }()
+
+	m.Get("a").SetSetPoint(7)
+	if err := <-done; err != nil {
+		t.Fatalf("Wait err: %v", err)
+	}
+	m.Delete("a")
+
+	ds, ok := m.Feed().Since(seq)
+	if !ok || len(ds) != 2 {
+		t.Fatalf("expected 2 deltas, got ok=%v %+v", ok, ds)
+	}
+	if ds[0].Key != "a" || ds[0].State.SetPoint != 7 {
+		t.Fatalf("unexpected setpoint delta: %+v", ds[0])
+	}
+	if !ds[1].Removed {
+		t.Fatalf("expected removal delta, got %+v", ds[1])
+	}
+}
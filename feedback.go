@@ -0,0 +1,109 @@
+package pidpool
+
+import (
+	"errors"
+	"math"
+	"time"
+)
+
+// PositionMismatch describes an actuator whose reported position has not
+// followed the commanded output for longer than the configured persistence.
+type PositionMismatch struct {
+	Time     time.Time
+	Command  float64
+	Position float64
+	// Duration is how long the mismatch has lasted.
+	Duration time.Duration
+}
+
+// PositionFeedback configures the comparison of the commanded output with
+// the actuator position feedback, e.g. a valve's position transmitter.
+type PositionFeedback struct {
+	// Tolerance is the largest acceptable |position - command|.
+	Tolerance float64
+	// Persistence is how long a mismatch must last before it is reported,
+	// which gives a healthy actuator time to travel.
+	Persistence time.Duration
+	// FreezeIntegral stops the integral from accumulating while a mismatch
+	// is reported, so controlling through a dead actuator cannot wind the
+	// integrator up.
+	FreezeIntegral bool
+	// OnMismatch is called once when a mismatch is reported.
+	OnMismatch func(PositionMismatch)
+	// OnRecover is called when the position follows the command again.
+	OnRecover func()
+}
+
+// SetPositionFeedback enables position feedback checking. Positions are
+// passed in with ReportPosition.
+func (pid *PID) SetPositionFeedback(cfg PositionFeedback) error {
+	if cfg.Tolerance < 0 || cfg.Persistence < 0 {
+		return errors.New("tolerance and persistence must not be negative")
+	}
+	pid.mu.Lock()
+	defer pid.mu.Unlock()
+	pid.feedback = &positionState{cfg: cfg}
+
+	return nil
+}
+
+type positionState struct {
+	cfg PositionFeedback
+
+	mismatchSince time.Time
+	reported      bool
+}
+
+// ReportPosition compares the measured actuator position with the last
+// output.
+func (pid *PID) ReportPosition(position float64) {
+	pid.ReportPositionAt(position, time.Now())
+}
+
+// ReportPositionAt is ReportPosition for a position measured at t.
+func (pid *PID) ReportPositionAt(position float64, t time.Time) {
+	pid.mu.Lock()
+	fb := pid.feedback
+	if fb == nil {
+		pid.mu.Unlock()
+		return
+	}
+	command := pid.lastOutput
+
+	var notify func()
+	if math.Abs(position-command) <= fb.cfg.Tolerance {
+		if fb.reported && fb.cfg.OnRecover != nil {
+			notify = fb.cfg.OnRecover
+		}
+		fb.mismatchSince, fb.reported = time.Time{}, false
+	} else {
+		if fb.mismatchSince.IsZero() {
+			fb.mismatchSince = t
+		}
+		if d := t.Sub(fb.mismatchSince); !fb.reported && d >= fb.cfg.Persistence {
+			fb.reported = true
+			if fb.cfg.OnMismatch != nil {
+				m := PositionMismatch{Time: t, Command: command, Position: position, Duration: d}
+				notify = func() { fb.cfg.OnMismatch(m) }
+			}
+		}
+	}
+	pid.mu.Unlock()
+
+	if notify != nil {
+		notify()
+	}
+}
+
+// PositionMismatched reports whether a position mismatch is reported.
+func (pid *PID) PositionMismatched() bool {
+	pid.mu.Lock()
+	defer pid.mu.Unlock()
+	return pid.feedback != nil && pid.feedback.reported
+}
+
+// integralFrozenLocked reports whether the integral must not accumulate.
+func (pid *PID) integralFrozenLocked() bool {
+	fb := pid.feedback
+	return pid.holdIntegral || fb != nil && fb.reported && fb.cfg.FreezeIntegral
+}
@@ -0,0 +1,49 @@
+package pidpool_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/ankur-anand/go-pidpool"
+)
+
+func TestPositionFeedback_StuckValve(t *testing.T) {
+	p := pidpool.NewPI(1, 1)
+	p.SetSetPoint(10)
+
+	var mismatches []pidpool.PositionMismatch
+	recovered := false
+	err := p.SetPositionFeedback(pidpool.PositionFeedback{
+		Tolerance:      1,
+		Persistence:    3 * time.Second,
+		FreezeIntegral: true,
+		OnMismatch:     func(m pidpool.PositionMismatch) { mismatches = append(mismatches, m) },
+		OnRecover:      func() { recovered = true },
+	})
+	if err != nil {
+		t.Fatalf("SetPositionFeedback err: %v", err)
+	}
+
+	t0 := time.Unix(0, 0)
+	for s := 0; s <= 3; s++ {
+		p.UpdateDuration(0, 1)
+		p.ReportPositionAt(0, t0.Add(time.Duration(s)*time.Second)) // valve stuck shut.
+	}
+	if len(mismatches) != 1 || !p.PositionMismatched() {
+		t.Fatalf("expected one mismatch, got %v", mismatches)
+	}
+	if m := mismatches[0]; m.Duration != 3*time.Second || m.Position != 0 {
+		t.Fatalf("unexpected mismatch %+v", m)
+	}
+
+	frozen := p.State().Integral
+	p.UpdateDuration(0, 1)
+	if got := p.State().Integral; got != frozen {
+		t.Fatalf("integral kept winding while frozen: %v -> %v", frozen, got)
+	}
+
+	p.ReportPositionAt(p.LastOutput(), t0.Add(5*time.Second))
+	if !recovered || p.PositionMismatched() {
+		t.Fatalf("expected recovery once the position follows")
+	}
+}
@@ -0,0 +1,121 @@
+package pidpool
+
+import (
+	"errors"
+	"fmt"
+)
+
+// SetFeedForward sets a feedforward value that is added to the output of
+// every update before the output limits are applied.
+func (pid *PID) SetFeedForward(v float64) {
+	pid.mu.Lock()
+	defer pid.mu.Unlock()
+	pid.feedForward = v
+}
+
+// GetFeedForward returns the feedforward value.
+func (pid *PID) GetFeedForward() float64 {
+	pid.mu.Lock()
+	defer pid.mu.Unlock()
+	return pid.feedForward
+}
+
+// LinkSignal selects which signal of the upstream controller is fed
+// forward.
+type LinkSignal int
+
+const (
+	// LinkOutput feeds forward the upstream controller's output.
+	LinkOutput LinkSignal = iota
+	// LinkValue feeds forward the upstream process value, e.g. a measured
+	// flow that disturbs a downstream temperature loop.
+	LinkValue
+)
+
+// Link wires a signal of one controller into the feedforward input of
+// another, scaled by Gain.
+type Link struct {
+	From   string
+	To     string
+	Gain   float64
+	Signal LinkSignal
+}
+
+// ffLink is a registered Link with its current contribution.
+type ffLink struct {
+	Link
+	from, to *PID
+	value    float64
+	remove   func()
+}
+
+// Link registers l. Every update of l.From sets its contribution to the
+// feedforward of l.To; contributions of several links into the same
+// controller are summed in registration order. Both controllers are
+// created if needed. The returned function removes the link and its
+// contribution; deleting either controller from the Manager does too.
+func (m *Manager) Link(l Link) (unlink func(), err error) {
+	if l.From == l.To {
+		return nil, errors.New("a controller cannot feed forward into itself")
+	}
+	if l.Signal != LinkOutput && l.Signal != LinkValue {
+		return nil, fmt.Errorf("unknown link signal %d", int(l.Signal))
+	}
+
+	fl := &ffLink{Link: l, from: m.Get(l.From), to: m.Get(l.To)}
+	fl.remove = fl.from.OnUpdateWith(func(ev UpdateEvent) {
+		v := ev.Output
+		if l.Signal == LinkValue {
+			v = ev.Value
+		}
+		m.setContribution(fl, l.Gain*v)
+	}, HookOptions{Name: "feedforward " + l.From + "->" + l.To})
+	m.mu.Lock()
+	m.links = append(m.links, fl)
+	m.mu.Unlock()
+
+	return func() { m.unlink(func(cur *ffLink) bool { return cur == fl }) }, nil
+}
+
+func (m *Manager) setContribution(fl *ffLink, v float64) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	fl.value = v
+	m.sumFeedForwardLocked(fl.to)
+}
+
+// unlink removes the links matched by fn and their contributions.
+func (m *Manager) unlink(fn func(*ffLink) bool) {
+	var removed []*ffLink
+	m.mu.Lock()
+	links := make([]*ffLink, 0, len(m.links))
+	for _, fl := range m.links {
+		if fn(fl) {
+			removed = append(removed, fl)
+			continue
+		}
+		links = append(links, fl)
+	}
+	m.links = links
+	for _, fl := range removed {
+		m.sumFeedForwardLocked(fl.to)
+	}
+	m.mu.Unlock()
+
+	for _, fl := range removed {
+		fl.remove()
+	}
+}
+
+// sumFeedForwardLocked sets the feedforward of to to the sum of the
+// contributions of its links, in registration order so the result does
+// not depend on map iteration.
+func (m *Manager) sumFeedForwardLocked(to *PID) {
+	sum := 0.0
+	for _, fl := range m.links {
+		if fl.to == to {
+			sum += fl.value
+		}
+	}
+	to.SetFeedForward(sum)
+}
@@ -0,0 +1,85 @@
+package pidpool_test
+
+import (
+	"testing"
+
+	"github.com/ankur-anand/go-pidpool"
+)
+
+func TestManager_LinkSumsContributions(t *testing.T) {
+	m := pidpool.NewManager(func(string) *pidpool.PID { return pidpool.NewP(1) })
+	un1, err := m.Link(pidpool.Link{From: "a", To: "c", Gain: 1})
+	if err != nil {
+		t.Fatalf("Link err: %v", err)
+	}
+	if _, err := m.Link(pidpool.Link{From: "b", To: "c", Gain: -2, Signal: pidpool.LinkValue}); err != nil {
+		t.Fatalf("Link err: %v", err)
+	}
+
+	m.Get("a").SetSetPoint(5)
+	m.Get("a").UpdateDuration(0, 1) // output 5
+	m.Get("b").UpdateDuration(1, 1) // value 1
+	if ff := m.Get("c").GetFeedForward(); ff != 3 {
+		t.Fatalf("expected summed feedforward 3, got %v", ff)
+	}
+
+	un1()
+	if ff := m.Get("c").GetFeedForward(); ff != -2 {
+		t.Fatalf("expected -2 after unlink, got %v", ff)
+	}
+	if _, err := m.Link(pidpool.Link{From: "c", To: "c"}); err == nil {
+		t.Fatalf("expected error for self link")
+	}
+}
+
+func TestManager_DeleteRemovesLinks(t *testing.T) {
+	m := pidpool.NewManager(func(string) *pidpool.PID { return pidpool.NewP(1) })
+	if _, err := m.Link(pidpool.Link{From: "a", To: "c", Gain: 1}); err != nil {
+		t.Fatalf("Link err: %v", err)
+	}
+	if _, err := m.Link(pidpool.Link{From: "b", To: "c", Gain: 1}); err != nil {
+		t.Fatalf("Link err: %v", err)
+	}
+	a, b, c := m.Get("a"), m.Get("b"), m.Get("c")
+	a.SetSetPoint(5)
+	a.UpdateDuration(0, 1)
+	b.SetSetPoint(0.1)
+	b.UpdateDuration(0, 1)
+
+	m.Delete("a")
+	if ff := c.GetFeedForward(); ff != 0.1 {
+		t.Fatalf("expected the deleted controller's contribution gone, got %v", ff)
+	}
+	a.UpdateDuration(0, 1)
+	if ff := c.GetFeedForward(); ff != 0.1 {
+		t.Fatalf("deleted controller still feeds forward: %v", ff)
+	}
+
+	m.Delete("c")
+	b.UpdateDuration(0, 1)
+	if ff := c.GetFeedForward(); ff != 0 {
+		t.Fatalf("expected a deleted target to be unlinked, got %v", ff)
+	}
+}
+
+func TestManager_FeedForwardSumOrder(t *testing.T) {
+	// floating point addition is not associative: 1e16 + 1 - 1e16 depends
+	// on the order, so the sum must follow registration order.
+	for i := 0; i < 20; i++ {
+		m := pidpool.NewManager(func(string) *pidpool.PID { return pidpool.NewP(1) })
+		for _, l := range []pidpool.Link{{From: "a", To: "z", Gain: 1}, {From: "b", To: "z", Gain: 1}, {From: "c", To: "z", Gain: 1}} {
+			if _, err := m.Link(l); err != nil {
+				t.Fatalf("Link err: %v", err)
+			}
+		}
+		for k, sp := range map[string]float64{"a": 1e16, "b": 1, "c": -1e16} {
+			m.Get(k).SetSetPoint(sp)
+		}
+		m.Get("b").UpdateDuration(0, 1)
+		m.Get("a").UpdateDuration(0, 1)
+		m.Get("c").UpdateDuration(0, 1)
+		if ff := m.Get("z").GetFeedForward(); ff != 0 {
+			t.Fatalf("expected (1e16+1)-1e16 = 0 in link order, got %v", ff)
+		}
+	}
+}
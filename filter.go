@@ -0,0 +1,247 @@
+package pidpool
+
+import (
+	"errors"
+	"fmt"
+)
+
+// FilterKind selects the measurement filter.
+type FilterKind int
+
+const (
+	// FilterNone passes measurements through.
+	FilterNone FilterKind = iota
+	// FilterEMA is an exponential moving average, y += Alpha*(x-y).
+	FilterEMA
+	// FilterSMA is a simple moving average over the last Window samples.
+	FilterSMA
+	// FilterAlphaBeta is an alpha-beta tracker estimating the value and its
+	// rate of change.
+	FilterAlphaBeta
+	// FilterKalman is a constant-velocity Kalman filter estimating the
+	// value and its rate of change.
+	FilterKalman
+)
+
+// String implements fmt.Stringer.
+func (k FilterKind) String() string {
+	switch k {
+	case FilterNone:
+		return "none"
+	case FilterEMA:
+		return "ema"
+	case FilterSMA:
+		return "sma"
+	case FilterAlphaBeta:
+		return "alphabeta"
+	case FilterKalman:
+		return "kalman"
+	}
+	return fmt.Sprintf("FilterKind(%d)", int(k))
+}
+
+// MarshalText implements encoding.TextMarshaler.
+func (k FilterKind) MarshalText() ([]byte, error) {
+	if k < FilterNone || k > FilterKalman {
+		return nil, fmt.Errorf("unknown filter kind %d", int(k))
+	}
+	return []byte(k.String()), nil
+}
+
+// UnmarshalText implements encoding.TextUnmarshaler.
+func (k *FilterKind) UnmarshalText(text []byte) error {
+	switch string(text) {
+	case "none":
+		*k = FilterNone
+	case "ema":
+		*k = FilterEMA
+	case "sma":
+		*k = FilterSMA
+	case "alphabeta":
+		*k = FilterAlphaBeta
+	case "kalman":
+		*k = FilterKalman
+	default:
+		return fmt.Errorf("unknown filter kind %q", text)
+	}
+	return nil
+}
+
+// MeasurementFilter conditions the measured value before the PID math. The
+// filtered value drives every term and is reported as UpdateEvent.Value;
+// the noise estimator still sees the raw measurement.
+//
+// The alpha-beta and Kalman estimators also estimate the rate of change of
+// the value, which the D term then uses directly instead of differencing
+// successive noisy measurements.
+type MeasurementFilter struct {
+	Kind FilterKind `json:"kind"`
+	// Alpha is the EMA smoothing factor in (0, 1]; 1 disables smoothing.
+	// For the alpha-beta tracker it is the position gain.
+	Alpha float64 `json:"alpha,omitempty"`
+	// Beta is the velocity gain of the alpha-beta tracker, in
+	// [0, 4-2*Alpha).
+	Beta float64 `json:"beta,omitempty"`
+	// Window is the SMA length in samples.
+	Window int `json:"window,omitempty"`
+	// ProcessNoise is the Kalman white-acceleration variance, in value
+	// units per second squared, squared.
+	ProcessNoise float64 `json:"processNoise,omitempty"`
+	// MeasurementNoise is the Kalman measurement variance.
+	MeasurementNoise float64 `json:"measurementNoise,omitempty"`
+}
+
+// Validate reports whether the filter is well formed.
+func (f MeasurementFilter) Validate() error {
+	switch f.Kind {
+	case FilterNone:
+	case FilterEMA:
+		if !(f.Alpha > 0 && f.Alpha <= 1) {
+			return errors.New("ema alpha must be in (0, 1]")
+		}
+	case FilterSMA:
+		if f.Window <= 0 {
+			return errors.New("sma window must be positive")
+		}
+	case FilterAlphaBeta:
+		if !(f.Alpha > 0 && f.Alpha <= 1) {
+			return errors.New("alpha-beta alpha must be in (0, 1]")
+		}
+		if !(f.Beta >= 0 && f.Beta < 4-2*f.Alpha) {
+			return errors.New("alpha-beta beta must be in [0, 4-2*alpha)")
+		}
+	case FilterKalman:
+		if !(f.ProcessNoise > 0) || !(f.MeasurementNoise > 0) {
+			return errors.New("kalman noise variances must be positive")
+		}
+	default:
+		return fmt.Errorf("unknown filter kind %d", int(f.Kind))
+	}
+	return nil
+}
+
+// SetMeasurementFilter installs f and clears the filter history.
+func (pid *PID) SetMeasurementFilter(f MeasurementFilter) error {
+	if err := f.Validate(); err != nil {
+		return err
+	}
+	defer pid.notifyChange()
+	pid.mu.Lock()
+	defer pid.mu.Unlock()
+	pid.filter = f
+	pid.filterHistory = nil
+	pid.filterQuality = nil
+
+	return nil
+}
+
+// GetMeasurementFilter returns the measurement filter.
+func (pid *PID) GetMeasurementFilter() MeasurementFilter {
+	pid.mu.Lock()
+	defer pid.mu.Unlock()
+	return pid.filter
+}
+
+// historyLen returns the length of the filter history once primed.
+func (f MeasurementFilter) historyLen() int {
+	switch f.Kind {
+	case FilterEMA:
+		return 1
+	case FilterSMA:
+		return f.Window
+	case FilterAlphaBeta:
+		return 2
+	case FilterKalman:
+		return 5
+	}
+	return 0
+}
+
+// filterLocked feeds x, measured dt seconds after the previous value,
+// through the filter and returns the filtered value. When the filter
+// estimates the rate of change, it is returned with ok set.
+//
+// filterHistory holds the EMA output; the SMA window oldest first; the
+// alpha-beta value and rate; or the Kalman value, rate and covariance
+// entries P00, P01 and P11.
+func (pid *PID) filterLocked(x, dt float64) (value, rate float64, ok bool) {
+	switch pid.filter.Kind {
+	case FilterEMA:
+		if len(pid.filterHistory) == 0 {
+			pid.filterHistory = []float64{x}
+			return x, 0, false
+		}
+		y := pid.filterHistory[0]
+		y += float64(pid.filter.Alpha * (x - y))
+		pid.filterHistory[0] = y
+		return y, 0, false
+	case FilterAlphaBeta:
+		return pid.alphaBetaLocked(x, dt)
+	case FilterKalman:
+		return pid.kalmanLocked(x, dt)
+	case FilterSMA:
+		if len(pid.filterHistory) == pid.filter.Window {
+			copy(pid.filterHistory, pid.filterHistory[1:])
+			pid.filterHistory = pid.filterHistory[:len(pid.filterHistory)-1]
+		}
+		pid.filterHistory = append(pid.filterHistory, x)
+		sum := 0.0
+		for _, v := range pid.filterHistory {
+			sum += v
+		}
+		return sum / float64(len(pid.filterHistory)), 0, false
+	}
+	return x, 0, false
+}
+
+func (pid *PID) alphaBetaLocked(z, dt float64) (float64, float64, bool) {
+	if len(pid.filterHistory) == 0 {
+		pid.filterHistory = []float64{z, 0}
+		return z, 0, true
+	}
+	x, v := pid.filterHistory[0], pid.filterHistory[1]
+	x += float64(v * dt)
+	r := z - x
+	x += float64(pid.filter.Alpha * r)
+	if dt > 0 {
+		v += float64(pid.filter.Beta * r / dt)
+	}
+	pid.filterHistory[0], pid.filterHistory[1] = x, v
+
+	return x, v, true
+}
+
+// kalmanInitialRateVariance is the initial variance of the rate estimate:
+// large, as nothing is known about it before the second measurement.
+const kalmanInitialRateVariance = 1e6
+
+func (pid *PID) kalmanLocked(z, dt float64) (float64, float64, bool) {
+	q, r := pid.filter.ProcessNoise, pid.filter.MeasurementNoise
+	if len(pid.filterHistory) == 0 {
+		pid.filterHistory = []float64{z, 0, r, 0, kalmanInitialRateVariance}
+		return z, 0, true
+	}
+	h := pid.filterHistory
+	x, v, p00, p01, p11 := h[0], h[1], h[2], h[3], h[4]
+
+	// predict with F = [[1 dt] [0 1]] and white-acceleration noise.
+	dt2 := float64(dt * dt)
+	x += float64(v * dt)
+	p00 += float64(2*dt*p01) + float64(dt2*p11) + float64(q*dt2*dt2/4)
+	p01 += float64(dt*p11) + float64(q*dt2*dt/2)
+	p11 += float64(q * dt2)
+
+	// update with H = [1 0].
+	s := p00 + r
+	k0, k1 := p00/s, p01/s
+	y := z - x
+	x += float64(k0 * y)
+	v += float64(k1 * y)
+	p11 -= float64(k1 * p01)
+	p01 -= float64(k1 * p00)
+	p00 -= float64(k0 * p00)
+
+	h[0], h[1], h[2], h[3], h[4] = x, v, p00, p01, p11
+
+	return x, v, true
+}
@@ -0,0 +1,96 @@
+package pidpool_test
+
+import (
+	"encoding/json"
+	"math"
+	"reflect"
+	"testing"
+
+	"github.com/ankur-anand/go-pidpool"
+)
+
+func TestMeasurementFilter_SMA(t *testing.T) {
+	p := pidpool.NewP(1)
+	if err := p.SetMeasurementFilter(pidpool.MeasurementFilter{Kind: pidpool.FilterSMA}); err == nil {
+		t.Fatalf("expected error for zero window")
+	}
+	if err := p.SetMeasurementFilter(pidpool.MeasurementFilter{Kind: pidpool.FilterSMA, Window: 3}); err != nil {
+		t.Fatalf("SetMeasurementFilter err: %v", err)
+	}
+
+	var last pidpool.UpdateEvent
+	p.OnUpdate(func(ev pidpool.UpdateEvent) { last = ev })
+	for i, want := range []float64{3, 4.5, 6, 9} {
+		p.UpdateDuration(float64(3*(i+1)), 1)
+		if last.Value != want {
+			t.Fatalf("step %d: expected filtered value %v, got %v", i, want, last.Value)
+		}
+	}
+
+	p.Reset()
+	p.UpdateDuration(30, 1)
+	if last.Value != 30 {
+		t.Fatalf("Reset must clear the filter, got %v", last.Value)
+	}
+}
+
+func TestMeasurementFilter_EMAState(t *testing.T) {
+	a := pidpool.NewPI(1, 0.5)
+	a.SetSetPoint(10)
+	_ = a.SetMeasurementFilter(pidpool.MeasurementFilter{Kind: pidpool.FilterEMA, Alpha: 0.25})
+	for _, v := range []float64{0, 4, 8} {
+		a.UpdateDuration(v, 1)
+	}
+
+	data, err := json.Marshal(a.State())
+	if err != nil {
+		t.Fatalf("Marshal err: %v", err)
+	}
+	var st pidpool.State
+	if err := json.Unmarshal(data, &st); err != nil {
+		t.Fatalf("Unmarshal err: %v", err)
+	}
+	if !reflect.DeepEqual(st.FilterHistory, []float64{2.75}) || st.Filter.Alpha != 0.25 {
+		t.Fatalf("filter state not round-tripped: %+v %v", st.Filter, st.FilterHistory)
+	}
+
+	b := pidpool.NewPID(0, 0, 0, 0)
+	if err := b.RestoreState(st); err != nil {
+		t.Fatalf("RestoreState err: %v", err)
+	}
+	if oa, ob := a.UpdateDuration(9, 1), b.UpdateDuration(9, 1); oa != ob {
+		t.Fatalf("restored filter diverged: %v != %v", oa, ob)
+	}
+}
+
+func TestMeasurementFilter_RateEstimators(t *testing.T) {
+	for _, f := range []pidpool.MeasurementFilter{
+		{Kind: pidpool.FilterAlphaBeta, Alpha: 0.5, Beta: 0.1},
+		{Kind: pidpool.FilterKalman, ProcessNoise: 0.01, MeasurementNoise: 0.25},
+	} {
+		// a pure D controller outputs -kd times the estimated rate.
+		p := pidpool.NewPD(0, 1)
+		if err := p.SetMeasurementFilter(f); err != nil {
+			t.Fatalf("%v: SetMeasurementFilter err: %v", f.Kind, err)
+		}
+
+		// a ramp of slope 2 with alternating noise of ±0.5, which finite
+		// differencing would turn into a rate error of ±10.
+		var out float64
+		for i := 0; i < 400; i++ {
+			noise := 0.5
+			if i%2 == 1 {
+				noise = -0.5
+			}
+			out = p.UpdateDuration(float64(2*i)*0.1+noise, 0.1)
+		}
+		if math.Abs(out+2) > 0.5 {
+			t.Fatalf("%v: expected rate estimate near 2, D output %v", f.Kind, out)
+		}
+	}
+
+	bad := pidpool.MeasurementFilter{Kind: pidpool.FilterAlphaBeta, Alpha: 1, Beta: 2}
+	if err := bad.Validate(); err == nil {
+		t.Fatalf("expected error for unstable alpha-beta gains")
+	}
+}
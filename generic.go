@@ -0,0 +1,141 @@
+package pidpool
+
+import (
+	"errors"
+	"math"
+	"sync"
+)
+
+// Float is the set of floating point types supported by PIDOf. It matches
+// golang.org/x/exp/constraints.Float.
+type Float interface {
+	~float32 | ~float64
+}
+
+// PIDOf is a PID controller computing in T, for targets where float64
+// conversions are unwanted, e.g. float32 on memory constrained devices.
+// It implements the same algorithm as PID (derivative on measurement,
+// dead-band, integral and output clamping) with an explicit dt; the hooks,
+// history and other extensions of PID are not available.
+type PIDOf[T Float] struct {
+	mu sync.Mutex
+
+	kp, ki, kd T
+
+	outputMin, outputMax     T
+	integralMin, integralMax T
+
+	setPoint  T
+	prevValue T
+	integral  T
+	deadBand  T
+}
+
+// NewPIDOf returns a new controller with the given gains and dead-band.
+func NewPIDOf[T Float](kp, ki, kd, deadBand T) *PIDOf[T] {
+	return &PIDOf[T]{
+		kp:          kp,
+		ki:          ki,
+		kd:          kd,
+		deadBand:    deadBand,
+		outputMin:   T(math.Inf(-1)),
+		outputMax:   T(math.Inf(1)),
+		integralMin: -100,
+		integralMax: 100,
+	}
+}
+
+// SetOutputLimits sets min and max output.
+func (pid *PIDOf[T]) SetOutputLimits(min, max T) error {
+	if min > max {
+		return errors.New("min output greater than max output")
+	}
+	pid.mu.Lock()
+	defer pid.mu.Unlock()
+	pid.outputMin, pid.outputMax = min, max
+
+	return nil
+}
+
+// SetIntegralLimits clamps the running sum (anti-windup).
+func (pid *PIDOf[T]) SetIntegralLimits(min, max T) error {
+	if min > max {
+		return errors.New("min integral greater than max integral")
+	}
+	pid.mu.Lock()
+	defer pid.mu.Unlock()
+	pid.integralMin, pid.integralMax = min, max
+	pid.integral = clampOf(pid.integral, min, max)
+
+	return nil
+}
+
+// SetSetPoint sets the setPoint.
+func (pid *PIDOf[T]) SetSetPoint(val T) {
+	pid.mu.Lock()
+	defer pid.mu.Unlock()
+	pid.setPoint = val
+}
+
+// GetSetPoint returns the current setPoint.
+func (pid *PIDOf[T]) GetSetPoint() T {
+	pid.mu.Lock()
+	defer pid.mu.Unlock()
+	return pid.setPoint
+}
+
+// SetPID sets the gains.
+func (pid *PIDOf[T]) SetPID(kp, ki, kd T) {
+	pid.mu.Lock()
+	defer pid.mu.Unlock()
+	pid.kp, pid.ki, pid.kd = kp, ki, kd
+}
+
+// GetPID returns the gains.
+func (pid *PIDOf[T]) GetPID() (T, T, T) {
+	pid.mu.Lock()
+	defer pid.mu.Unlock()
+	return pid.kp, pid.ki, pid.kd
+}
+
+// Reset clears the integral and measurement history.
+func (pid *PIDOf[T]) Reset() {
+	pid.mu.Lock()
+	defer pid.mu.Unlock()
+	pid.integral, pid.prevValue = 0, 0
+}
+
+// UpdateDuration runs the PID calculation for a measurement taken dt
+// seconds after the previous one.
+func (pid *PIDOf[T]) UpdateDuration(value, dt T) T {
+	pid.mu.Lock()
+	defer pid.mu.Unlock()
+
+	err := pid.setPoint - value
+	if err < pid.deadBand && -err < pid.deadBand {
+		err = 0
+	}
+
+	pid.integral = clampOf(pid.integral+T(err*dt), pid.integralMin, pid.integralMax)
+
+	var derivative T
+	if dt > 0 {
+		derivative = -(value - pid.prevValue) / dt
+	}
+	pid.prevValue = value
+
+	output := T(pid.kp*err) + T(pid.ki*pid.integral)
+	output += T(pid.kd * derivative)
+
+	return clampOf(output, pid.outputMin, pid.outputMax)
+}
+
+func clampOf[T Float](v, lo, hi T) T {
+	if v > hi {
+		return hi
+	}
+	if v < lo {
+		return lo
+	}
+	return v
+}
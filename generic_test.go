@@ -0,0 +1,37 @@
+package pidpool_test
+
+import (
+	"testing"
+
+	"github.com/ankur-anand/go-pidpool"
+)
+
+func TestPIDOf_MatchesPID(t *testing.T) {
+	a := pidpool.NewPID(0.5, 0.25, 0.125, 0)
+	b := pidpool.NewPIDOf[float64](0.5, 0.25, 0.125, 0)
+	a.SetSetPoint(4)
+	b.SetSetPoint(4)
+	for i := 0; i < 20; i++ {
+		v := float64(i) / 4
+		if x, y := a.UpdateDuration(v, 0.5), b.UpdateDuration(v, 0.5); x != y {
+			t.Fatalf("step %d: PID %v, PIDOf %v", i, x, y)
+		}
+	}
+}
+
+func TestPIDOf_Float32Limits(t *testing.T) {
+	p := pidpool.NewPIDOf[float32](1, 0, 0, 0.5)
+	if err := p.SetOutputLimits(-1, 1); err != nil {
+		t.Fatalf("SetOutputLimits err: %v", err)
+	}
+	p.SetSetPoint(10)
+	if out := p.UpdateDuration(0, 0.1); out != 1 {
+		t.Fatalf("expected clamped output 1, got %v", out)
+	}
+	if out := p.UpdateDuration(9.75, 0.1); out != 0 {
+		t.Fatalf("expected dead-band to zero the error, got %v", out)
+	}
+	if err := p.SetIntegralLimits(1, 0); err == nil {
+		t.Fatalf("expected error for min>max")
+	}
+}
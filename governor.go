@@ -0,0 +1,22 @@
+package pidpool
+
+// Govern prepares pid to drive a bounded actuator such as a worker count,
+// a concurrency limit or a rate: it sets both the output and the integral
+// term limits to [min, max] and the setpoint to target. The governors in
+// this module, e.g. WorkerPool, AdaptiveLimiter and NewRateControl, call it
+// on the PID they are given.
+//
+// The sign of the gains sets the direction. When raising the output lowers
+// the measured value, e.g. more workers shorten the queue, the controller
+// must be reverse acting, i.e. have negative gains.
+func (pid *PID) Govern(target, min, max float64) error {
+	if err := pid.SetOutputLimits(min, max); err != nil {
+		return err
+	}
+	if err := pid.SetIntegralTermLimits(min, max); err != nil {
+		return err
+	}
+	pid.SetSetPoint(target)
+
+	return nil
+}
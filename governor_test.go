@@ -0,0 +1,26 @@
+package pidpool_test
+
+import (
+	"testing"
+
+	"github.com/ankur-anand/go-pidpool"
+)
+
+func TestPID_Govern(t *testing.T) {
+	pid := pidpool.NewPI(-1, -0.5)
+	if err := pid.Govern(3, 1, 8); err != nil {
+		t.Fatalf("Govern err: %v", err)
+	}
+	if sp := pid.GetSetPoint(); sp != 3 {
+		t.Fatalf("expected setpoint 3, got %v", sp)
+	}
+	if lo, hi, ok := pid.GetIntegralTermLimits(); !ok || lo != 1 || hi != 8 {
+		t.Fatalf("expected integral term limits [1, 8], got [%v, %v] %v", lo, hi, ok)
+	}
+	if out := pid.UpdateDuration(100, 1); out != 8 {
+		t.Fatalf("expected output clamped to 8, got %v", out)
+	}
+	if err := pid.Govern(0, 2, 1); err == nil {
+		t.Fatalf("expected error for min > max")
+	}
+}
@@ -0,0 +1,307 @@
+package pidpool
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"sync"
+	"time"
+)
+
+// Zone is a named controller owned by a Group.
+type Zone struct {
+	Name string
+	PID  *PID
+}
+
+// ZoneTelemetry is the result of the last update of one zone.
+type ZoneTelemetry struct {
+	Name      string
+	SetPoint  float64
+	Value     float64
+	Error     float64
+	Output    float64
+	Saturated bool
+}
+
+// GroupTelemetry aggregates the last update of every zone of a Group.
+type GroupTelemetry struct {
+	Time  time.Time
+	Zones []ZoneTelemetry
+
+	MeanValue float64
+	MinValue  float64
+	MaxValue  float64
+	// Spread is MaxValue - MinValue, e.g. the temperature uniformity of an
+	// oven.
+	Spread      float64
+	MaxAbsError float64
+	MeanOutput  float64
+	// Saturated counts the zones whose output was clamped.
+	Saturated int
+}
+
+type groupZone struct {
+	name string
+	pid  *PID
+	base float64
+	trim float64
+}
+
+// Group runs the zones of a multi-zone process, e.g. the heaters of a
+// reflow oven, from one sample batch per tick. Unlike MultiPID the zones
+// are independent controllers that keep their own gains, limits and
+// filters; the group adds synchronized updates, shared setpoint handling
+// and aggregate telemetry.
+//
+// The setpoint of every zone is its base setpoint plus the group-wide
+// offset plus its own trim, and is pushed to the zone's controller
+// whenever one of them changes.
+type Group struct {
+	// stepMu serializes updates and setpoint pushes; mu guards the fields
+	// below and is never held while a zone steps or takes a setpoint, so
+	// the zones' hooks and watchers may read the group, e.g. Telemetry.
+	stepMu sync.Mutex
+	mu     sync.Mutex
+
+	zones  []groupZone
+	index  map[string]int
+	offset float64
+
+	lastUpdate time.Time
+	telemetry  GroupTelemetry
+}
+
+// NewGroup returns a group of zones in the given order. The current
+// setpoint of every controller becomes its base setpoint.
+func NewGroup(zones ...Zone) (*Group, error) {
+	if len(zones) == 0 {
+		return nil, errors.New("at least one zone is required")
+	}
+	g := &Group{index: make(map[string]int, len(zones)), lastUpdate: time.Now()}
+	for i, z := range zones {
+		if z.Name == "" || z.PID == nil {
+			return nil, errors.New("zones need a name and a pid")
+		}
+		if _, ok := g.index[z.Name]; ok {
+			return nil, fmt.Errorf("duplicate zone %q", z.Name)
+		}
+		g.index[z.Name] = i
+		g.zones = append(g.zones, groupZone{name: z.Name, pid: z.PID, base: z.PID.GetSetPoint()})
+	}
+
+	return g, nil
+}
+
+// Len returns the number of zones.
+func (g *Group) Len() int {
+	return len(g.zones)
+}
+
+// Names returns the zone names in update order.
+func (g *Group) Names() []string {
+	names := make([]string, len(g.zones))
+	for i, z := range g.zones {
+		names[i] = z.name
+	}
+	return names
+}
+
+// Zone returns the controller of the named zone.
+func (g *Group) Zone(name string) (*PID, bool) {
+	i, ok := g.index[name]
+	if !ok {
+		return nil, false
+	}
+	return g.zones[i].pid, true
+}
+
+func (g *Group) zone(name string) (*groupZone, error) {
+	i, ok := g.index[name]
+	if !ok {
+		return nil, fmt.Errorf("zone %q not found", name)
+	}
+	return &g.zones[i], nil
+}
+
+// SetSetPoint sets the base setpoint of every zone.
+func (g *Group) SetSetPoint(val float64) {
+	g.stepMu.Lock()
+	defer g.stepMu.Unlock()
+	g.mu.Lock()
+	for i := range g.zones {
+		g.zones[i].base = val
+	}
+	apply := g.setPointsLocked(g.zones...)
+	g.mu.Unlock()
+	apply()
+}
+
+// SetZoneSetPoint sets the base setpoint of one zone.
+func (g *Group) SetZoneSetPoint(name string, val float64) error {
+	g.stepMu.Lock()
+	defer g.stepMu.Unlock()
+	g.mu.Lock()
+	z, err := g.zone(name)
+	if err != nil {
+		g.mu.Unlock()
+		return err
+	}
+	z.base = val
+	apply := g.setPointsLocked(*z)
+	g.mu.Unlock()
+	apply()
+
+	return nil
+}
+
+// SetOffset shifts the setpoint of every zone by val, e.g. to ramp a
+// whole oven up or down while keeping the zone profile.
+func (g *Group) SetOffset(val float64) {
+	g.stepMu.Lock()
+	defer g.stepMu.Unlock()
+	g.mu.Lock()
+	g.offset = val
+	apply := g.setPointsLocked(g.zones...)
+	g.mu.Unlock()
+	apply()
+}
+
+// Offset returns the group-wide setpoint offset.
+func (g *Group) Offset() float64 {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	return g.offset
+}
+
+// SetTrim sets the setpoint trim of one zone, e.g. to compensate a zone
+// that reads high.
+func (g *Group) SetTrim(name string, val float64) error {
+	g.stepMu.Lock()
+	defer g.stepMu.Unlock()
+	g.mu.Lock()
+	z, err := g.zone(name)
+	if err != nil {
+		g.mu.Unlock()
+		return err
+	}
+	z.trim = val
+	apply := g.setPointsLocked(*z)
+	g.mu.Unlock()
+	apply()
+
+	return nil
+}
+
+// Trim returns the setpoint trim of one zone.
+func (g *Group) Trim(name string) (float64, error) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	z, err := g.zone(name)
+	if err != nil {
+		return 0, err
+	}
+	return z.trim, nil
+}
+
+// setPointsLocked computes the setpoints of zones and returns a function
+// pushing them to the controllers, to be called with stepMu held and mu
+// released.
+func (g *Group) setPointsLocked(zones ...groupZone) func() {
+	sps := make([]float64, len(zones))
+	for i, z := range zones {
+		sps[i] = z.base + g.offset + z.trim
+	}
+	return func() {
+		for i, z := range zones {
+			z.pid.SetSetPoint(sps[i])
+		}
+	}
+}
+
+// Update runs one step of every zone for the measured values, given in
+// zone order. Every zone sees the same dt, the wall time since the
+// previous group update.
+func (g *Group) Update(values []float64) ([]float64, error) {
+	if len(values) != len(g.zones) {
+		return nil, ErrDimension
+	}
+	g.stepMu.Lock()
+	defer g.stepMu.Unlock()
+	g.mu.Lock()
+	now := time.Now()
+	dt := now.Sub(g.lastUpdate).Seconds()
+	g.lastUpdate = now
+	g.mu.Unlock()
+
+	return g.step(values, dt, now), nil
+}
+
+// UpdateDuration allows custom duration between updates.
+func (g *Group) UpdateDuration(values []float64, dt float64) ([]float64, error) {
+	if len(values) != len(g.zones) {
+		return nil, ErrDimension
+	}
+	g.stepMu.Lock()
+	defer g.stepMu.Unlock()
+	return g.step(values, dt, time.Now()), nil
+}
+
+// step steps every zone through its own update path, so hooks, history
+// and the concurrency policy of each controller still apply. It must be
+// called with stepMu held; the zones are fixed at construction, so they
+// are read without mu.
+func (g *Group) step(values []float64, dt float64, now time.Time) []float64 {
+	out := make([]float64, len(g.zones))
+	t := GroupTelemetry{
+		Time:     now,
+		Zones:    make([]ZoneTelemetry, len(g.zones)),
+		MinValue: math.Inf(1),
+		MaxValue: math.Inf(-1),
+	}
+	for i, z := range g.zones {
+		pid := z.pid
+		ev := pid.step(func() UpdateEvent {
+			ev := pid.updateInternal(values[i], dt)
+			ev.Time = now
+			return ev
+		})
+		out[i] = ev.Output
+
+		zt := ZoneTelemetry{
+			Name:      z.name,
+			SetPoint:  ev.SetPoint,
+			Value:     ev.Value,
+			Error:     ev.Error,
+			Output:    ev.Output,
+			Saturated: ev.Status().Saturated,
+		}
+		t.Zones[i] = zt
+		t.MeanValue += zt.Value
+		t.MeanOutput += zt.Output
+		t.MinValue = math.Min(t.MinValue, zt.Value)
+		t.MaxValue = math.Max(t.MaxValue, zt.Value)
+		t.MaxAbsError = math.Max(t.MaxAbsError, math.Abs(zt.Error))
+		if zt.Saturated {
+			t.Saturated++
+		}
+	}
+	n := float64(len(g.zones))
+	t.MeanValue /= n
+	t.MeanOutput /= n
+	t.Spread = t.MaxValue - t.MinValue
+	g.mu.Lock()
+	g.telemetry = t
+	g.mu.Unlock()
+
+	return out
+}
+
+// Telemetry returns the aggregate telemetry of the last update.
+func (g *Group) Telemetry() GroupTelemetry {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	t := g.telemetry
+	t.Zones = append([]ZoneTelemetry(nil), t.Zones...)
+	return t
+}
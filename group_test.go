@@ -0,0 +1,80 @@
+package pidpool_test
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/ankur-anand/go-pidpool"
+)
+
+func TestGroup(t *testing.T) {
+	front, back := pidpool.NewP(1), pidpool.NewP(1)
+	back.SetOutputLimits(0, 5)
+	g, err := pidpool.NewGroup(pidpool.Zone{Name: "front", PID: front}, pidpool.Zone{Name: "back", PID: back})
+	if err != nil {
+		t.Fatalf("NewGroup err: %v", err)
+	}
+
+	g.SetSetPoint(200)
+	g.SetOffset(-10)
+	if err := g.SetTrim("back", 4); err != nil {
+		t.Fatalf("SetTrim err: %v", err)
+	}
+	if front.GetSetPoint() != 190 || back.GetSetPoint() != 194 {
+		t.Fatalf("expected setpoints 190 and 194, got %v and %v", front.GetSetPoint(), back.GetSetPoint())
+	}
+
+	out, err := g.UpdateDuration([]float64{180, 184}, 1)
+	if err != nil {
+		t.Fatalf("UpdateDuration err: %v", err)
+	}
+	if out[0] != 10 || out[1] != 5 {
+		t.Fatalf("expected outputs [10 5], got %v", out)
+	}
+
+	tel := g.Telemetry()
+	if tel.MeanValue != 182 || tel.Spread != 4 || tel.MaxAbsError != 10 || tel.Saturated != 1 || tel.MeanOutput != 7.5 {
+		t.Fatalf("unexpected telemetry %+v", tel)
+	}
+	if tel.Zones[1].Name != "back" || !tel.Zones[1].Saturated {
+		t.Fatalf("unexpected zone telemetry %+v", tel.Zones[1])
+	}
+
+	if _, err := g.UpdateDuration([]float64{1}, 1); !errors.Is(err, pidpool.ErrDimension) {
+		t.Fatalf("expected ErrDimension, got %v", err)
+	}
+	if err := g.SetTrim("side", 1); err == nil {
+		t.Fatalf("expected error for unknown zone")
+	}
+	if _, err := pidpool.NewGroup(pidpool.Zone{Name: "a", PID: front}, pidpool.Zone{Name: "a", PID: back}); err == nil {
+		t.Fatalf("expected error for duplicate zone")
+	}
+}
+
+func TestGroup_HookReadsGroup(t *testing.T) {
+	front, back := pidpool.NewP(1), pidpool.NewP(1)
+	g, err := pidpool.NewGroup(pidpool.Zone{Name: "front", PID: front}, pidpool.Zone{Name: "back", PID: back})
+	if err != nil {
+		t.Fatalf("NewGroup err: %v", err)
+	}
+	var seen int
+	back.OnUpdate(func(pidpool.UpdateEvent) { seen = len(g.Telemetry().Zones) })
+	back.Subscribe(func(pidpool.Event) { g.Telemetry() })
+
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		g.SetOffset(5)
+		g.UpdateDuration([]float64{0, 0}, 1)
+		g.UpdateDuration([]float64{0, 0}, 1)
+	}()
+	select {
+	case <-done:
+	case <-time.After(2 * time.Second):
+		t.Fatalf("group deadlocked in a zone hook")
+	}
+	if seen != 2 {
+		t.Fatalf("expected the hook to see the last telemetry, got %d zones", seen)
+	}
+}
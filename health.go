@@ -0,0 +1,297 @@
+package pidpool
+
+import (
+	"errors"
+	"math"
+	"sort"
+	"sync"
+)
+
+// Health is a rolling assessment of how well a loop performs.
+type Health struct {
+	// Score combines the indices into one number in [0, 1], higher being
+	// better: HarrisIndex * (1 - SaturatedFraction).
+	Score float64
+	// HarrisIndex is MinimumVariance / Variance in [0, 1]: how close the
+	// error variance is to the best any controller could achieve given the
+	// dead time. Well tuned loops typically score above 0.5.
+	HarrisIndex float64
+	// Variance is the mean square error over the window and
+	// MinimumVariance the minimum-variance benchmark estimated from it.
+	Variance        float64
+	MinimumVariance float64
+	// SaturatedFraction is the fraction of the window spent with the output
+	// clamped.
+	SaturatedFraction float64
+	Samples           int
+	// Ready reports whether the window holds enough samples for the
+	// indices; they are zero until then.
+	Ready bool
+}
+
+// HealthConfig configures a HealthMonitor. Zero fields select the
+// defaults.
+type HealthConfig struct {
+	// Window is the number of recent updates assessed. Defaults to 500.
+	Window int
+	// DeadTime is the process dead time in updates, at least 1, which
+	// bounds how fast any controller can reject a disturbance. Defaults
+	// to 1.
+	DeadTime int
+	// Order is the order of the autoregressive model fitted to the error.
+	// Defaults to 10.
+	Order int
+}
+
+// HealthMonitor scores a loop from its recent errors. The minimum-variance
+// benchmark is estimated from closed-loop data only (Harris, 1989): an
+// autoregressive model fitted to the error is expanded into its impulse
+// response, whose first DeadTime terms are the part of the error no
+// controller can remove.
+type HealthMonitor struct {
+	cfg HealthConfig
+
+	mu        sync.Mutex
+	errs      []float64
+	saturated []bool
+	next      int
+	full      bool
+}
+
+// NewHealthMonitor returns a monitor for the given configuration.
+func NewHealthMonitor(cfg HealthConfig) (*HealthMonitor, error) {
+	if cfg.Window < 0 || cfg.DeadTime < 0 || cfg.Order < 0 {
+		return nil, errors.New("health parameters must not be negative")
+	}
+	if cfg.Window == 0 {
+		cfg.Window = 500
+	}
+	if cfg.DeadTime == 0 {
+		cfg.DeadTime = 1
+	}
+	if cfg.Order == 0 {
+		cfg.Order = 10
+	}
+	if cfg.Window < 4*cfg.Order {
+		return nil, errors.New("health window must hold at least four times the model order")
+	}
+	return &HealthMonitor{
+		cfg:       cfg,
+		errs:      make([]float64, cfg.Window),
+		saturated: make([]bool, cfg.Window),
+	}, nil
+}
+
+// Observe adds an update to the window. Updates with Bad quality are
+// ignored.
+func (h *HealthMonitor) Observe(ev UpdateEvent) {
+	if ev.Quality.IsBad() {
+		return
+	}
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.errs[h.next] = ev.Error
+	h.saturated[h.next] = ev.Status().Saturated
+	h.next = (h.next + 1) % len(h.errs)
+	h.full = h.full || h.next == 0
+}
+
+// Reset empties the window.
+func (h *HealthMonitor) Reset() {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.next, h.full = 0, false
+}
+
+// Health assesses the window.
+func (h *HealthMonitor) Health() Health {
+	h.mu.Lock()
+	n := h.next
+	if h.full {
+		n = len(h.errs)
+	}
+	errs := make([]float64, 0, n)
+	sat := 0
+	start := 0
+	if h.full {
+		start = h.next
+	}
+	for i := 0; i < n; i++ {
+		j := (start + i) % len(h.errs)
+		errs = append(errs, h.errs[j])
+		if h.saturated[j] {
+			sat++
+		}
+	}
+	h.mu.Unlock()
+
+	hl := Health{Samples: n}
+	if n == 0 {
+		return hl
+	}
+	hl.SaturatedFraction = float64(sat) / float64(n)
+	if n < 4*h.cfg.Order {
+		return hl
+	}
+
+	mse, mean := 0.0, 0.0
+	for _, e := range errs {
+		mse += e * e
+		mean += e
+	}
+	mse /= float64(n)
+	mean /= float64(n)
+	hl.Variance = mse
+	if mse == 0 {
+		// a perfect loop.
+		hl.HarrisIndex, hl.Ready = 1, true
+		hl.Score = 1 - hl.SaturatedFraction
+		return hl
+	}
+
+	phi, residual, ok := fitAR(errs, mean, h.cfg.Order)
+	if !ok {
+		return hl
+	}
+	// impulse response of the AR model; its first DeadTime terms are the
+	// unavoidable part of the error.
+	psi := make([]float64, h.cfg.DeadTime)
+	psi[0] = 1
+	sum := 1.0
+	for j := 1; j < len(psi); j++ {
+		for i := 1; i <= min(j, len(phi)); i++ {
+			psi[j] += phi[i-1] * psi[j-i]
+		}
+		sum += psi[j] * psi[j]
+	}
+	hl.MinimumVariance = residual * sum
+	hl.HarrisIndex = math.Min(1, hl.MinimumVariance/mse)
+	hl.Score = hl.HarrisIndex * (1 - hl.SaturatedFraction)
+	hl.Ready = true
+
+	return hl
+}
+
+// fitAR fits e[k] - mean = sum phi[i] (e[k-1-i] - mean) + a[k] by least
+// squares and returns phi and the residual variance.
+func fitAR(errs []float64, mean float64, order int) (phi []float64, residual float64, ok bool) {
+	a := make([][]float64, order)
+	for i := range a {
+		a[i] = make([]float64, order)
+	}
+	b := make([]float64, order)
+	for k := order; k < len(errs); k++ {
+		y := errs[k] - mean
+		for i := 0; i < order; i++ {
+			xi := errs[k-1-i] - mean
+			b[i] += xi * y
+			for j := 0; j < order; j++ {
+				a[i][j] += xi * (errs[k-1-j] - mean)
+			}
+		}
+	}
+	phi, ok = solveLinear(a, b)
+	if !ok {
+		return nil, 0, false
+	}
+
+	for k := order; k < len(errs); k++ {
+		r := errs[k] - mean
+		for i := 0; i < order; i++ {
+			r -= phi[i] * (errs[k-1-i] - mean)
+		}
+		residual += r * r
+	}
+	return phi, residual / float64(len(errs)-order), true
+}
+
+// solveLinear solves a x = b by Gaussian elimination with partial pivoting,
+// overwriting a and b.
+func solveLinear(a [][]float64, b []float64) ([]float64, bool) {
+	n := len(b)
+	for c := 0; c < n; c++ {
+		p := c
+		for r := c + 1; r < n; r++ {
+			if math.Abs(a[r][c]) > math.Abs(a[p][c]) {
+				p = r
+			}
+		}
+		if math.Abs(a[p][c]) < 1e-12 {
+			return nil, false
+		}
+		a[c], a[p] = a[p], a[c]
+		b[c], b[p] = b[p], b[c]
+		for r := c + 1; r < n; r++ {
+			f := a[r][c] / a[c][c]
+			for k := c; k < n; k++ {
+				a[r][k] -= f * a[c][k]
+			}
+			b[r] -= f * b[c]
+		}
+	}
+	x := make([]float64, n)
+	for r := n - 1; r >= 0; r-- {
+		s := b[r]
+		for k := r + 1; k < n; k++ {
+			s -= a[r][k] * x[k]
+		}
+		x[r] = s / a[r][r]
+	}
+	return x, true
+}
+
+// EnableHealthMonitoring starts scoring the loop on every update with a
+// HealthMonitor for cfg, replacing any previous one. The score is exported
+// by the registry and the metrics adapters.
+func (pid *PID) EnableHealthMonitoring(cfg HealthConfig) error {
+	h, err := NewHealthMonitor(cfg)
+	if err != nil {
+		return err
+	}
+	pid.mu.Lock()
+	defer pid.mu.Unlock()
+	pid.health = h
+
+	return nil
+}
+
+// DisableHealthMonitoring stops scoring the loop.
+func (pid *PID) DisableHealthMonitoring() {
+	pid.mu.Lock()
+	defer pid.mu.Unlock()
+	pid.health = nil
+}
+
+// Health returns the health of the loop. The boolean is false when health
+// monitoring is not enabled.
+func (pid *PID) Health() (Health, bool) {
+	pid.mu.Lock()
+	h := pid.health
+	pid.mu.Unlock()
+	if h == nil {
+		return Health{}, false
+	}
+	return h.Health(), true
+}
+
+// LoopHealth is the health of a named controller.
+type LoopHealth struct {
+	Name   string
+	Health Health
+}
+
+// HealthRanking returns the health of every controller with health
+// monitoring enabled and a ready assessment, worst score first, so a fleet
+// of loops can be triaged.
+func (r *Registry) HealthRanking() []LoopHealth {
+	var out []LoopHealth
+	r.Range(func(name string, pid *PID) bool {
+		if h, ok := pid.Health(); ok && h.Ready {
+			out = append(out, LoopHealth{Name: name, Health: h})
+		}
+		return true
+	})
+	sort.SliceStable(out, func(i, j int) bool { return out[i].Health.Score < out[j].Health.Score })
+
+	return out
+}
@@ -0,0 +1,79 @@
+package pidpool_test
+
+import (
+	"math/rand"
+	"testing"
+
+	"github.com/ankur-anand/go-pidpool"
+)
+
+// runDisturbed drives pid against a first-order plant with a drifting load
+// disturbance for n samples.
+func runDisturbed(pid *pidpool.PID, seed int64, n int) {
+	rng := rand.New(rand.NewSource(seed))
+	y, d, u := 0.0, 0.0, 0.0
+	for i := 0; i < n; i++ {
+		d = 0.95*d + rng.NormFloat64()
+		y = 0.8*y + 0.2*u + 0.2*d
+		u = pid.UpdateDuration(y, 1)
+	}
+}
+
+func TestHealth_RanksSluggishLoopWorse(t *testing.T) {
+	tight := pidpool.NewPI(4, 3)
+	sluggish := pidpool.NewPI(0.2, 0.02)
+	reg := pidpool.NewRegistry()
+	for name, pid := range map[string]*pidpool.PID{"tight": tight, "sluggish": sluggish} {
+		pid.SetIntegralLimits(-1e6, 1e6)
+		if err := pid.EnableHealthMonitoring(pidpool.HealthConfig{Window: 1000}); err != nil {
+			t.Fatalf("EnableHealthMonitoring err: %v", err)
+		}
+		reg.Register(name, pid)
+	}
+	reg.Register("unmonitored", pidpool.NewP(1))
+
+	if h, _ := tight.Health(); h.Ready {
+		t.Fatalf("expected no assessment without data: %+v", h)
+	}
+	runDisturbed(tight, 1, 2000)
+	runDisturbed(sluggish, 1, 2000)
+
+	ht, _ := tight.Health()
+	hs, _ := sluggish.Health()
+	if !ht.Ready || ht.Samples != 1000 || ht.HarrisIndex < 0.5 {
+		t.Fatalf("expected a healthy tight loop: %+v", ht)
+	}
+	if hs.HarrisIndex >= ht.HarrisIndex/2 {
+		t.Fatalf("expected the sluggish loop to score much lower: %+v vs %+v", hs, ht)
+	}
+
+	rank := reg.HealthRanking()
+	if len(rank) != 2 || rank[0].Name != "sluggish" || rank[1].Name != "tight" {
+		t.Fatalf("unexpected ranking: %+v", rank)
+	}
+
+	if _, ok := pidpool.NewP(1).Health(); ok {
+		t.Fatalf("expected no health without monitoring")
+	}
+}
+
+func TestHealth_Saturation(t *testing.T) {
+	h, err := pidpool.NewHealthMonitor(pidpool.HealthConfig{Window: 100, Order: 2})
+	if err != nil {
+		t.Fatalf("NewHealthMonitor err: %v", err)
+	}
+	for i := 0; i < 100; i++ {
+		ev := pidpool.UpdateEvent{Output: 1, RawOutput: 1}
+		if i%4 == 0 {
+			ev.RawOutput = 2
+		}
+		h.Observe(ev)
+	}
+	got := h.Health()
+	if !got.Ready || got.SaturatedFraction != 0.25 || got.Score != 0.75 {
+		t.Fatalf("expected a quarter saturated perfect loop: %+v", got)
+	}
+	if _, err := pidpool.NewHealthMonitor(pidpool.HealthConfig{Window: 10}); err == nil {
+		t.Fatalf("expected error for a window shorter than the model needs")
+	}
+}
@@ -0,0 +1,59 @@
+package pidpool
+
+import "errors"
+
+// eventRing is a fixed capacity ring buffer of update events.
+type eventRing struct {
+	buf   []UpdateEvent
+	start int
+}
+
+func (r *eventRing) add(ev UpdateEvent) {
+	if len(r.buf) < cap(r.buf) {
+		r.buf = append(r.buf, ev)
+		return
+	}
+	r.buf[r.start] = ev
+	r.start = (r.start + 1) % len(r.buf)
+}
+
+func (r *eventRing) events() []UpdateEvent {
+	out := make([]UpdateEvent, 0, len(r.buf))
+	out = append(out, r.buf[r.start:]...)
+	return append(out, r.buf[:r.start]...)
+}
+
+// EnableHistory keeps the last n update events in a bounded ring buffer.
+// Passing 0 disables the history and drops any recorded events.
+func (pid *PID) EnableHistory(n int) error {
+	if n < 0 {
+		return errors.New("history size must not be negative")
+	}
+	pid.mu.Lock()
+	defer pid.mu.Unlock()
+	if n == 0 {
+		pid.history = nil
+		return nil
+	}
+
+	r := &eventRing{buf: make([]UpdateEvent, 0, n)}
+	if pid.history != nil {
+		for _, ev := range pid.history.events() {
+			r.add(ev)
+		}
+	}
+	pid.history = r
+
+	return nil
+}
+
+// History returns the recorded update events, oldest first.
+func (pid *PID) History() []UpdateEvent {
+	pid.mu.Lock()
+	defer pid.mu.Unlock()
+	if pid.history == nil {
+		return nil
+	}
+
+	return pid.history.events()
+}
@@ -0,0 +1,42 @@
+package pidpool_test
+
+import (
+	"testing"
+
+	"github.com/ankur-anand/go-pidpool"
+)
+
+func TestHistory_KeepsLastN(t *testing.T) {
+	p := pidpool.NewPID(1, 0, 0, 0)
+	if h := p.History(); h != nil {
+		t.Fatalf("expected no history by default, got %d events", len(h))
+	}
+	if err := p.EnableHistory(3); err != nil {
+		t.Fatalf("EnableHistory err: %v", err)
+	}
+
+	for i := 0; i < 5; i++ {
+		p.UpdateDuration(float64(i), 1)
+	}
+
+	h := p.History()
+	if len(h) != 3 {
+		t.Fatalf("expected 3 events, got %d", len(h))
+	}
+	for i, ev := range h {
+		if ev.Value != float64(i+2) {
+			t.Fatalf("event %d: expected value %v, got %v", i, i+2, ev.Value)
+		}
+		if ev.Time.IsZero() {
+			t.Fatalf("event %d has no timestamp", i)
+		}
+	}
+
+	// shrinking keeps the newest events.
+	if err := p.EnableHistory(1); err != nil {
+		t.Fatalf("EnableHistory err: %v", err)
+	}
+	if h := p.History(); len(h) != 1 || h[0].Value != 4 {
+		t.Fatalf("unexpected history after resize: %+v", h)
+	}
+}
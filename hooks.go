@@ -0,0 +1,223 @@
+package pidpool
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+)
+
+// UpdateEvent describes a single controller update.
+type UpdateEvent struct {
+	Time     time.Time
+	SetPoint float64
+	// Value is the process value passed to the update.
+	Value float64
+	Error float64
+	DT    float64
+
+	// P, I and D are the individual term contributions to the output.
+	P float64
+	I float64
+	D float64
+
+	// FeedForward is the feedforward contribution to the output.
+	FeedForward float64
+
+	// RawOutput is P+I+D+FeedForward, plus the low end of the output range
+	// when one is set, before the output limits are applied.
+	RawOutput float64
+	// Output is the value returned to the caller.
+	Output float64
+	// IntegralClamped reports whether anti-windup held the integral back.
+	IntegralClamped bool
+	// QuantizationError is Output minus the limited output before it was
+	// snapped to a quantization position and held by the output deadband;
+	// zero without either.
+	QuantizationError float64
+
+	// Quality is the quality of the value that drove the update; Good
+	// unless the update came through UpdateWithQuality.
+	Quality Quality
+}
+
+// HookOptions controls how an update hook is run.
+type HookOptions struct {
+	// Name identifies the hook in HookError reports.
+	Name string
+	// Order positions the hook relative to others; lower runs first and
+	// hooks with equal Order run in registration order.
+	Order int
+	// Async delivers events through a bounded queue drained by a dedicated
+	// goroutine, so a slow hook can never stall the update path. Events
+	// are dropped, and reported as ErrHookQueueFull, while the queue is full.
+	Async bool
+	// QueueSize is the async queue capacity. Zero selects 64.
+	QueueSize int
+	// DisableOnPanic removes the hook after its first panic.
+	DisableOnPanic bool
+}
+
+// HookError reports a failure of an update hook.
+type HookError struct {
+	Name string
+	Err  error
+}
+
+func (e HookError) Error() string {
+	return fmt.Sprintf("hook %q: %v", e.Name, e.Err)
+}
+
+// ErrHookQueueFull is reported when an async hook drops an event.
+var ErrHookQueueFull = errors.New("hook queue full, event dropped")
+
+type updateHook struct {
+	id   uint64
+	opts HookOptions
+	fn   func(UpdateEvent)
+
+	// async delivery.
+	mu     sync.Mutex
+	queue  chan UpdateEvent
+	closed bool
+}
+
+// OnUpdate registers fn to be called after every update with the full term
+// breakdown. It is OnUpdateWith with zero HookOptions. The returned function
+// removes the hook.
+func (pid *PID) OnUpdate(fn func(UpdateEvent)) (remove func()) {
+	return pid.OnUpdateWith(fn, HookOptions{})
+}
+
+// OnUpdateWith registers fn with explicit ordering and isolation options.
+//
+// After every update the controller first records the event in its history
+// (if enabled), then runs the hooks by ascending Order, after the
+// controller lock is released. Every hook runs isolated: a panic is
+// recovered and reported to the handler set by SetHookErrorHandler, and
+// never reaches the caller of Update or the hooks after it.
+//
+// Synchronous hooks are part of the update: they may read or configure the
+// controller, but must not update it themselves.
+func (pid *PID) OnUpdateWith(fn func(UpdateEvent), opts HookOptions) (remove func()) {
+	h := &updateHook{opts: opts, fn: fn}
+	if opts.Async {
+		size := opts.QueueSize
+		if size <= 0 {
+			size = 64
+		}
+		h.queue = make(chan UpdateEvent, size)
+		go pid.drainHook(h)
+	}
+
+	pid.mu.Lock()
+	pid.hookID++
+	h.id = pid.hookID
+
+	// copy on write, so in-flight notifications keep their own slice.
+	i := sort.Search(len(pid.hooks), func(i int) bool { return pid.hooks[i].opts.Order > opts.Order })
+	hooks := make([]*updateHook, 0, len(pid.hooks)+1)
+	hooks = append(hooks, pid.hooks[:i]...)
+	hooks = append(hooks, h)
+	pid.hooks = append(hooks, pid.hooks[i:]...)
+	pid.mu.Unlock()
+
+	return func() { pid.removeHook(h) }
+}
+
+func (pid *PID) removeHook(h *updateHook) {
+	pid.mu.Lock()
+	hooks := make([]*updateHook, 0, len(pid.hooks))
+	for _, o := range pid.hooks {
+		if o != h {
+			hooks = append(hooks, o)
+		}
+	}
+	pid.hooks = hooks
+	pid.mu.Unlock()
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if h.queue != nil && !h.closed {
+		close(h.queue)
+	}
+	h.closed = true
+}
+
+// SetHookErrorHandler sets the function that receives hook panics and
+// dropped async events. It is called on the goroutine that ran the hook.
+func (pid *PID) SetHookErrorHandler(fn func(HookError)) {
+	pid.mu.Lock()
+	defer pid.mu.Unlock()
+	pid.hookErr = fn
+}
+
+func (pid *PID) runHooks(hooks []*updateHook, ev UpdateEvent) {
+	for _, h := range hooks {
+		if h.queue == nil {
+			pid.callHook(h, ev)
+			continue
+		}
+
+		h.mu.Lock()
+		dropped := false
+		if !h.closed {
+			select {
+			case h.queue <- ev:
+			default:
+				dropped = true
+			}
+		}
+		h.mu.Unlock()
+		if dropped {
+			pid.reportHookError(HookError{Name: h.opts.Name, Err: ErrHookQueueFull})
+		}
+	}
+}
+
+func (pid *PID) drainHook(h *updateHook) {
+	for ev := range h.queue {
+		pid.callHook(h, ev)
+	}
+}
+
+func (pid *PID) callHook(h *updateHook, ev UpdateEvent) {
+	defer func() {
+		if r := recover(); r != nil {
+			pid.reportHookError(HookError{Name: h.opts.Name, Err: fmt.Errorf("panic: %v", r)})
+			if h.opts.DisableOnPanic {
+				pid.removeHook(h)
+			}
+		}
+	}()
+	h.fn(ev)
+}
+
+func (pid *PID) reportHookError(e HookError) {
+	pid.mu.Lock()
+	fn := pid.hookErr
+	pid.mu.Unlock()
+	if fn == nil {
+		return
+	}
+
+	// a panicking error handler must not break the update path either.
+	defer func() { _ = recover() }()
+	fn(e)
+}
+
+// Terms is the output of an update together with its term contributions.
+type Terms struct {
+	Output float64
+	P      float64
+	I      float64
+	D      float64
+}
+
+// UpdateWithTerms is like Update but also returns the individual P, I and D
+// contributions of this cycle.
+func (pid *PID) UpdateWithTerms(value float64) Terms {
+	ev := pid.updateNow(value)
+	return Terms{Output: ev.Output, P: ev.P, I: ev.I, D: ev.D}
+}
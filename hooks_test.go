@@ -0,0 +1,108 @@
+package pidpool_test
+
+import (
+	"testing"
+
+	"github.com/ankur-anand/go-pidpool"
+)
+
+func TestOnUpdate_TermBreakdown(t *testing.T) {
+	p := pidpool.NewPID(2, 1, 0.5, 0)
+	if err := p.SetOutputLimits(-10, 10); err != nil {
+		t.Fatalf("SetOutputLimits err: %v", err)
+	}
+	p.SetSetPoint(10)
+
+	var events []pidpool.UpdateEvent
+	remove := p.OnUpdate(func(ev pidpool.UpdateEvent) {
+		// hooks run without the lock held.
+		_ = p.GetSetPoint()
+		events = append(events, ev)
+	})
+
+	p.UpdateDuration(4, 1)
+	p.UpdateDuration(6, 1)
+	if len(events) != 2 {
+		t.Fatalf("expected 2 events, got %d", len(events))
+	}
+
+	ev := events[1]
+	if ev.Error != 4 || ev.P != 8 || ev.I != 10 || ev.D != -1 {
+		t.Fatalf("unexpected terms: %+v", ev)
+	}
+	if ev.RawOutput != 17 || ev.Output != 10 {
+		t.Fatalf("unexpected outputs: raw %v clamped %v", ev.RawOutput, ev.Output)
+	}
+
+	remove()
+	p.UpdateDuration(6, 1)
+	if len(events) != 2 {
+		t.Fatalf("hook still called after remove")
+	}
+}
+
+func TestUpdateWithTerms(t *testing.T) {
+	p := pidpool.NewPID(2, 0, 0, 0)
+	p.SetSetPoint(5)
+	terms := p.UpdateWithTerms(3)
+	if terms.P != 4 || terms.I != 0 || terms.D > 0 {
+		t.Fatalf("unexpected terms: %+v", terms)
+	}
+	if terms.Output != terms.P+terms.I+terms.D {
+		t.Fatalf("output %v does not match term sum", terms.Output)
+	}
+}
+
+func TestOnUpdateWith_OrderAndPanicIsolation(t *testing.T) {
+	p := pidpool.NewPID(1, 0, 0, 0)
+
+	var errs []pidpool.HookError
+	p.SetHookErrorHandler(func(e pidpool.HookError) { errs = append(errs, e) })
+
+	var calls []string
+	p.OnUpdateWith(func(pidpool.UpdateEvent) { calls = append(calls, "metrics") }, pidpool.HookOptions{Order: 10})
+	p.OnUpdateWith(func(pidpool.UpdateEvent) {
+		calls = append(calls, "exporter")
+		panic("boom")
+	}, pidpool.HookOptions{Name: "exporter", Order: 5, DisableOnPanic: true})
+	p.OnUpdateWith(func(pidpool.UpdateEvent) { calls = append(calls, "log") }, pidpool.HookOptions{Order: 0})
+
+	p.UpdateDuration(1, 1)
+	p.UpdateDuration(1, 1)
+
+	want := []string{"log", "exporter", "metrics", "log", "metrics"}
+	if len(calls) != len(want) {
+		t.Fatalf("unexpected calls: %v", calls)
+	}
+	for i := range want {
+		if calls[i] != want[i] {
+			t.Fatalf("unexpected calls: %v", calls)
+		}
+	}
+	if len(errs) != 1 || errs[0].Name != "exporter" {
+		t.Fatalf("expected one exporter error, got %+v", errs)
+	}
+}
+
+func TestOnUpdateWith_AsyncNeverBlocks(t *testing.T) {
+	p := pidpool.NewPID(1, 0, 0, 0)
+
+	dropped := make(chan struct{}, 16)
+	p.SetHookErrorHandler(func(e pidpool.HookError) {
+		if e.Err == pidpool.ErrHookQueueFull {
+			dropped <- struct{}{}
+		}
+	})
+
+	block := make(chan struct{})
+	remove := p.OnUpdateWith(func(pidpool.UpdateEvent) { <-block }, pidpool.HookOptions{Async: true, QueueSize: 1})
+	defer remove()
+	defer close(block)
+
+	for i := 0; i < 5; i++ {
+		p.UpdateDuration(1, 1)
+	}
+	if len(dropped) == 0 {
+		t.Fatalf("expected dropped events while the async hook is stalled")
+	}
+}
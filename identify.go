@@ -0,0 +1,172 @@
+package pidpool
+
+import (
+	"errors"
+	"math"
+)
+
+// IOSample is one observation of a loop: the controller output applied to
+// the process and the process value measured at the same instant.
+type IOSample struct {
+	Output float64
+	Value  float64
+}
+
+// FOPDT is a first-order-plus-dead-time process model:
+//
+//	G(s) = Gain * exp(-DeadTime*s) / (TimeConstant*s + 1)
+//
+// TimeConstant and DeadTime are in seconds.
+type FOPDT struct {
+	Gain         float64 `json:"gain"`
+	TimeConstant float64 `json:"timeConstant"`
+	DeadTime     float64 `json:"deadTime"`
+}
+
+// ErrInsufficientExcitation is returned when the data does not contain
+// enough movement (setpoint changes or disturbances) to identify a model.
+var ErrInsufficientExcitation = errors.New("insufficient excitation in data")
+
+// IdentifyFOPDT estimates a FOPDT model from uniformly sampled closed-loop
+// data, so no open-loop step test is required. dt is the sample period in
+// seconds and maxDelay bounds the dead time search, in samples.
+//
+// The model is fitted as the discrete ARX form
+//
+//	y[k+1] = a*y[k] + b*u[k-d] + c
+//
+// by least squares for every candidate delay d, keeping the best fit. The
+// constant c absorbs operating point offsets. The data should contain
+// setpoint changes or load disturbances; a loop that sat at steady state
+// carries no information about the process.
+func IdentifyFOPDT(data []IOSample, dt float64, maxDelay int) (FOPDT, error) {
+	if dt <= 0 {
+		return FOPDT{}, errors.New("dt must be positive")
+	}
+	if maxDelay < 0 {
+		return FOPDT{}, errors.New("maxDelay must not be negative")
+	}
+	if len(data) < maxDelay+10 {
+		return FOPDT{}, errors.New("not enough samples")
+	}
+
+	bestSSE := math.Inf(1)
+	var best [3]float64
+	bestDelay := -1
+	for d := 0; d <= maxDelay; d++ {
+		theta, sse, ok := fitARX(data, d)
+		if !ok || theta[0] <= 0 || theta[0] >= 1 {
+			continue
+		}
+		if sse < bestSSE {
+			bestSSE, best, bestDelay = sse, theta, d
+		}
+	}
+	if bestDelay < 0 {
+		return FOPDT{}, ErrInsufficientExcitation
+	}
+
+	a, b := best[0], best[1]
+	return FOPDT{
+		Gain:         b / (1 - a),
+		TimeConstant: -dt / math.Log(a),
+		DeadTime:     float64(bestDelay) * dt,
+	}, nil
+}
+
+// fitARX solves the least squares problem for a single delay and returns
+// the parameters (a, b, c) with the residual sum of squares.
+func fitARX(data []IOSample, d int) ([3]float64, float64, bool) {
+	var ata [3][3]float64
+	var aty [3]float64
+	for k := d; k+1 < len(data); k++ {
+		x := [3]float64{data[k].Value, data[k-d].Output, 1}
+		y := data[k+1].Value
+		for i := 0; i < 3; i++ {
+			for j := 0; j < 3; j++ {
+				ata[i][j] += x[i] * x[j]
+			}
+			aty[i] += x[i] * y
+		}
+	}
+
+	theta, ok := solve3(ata, aty)
+	if !ok {
+		return theta, 0, false
+	}
+
+	sse := 0.0
+	for k := d; k+1 < len(data); k++ {
+		r := data[k+1].Value - (theta[0]*data[k].Value + theta[1]*data[k-d].Output + theta[2])
+		sse += r * r
+	}
+
+	return theta, sse, true
+}
+
+// solve3 solves a 3x3 linear system by Gaussian elimination with partial
+// pivoting.
+func solve3(a [3][3]float64, b [3]float64) ([3]float64, bool) {
+	scale := 0.0
+	for i := range a {
+		for j := range a[i] {
+			scale = math.Max(scale, math.Abs(a[i][j]))
+		}
+	}
+	if scale == 0 {
+		return [3]float64{}, false
+	}
+
+	for col := 0; col < 3; col++ {
+		pivot := col
+		for r := col + 1; r < 3; r++ {
+			if math.Abs(a[r][col]) > math.Abs(a[pivot][col]) {
+				pivot = r
+			}
+		}
+		if math.Abs(a[pivot][col]) < 1e-12*scale {
+			return [3]float64{}, false
+		}
+		a[col], a[pivot] = a[pivot], a[col]
+		b[col], b[pivot] = b[pivot], b[col]
+
+		for r := col + 1; r < 3; r++ {
+			f := a[r][col] / a[col][col]
+			for c := col; c < 3; c++ {
+				a[r][c] -= f * a[col][c]
+			}
+			b[r] -= f * b[col]
+		}
+	}
+
+	var x [3]float64
+	for i := 2; i >= 0; i-- {
+		s := b[i]
+		for j := i + 1; j < 3; j++ {
+			s -= a[i][j] * x[j]
+		}
+		x[i] = s / a[i][i]
+	}
+
+	return x, true
+}
+
+// SIMC returns PI gains for the model using Skogestad's SIMC rule with
+// closed-loop time constant tc. A tc <= 0 selects the default tc = DeadTime,
+// or TimeConstant/10 when the model has no dead time.
+func (m FOPDT) SIMC(tc float64) (kp, ki, kd float64, err error) {
+	if m.Gain == 0 || m.TimeConstant <= 0 {
+		return 0, 0, 0, errors.New("model gain and time constant required")
+	}
+	if tc <= 0 {
+		tc = m.DeadTime
+		if tc == 0 {
+			tc = m.TimeConstant / 10
+		}
+	}
+
+	kp = m.TimeConstant / (m.Gain * (tc + m.DeadTime))
+	ti := math.Min(m.TimeConstant, 4*(tc+m.DeadTime))
+
+	return kp, kp / ti, 0, nil
+}
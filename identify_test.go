@@ -0,0 +1,63 @@
+package pidpool_test
+
+import (
+	"math"
+	"math/rand/v2"
+	"testing"
+
+	"github.com/ankur-anand/go-pidpool"
+)
+
+// closedLoopData runs a PI controller against a discrete FOPDT process with
+// a few setpoint steps and returns the recorded samples.
+func closedLoopData(m pidpool.FOPDT, dt float64, n int) []pidpool.IOSample {
+	a := math.Exp(-dt / m.TimeConstant)
+	delay := int(m.DeadTime / dt)
+
+	p := pidpool.NewPID(0.5, 0.1, 0, 0)
+	r := rand.New(rand.NewPCG(5, 6))
+	data := make([]pidpool.IOSample, n)
+	outputs := make([]float64, n)
+	y := 0.0
+	for k := 0; k < n; k++ {
+		p.SetSetPoint(float64((k / 150) % 3 * 10))
+		u := p.UpdateDuration(y, dt)
+		outputs[k] = u
+		data[k] = pidpool.IOSample{Output: u, Value: y}
+
+		uDelayed := 0.0
+		if k-delay >= 0 {
+			uDelayed = outputs[k-delay]
+		}
+		y = a*y + m.Gain*(1-a)*uDelayed + r.NormFloat64()*0.001
+	}
+
+	return data
+}
+
+func TestIdentifyFOPDT_ClosedLoop(t *testing.T) {
+	truth := pidpool.FOPDT{Gain: 2, TimeConstant: 5, DeadTime: 1}
+	data := closedLoopData(truth, 0.1, 3000)
+
+	m, err := pidpool.IdentifyFOPDT(data, 0.1, 30)
+	if err != nil {
+		t.Fatalf("IdentifyFOPDT err: %v", err)
+	}
+	if math.Abs(m.Gain-truth.Gain) > 0.1 ||
+		math.Abs(m.TimeConstant-truth.TimeConstant) > 0.3 ||
+		math.Abs(m.DeadTime-truth.DeadTime) > 0.15 {
+		t.Fatalf("model too far from truth: got %+v, want %+v", m, truth)
+	}
+
+	kp, ki, kd, err := m.SIMC(0)
+	if err != nil || kp <= 0 || ki <= 0 || kd != 0 {
+		t.Fatalf("unexpected SIMC gains (%v,%v,%v) err %v", kp, ki, kd, err)
+	}
+}
+
+func TestIdentifyFOPDT_NoExcitation(t *testing.T) {
+	data := make([]pidpool.IOSample, 100)
+	if _, err := pidpool.IdentifyFOPDT(data, 0.1, 5); err != pidpool.ErrInsufficientExcitation {
+		t.Fatalf("expected ErrInsufficientExcitation, got %v", err)
+	}
+}
@@ -0,0 +1,116 @@
+package pidpool
+
+import (
+	"errors"
+	"fmt"
+	"math"
+)
+
+// InvalidInputPolicy defines what a controller does with a NaN or infinite
+// measurement, or a NaN, infinite or negative dt. Letting such a value
+// through would poison the integral for good.
+type InvalidInputPolicy int
+
+const (
+	// InvalidHold rejects the update and holds the last output. This is
+	// the default.
+	InvalidHold InvalidInputPolicy = iota
+	// InvalidSubstitute runs the update with the last good measurement and
+	// a dt of 0 in place of the invalid ones. Until a good measurement has
+	// been seen it behaves like InvalidHold.
+	InvalidSubstitute
+	// InvalidFault puts the controller in the fault state, see Trip, in
+	// which every update is rejected and outputs the fail-safe value until
+	// ClearFault.
+	InvalidFault
+)
+
+var (
+	// ErrInvalidInput is reported by TryUpdate when the measurement or dt
+	// is invalid. The output is still produced according to the policy.
+	ErrInvalidInput = errors.New("invalid input")
+	// ErrFaulted is reported by TryUpdate while the controller is in the
+	// fault state.
+	ErrFaulted = errors.New("controller faulted")
+)
+
+// SetInvalidInputPolicy sets how invalid measurements and dt are handled.
+func (pid *PID) SetInvalidInputPolicy(p InvalidInputPolicy) error {
+	if p < InvalidHold || p > InvalidFault {
+		return fmt.Errorf("unknown invalid input policy %d", int(p))
+	}
+	pid.mu.Lock()
+	defer pid.mu.Unlock()
+	pid.invalidPolicy = p
+
+	return nil
+}
+
+// Faulted reports whether the controller is in the fault state, entered
+// under InvalidFault or through Trip.
+func (pid *PID) Faulted() bool {
+	pid.mu.Lock()
+	defer pid.mu.Unlock()
+	return pid.faulted
+}
+
+// ClearFault leaves the fault state.
+func (pid *PID) ClearFault() {
+	pid.mu.Lock()
+	pid.faulted = false
+	pid.fault = Fault{}
+	bus := pid.bus
+	pid.mu.Unlock()
+	bus.syncFault(pid)
+}
+
+func validInput(value, dt float64) bool {
+	return !math.IsNaN(value) && !math.IsInf(value, 0) &&
+		!math.IsNaN(dt) && !math.IsInf(dt, 0) && dt >= 0
+}
+
+// checkInputLocked applies the invalid input policy. It returns the
+// measurement and dt to run the update with, or ok false when the update
+// must be replaced by holdLocked. The error for TryUpdate is left in
+// pid.stepErr.
+func (pid *PID) checkInputLocked(value, dt float64) (float64, float64, bool) {
+	if pid.faulted {
+		pid.stepErr = fmt.Errorf("%w: %s", ErrFaulted, pid.fault.Reason)
+		return value, dt, false
+	}
+	if validInput(value, dt) {
+		pid.lastGood, pid.hasLastGood = value, true
+		return value, dt, true
+	}
+
+	pid.stepErr = fmt.Errorf("%w: value %v, dt %v", ErrInvalidInput, value, dt)
+	switch pid.invalidPolicy {
+	case InvalidSubstitute:
+		if !pid.hasLastGood {
+			return value, dt, false
+		}
+		if math.IsNaN(value) || math.IsInf(value, 0) {
+			value = pid.lastGood
+		}
+		if !(dt >= 0) || math.IsInf(dt, 0) {
+			dt = 0
+		}
+		return value, dt, true
+	case InvalidFault:
+		pid.tripLocked(fmt.Sprintf("invalid input: value %v, dt %v", value, dt))
+	}
+
+	return value, dt, false
+}
+
+// holdLocked returns the event of an update that was not applied.
+func (pid *PID) holdLocked(value, dt float64) UpdateEvent {
+	return UpdateEvent{
+		SetPoint:  pid.setPoint,
+		Value:     value,
+		DT:        dt,
+		RawOutput: pid.lastOutput,
+		Output:    pid.lastOutput,
+		Quality:   QualityBad,
+	}
+}
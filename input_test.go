@@ -0,0 +1,60 @@
+package pidpool_test
+
+import (
+	"errors"
+	"math"
+	"testing"
+
+	"github.com/ankur-anand/go-pidpool"
+)
+
+func TestInvalidInput_HoldKeepsIntegral(t *testing.T) {
+	p := pidpool.NewPI(1, 1)
+	p.SetSetPoint(10)
+	good := p.UpdateDuration(5, 1)
+
+	if out := p.UpdateDuration(math.NaN(), 1); out != good {
+		t.Fatalf("expected held output %v, got %v", good, out)
+	}
+	if out := p.UpdateDuration(5, math.Inf(1)); out != good {
+		t.Fatalf("expected held output for infinite dt, got %v", out)
+	}
+	if st := p.State(); st.Integral != 5 {
+		t.Fatalf("integral poisoned: %v", st.Integral)
+	}
+	if _, err := p.TryUpdate(math.Inf(-1)); !errors.Is(err, pidpool.ErrInvalidInput) {
+		t.Fatalf("expected ErrInvalidInput, got %v", err)
+	}
+}
+
+func TestInvalidInput_Substitute(t *testing.T) {
+	p := pidpool.NewP(1)
+	p.SetSetPoint(10)
+	if err := p.SetInvalidInputPolicy(pidpool.InvalidSubstitute); err != nil {
+		t.Fatalf("SetInvalidInputPolicy err: %v", err)
+	}
+	p.UpdateDuration(4, 1)
+	p.SetSetPoint(20)
+	if out := p.UpdateDuration(math.NaN(), 1); out != 16 {
+		t.Fatalf("expected update with last good value, got %v", out)
+	}
+}
+
+func TestInvalidInput_Fault(t *testing.T) {
+	p := pidpool.NewP(1)
+	p.SetSetPoint(10)
+	_ = p.SetInvalidInputPolicy(pidpool.InvalidFault)
+	p.UpdateDuration(4, 1)
+	p.UpdateDuration(math.NaN(), 1)
+	if !p.Faulted() {
+		t.Fatalf("expected fault state")
+	}
+	if out, err := p.TryUpdate(0); !errors.Is(err, pidpool.ErrFaulted) || out != 6 {
+		t.Fatalf("expected faulted hold at 6, got %v %v", out, err)
+	}
+
+	p.ClearFault()
+	if out, err := p.TryUpdate(0); err != nil || out != 10 {
+		t.Fatalf("expected normal update after ClearFault, got %v %v", out, err)
+	}
+}
@@ -0,0 +1,86 @@
+package pidpool
+
+import (
+	"errors"
+	"math"
+	"time"
+)
+
+// SetIntegralTermLimits bounds the integral term's contribution to the
+// output, ki*integral, to [min, max] in output units. Unlike
+// SetIntegralLimits, the bounds stay meaningful when the gains change: the
+// accumulator limits are recomputed from ki on every SetPID.
+//
+// While ki is zero the integral term contributes nothing and the
+// accumulator limits are left untouched.
+func (pid *PID) SetIntegralTermLimits(min, max float64) error {
+	if min > max {
+		return errors.New("min integral term greater than max integral term")
+	}
+	defer pid.notifyChange()
+	pid.mu.Lock()
+	defer pid.mu.Unlock()
+	pid.termLimits = true
+	pid.termMin, pid.termMax = min, max
+	pid.applyTermLimitsLocked()
+
+	return nil
+}
+
+// GetIntegralTermLimits returns the integral term limits in output units.
+// The boolean is false when the limits are set on the raw accumulator via
+// SetIntegralLimits instead.
+func (pid *PID) GetIntegralTermLimits() (min, max float64, ok bool) {
+	pid.mu.Lock()
+	defer pid.mu.Unlock()
+	return pid.termMin, pid.termMax, pid.termLimits
+}
+
+func (pid *PID) applyTermLimitsLocked() {
+	if !pid.termLimits || pid.ki == 0 {
+		return
+	}
+
+	pid.setIntegralLimitsLocked(termToAccumulator(pid.termMin, pid.termMax, pid.ki*pid.gainScaleLocked()))
+}
+
+// termToAccumulator converts integral term limits in output units into
+// accumulator limits for the given, non-zero, ki.
+func termToAccumulator(min, max, ki float64) (float64, float64) {
+	if ki < 0 {
+		return max / ki, min / ki
+	}
+	return min / ki, max / ki
+}
+
+// SetIntegralDecay makes the integral leak towards zero with time constant
+// tau: between updates dt seconds apart the accumulator is scaled by
+// exp(-dt/tau) before the new error is added. This stops small persistent
+// biases from holding the actuator at an extreme in the long run. Zero
+// disables the leak.
+func (pid *PID) SetIntegralDecay(tau time.Duration) error {
+	if tau < 0 {
+		return errors.New("integral decay must not be negative")
+	}
+	defer pid.notifyChange()
+	pid.mu.Lock()
+	defer pid.mu.Unlock()
+	pid.integralDecay = tau
+
+	return nil
+}
+
+// GetIntegralDecay returns the integral decay time constant.
+func (pid *PID) GetIntegralDecay() time.Duration {
+	pid.mu.Lock()
+	defer pid.mu.Unlock()
+	return pid.integralDecay
+}
+
+// decayIntegralLocked applies the integrator leak for a step of dt seconds.
+func (pid *PID) decayIntegralLocked(dt float64) {
+	if pid.integralDecay <= 0 || dt <= 0 {
+		return
+	}
+	pid.integral = float64(pid.integral * math.Exp(-dt/pid.integralDecay.Seconds()))
+}
@@ -0,0 +1,72 @@
+package pidpool_test
+
+import (
+	"math"
+	"testing"
+	"time"
+
+	"github.com/ankur-anand/go-pidpool"
+)
+
+func TestIntegralTermLimits_FollowGains(t *testing.T) {
+	p := pidpool.NewPID(0, 2, 0, 0)
+	if err := p.SetIntegralTermLimits(-10, 10); err != nil {
+		t.Fatalf("SetIntegralTermLimits err: %v", err)
+	}
+
+	p.SetSetPoint(100)
+	for i := 0; i < 100; i++ {
+		p.UpdateDuration(0, 1)
+	}
+	if out := p.UpdateDuration(0, 1); out != 10 {
+		t.Fatalf("I-term not limited to 10 output units: got %v", out)
+	}
+
+	// halving ki must keep the limit in output units.
+	p.SetPID(0, 1, 0)
+	if out := p.UpdateDuration(0, 1); out != 10 {
+		t.Fatalf("I-term limit went stale after gain change: got %v", out)
+	}
+	if st := p.State(); st.IntegralMax != 10 {
+		t.Fatalf("expected accumulator limit 10, got %v", st.IntegralMax)
+	}
+
+	// raw limits switch the mode back.
+	if err := p.SetIntegralLimits(-1, 1); err != nil {
+		t.Fatalf("SetIntegralLimits err: %v", err)
+	}
+	if _, _, ok := p.GetIntegralTermLimits(); ok {
+		t.Fatalf("expected term limits to be cleared by SetIntegralLimits")
+	}
+}
+
+func TestIntegralTermLimits_ReverseActing(t *testing.T) {
+	p := pidpool.NewPID(0, -2, 0, 0)
+	if err := p.SetIntegralTermLimits(-4, 6); err != nil {
+		t.Fatalf("SetIntegralTermLimits err: %v", err)
+	}
+	if st := p.State(); st.IntegralMin != -3 || st.IntegralMax != 2 {
+		t.Fatalf("unexpected accumulator limits: [%v,%v]", st.IntegralMin, st.IntegralMax)
+	}
+}
+
+func TestIntegralDecay(t *testing.T) {
+	p := pidpool.NewPID(0, 1, 0, 0)
+	if err := p.SetIntegralDecay(-time.Second); err == nil {
+		t.Fatalf("expected error for negative decay")
+	}
+	if err := p.SetIntegralDecay(10 * time.Second); err != nil {
+		t.Fatalf("SetIntegralDecay err: %v", err)
+	}
+
+	p.SetSetPoint(1)
+	p.UpdateDuration(0, 5) // integral 5.
+	p.SetSetPoint(0)
+	out := p.UpdateDuration(0, 10)
+	if want := 5 * math.Exp(-1); math.Abs(out-want) > 1e-12 {
+		t.Fatalf("expected integral to decay to %v, got %v", want, out)
+	}
+	if st := p.State(); st.IntegralDecay != 10*time.Second {
+		t.Fatalf("decay not in state: %v", st.IntegralDecay)
+	}
+}
@@ -0,0 +1,9 @@
+//go:build amd64
+
+package locking
+
+// Default is the kind returned by New. On amd64 the snapshot store wins for
+// read-mostly access in the single-CPU benchmarks in locking_test.go, and
+// roughly ties the mutex on write-heavy mixes. Multi-CPU contention has not
+// been measured.
+const Default = KindAtomic
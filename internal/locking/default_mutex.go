@@ -0,0 +1,8 @@
+//go:build !amd64
+
+package locking
+
+// Default is the kind returned by New. Targets without benchmark numbers,
+// arm64 included, keep the plain mutex, which has the most predictable
+// cost.
+const Default = KindMutex
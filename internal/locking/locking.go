@@ -0,0 +1,123 @@
+// Package locking provides interchangeable ways of guarding a read-mostly
+// value, so the controllers can pick the cheapest one for the target.
+//
+// Each Store serializes writers; they differ in what readers pay:
+//
+//   - Mutex: readers and writers share one sync.Mutex.
+//   - RWMutex: readers share a read lock, which scales with readers but
+//     costs more per operation when uncontended.
+//   - Atomic: readers load an immutable snapshot through an atomic
+//     pointer and never block; every write allocates a new snapshot.
+//
+// New returns the implementation that benchmarks fastest on the build
+// target (see default_*.go and the benchmarks in locking_test.go).
+package locking
+
+import (
+	"sync"
+	"sync/atomic"
+)
+
+// Store holds a value of type T.
+type Store[T any] interface {
+	// Load returns the current value.
+	Load() T
+	// Update replaces the value with fn applied to it. Updates are
+	// serialized, so fn always sees the result of the previous update.
+	Update(fn func(T) T)
+}
+
+// Kind identifies a Store implementation.
+type Kind int
+
+const (
+	// KindMutex selects a sync.Mutex based store.
+	KindMutex Kind = iota
+	// KindRWMutex selects a sync.RWMutex based store.
+	KindRWMutex
+	// KindAtomic selects a copy-on-write atomic snapshot store.
+	KindAtomic
+)
+
+// String returns the name of the kind.
+func (k Kind) String() string {
+	switch k {
+	case KindMutex:
+		return "mutex"
+	case KindRWMutex:
+		return "rwmutex"
+	case KindAtomic:
+		return "atomic"
+	default:
+		return "unknown"
+	}
+}
+
+// New returns a store holding v, using the default kind for the target.
+func New[T any](v T) Store[T] {
+	return NewKind(Default, v)
+}
+
+// NewKind returns a store of the given kind holding v.
+func NewKind[T any](k Kind, v T) Store[T] {
+	switch k {
+	case KindRWMutex:
+		return &rwStore[T]{v: v}
+	case KindAtomic:
+		s := &atomicStore[T]{}
+		s.p.Store(&v)
+		return s
+	default:
+		return &mutexStore[T]{v: v}
+	}
+}
+
+type mutexStore[T any] struct {
+	mu sync.Mutex
+	v  T
+}
+
+func (s *mutexStore[T]) Load() T {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.v
+}
+
+func (s *mutexStore[T]) Update(fn func(T) T) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.v = fn(s.v)
+}
+
+type rwStore[T any] struct {
+	mu sync.RWMutex
+	v  T
+}
+
+func (s *rwStore[T]) Load() T {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.v
+}
+
+func (s *rwStore[T]) Update(fn func(T) T) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.v = fn(s.v)
+}
+
+type atomicStore[T any] struct {
+	mu sync.Mutex // serializes writers.
+	p  atomic.Pointer[T]
+}
+
+func (s *atomicStore[T]) Load() T {
+	return *s.p.Load()
+}
+
+func (s *atomicStore[T]) Update(fn func(T) T) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	v := fn(*s.p.Load())
+	s.p.Store(&v)
+}
@@ -0,0 +1,155 @@
+package pidpool
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+)
+
+// LagControl configures a LagController.
+type LagControl struct {
+	// PID computes the knob setting from the consumer lag. A larger knob,
+	// e.g. processing rate or fetch concurrency, lowers the lag, so the
+	// gains are negative. The controller governs it over [Min, Max], see
+	// PID.Govern.
+	PID *PID
+	// Lag reads the consumer lag, e.g. summed over the partitions of a
+	// Kafka consumer group.
+	Lag Source
+	// Knob receives the new setting after every step.
+	Knob   Sink
+	Target float64
+
+	Min float64
+	Max float64
+	// Initial is the knob setting before the first step, clamped to
+	// [Min, Max].
+	Initial float64
+	// BurstLimit is the largest change of the knob per second, so a lag
+	// spike does not slam a downstream system. Zero means unlimited. While
+	// the knob is held back by the limit the integral is frozen, so the
+	// controller does not wind up on a setting the knob has not reached.
+	BurstLimit float64
+
+	// SaturationAfter is the number of consecutive steps the knob must sit
+	// at Max with the lag above Target before the target is reported
+	// unreachable. Defaults to 3.
+	SaturationAfter int
+	// OnSaturation is called when the target becomes unreachable and when
+	// it becomes reachable again.
+	OnSaturation func(saturated bool)
+}
+
+// LagController keeps consumer lag at a target by driving a processing
+// knob.
+type LagController struct {
+	cfg   LagControl
+	stage *OutputStage
+
+	mu        sync.Mutex
+	last      time.Time
+	knob      float64
+	slewing   bool
+	atMax     int
+	saturated bool
+}
+
+// NewLagController returns a controller for cfg.
+func NewLagController(cfg LagControl) (*LagController, error) {
+	if cfg.PID == nil || cfg.Lag == nil || cfg.Knob == nil {
+		return nil, errors.New("pid, lag and knob are required")
+	}
+	if cfg.SaturationAfter < 0 {
+		return nil, errors.New("saturation count must not be negative")
+	}
+	if cfg.SaturationAfter == 0 {
+		cfg.SaturationAfter = 3
+	}
+	stage, err := NewOutputStage(OutputConfig{Min: cfg.Min, Max: cfg.Max, SlewRate: cfg.BurstLimit})
+	if err != nil {
+		return nil, err
+	}
+	if err := cfg.PID.Govern(cfg.Target, cfg.Min, cfg.Max); err != nil {
+		return nil, err
+	}
+
+	c := &LagController{cfg: cfg, stage: stage, last: time.Now()}
+	c.knob = stage.Apply(cfg.Initial, 0)
+
+	return c, nil
+}
+
+// Step reads the lag, updates the controller and writes the new knob
+// setting.
+func (c *LagController) Step(ctx context.Context) error {
+	lag, err := c.cfg.Lag.Read(ctx)
+	if err != nil {
+		return err
+	}
+	c.mu.Lock()
+	hold := c.slewing
+	c.mu.Unlock()
+	pid := c.cfg.PID
+	st := pid.step(func() UpdateEvent {
+		pid.holdIntegral = hold
+		ev := pid.wallClockStep(lag)
+		pid.holdIntegral = false
+		return ev
+	}).Status()
+
+	c.mu.Lock()
+	now := time.Now()
+	c.knob = c.stage.Apply(st.Value, now.Sub(c.last).Seconds())
+	c.last = now
+	c.slewing = c.knob != clamp(st.Value, c.cfg.Min, c.cfg.Max)
+
+	if st.Saturated && c.knob == c.cfg.Max && lag > c.cfg.Target {
+		c.atMax++
+	} else {
+		c.atMax = 0
+	}
+	var notify func()
+	if saturated := c.atMax >= c.cfg.SaturationAfter; saturated != c.saturated {
+		c.saturated = saturated
+		if c.cfg.OnSaturation != nil {
+			notify = func() { c.cfg.OnSaturation(saturated) }
+		}
+	}
+	knob := c.knob
+	c.mu.Unlock()
+
+	if notify != nil {
+		notify()
+	}
+	return c.cfg.Knob.Write(ctx, knob)
+}
+
+// Run steps every interval until ctx is done. Step errors are skipped; the
+// knob keeps its last setting.
+func (c *LagController) Run(ctx context.Context, interval time.Duration) {
+	t := time.NewTicker(interval)
+	defer t.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-t.C:
+			_ = c.Step(ctx)
+		}
+	}
+}
+
+// Knob returns the last knob setting.
+func (c *LagController) Knob() float64 {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.knob
+}
+
+// Saturated reports whether the lag target is currently unreachable.
+func (c *LagController) Saturated() bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.saturated
+}
@@ -0,0 +1,88 @@
+package pidpool_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/ankur-anand/go-pidpool"
+)
+
+func TestLagController_SaturatesWhenUnreachable(t *testing.T) {
+	lag := 10000.0
+	var events []bool
+	c, err := pidpool.NewLagController(pidpool.LagControl{
+		PID:          pidpool.NewPI(-0.1, -1),
+		Lag:          pidpool.SourceFunc(func(context.Context) (float64, error) { return lag, nil }),
+		Knob:         pidpool.SinkFunc(func(context.Context, float64) error { return nil }),
+		Target:       100,
+		Min:          1,
+		Max:          50,
+		BurstLimit:   1000,
+		OnSaturation: func(s bool) { events = append(events, s) },
+	})
+	if err != nil {
+		t.Fatalf("NewLagController err: %v", err)
+	}
+
+	ctx := context.Background()
+	time.Sleep(5 * time.Millisecond)
+	if err := c.Step(ctx); err != nil {
+		t.Fatalf("Step err: %v", err)
+	}
+	// the knob starts at Min and may move by at most 1000/s.
+	if k := c.Knob(); k <= 1 || k >= 50 {
+		t.Fatalf("burst limit not applied: %v", k)
+	}
+
+	for i := 0; i < 100 && !c.Saturated(); i++ {
+		time.Sleep(time.Millisecond)
+		_ = c.Step(ctx)
+	}
+	if !c.Saturated() || c.Knob() != 50 {
+		t.Fatalf("expected saturation at max, knob %v", c.Knob())
+	}
+
+	lag = 0
+	for i := 0; i < 100 && c.Saturated(); i++ {
+		time.Sleep(time.Millisecond)
+		_ = c.Step(ctx)
+	}
+	if len(events) != 2 || !events[0] || events[1] {
+		t.Fatalf("unexpected saturation events %v", events)
+	}
+}
+
+func TestLagController_BurstLimitFreezesIntegral(t *testing.T) {
+	pid := pidpool.NewPI(-1, -1)
+	c, err := pidpool.NewLagController(pidpool.LagControl{
+		PID:        pid,
+		Lag:        pidpool.SourceFunc(func(context.Context) (float64, error) { return 1000, nil }),
+		Knob:       pidpool.SinkFunc(func(context.Context, float64) error { return nil }),
+		Target:     0,
+		Min:        0,
+		Max:        100,
+		BurstLimit: 1e-3,
+	})
+	if err != nil {
+		t.Fatalf("NewLagController err: %v", err)
+	}
+
+	ctx := context.Background()
+	if err := c.Step(ctx); err != nil {
+		t.Fatalf("Step err: %v", err)
+	}
+	integral := pid.State().Integral
+	for i := 0; i < 5; i++ {
+		time.Sleep(time.Millisecond)
+		if err := c.Step(ctx); err != nil {
+			t.Fatalf("Step err: %v", err)
+		}
+	}
+	if k := c.Knob(); k >= 1 {
+		t.Fatalf("expected the knob held back by the burst limit, got %v", k)
+	}
+	if got := pid.State().Integral; got != integral {
+		t.Fatalf("integral wound up while slew limited: %v -> %v", integral, got)
+	}
+}
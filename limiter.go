@@ -0,0 +1,229 @@
+package pidpool
+
+import (
+	"context"
+	"errors"
+	"math"
+	"math/rand/v2"
+	"slices"
+	"sync"
+	"time"
+)
+
+// maxLatencySamples bounds the latency samples kept per interval; beyond
+// it, samples are reservoir sampled.
+const maxLatencySamples = 4096
+
+// AdaptiveLimiterConfig configures an AdaptiveLimiter.
+type AdaptiveLimiterConfig struct {
+	// PID computes the concurrency limit from the observed latency
+	// percentile, in seconds, governed over [MinLimit, MaxLimit], see
+	// PID.Govern.
+	PID *PID
+	// Target is the latency to hold the percentile at.
+	Target time.Duration
+	// Percentile is the latency percentile tracked, in (0, 1]. Defaults to
+	// 0.99.
+	Percentile float64
+
+	MinLimit int
+	MaxLimit int
+	// InitialLimit is the limit before the first adjustment. Defaults to
+	// MinLimit.
+	InitialLimit int
+	// Interval is the control period. Defaults to one second.
+	Interval time.Duration
+}
+
+// AdaptiveLimiter is a semaphore whose capacity is adjusted every interval
+// by a PID loop holding a latency percentile at a target, in the manner of
+// adaptive concurrency limits for servers. Latencies are reported with
+// Observe, or measured by Do.
+type AdaptiveLimiter struct {
+	cfg AdaptiveLimiterConfig
+
+	mu       sync.Mutex
+	limit    int
+	inFlight int
+	waiters  []chan struct{}
+	samples  []float64
+	seen     int
+
+	stop chan struct{}
+	done chan struct{}
+	once sync.Once
+}
+
+// NewAdaptiveLimiter returns a limiter and starts its control loop.
+func NewAdaptiveLimiter(cfg AdaptiveLimiterConfig) (*AdaptiveLimiter, error) {
+	if cfg.PID == nil {
+		return nil, errors.New("pid is required")
+	}
+	if cfg.MinLimit < 1 || cfg.MaxLimit < cfg.MinLimit {
+		return nil, errors.New("limits must satisfy 1 <= min <= max")
+	}
+	if cfg.Target <= 0 {
+		return nil, errors.New("target must be positive")
+	}
+	if cfg.Percentile == 0 {
+		cfg.Percentile = 0.99
+	}
+	if !(cfg.Percentile > 0 && cfg.Percentile <= 1) {
+		return nil, errors.New("percentile must be in (0, 1]")
+	}
+	if cfg.InitialLimit == 0 {
+		cfg.InitialLimit = cfg.MinLimit
+	}
+	if cfg.InitialLimit < cfg.MinLimit || cfg.InitialLimit > cfg.MaxLimit {
+		return nil, errors.New("initial limit out of range")
+	}
+	if cfg.Interval < 0 {
+		return nil, errors.New("interval must not be negative")
+	}
+	if cfg.Interval == 0 {
+		cfg.Interval = time.Second
+	}
+	if err := cfg.PID.Govern(cfg.Target.Seconds(), float64(cfg.MinLimit), float64(cfg.MaxLimit)); err != nil {
+		return nil, err
+	}
+
+	l := &AdaptiveLimiter{
+		cfg:   cfg,
+		limit: cfg.InitialLimit,
+		stop:  make(chan struct{}),
+		done:  make(chan struct{}),
+	}
+	go l.loop()
+
+	return l, nil
+}
+
+// Acquire blocks until a slot is free or ctx is done. Every successful
+// Acquire must be paired with a Release.
+func (l *AdaptiveLimiter) Acquire(ctx context.Context) error {
+	l.mu.Lock()
+	if l.inFlight < l.limit && len(l.waiters) == 0 {
+		l.inFlight++
+		l.mu.Unlock()
+		return nil
+	}
+	ch := make(chan struct{})
+	l.waiters = append(l.waiters, ch)
+	l.mu.Unlock()
+
+	select {
+	case <-ch:
+		return nil
+	case <-ctx.Done():
+		l.mu.Lock()
+		defer l.mu.Unlock()
+		if i := slices.Index(l.waiters, ch); i >= 0 {
+			l.waiters = slices.Delete(l.waiters, i, i+1)
+			return ctx.Err()
+		}
+		// granted concurrently: hand the slot on.
+		l.inFlight--
+		l.grantLocked()
+		return ctx.Err()
+	}
+}
+
+// Release frees a slot taken by Acquire.
+func (l *AdaptiveLimiter) Release() {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.inFlight--
+	l.grantLocked()
+}
+
+func (l *AdaptiveLimiter) grantLocked() {
+	for l.inFlight < l.limit && len(l.waiters) > 0 {
+		close(l.waiters[0])
+		l.waiters = l.waiters[1:]
+		l.inFlight++
+	}
+}
+
+// Observe reports the latency of a request.
+func (l *AdaptiveLimiter) Observe(latency time.Duration) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.seen++
+	if len(l.samples) < maxLatencySamples {
+		l.samples = append(l.samples, latency.Seconds())
+	} else if i := rand.IntN(l.seen); i < maxLatencySamples {
+		l.samples[i] = latency.Seconds()
+	}
+}
+
+// Do runs fn within a slot and observes its in-flight time. The time spent
+// waiting for the slot is not included: it grows with the offered load, not
+// with the strain on the protected resource.
+func (l *AdaptiveLimiter) Do(ctx context.Context, fn func() error) error {
+	if err := l.Acquire(ctx); err != nil {
+		return err
+	}
+	defer l.Release()
+	start := time.Now()
+	err := fn()
+	l.Observe(time.Since(start))
+
+	return err
+}
+
+// Limit returns the current concurrency limit.
+func (l *AdaptiveLimiter) Limit() int {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return l.limit
+}
+
+// InFlight returns the number of slots in use.
+func (l *AdaptiveLimiter) InFlight() int {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return l.inFlight
+}
+
+// Close stops the control loop. The limit stays where it is.
+func (l *AdaptiveLimiter) Close() {
+	l.once.Do(func() { close(l.stop) })
+	<-l.done
+}
+
+func (l *AdaptiveLimiter) loop() {
+	defer close(l.done)
+	t := time.NewTicker(l.cfg.Interval)
+	defer t.Stop()
+	last := time.Now()
+	for {
+		select {
+		case <-l.stop:
+			return
+		case now := <-t.C:
+			l.control(now.Sub(last))
+			last = now
+		}
+	}
+}
+
+// control runs one control step. An interval without samples leaves the
+// limit unchanged.
+func (l *AdaptiveLimiter) control(dt time.Duration) {
+	l.mu.Lock()
+	samples := l.samples
+	l.samples, l.seen = nil, 0
+	l.mu.Unlock()
+	if len(samples) == 0 {
+		return
+	}
+
+	slices.Sort(samples)
+	i := int(math.Ceil(l.cfg.Percentile*float64(len(samples)))) - 1
+	out := l.cfg.PID.UpdateDuration(samples[max(i, 0)], dt.Seconds())
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.limit = int(math.Round(out))
+	l.grantLocked()
+}
@@ -0,0 +1,84 @@
+package pidpool_test
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/ankur-anand/go-pidpool"
+)
+
+func TestAdaptiveLimiter_HoldsLatency(t *testing.T) {
+	l, err := pidpool.NewAdaptiveLimiter(pidpool.AdaptiveLimiterConfig{
+		PID:          pidpool.NewPI(200, 2000),
+		Target:       10 * time.Millisecond,
+		MinLimit:     1,
+		MaxLimit:     64,
+		InitialLimit: 32,
+		Interval:     20 * time.Millisecond,
+	})
+	if err != nil {
+		t.Fatalf("NewAdaptiveLimiter err: %v", err)
+	}
+	defer l.Close()
+
+	// the backend slows down by 1ms per concurrent request.
+	var mu sync.Mutex
+	active := 0
+	backend := func() error {
+		mu.Lock()
+		active++
+		d := time.Duration(active) * time.Millisecond
+		mu.Unlock()
+		time.Sleep(d)
+		mu.Lock()
+		active--
+		mu.Unlock()
+		return nil
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 600*time.Millisecond)
+	defer cancel()
+	var wg sync.WaitGroup
+	for i := 0; i < 64; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for ctx.Err() == nil {
+				_ = l.Do(ctx, backend)
+			}
+		}()
+	}
+	wg.Wait()
+
+	if n := l.Limit(); n >= 20 || n < 4 {
+		t.Fatalf("limit did not adapt: %d", n)
+	}
+	if n := l.InFlight(); n != 0 {
+		t.Fatalf("slots leaked: %d", n)
+	}
+}
+
+func TestAdaptiveLimiter_AcquireCancel(t *testing.T) {
+	l, err := pidpool.NewAdaptiveLimiter(pidpool.AdaptiveLimiterConfig{
+		PID: pidpool.NewP(1), Target: time.Second, MinLimit: 1, MaxLimit: 1,
+	})
+	if err != nil {
+		t.Fatalf("NewAdaptiveLimiter err: %v", err)
+	}
+	defer l.Close()
+	if err := l.Acquire(context.Background()); err != nil {
+		t.Fatalf("Acquire err: %v", err)
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	if err := l.Acquire(ctx); !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("expected deadline, got %v", err)
+	}
+	l.Release()
+	if err := l.Acquire(context.Background()); err != nil {
+		t.Fatalf("slot not released: %v", err)
+	}
+}
@@ -0,0 +1,185 @@
+package pidpool
+
+import (
+	"encoding/gob"
+	"errors"
+	"fmt"
+	"io"
+	"sort"
+	"sync"
+)
+
+// Manager owns a set of controllers keyed by an arbitrary string, e.g. one
+// limiter per tenant. Controllers are created on first use.
+type Manager struct {
+	mu sync.RWMutex
+
+	newPID  func(key string) *PID
+	pids    map[string]*PID
+	unwatch map[string]func()
+	feed    *ChangeFeed
+
+	profileEvery int
+
+	// feedforward links in registration order.
+	links []*ffLink
+}
+
+// defaultFeedCapacity is the number of deltas a Manager's feed retains.
+const defaultFeedCapacity = 4096
+
+// NewManager returns a Manager that creates controllers with newPID.
+func NewManager(newPID func(key string) *PID) *Manager {
+	feed, _ := NewChangeFeed(defaultFeedCapacity)
+	return &Manager{
+		newPID:  newPID,
+		pids:    make(map[string]*PID),
+		unwatch: make(map[string]func()),
+		feed:    feed,
+	}
+}
+
+// Feed returns the change feed of the manager. A delta is published when a
+// controller is created, removed, or has its configuration changed.
+func (m *Manager) Feed() *ChangeFeed {
+	return m.feed
+}
+
+// Snapshot returns the full state of every controller together with the
+// feed sequence number it is consistent with. Consumers apply Feed().Since
+// of that sequence number to stay in sync afterwards.
+func (m *Manager) Snapshot() (uint64, map[string]State) {
+	seq := m.feed.Seq()
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	states := make(map[string]State, len(m.pids))
+	for k, pid := range m.pids {
+		states[k] = pid.State()
+	}
+
+	return seq, states
+}
+
+func (m *Manager) attach(key string, pid *PID) {
+	m.unwatch[key] = pid.SubscribeWith(func(ev Event) {
+		if ev.Kind != EventConfigChanged {
+			return
+		}
+		if cur, ok := m.Lookup(key); ok && cur == pid {
+			m.feed.Publish(key, ev.State)
+		}
+	}, SubscribeOptions{Config: true})
+	m.feed.Publish(key, pid.State())
+}
+
+// Get returns the controller for key, creating it if needed.
+func (m *Manager) Get(key string) *PID {
+	m.mu.RLock()
+	pid, ok := m.pids[key]
+	m.mu.RUnlock()
+	if ok {
+		return pid
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if pid, ok := m.pids[key]; ok {
+		return pid
+	}
+	pid = m.newPID(key)
+	if m.profileEvery > 0 {
+		_ = pid.SetProfiling(m.profileEvery)
+	}
+	m.pids[key] = pid
+	m.attach(key, pid)
+
+	return pid
+}
+
+// Lookup returns the controller for key without creating it.
+func (m *Manager) Lookup(key string) (*PID, bool) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	pid, ok := m.pids[key]
+	return pid, ok
+}
+
+// Delete removes the controller for key and the feedforward links into
+// and out of it.
+func (m *Manager) Delete(key string) {
+	m.mu.Lock()
+	pid, ok := m.pids[key]
+	if !ok {
+		m.mu.Unlock()
+		return
+	}
+	delete(m.pids, key)
+	m.unwatch[key]()
+	delete(m.unwatch, key)
+	m.feed.PublishRemoved(key)
+	m.mu.Unlock()
+
+	m.unlink(func(fl *ffLink) bool { return fl.from == pid || fl.to == pid })
+}
+
+// Keys returns the sorted keys of all controllers.
+func (m *Manager) Keys() []string {
+	m.mu.RLock()
+	keys := make([]string, 0, len(m.pids))
+	for k := range m.pids {
+		keys = append(keys, k)
+	}
+	m.mu.RUnlock()
+	sort.Strings(keys)
+
+	return keys
+}
+
+// Len returns the number of controllers.
+func (m *Manager) Len() int {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	return len(m.pids)
+}
+
+type exportRecord struct {
+	Key   string
+	State State
+}
+
+// Export streams the state of every controller to w, one record at a time,
+// so a peer can warm-start its controllers with Import.
+func (m *Manager) Export(w io.Writer) error {
+	enc := gob.NewEncoder(w)
+	for _, key := range m.Keys() {
+		pid, ok := m.Lookup(key)
+		if !ok {
+			continue
+		}
+		if err := enc.Encode(exportRecord{Key: key, State: pid.State()}); err != nil {
+			return fmt.Errorf("export %q: %w", key, err)
+		}
+	}
+
+	return nil
+}
+
+// Import reads a stream written by Export and restores each controller,
+// creating it if needed. It returns the number of controllers imported.
+func (m *Manager) Import(r io.Reader) (int, error) {
+	dec := gob.NewDecoder(r)
+	n := 0
+	for {
+		var rec exportRecord
+		if err := dec.Decode(&rec); err != nil {
+			if errors.Is(err, io.EOF) {
+				return n, nil
+			}
+			return n, fmt.Errorf("import record %d: %w", n, err)
+		}
+		if err := m.Get(rec.Key).RestoreState(rec.State); err != nil {
+			return n, fmt.Errorf("import %q: %w", rec.Key, err)
+		}
+		n++
+	}
+}
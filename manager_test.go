@@ -0,0 +1,64 @@
+package pidpool_test
+
+import (
+	"bytes"
+	"fmt"
+	"reflect"
+	"testing"
+
+	"github.com/ankur-anand/go-pidpool"
+)
+
+func newTenantPID(string) *pidpool.PID {
+	p := pidpool.NewPID(1, 0.5, 0, 0)
+	p.SetSetPoint(100)
+	return p
+}
+
+func TestManager_GetCreatesOnce(t *testing.T) {
+	m := pidpool.NewManager(newTenantPID)
+	a := m.Get("tenant-a")
+	if m.Get("tenant-a") != a {
+		t.Fatalf("Get returned a different controller for the same key")
+	}
+	if _, ok := m.Lookup("tenant-b"); ok {
+		t.Fatalf("Lookup must not create controllers")
+	}
+	m.Delete("tenant-a")
+	if m.Len() != 0 {
+		t.Fatalf("expected empty manager, got %d", m.Len())
+	}
+}
+
+func TestManager_ExportImport(t *testing.T) {
+	src := pidpool.NewManager(newTenantPID)
+	for i := 0; i < 50; i++ {
+		src.Get(fmt.Sprintf("tenant-%02d", i)).UpdateDuration(float64(i), 1)
+	}
+
+	var buf bytes.Buffer
+	if err := src.Export(&buf); err != nil {
+		t.Fatalf("Export err: %v", err)
+	}
+
+	dst := pidpool.NewManager(newTenantPID)
+	n, err := dst.Import(&buf)
+	if err != nil {
+		t.Fatalf("Import err: %v", err)
+	}
+	if n != 50 || dst.Len() != 50 {
+		t.Fatalf("expected 50 controllers, imported %d, have %d", n, dst.Len())
+	}
+	for _, key := range src.Keys() {
+		a, _ := src.Lookup(key)
+		b, _ := dst.Lookup(key)
+		sa, sb := a.State(), b.State()
+		if !sa.LastUpdate.Equal(sb.LastUpdate) {
+			t.Fatalf("%s: lastUpdate mismatch", key)
+		}
+		sa.LastUpdate = sb.LastUpdate
+		if !reflect.DeepEqual(sa, sb) {
+			t.Fatalf("%s: state mismatch:\n%+v\n%+v", key, sa, sb)
+		}
+	}
+}
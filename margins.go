@@ -0,0 +1,132 @@
+package pidpool
+
+import (
+	"math"
+	"math/cmplx"
+)
+
+// Margins are the classical robustness margins of a control loop.
+type Margins struct {
+	// GainMargin is the factor by which the loop gain can grow before the
+	// loop becomes unstable, +Inf when the phase never reaches -180°.
+	GainMargin float64
+	// PhaseMargin is the additional phase lag, in degrees, the loop
+	// tolerates at the gain crossover, +Inf when the loop gain never
+	// reaches 1.
+	PhaseMargin float64
+	// GainCrossover is the frequency in rad/s where the loop gain is 1 and
+	// PhaseCrossover the one where the phase is -180°; zero when there is
+	// none.
+	GainCrossover  float64
+	PhaseCrossover float64
+}
+
+// GainMarginDB returns the gain margin in decibels.
+func (m Margins) GainMarginDB() float64 {
+	return 20 * math.Log10(m.GainMargin)
+}
+
+// Stable reports whether both margins are positive, which for an
+// open-loop stable plant means the closed loop is stable.
+func (m Margins) Stable() bool {
+	return m.GainMargin > 1 && m.PhaseMargin > 0
+}
+
+// StabilityMargins computes the margins of the loop formed by a controller
+// with gains g and the plant, e.g. FOPDT.TransferFunction(), so new gains
+// can be checked for robustness before they are deployed. The derivative is
+// ideal; use LoopMargins with Gains.TransferFunction for a filtered one.
+// Typical robust tunings have a gain margin above 2 and a phase margin
+// above 45°.
+func StabilityMargins(plant TransferFunction, g Gains) (Margins, error) {
+	if err := g.Validate(); err != nil {
+		return Margins{}, err
+	}
+	return LoopMargins(g.TransferFunction(0).Mul(plant))
+}
+
+// Frequency range and density of the margin search.
+const (
+	marginMinFreq   = 1e-6
+	marginDecades   = 12
+	marginPerDecade = 200
+)
+
+// LoopMargins computes the margins of the open loop transfer function. With
+// several crossovers, the smallest margins are reported.
+func LoopMargins(loop TransferFunction) (Margins, error) {
+	if err := loop.Validate(); err != nil {
+		return Margins{}, err
+	}
+
+	m := Margins{GainMargin: math.Inf(1), PhaseMargin: math.Inf(1)}
+	r := phaseTracker{tf: loop}
+	n := marginDecades * marginPerDecade
+	prevW := marginMinFreq
+	prevPhase := r.startPhase(prevW)
+	prevMag := cmplx.Abs(loop.Eval(prevW))
+	for i := 1; i <= n; i++ {
+		w := marginMinFreq * math.Pow(10, float64(i)/marginPerDecade)
+		phase := r.phase(w, prevW, prevPhase)
+		mag := cmplx.Abs(loop.Eval(w))
+
+		if (prevMag-1)*(mag-1) <= 0 && prevMag != mag {
+			wc := bisectLog(prevW, w, func(x float64) float64 { return cmplx.Abs(loop.Eval(x)) - 1 })
+			if pm := 180 + r.phase(wc, prevW, prevPhase)*180/math.Pi; pm < m.PhaseMargin {
+				m.PhaseMargin, m.GainCrossover = pm, wc
+			}
+		}
+		// crossings of -180° - k*360°.
+		if k := math.Floor((prevPhase + math.Pi) / (2 * math.Pi)); k != math.Floor((phase+math.Pi)/(2*math.Pi)) {
+			target := 2*math.Pi*math.Max(k, math.Floor((phase+math.Pi)/(2*math.Pi))) - math.Pi
+			w180 := bisectLog(prevW, w, func(x float64) float64 { return r.phase(x, prevW, prevPhase) - target })
+			if gm := 1 / cmplx.Abs(loop.Eval(w180)); gm < m.GainMargin {
+				m.GainMargin, m.PhaseCrossover = gm, w180
+			}
+		}
+		prevW, prevPhase, prevMag = w, phase, mag
+	}
+
+	return m, nil
+}
+
+// phaseTracker computes the continuous (unwrapped) phase of a transfer
+// function. The phase of the rational part is unwrapped along a dense
+// frequency scan and the dead time adds its exact -w*DeadTime.
+type phaseTracker struct {
+	tf TransferFunction
+}
+
+// startPhase returns the phase at the low frequency w. Integrators make
+// the low frequency phase lag, never lead, so a phase of +180° is taken as
+// -180°.
+func (r phaseTracker) startPhase(w float64) float64 {
+	p := cmplx.Phase(r.tf.rational(w))
+	if p > math.Pi/2 {
+		p -= 2 * math.Pi
+	}
+	return p - w*r.tf.DeadTime
+}
+
+// phase returns the phase at w given the phase at the nearby frequency ref.
+func (r phaseTracker) phase(w, ref, refPhase float64) float64 {
+	d := cmplx.Phase(r.tf.rational(w)) - cmplx.Phase(r.tf.rational(ref))
+	d = wrapDelta(d, 2*math.Pi)
+	return refPhase + d - (w-ref)*r.tf.DeadTime
+}
+
+// bisectLog finds a root of f between lo and hi, bisecting in log
+// frequency.
+func bisectLog(lo, hi float64, f func(float64) float64) float64 {
+	flo := f(lo)
+	for i := 0; i < 60; i++ {
+		mid := math.Sqrt(lo * hi)
+		fm := f(mid)
+		if (fm < 0) == (flo < 0) {
+			lo, flo = mid, fm
+		} else {
+			hi = mid
+		}
+	}
+	return math.Sqrt(lo * hi)
+}
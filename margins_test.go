@@ -0,0 +1,58 @@
+package pidpool_test
+
+import (
+	"math"
+	"testing"
+
+	"github.com/ankur-anand/go-pidpool"
+)
+
+func TestLoopMargins_ThirdOrder(t *testing.T) {
+	// L = 1/(s(s+1)(s+2)): the phase reaches -180° at √2 rad/s where the
+	// gain is 1/6.
+	m, err := pidpool.LoopMargins(pidpool.TransferFunction{Num: []float64{1}, Den: []float64{1, 3, 2, 0}})
+	if err != nil {
+		t.Fatalf("LoopMargins err: %v", err)
+	}
+	if math.Abs(m.GainMargin-6) > 1e-6 || math.Abs(m.PhaseCrossover-math.Sqrt2) > 1e-6 {
+		t.Fatalf("expected gain margin 6 at √2 rad/s, got %+v", m)
+	}
+	// |L(jw)| = 1 at w ≈ 0.4457, where the phase is -126.6°.
+	if math.Abs(m.GainCrossover-0.4457) > 1e-3 || math.Abs(m.PhaseMargin-53.4) > 0.1 {
+		t.Fatalf("unexpected phase margin: %+v", m)
+	}
+	if !m.Stable() || math.Abs(m.GainMarginDB()-15.563) > 1e-3 {
+		t.Fatalf("unexpected stability: %+v", m)
+	}
+}
+
+func TestStabilityMargins_FOPDT(t *testing.T) {
+	model := pidpool.FOPDT{Gain: 2, TimeConstant: 30, DeadTime: 5}
+	kp, ki, kd, err := model.SIMC(0)
+	if err != nil {
+		t.Fatalf("SIMC err: %v", err)
+	}
+	m, err := pidpool.StabilityMargins(model.TransferFunction(), pidpool.Gains{Kp: kp, Ki: ki, Kd: kd})
+	if err != nil {
+		t.Fatalf("StabilityMargins err: %v", err)
+	}
+	// the PI zero cancels the lag, leaving L = exp(-5s)/(10s): crossover at
+	// 0.1 rad/s with a phase margin of 90° - 0.5 rad, and a gain margin of
+	// π at π/10 rad/s.
+	if math.Abs(m.GainCrossover-0.1) > 1e-6 || math.Abs(m.PhaseMargin-(90-0.5*180/math.Pi)) > 1e-4 {
+		t.Fatalf("unexpected SIMC phase margin: %+v", m)
+	}
+	if math.Abs(m.GainMargin-math.Pi) > 1e-6 || math.Abs(m.PhaseCrossover-math.Pi/10) > 1e-6 {
+		t.Fatalf("unexpected SIMC gain margin: %+v", m)
+	}
+
+	// the loop becomes unstable with four times the gain.
+	m, _ = pidpool.StabilityMargins(model.TransferFunction(), pidpool.Gains{Kp: 4 * kp, Ki: 4 * ki})
+	if m.Stable() {
+		t.Fatalf("expected an unstable loop: %+v", m)
+	}
+
+	if _, err := pidpool.StabilityMargins(pidpool.TransferFunction{Num: []float64{1}}, pidpool.Gains{Kp: 1}); err == nil {
+		t.Fatalf("expected error for a missing denominator")
+	}
+}
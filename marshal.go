@@ -0,0 +1,218 @@
+package pidpool
+
+import (
+	"bytes"
+	"encoding/gob"
+	"encoding/json"
+	"math"
+	"time"
+)
+
+// stateJSON is the wire form of State. Limits are pointers because JSON
+// cannot represent infinity; an unbounded limit is encoded as null.
+type stateJSON struct {
+	Kp float64 `json:"kp"`
+	Ki float64 `json:"ki"`
+	Kd float64 `json:"kd"`
+
+	SetPoint float64 `json:"setPoint"`
+	DeadBand float64 `json:"deadBand"`
+
+	AdaptiveDeadBand *AdaptiveDeadBand `json:"adaptiveDeadBand,omitempty"`
+
+	OutputMin   *float64 `json:"outputMin"`
+	OutputMax   *float64 `json:"outputMax"`
+	IntegralMin *float64 `json:"integralMin"`
+	IntegralMax *float64 `json:"integralMax"`
+
+	IntegralTermLimits bool     `json:"integralTermLimits,omitempty"`
+	IntegralTermMin    *float64 `json:"integralTermMin,omitempty"`
+	IntegralTermMax    *float64 `json:"integralTermMax,omitempty"`
+
+	IntegralDecay time.Duration `json:"integralDecay,omitempty"`
+
+	Mode         Mode    `json:"mode"`
+	ManualOutput float64 `json:"manualOutput,omitempty"`
+
+	NegativeError       *DirectionalParams `json:"negativeError,omitempty"`
+	NegativeErrorActive bool               `json:"negativeErrorActive,omitempty"`
+
+	Quantization   *Quantization `json:"quantization,omitempty"`
+	OutputDeadband float64       `json:"outputDeadband,omitempty"`
+	CircularRange  *Limits       `json:"circularRange,omitempty"`
+	InputRange     *Limits       `json:"inputRange,omitempty"`
+	OutputRange    *Limits       `json:"outputRange,omitempty"`
+
+	Discretization  *Discretization `json:"discretization,omitempty"`
+	DerivativeState float64         `json:"derivativeState,omitempty"`
+
+	Calibration *Calibration `json:"calibration,omitempty"`
+
+	Filter        *MeasurementFilter `json:"filter,omitempty"`
+	FilterHistory []float64          `json:"filterHistory,omitempty"`
+
+	Integral   float64   `json:"integral"`
+	PrevValue  float64   `json:"prevValue"`
+	PrevError  float64   `json:"prevError"`
+	LastUpdate time.Time `json:"lastUpdate"`
+	LastOutput float64   `json:"lastOutput,omitempty"`
+
+	Annotations map[string]string `json:"annotations,omitempty"`
+}
+
+func limitToJSON(v float64) *float64 {
+	if math.IsInf(v, 0) {
+		return nil
+	}
+	return &v
+}
+
+func limitFromJSON(v *float64, unbounded float64) float64 {
+	if v == nil {
+		return unbounded
+	}
+	return *v
+}
+
+// MarshalJSON implements json.Marshaler.
+func (s State) MarshalJSON() ([]byte, error) {
+	js := stateJSON{
+		Kp:                  s.Kp,
+		Ki:                  s.Ki,
+		Kd:                  s.Kd,
+		SetPoint:            s.SetPoint,
+		DeadBand:            s.DeadBand,
+		AdaptiveDeadBand:    s.AdaptiveDeadBand,
+		OutputMin:           limitToJSON(s.OutputMin),
+		OutputMax:           limitToJSON(s.OutputMax),
+		IntegralMin:         limitToJSON(s.IntegralMin),
+		IntegralMax:         limitToJSON(s.IntegralMax),
+		IntegralTermLimits:  s.IntegralTermLimits,
+		IntegralDecay:       s.IntegralDecay,
+		Mode:                s.Mode,
+		ManualOutput:        s.ManualOutput,
+		NegativeError:       s.NegativeError,
+		NegativeErrorActive: s.NegativeErrorActive,
+		OutputDeadband:      s.OutputDeadband,
+		CircularRange:       s.CircularRange,
+		InputRange:          s.InputRange,
+		OutputRange:         s.OutputRange,
+		DerivativeState:     s.DerivativeState,
+		Integral:            s.Integral,
+		PrevValue:           s.PrevValue,
+		PrevError:           s.PrevError,
+		LastUpdate:          s.LastUpdate,
+		LastOutput:          s.LastOutput,
+		Annotations:         s.Annotations,
+	}
+	if s.Quantization != (Quantization{}) {
+		q := s.Quantization
+		js.Quantization = &q
+	}
+	if s.Discretization != (Discretization{}) {
+		d := s.Discretization
+		js.Discretization = &d
+	}
+	if s.Calibration != (Calibration{}) {
+		c := s.Calibration
+		js.Calibration = &c
+	}
+	if s.Filter.Kind != FilterNone {
+		f := s.Filter
+		js.Filter, js.FilterHistory = &f, s.FilterHistory
+	}
+	if s.IntegralTermLimits {
+		js.IntegralTermMin = limitToJSON(s.IntegralTermMin)
+		js.IntegralTermMax = limitToJSON(s.IntegralTermMax)
+	}
+
+	return json.Marshal(js)
+}
+
+// UnmarshalJSON implements json.Unmarshaler.
+func (s *State) UnmarshalJSON(data []byte) error {
+	var js stateJSON
+	if err := json.Unmarshal(data, &js); err != nil {
+		return err
+	}
+	*s = State{
+		Kp:                  js.Kp,
+		Ki:                  js.Ki,
+		Kd:                  js.Kd,
+		SetPoint:            js.SetPoint,
+		DeadBand:            js.DeadBand,
+		AdaptiveDeadBand:    js.AdaptiveDeadBand,
+		OutputMin:           limitFromJSON(js.OutputMin, math.Inf(-1)),
+		OutputMax:           limitFromJSON(js.OutputMax, math.Inf(1)),
+		IntegralMin:         limitFromJSON(js.IntegralMin, math.Inf(-1)),
+		IntegralMax:         limitFromJSON(js.IntegralMax, math.Inf(1)),
+		IntegralTermLimits:  js.IntegralTermLimits,
+		IntegralDecay:       js.IntegralDecay,
+		Mode:                js.Mode,
+		ManualOutput:        js.ManualOutput,
+		NegativeError:       js.NegativeError,
+		NegativeErrorActive: js.NegativeErrorActive,
+		OutputDeadband:      js.OutputDeadband,
+		CircularRange:       js.CircularRange,
+		InputRange:          js.InputRange,
+		OutputRange:         js.OutputRange,
+		DerivativeState:     js.DerivativeState,
+		Integral:            js.Integral,
+		PrevValue:           js.PrevValue,
+		PrevError:           js.PrevError,
+		LastUpdate:          js.LastUpdate,
+		LastOutput:          js.LastOutput,
+		Annotations:         js.Annotations,
+	}
+	if js.Quantization != nil {
+		s.Quantization = *js.Quantization
+	}
+	if js.Discretization != nil {
+		s.Discretization = *js.Discretization
+	}
+	if js.Calibration != nil {
+		s.Calibration = *js.Calibration
+	}
+	if js.Filter != nil {
+		s.Filter, s.FilterHistory = *js.Filter, js.FilterHistory
+	}
+	if js.IntegralTermLimits {
+		s.IntegralTermMin = limitFromJSON(js.IntegralTermMin, math.Inf(-1))
+		s.IntegralTermMax = limitFromJSON(js.IntegralTermMax, math.Inf(1))
+	}
+
+	return nil
+}
+
+// MarshalJSON implements json.Marshaler. It encodes the controller's
+// configuration and internal state.
+func (pid *PID) MarshalJSON() ([]byte, error) {
+	return json.Marshal(pid.State())
+}
+
+// UnmarshalJSON implements json.Unmarshaler.
+func (pid *PID) UnmarshalJSON(data []byte) error {
+	var s State
+	if err := json.Unmarshal(data, &s); err != nil {
+		return err
+	}
+	return pid.RestoreState(s)
+}
+
+// GobEncode implements gob.GobEncoder.
+func (pid *PID) GobEncode() ([]byte, error) {
+	var buf bytes.Buffer
+	if err := gob.NewEncoder(&buf).Encode(pid.State()); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// GobDecode implements gob.GobDecoder.
+func (pid *PID) GobDecode(data []byte) error {
+	var s State
+	if err := gob.NewDecoder(bytes.NewReader(data)).Decode(&s); err != nil {
+		return err
+	}
+	return pid.RestoreState(s)
+}
@@ -0,0 +1,68 @@
+package pidpool_test
+
+import (
+	"bytes"
+	"encoding/gob"
+	"encoding/json"
+	"math"
+	"reflect"
+	"testing"
+
+	"github.com/ankur-anand/go-pidpool"
+)
+
+func newMarshalPID(t *testing.T) *pidpool.PID {
+	t.Helper()
+	p := pidpool.NewPID(1.5, 0.25, 0.05, 0.1)
+	p.SetSetPoint(20)
+	if err := p.SetIntegralLimits(-5, 5); err != nil {
+		t.Fatalf("SetIntegralLimits err: %v", err)
+	}
+	if err := p.SetAnnotation("asset", "oven-3"); err != nil {
+		t.Fatalf("SetAnnotation err: %v", err)
+	}
+	p.UpdateDuration(15, 1)
+	p.UpdateDuration(17, 1)
+	return p
+}
+
+func TestPID_JSONRoundTrip(t *testing.T) {
+	p := newMarshalPID(t)
+	data, err := json.Marshal(p)
+	if err != nil {
+		t.Fatalf("Marshal err: %v", err)
+	}
+
+	var q pidpool.PID
+	if err := json.Unmarshal(data, &q); err != nil {
+		t.Fatalf("Unmarshal err: %v", err)
+	}
+
+	sp, sq := p.State(), q.State()
+	if !math.IsInf(sq.OutputMax, 1) || !math.IsInf(sq.OutputMin, -1) {
+		t.Fatalf("unbounded output limits lost: %+v", sq)
+	}
+	if !sp.LastUpdate.Equal(sq.LastUpdate) {
+		t.Fatalf("lastUpdate mismatch")
+	}
+	sp.LastUpdate = sq.LastUpdate
+	if !reflect.DeepEqual(sp, sq) {
+		t.Fatalf("state mismatch:\n%+v\n%+v", sp, sq)
+	}
+}
+
+func TestPID_GobRoundTrip(t *testing.T) {
+	p := newMarshalPID(t)
+	var buf bytes.Buffer
+	if err := gob.NewEncoder(&buf).Encode(p); err != nil {
+		t.Fatalf("Encode err: %v", err)
+	}
+
+	q := new(pidpool.PID)
+	if err := gob.NewDecoder(&buf).Decode(q); err != nil {
+		t.Fatalf("Decode err: %v", err)
+	}
+	if a, b := p.UpdateDuration(18, 1), q.UpdateDuration(18, 1); a != b {
+		t.Fatalf("decoded controller diverged: %v != %v", a, b)
+	}
+}
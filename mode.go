@@ -0,0 +1,126 @@
+package pidpool
+
+import (
+	"fmt"
+	"math"
+	"time"
+)
+
+// Mode is the operating mode of a controller.
+type Mode int
+
+const (
+	// Auto computes the output from the PID terms.
+	Auto Mode = iota
+	// Manual holds the output at the manual output value. The controller
+	// keeps tracking the process value, so switching back to Auto is
+	// bumpless.
+	Manual
+)
+
+// String implements fmt.Stringer.
+func (m Mode) String() string {
+	switch m {
+	case Auto:
+		return "auto"
+	case Manual:
+		return "manual"
+	}
+	return fmt.Sprintf("Mode(%d)", int(m))
+}
+
+// MarshalText implements encoding.TextMarshaler.
+func (m Mode) MarshalText() ([]byte, error) {
+	if m != Auto && m != Manual {
+		return nil, fmt.Errorf("unknown mode %d", int(m))
+	}
+	return []byte(m.String()), nil
+}
+
+// UnmarshalText implements encoding.TextUnmarshaler.
+func (m *Mode) UnmarshalText(text []byte) error {
+	switch string(text) {
+	case "auto":
+		*m = Auto
+	case "manual":
+		*m = Manual
+	default:
+		return fmt.Errorf("unknown mode %q", text)
+	}
+	return nil
+}
+
+// SetMode switches the controller between Auto and Manual. Switching from
+// Manual to Auto back-calculates the integral so the first automatic output
+// continues from the manual output.
+func (pid *PID) SetMode(m Mode) error {
+	if m != Auto && m != Manual {
+		return fmt.Errorf("unknown mode %d", int(m))
+	}
+	defer pid.notifyChange()
+	pid.mu.Lock()
+	defer pid.mu.Unlock()
+	if g := pid.activeGainsLocked(); pid.mode == Manual && m == Auto && g.ki != 0 {
+		integral := (pid.manualOutput - pid.outputBiasLocked() - pid.feedForward - g.kp*pid.prevError) / g.ki
+		pid.integral = math.Max(g.integralMin, math.Min(g.integralMax, integral))
+	}
+	pid.mode = m
+
+	return nil
+}
+
+// GetMode returns the operating mode.
+func (pid *PID) GetMode() Mode {
+	pid.mu.Lock()
+	defer pid.mu.Unlock()
+	return pid.mode
+}
+
+// SetManualOutput sets the output used in Manual mode.
+func (pid *PID) SetManualOutput(v float64) {
+	defer pid.notifyChange()
+	pid.mu.Lock()
+	defer pid.mu.Unlock()
+	pid.manualOutput = v
+}
+
+// GetManualOutput returns the output used in Manual mode.
+func (pid *PID) GetManualOutput() float64 {
+	pid.mu.Lock()
+	defer pid.mu.Unlock()
+	return pid.manualOutput
+}
+
+// InitializeOutput warm-starts the controller on a running process: it
+// seeds the integral so that the first update for currentPV reproduces
+// currentOutput, the actuator position found at startup, instead of
+// slamming the output to a limit. currentPV is the raw measurement, as
+// passed to Update.
+//
+// The previous value is set to currentPV, so the first derivative is zero,
+// the measurement filter restarts from it and dt is measured from now. The
+// integral is clamped to its limits; with a zero integral gain there is
+// nothing to seed and only the measurement history is set.
+func (pid *PID) InitializeOutput(currentOutput, currentPV float64) {
+	defer pid.notifyChange()
+	pid.mu.Lock()
+	defer pid.mu.Unlock()
+	value := pid.unwrapLocked(pid.calibration.Apply(currentPV))
+	err := pid.errorLocked(value)
+	if math.Abs(err) < pid.deadBand {
+		err = 0
+	}
+
+	g := pid.gainsLocked(err)
+	if g.ki != 0 {
+		integral := (currentOutput - g.kp*err - pid.feedForward - pid.outputBiasLocked()) / g.ki
+		pid.integral = math.Max(g.integralMin, math.Min(g.integralMax, integral))
+	}
+	pid.prevValue = value
+	pid.prevError = err
+	pid.derivativeState = 0
+	pid.filterHistory = nil
+	pid.filterQuality = nil
+	pid.lastOutput = currentOutput
+	pid.lastUpdate = time.Now()
+}
@@ -0,0 +1,154 @@
+package pidpool
+
+import (
+	"errors"
+	"math"
+	"time"
+)
+
+// ReferenceModel is the response a model-reference adaptive loop should
+// have to its setpoint.
+type ReferenceModel interface {
+	// Step advances the model by dt seconds towards the setpoint r and
+	// returns its output.
+	Step(r, dt float64) float64
+	// Reset restarts the model at rest at y.
+	Reset(y float64)
+}
+
+// FirstOrderModel is a first-order lag reference model.
+type FirstOrderModel struct {
+	tau float64
+	y   float64
+}
+
+// NewFirstOrderModel returns a first-order model with time constant tau.
+func NewFirstOrderModel(tau time.Duration) (*FirstOrderModel, error) {
+	if tau <= 0 {
+		return nil, errors.New("model time constant must be positive")
+	}
+	return &FirstOrderModel{tau: tau.Seconds()}, nil
+}
+
+// Step implements ReferenceModel. The lag is discretized exactly, so any
+// dt is stable.
+func (m *FirstOrderModel) Step(r, dt float64) float64 {
+	alpha := 1 - math.Exp(-dt/m.tau)
+	m.y += float64(alpha * (r - m.y))
+	return m.y
+}
+
+// Reset implements ReferenceModel.
+func (m *FirstOrderModel) Reset(y float64) {
+	m.y = y
+}
+
+// SecondOrderModel is a second-order reference model with natural
+// frequency Omega, in radians per second, and damping ratio Zeta, for loops
+// that should respond with a defined overshoot.
+type SecondOrderModel struct {
+	omega, zeta float64
+	y, v        float64
+}
+
+// NewSecondOrderModel returns a second-order model.
+func NewSecondOrderModel(omega, zeta float64) (*SecondOrderModel, error) {
+	if !(omega > 0) || math.IsInf(omega, 0) {
+		return nil, errors.New("natural frequency must be positive")
+	}
+	if !(zeta > 0) || math.IsInf(zeta, 0) {
+		return nil, errors.New("damping ratio must be positive")
+	}
+	return &SecondOrderModel{omega: omega, zeta: zeta}, nil
+}
+
+// Step implements ReferenceModel. Large steps are split into substeps of
+// at most a tenth of the natural period, keeping the semi-implicit
+// integration accurate.
+func (m *SecondOrderModel) Step(r, dt float64) float64 {
+	n := int(math.Ceil(dt * m.omega * 10 / (2 * math.Pi)))
+	h := dt / float64(max(n, 1))
+	for i := 0; i < n; i++ {
+		a := m.omega*m.omega*(r-m.y) - 2*m.zeta*m.omega*m.v
+		m.v += a * h
+		m.y += m.v * h
+	}
+	return m.y
+}
+
+// Reset implements ReferenceModel.
+func (m *SecondOrderModel) Reset(y float64) {
+	m.y, m.v = y, 0
+}
+
+// MRAC is a model-reference adaptive controller: a PID whose gains an
+// Adaptive adapter keeps adjusting so the closed loop tracks the reference
+// model's response to the setpoint.
+type MRAC struct {
+	pid      *PID
+	adaptive *Adaptive
+	remove   func()
+}
+
+var _ Controller = (*MRAC)(nil)
+
+// NewMRAC wraps pid, adapting its gains as configured by cfg, until Close.
+func NewMRAC(pid *PID, cfg AdaptiveConfig) (*MRAC, error) {
+	if pid == nil {
+		return nil, errors.New("pid is required")
+	}
+	a, err := NewAdaptive(cfg)
+	if err != nil {
+		return nil, err
+	}
+	return &MRAC{pid: pid, adaptive: a, remove: a.Attach(pid)}, nil
+}
+
+// Update implements Controller.
+func (m *MRAC) Update(value float64) float64 {
+	return m.pid.Update(value)
+}
+
+// UpdateDuration allows custom duration between updates.
+func (m *MRAC) UpdateDuration(value, dt float64) float64 {
+	return m.pid.UpdateDuration(value, dt)
+}
+
+// SetSetPoint implements Controller.
+func (m *MRAC) SetSetPoint(val float64) {
+	m.pid.SetSetPoint(val)
+}
+
+// Reset implements Controller. The adapted gains are kept; the reference
+// model restarts from the next value.
+func (m *MRAC) Reset() {
+	m.pid.Reset()
+	m.adaptive.Reset()
+}
+
+// PID returns the underlying controller.
+func (m *MRAC) PID() *PID {
+	return m.pid
+}
+
+// Adaptive returns the adapter, e.g. to Freeze it.
+func (m *MRAC) Adaptive() *Adaptive {
+	return m.adaptive
+}
+
+// MRACState is a snapshot of an MRAC for monitoring.
+type MRACState struct {
+	Gains Gains
+	AdaptationState
+}
+
+// State returns the current gains and adaptation state.
+func (m *MRAC) State() MRACState {
+	return MRACState{Gains: m.pid.Gains(), AdaptationState: m.adaptive.State()}
+}
+
+// Close stops the adaptation, leaving the controller with its current
+// gains.
+func (m *MRAC) Close() {
+	m.remove()
+}
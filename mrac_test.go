@@ -0,0 +1,88 @@
+package pidpool_test
+
+import (
+	"math"
+	"testing"
+	"time"
+
+	"github.com/ankur-anand/go-pidpool"
+)
+
+func TestSecondOrderModel_Overshoot(t *testing.T) {
+	m, err := pidpool.NewSecondOrderModel(1, 0.5)
+	if err != nil {
+		t.Fatalf("NewSecondOrderModel err: %v", err)
+	}
+	peak, y := 0.0, 0.0
+	for i := 0; i < 200; i++ {
+		y = m.Step(1, 0.1)
+		peak = math.Max(peak, y)
+	}
+	// zeta 0.5 overshoots by exp(-pi*zeta/sqrt(1-zeta^2)), about 16%.
+	if peak < 1.14 || peak > 1.18 {
+		t.Fatalf("expected about 16%% overshoot, peak %v", peak)
+	}
+	if math.Abs(y-1) > 1e-3 {
+		t.Fatalf("expected the model to settle at 1, got %v", y)
+	}
+
+	if _, err := pidpool.NewSecondOrderModel(1, 0); err == nil {
+		t.Fatalf("expected error for zero damping")
+	}
+}
+
+func TestMRAC_TracksReferenceModel(t *testing.T) {
+	pid := pidpool.NewPI(0.5, 0.1)
+	pid.SetOutputLimits(-100, 100)
+	model, err := pidpool.NewSecondOrderModel(0.5, 0.9)
+	if err != nil {
+		t.Fatalf("NewSecondOrderModel err: %v", err)
+	}
+	m, err := pidpool.NewMRAC(pid, pidpool.AdaptiveConfig{
+		Model: model,
+		Rate:  0.5,
+		Min:   pidpool.Gains{Kp: 0.1, Ki: 0.01},
+		Max:   pidpool.Gains{Kp: 5, Ki: 1},
+	})
+	if err != nil {
+		t.Fatalf("NewMRAC err: %v", err)
+	}
+	defer m.Close()
+
+	const dt, tau, gain = 0.1, 4.0, 0.2
+	y, u := 0.0, 0.0
+	for i := 0; i < 20000; i++ {
+		if i%1000 == 0 {
+			m.SetSetPoint(float64((i / 1000) % 2 * 10))
+		}
+		y += dt / tau * (gain*u - y)
+		u = m.UpdateDuration(y, dt)
+	}
+
+	s := m.State()
+	if s.Gains.Kp <= 0.5 || s.Gains.Ki <= 0.1 {
+		t.Fatalf("expected the gains to grow, got %+v", s.Gains)
+	}
+	if s.Adaptations == 0 || s.Frozen {
+		t.Fatalf("unexpected adaptation state: %+v", s)
+	}
+	if math.Abs(s.ModelError-(y-s.ModelOutput)) > 1e-9 {
+		t.Fatalf("model error %v does not match value %v minus model %v", s.ModelError, y, s.ModelOutput)
+	}
+
+	m.Close()
+	before := pid.Gains()
+	m.UpdateDuration(0, dt)
+	if pid.Gains() != before {
+		t.Fatalf("gains adapted after Close")
+	}
+}
+
+func TestNewMRAC_Validates(t *testing.T) {
+	if _, err := pidpool.NewMRAC(pidpool.NewPI(1, 1), pidpool.AdaptiveConfig{Rate: 1}); err == nil {
+		t.Fatalf("expected error without a reference model")
+	}
+	if _, err := pidpool.NewMRAC(nil, pidpool.AdaptiveConfig{ModelTimeConstant: time.Second, Rate: 1}); err == nil {
+		t.Fatalf("expected error without a pid")
+	}
+}
@@ -0,0 +1,253 @@
+package pidpool
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"sync"
+	"time"
+)
+
+// ErrDimension is returned when a slice or matrix passed to a MultiPID or a
+// Group does not match the number of loops.
+var ErrDimension = errors.New("dimension mismatch")
+
+// MultiPID runs N interacting loops, e.g. the zones of a multi-zone oven,
+// as one unit. All loops are updated in a single call under one lock, and
+// an optional static decoupling matrix D turns the loop outputs v into the
+// actuator commands u = D·v, so each actuator can compensate for the
+// cross-coupling between zones.
+//
+// Output limits apply to the decoupled commands u; the integral limits of
+// each loop bound its own accumulator. While a command is clamped, the
+// loops whose integral would push it further past the limit keep their
+// previous integral, the same back-off a single PID applies at its output
+// limits.
+type MultiPID struct {
+	mu sync.Mutex
+
+	loops    []*PID
+	decouple [][]float64
+	outMin   []float64
+	outMax   []float64
+
+	lastUpdate time.Time
+}
+
+// NewMultiPID returns n loops sharing the given gains and dead-band, with no
+// decoupling.
+func NewMultiPID(n int, kp, ki, kd, deadBand float64) (*MultiPID, error) {
+	if n <= 0 {
+		return nil, errors.New("at least one loop is required")
+	}
+	m := &MultiPID{
+		loops:      make([]*PID, n),
+		outMin:     make([]float64, n),
+		outMax:     make([]float64, n),
+		lastUpdate: time.Now(),
+	}
+	for i := range m.loops {
+		m.loops[i] = NewPID(kp, ki, kd, deadBand)
+		m.outMin[i], m.outMax[i] = math.Inf(-1), math.Inf(1)
+	}
+
+	return m, nil
+}
+
+// Len returns the number of loops.
+func (m *MultiPID) Len() int {
+	return len(m.loops)
+}
+
+func (m *MultiPID) loop(i int) (*PID, error) {
+	if i < 0 || i >= len(m.loops) {
+		return nil, fmt.Errorf("loop %d out of range [0, %d)", i, len(m.loops))
+	}
+	return m.loops[i], nil
+}
+
+// SetPID sets the gains of loop i.
+func (m *MultiPID) SetPID(i int, kp, ki, kd float64) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	l, err := m.loop(i)
+	if err != nil {
+		return err
+	}
+	l.kp, l.ki, l.kd = kp, ki, kd
+	l.applyTermLimitsLocked()
+
+	return nil
+}
+
+// SetSetPoint sets the setpoint of loop i.
+func (m *MultiPID) SetSetPoint(i int, val float64) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	l, err := m.loop(i)
+	if err != nil {
+		return err
+	}
+	l.setPoint = val
+
+	return nil
+}
+
+// SetSetPoints sets the setpoints of all loops at once.
+func (m *MultiPID) SetSetPoints(vals []float64) error {
+	if len(vals) != len(m.loops) {
+		return ErrDimension
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	for i, v := range vals {
+		m.loops[i].setPoint = v
+	}
+
+	return nil
+}
+
+// SetPoints returns the setpoints of all loops.
+func (m *MultiPID) SetPoints() []float64 {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	sp := make([]float64, len(m.loops))
+	for i, l := range m.loops {
+		sp[i] = l.setPoint
+	}
+
+	return sp
+}
+
+// SetOutputLimits sets min and max of the decoupled command i.
+func (m *MultiPID) SetOutputLimits(i int, min, max float64) error {
+	if min > max {
+		return errors.New("min output greater than max output")
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if _, err := m.loop(i); err != nil {
+		return err
+	}
+	m.outMin[i], m.outMax[i] = min, max
+
+	return nil
+}
+
+// SetIntegralLimits clamps the running sum of loop i.
+func (m *MultiPID) SetIntegralLimits(i int, min, max float64) error {
+	if min > max {
+		return errors.New("min integral greater than max integral")
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	l, err := m.loop(i)
+	if err != nil {
+		return err
+	}
+	l.termLimits = false
+	l.setIntegralLimitsLocked(min, max)
+
+	return nil
+}
+
+// SetDecoupling sets the N×N decoupling matrix; row i holds the weights of
+// every loop output in command i. A nil matrix disables decoupling.
+func (m *MultiPID) SetDecoupling(d [][]float64) error {
+	var cp [][]float64
+	if d != nil {
+		if len(d) != len(m.loops) {
+			return ErrDimension
+		}
+		cp = make([][]float64, len(d))
+		for i, row := range d {
+			if len(row) != len(m.loops) {
+				return ErrDimension
+			}
+			cp[i] = append([]float64(nil), row...)
+		}
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.decouple = cp
+
+	return nil
+}
+
+// Update runs one step of every loop for the measured values. Uses wall
+// time for dt.
+func (m *MultiPID) Update(values []float64) ([]float64, error) {
+	if len(values) != len(m.loops) {
+		return nil, ErrDimension
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	now := time.Now()
+	dt := now.Sub(m.lastUpdate).Seconds()
+	m.lastUpdate = now
+
+	return m.updateLocked(values, dt), nil
+}
+
+// UpdateDuration allows custom duration between updates.
+func (m *MultiPID) UpdateDuration(values []float64, dt float64) ([]float64, error) {
+	if len(values) != len(m.loops) {
+		return nil, ErrDimension
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.updateLocked(values, dt), nil
+}
+
+// updateLocked steps the loops directly: they are private to the MultiPID,
+// so m.mu guards them and their own locks are not taken.
+func (m *MultiPID) updateLocked(values []float64, dt float64) []float64 {
+	v := make([]float64, len(m.loops))
+	before := make([]float64, len(m.loops))
+	for i, l := range m.loops {
+		before[i] = l.integral
+		v[i] = l.updateInternal(values[i], dt).Output
+	}
+
+	u := v
+	if m.decouple != nil {
+		u = make([]float64, len(v))
+		for i, row := range m.decouple {
+			sum := 0.0
+			for j, w := range row {
+				sum += float64(w * v[j])
+			}
+			u[i] = sum
+		}
+	}
+	for i := range u {
+		var dir float64
+		if u[i] > m.outMax[i] {
+			u[i], dir = m.outMax[i], 1
+		} else if u[i] < m.outMin[i] {
+			u[i], dir = m.outMin[i], -1
+		}
+		if dir != 0 {
+			m.backOffLocked(i, dir, before)
+		}
+	}
+
+	return u
+}
+
+// backOffLocked restores the integral of every loop whose last integration
+// moved command i further in direction dir, past its limit.
+func (m *MultiPID) backOffLocked(i int, dir float64, before []float64) {
+	for j, l := range m.loops {
+		w := 0.0
+		switch {
+		case m.decouple != nil:
+			w = m.decouple[i][j]
+		case i == j:
+			w = 1
+		}
+		if w*l.activeGainsLocked().ki*(l.integral-before[j])*dir > 0 {
+			l.integral = before[j]
+		}
+	}
+}
@@ -0,0 +1,82 @@
+package pidpool_test
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/ankur-anand/go-pidpool"
+)
+
+func TestMultiPID_Decoupling(t *testing.T) {
+	m, err := pidpool.NewMultiPID(2, 1, 0, 0, 0)
+	if err != nil {
+		t.Fatalf("NewMultiPID err: %v", err)
+	}
+	if err := m.SetSetPoints([]float64{10, 20}); err != nil {
+		t.Fatalf("SetSetPoints err: %v", err)
+	}
+
+	out, err := m.UpdateDuration([]float64{0, 0}, 1)
+	if err != nil {
+		t.Fatalf("UpdateDuration err: %v", err)
+	}
+	if out[0] != 10 || out[1] != 20 {
+		t.Fatalf("expected [10 20] without decoupling, got %v", out)
+	}
+
+	if err := m.SetDecoupling([][]float64{{1, -0.5}, {0, 1}}); err != nil {
+		t.Fatalf("SetDecoupling err: %v", err)
+	}
+	if err := m.SetOutputLimits(1, 0, 15); err != nil {
+		t.Fatalf("SetOutputLimits err: %v", err)
+	}
+	out, _ = m.UpdateDuration([]float64{0, 0}, 1)
+	if out[0] != 0 || out[1] != 15 {
+		t.Fatalf("expected [0 15] with decoupling and limits, got %v", out)
+	}
+}
+
+func TestMultiPID_Dimensions(t *testing.T) {
+	m, _ := pidpool.NewMultiPID(2, 1, 0, 0, 0)
+	if _, err := m.UpdateDuration([]float64{1}, 1); !errors.Is(err, pidpool.ErrDimension) {
+		t.Fatalf("expected ErrDimension, got %v", err)
+	}
+	if err := m.SetDecoupling([][]float64{{1, 0}, {1}}); !errors.Is(err, pidpool.ErrDimension) {
+		t.Fatalf("expected ErrDimension for ragged matrix, got %v", err)
+	}
+	if err := m.SetPID(2, 1, 1, 1); err == nil {
+		t.Fatalf("expected out of range error")
+	}
+}
+
+func TestMultiPID_AntiWindup(t *testing.T) {
+	m, _ := pidpool.NewMultiPID(2, 0, 1, 0, 0)
+	if err := m.SetSetPoints([]float64{10, 10}); err != nil {
+		t.Fatalf("SetSetPoints err: %v", err)
+	}
+	if err := m.SetDecoupling([][]float64{{1, 0.5}, {0, 1}}); err != nil {
+		t.Fatalf("SetDecoupling err: %v", err)
+	}
+	if err := m.SetOutputLimits(1, 0, 5); err != nil {
+		t.Fatalf("SetOutputLimits err: %v", err)
+	}
+	for i := 0; i < 50; i++ {
+		if _, err := m.UpdateDuration([]float64{0, 0}, 1); err != nil {
+			t.Fatalf("UpdateDuration err: %v", err)
+		}
+	}
+
+	// command 1 is pinned at 5 by loop 1; a wound-up integral would hold
+	// it there long after the error reverses.
+	var out []float64
+	for i := 0; i < 3; i++ {
+		out, _ = m.UpdateDuration([]float64{20, 20}, 1)
+	}
+	if out[1] >= 5 {
+		t.Fatalf("expected command 1 to leave its limit once the error reversed, got %v", out)
+	}
+	// command 0 is unclamped, so loop 0 keeps integrating.
+	if out[0] <= 5 {
+		t.Fatalf("expected loop 0 to integrate, got %v", out)
+	}
+}
@@ -0,0 +1,129 @@
+package pidpool
+
+import (
+	"errors"
+	"math"
+)
+
+// NoiseStats describes the measurement noise seen by a controller.
+type NoiseStats struct {
+	// Variance is the estimated variance of the measurement noise.
+	Variance float64
+	// SignalVariance is the estimated variance of the underlying signal.
+	SignalVariance float64
+	// SNR is the signal-to-noise ratio in decibels.
+	SNR float64
+	// Samples is the number of measurements seen by the estimator.
+	Samples int
+}
+
+// NoiseEstimator is an online estimator of measurement noise variance.
+//
+// The noise variance is estimated from second differences of the
+// measurement, which cancel out constant levels and linear ramps. For white
+// noise with variance s², the second difference has variance 6s². The total
+// variance of the measurement is tracked alongside it, and the remainder is
+// attributed to the signal.
+type NoiseEstimator struct {
+	alpha float64
+
+	n     int
+	prev1 float64
+	prev2 float64
+
+	mean     float64
+	variance float64
+	noise    float64
+}
+
+// NewNoiseEstimator returns an estimator with the given EWMA smoothing
+// factor in (0, 1]. Smaller values average over a longer window.
+func NewNoiseEstimator(alpha float64) (*NoiseEstimator, error) {
+	if !(alpha > 0 && alpha <= 1) {
+		return nil, errors.New("alpha must be in (0, 1]")
+	}
+
+	return &NoiseEstimator{alpha: alpha}, nil
+}
+
+// Add feeds a new measurement into the estimator.
+func (e *NoiseEstimator) Add(value float64) {
+	e.n++
+	switch e.n {
+	case 1:
+		e.mean = value
+	default:
+		d := value - e.mean
+		e.mean += e.alpha * d
+		e.variance = (1 - e.alpha) * (e.variance + e.alpha*d*d)
+	}
+
+	if e.n >= 3 {
+		d2 := value - 2*e.prev1 + e.prev2
+		sample := d2 * d2 / 6
+		if e.n == 3 {
+			e.noise = sample
+		} else {
+			e.noise += e.alpha * (sample - e.noise)
+		}
+	}
+
+	e.prev2, e.prev1 = e.prev1, value
+}
+
+// Reset clears the estimator.
+func (e *NoiseEstimator) Reset() {
+	*e = NoiseEstimator{alpha: e.alpha}
+}
+
+// Stats returns the current estimate.
+func (e *NoiseEstimator) Stats() NoiseStats {
+	st := NoiseStats{
+		Variance:       e.noise,
+		SignalVariance: math.Max(e.variance-e.noise, 0),
+		Samples:        e.n,
+	}
+
+	switch {
+	case st.Variance == 0 && st.SignalVariance == 0:
+		st.SNR = 0
+	case st.Variance == 0:
+		st.SNR = math.Inf(1)
+	case st.SignalVariance == 0:
+		st.SNR = math.Inf(-1)
+	default:
+		st.SNR = 10 * math.Log10(st.SignalVariance/st.Variance)
+	}
+
+	return st
+}
+
+// EnableNoiseEstimation starts estimating measurement noise on every update
+// using the given EWMA smoothing factor. Passing 0 disables it.
+func (pid *PID) EnableNoiseEstimation(alpha float64) error {
+	pid.mu.Lock()
+	defer pid.mu.Unlock()
+	if alpha == 0 {
+		pid.noise = nil
+		return nil
+	}
+	e, err := NewNoiseEstimator(alpha)
+	if err != nil {
+		return err
+	}
+	pid.noise = e
+
+	return nil
+}
+
+// NoiseStats returns the current noise estimate. The boolean is false when
+// noise estimation is not enabled.
+func (pid *PID) NoiseStats() (NoiseStats, bool) {
+	pid.mu.Lock()
+	defer pid.mu.Unlock()
+	if pid.noise == nil {
+		return NoiseStats{}, false
+	}
+
+	return pid.noise.Stats(), true
+}
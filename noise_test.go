@@ -0,0 +1,52 @@
+package pidpool_test
+
+import (
+	"math"
+	"math/rand/v2"
+	"testing"
+
+	"github.com/ankur-anand/go-pidpool"
+)
+
+func TestNoiseEstimator_WhiteNoiseOnRamp(t *testing.T) {
+	e, err := pidpool.NewNoiseEstimator(0.01)
+	if err != nil {
+		t.Fatalf("NewNoiseEstimator err: %v", err)
+	}
+
+	r := rand.New(rand.NewPCG(1, 2))
+	for i := 0; i < 20000; i++ {
+		e.Add(float64(i)*0.5 + r.NormFloat64()*0.2)
+	}
+
+	st := e.Stats()
+	if math.Abs(st.Variance-0.04) > 0.01 {
+		t.Fatalf("noise variance: expected ~0.04, got %v", st.Variance)
+	}
+}
+
+func TestPID_NoiseStats(t *testing.T) {
+	p := pidpool.NewPID(1, 0, 0, 0)
+	if _, ok := p.NoiseStats(); ok {
+		t.Fatalf("expected noise stats to be disabled by default")
+	}
+	if err := p.EnableNoiseEstimation(2); err == nil {
+		t.Fatalf("expected error for alpha > 1")
+	}
+	if err := p.EnableNoiseEstimation(0.1); err != nil {
+		t.Fatalf("EnableNoiseEstimation err: %v", err)
+	}
+
+	r := rand.New(rand.NewPCG(3, 4))
+	for i := 0; i < 2000; i++ {
+		p.UpdateDuration(10*math.Sin(float64(i)/200)+r.NormFloat64()*0.01, 0.1)
+	}
+
+	st, ok := p.NoiseStats()
+	if !ok || st.Samples != 2000 {
+		t.Fatalf("unexpected stats: %+v", st)
+	}
+	if st.SNR < 20 {
+		t.Fatalf("expected high SNR for a clean signal, got %v dB", st.SNR)
+	}
+}
@@ -0,0 +1,130 @@
+package pidpool
+
+import (
+	"errors"
+	"math"
+	"sync"
+)
+
+// OutputConfig configures an OutputStage.
+type OutputConfig struct {
+	// Scale and Offset map the control signal u onto the actuator as
+	// Scale*u + Offset. A zero Scale means 1.
+	Scale  float64
+	Offset float64
+	// Min and Max bound the actuator value.
+	Min float64
+	Max float64
+	// SlewRate is the largest change per second. Zero means unlimited.
+	SlewRate float64
+	// Step quantizes the value to multiples of Step. Zero means continuous.
+	Step float64
+}
+
+// OutputStage maps a control signal onto a single actuator with its own
+// scaling, limits, slew rate and quantization.
+type OutputStage struct {
+	cfg OutputConfig
+
+	last   float64
+	primed bool
+}
+
+// NewOutputStage returns a stage for cfg.
+func NewOutputStage(cfg OutputConfig) (*OutputStage, error) {
+	if cfg.Min > cfg.Max {
+		return nil, errors.New("min output greater than max output")
+	}
+	if cfg.SlewRate < 0 || cfg.Step < 0 {
+		return nil, errors.New("slew rate and step must not be negative")
+	}
+	if cfg.Scale == 0 {
+		cfg.Scale = 1
+	}
+
+	return &OutputStage{cfg: cfg}, nil
+}
+
+// Apply maps the control signal u, dt seconds after the previous call,
+// onto the actuator.
+func (s *OutputStage) Apply(u, dt float64) float64 {
+	c := s.cfg
+	v := clamp(c.Scale*u+c.Offset, c.Min, c.Max)
+
+	if c.SlewRate > 0 && s.primed && dt > 0 {
+		maxStep := c.SlewRate * dt
+		v = clamp(v, s.last-maxStep, s.last+maxStep)
+	}
+	if c.Step > 0 {
+		v = clamp(math.Round(v/c.Step)*c.Step, c.Min, c.Max)
+	}
+	s.last, s.primed = v, true
+
+	return v
+}
+
+// Last returns the last value produced by the stage.
+func (s *OutputStage) Last() float64 {
+	return s.last
+}
+
+// Reset forgets the previous value, so the slew limit does not apply to the
+// next call.
+func (s *OutputStage) Reset() {
+	s.last, s.primed = 0, false
+}
+
+func clamp(v, min, max float64) float64 {
+	if v > max {
+		return max
+	}
+	if v < min {
+		return min
+	}
+	return v
+}
+
+// MultiOutput drives several independent output stages from one control
+// signal, e.g. damper position and fan speed from the same loop.
+type MultiOutput struct {
+	mu     sync.Mutex
+	stages []*OutputStage
+}
+
+// NewMultiOutput returns a MultiOutput over the given stages.
+func NewMultiOutput(stages ...*OutputStage) *MultiOutput {
+	return &MultiOutput{stages: stages}
+}
+
+// Apply maps u onto every stage and returns one value per stage.
+func (m *MultiOutput) Apply(u, dt float64) []float64 {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	out := make([]float64, len(m.stages))
+	for i, s := range m.stages {
+		out[i] = s.Apply(u, dt)
+	}
+	return out
+}
+
+// Last returns the last value of every stage.
+func (m *MultiOutput) Last() []float64 {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	out := make([]float64, len(m.stages))
+	for i, s := range m.stages {
+		out[i] = s.Last()
+	}
+	return out
+}
+
+// Attach applies every update of pid to the stages and passes the results
+// to fn, if not nil. The returned function detaches it.
+func (m *MultiOutput) Attach(pid *PID, fn func([]float64)) (remove func()) {
+	return pid.OnUpdate(func(ev UpdateEvent) {
+		out := m.Apply(ev.Output, ev.DT)
+		if fn != nil {
+			fn(out)
+		}
+	})
+}
@@ -0,0 +1,39 @@
+package pidpool_test
+
+import (
+	"testing"
+
+	"github.com/ankur-anand/go-pidpool"
+)
+
+func TestMultiOutput_IndependentStages(t *testing.T) {
+	damper, err := pidpool.NewOutputStage(pidpool.OutputConfig{Min: 0, Max: 100})
+	if err != nil {
+		t.Fatalf("NewOutputStage err: %v", err)
+	}
+	fan, err := pidpool.NewOutputStage(pidpool.OutputConfig{Scale: 30, Offset: 600, Min: 600, Max: 3000, SlewRate: 100, Step: 50})
+	if err != nil {
+		t.Fatalf("NewOutputStage err: %v", err)
+	}
+	mo := pidpool.NewMultiOutput(damper, fan)
+
+	p := pidpool.NewPID(1, 0, 0, 0)
+	p.SetSetPoint(50)
+	var got []float64
+	mo.Attach(p, func(out []float64) { got = out })
+
+	p.UpdateDuration(0, 1)
+	if got[0] != 50 || got[1] != 2100 {
+		t.Fatalf("first update: %v", got)
+	}
+
+	// fan is slew limited to 100/s, damper follows immediately.
+	p.UpdateDuration(-30, 1)
+	if got[0] != 80 || got[1] != 2200 {
+		t.Fatalf("second update: %v", got)
+	}
+
+	if _, err := pidpool.NewOutputStage(pidpool.OutputConfig{Min: 1, Max: 0}); err == nil {
+		t.Fatalf("expected error for min>max")
+	}
+}
@@ -0,0 +1,182 @@
+package pidpool
+
+import (
+	"encoding/json"
+	"errors"
+	"math"
+)
+
+// Gains are the controller gains. Kp is in output units per unit of error,
+// Ki in output units per unit of error-second and Kd in output units per
+// unit of error per second.
+type Gains struct {
+	Kp float64 `json:"kp"`
+	Ki float64 `json:"ki"`
+	Kd float64 `json:"kd"`
+}
+
+// Validate reports whether the gains are finite.
+func (g Gains) Validate() error {
+	for _, v := range []float64{g.Kp, g.Ki, g.Kd} {
+		if math.IsNaN(v) || math.IsInf(v, 0) {
+			return errors.New("gains must be finite")
+		}
+	}
+	return nil
+}
+
+// Limits is a [Min, Max] range. Use math.Inf for an unbounded side; in JSON
+// an unbounded side is encoded as null.
+type Limits struct {
+	Min float64
+	Max float64
+}
+
+// Unbounded returns limits that clamp nothing.
+func Unbounded() Limits {
+	return Limits{Min: math.Inf(-1), Max: math.Inf(1)}
+}
+
+// Validate reports whether the range is well formed.
+func (l Limits) Validate() error {
+	if math.IsNaN(l.Min) || math.IsNaN(l.Max) {
+		return errors.New("limits must not be NaN")
+	}
+	if l.Min > l.Max {
+		return errors.New("min greater than max")
+	}
+	return nil
+}
+
+type limitsJSON struct {
+	Min *float64 `json:"min"`
+	Max *float64 `json:"max"`
+}
+
+// MarshalJSON implements json.Marshaler.
+func (l Limits) MarshalJSON() ([]byte, error) {
+	return json.Marshal(limitsJSON{Min: limitToJSON(l.Min), Max: limitToJSON(l.Max)})
+}
+
+// UnmarshalJSON implements json.Unmarshaler.
+func (l *Limits) UnmarshalJSON(data []byte) error {
+	var js limitsJSON
+	if err := json.Unmarshal(data, &js); err != nil {
+		return err
+	}
+	l.Min = limitFromJSON(js.Min, math.Inf(-1))
+	l.Max = limitFromJSON(js.Max, math.Inf(1))
+
+	return nil
+}
+
+// Params configures a controller built by New. Nil limits keep the
+// defaults of NewPID.
+type Params struct {
+	Gains          Gains   `json:"gains"`
+	DeadBand       float64 `json:"deadBand"`
+	OutputLimits   *Limits `json:"outputLimits,omitempty"`
+	IntegralLimits *Limits `json:"integralLimits,omitempty"`
+}
+
+// Validate reports whether the parameters are consistent.
+func (p Params) Validate() error {
+	if err := p.Gains.Validate(); err != nil {
+		return err
+	}
+	if p.DeadBand < 0 || math.IsNaN(p.DeadBand) {
+		return errors.New("dead-band must not be negative")
+	}
+	if p.OutputLimits != nil {
+		if err := p.OutputLimits.Validate(); err != nil {
+			return errors.New("output limits: " + err.Error())
+		}
+	}
+	if p.IntegralLimits != nil {
+		if err := p.IntegralLimits.Validate(); err != nil {
+			return errors.New("integral limits: " + err.Error())
+		}
+	}
+	return nil
+}
+
+// New returns a controller configured from p.
+func New(p Params) (*PID, error) {
+	if err := p.Validate(); err != nil {
+		return nil, err
+	}
+	pid := NewPID(p.Gains.Kp, p.Gains.Ki, p.Gains.Kd, p.DeadBand)
+	if p.OutputLimits != nil {
+		pid.outputMin, pid.outputMax = p.OutputLimits.Min, p.OutputLimits.Max
+	}
+	if p.IntegralLimits != nil {
+		pid.integralMin, pid.integralMax = p.IntegralLimits.Min, p.IntegralLimits.Max
+	}
+
+	return pid, nil
+}
+
+// SetGains sets the PID gains after validating them.
+func (pid *PID) SetGains(g Gains) error {
+	if err := g.Validate(); err != nil {
+		return err
+	}
+	pid.SetPID(g.Kp, g.Ki, g.Kd)
+
+	return nil
+}
+
+// UpdateGains replaces the gains with fn applied to the current ones, as
+// one change, so a client setting a single gain cannot lose a concurrent
+// change of another. The result is validated. fn runs with the controller
+// locked and must not call back into it.
+func (pid *PID) UpdateGains(fn func(Gains) Gains) error {
+	pid.mu.Lock()
+	g := fn(Gains{Kp: pid.kp, Ki: pid.ki, Kd: pid.kd})
+	if err := g.Validate(); err != nil {
+		pid.mu.Unlock()
+		return err
+	}
+	pid.kp, pid.ki, pid.kd = g.Kp, g.Ki, g.Kd
+	pid.applyTermLimitsLocked()
+	pid.mu.Unlock()
+	pid.notifyChange()
+
+	return nil
+}
+
+// Gains returns the PID gains.
+func (pid *PID) Gains() Gains {
+	kp, ki, kd := pid.GetPID()
+	return Gains{Kp: kp, Ki: ki, Kd: kd}
+}
+
+// SetOutputBounds is SetOutputLimits taking a Limits.
+func (pid *PID) SetOutputBounds(l Limits) error {
+	if err := l.Validate(); err != nil {
+		return errors.New("output limits: " + err.Error())
+	}
+	return pid.SetOutputLimits(l.Min, l.Max)
+}
+
+// OutputBounds returns the output limits.
+func (pid *PID) OutputBounds() Limits {
+	pid.mu.Lock()
+	defer pid.mu.Unlock()
+	return Limits{Min: pid.outputMin, Max: pid.outputMax}
+}
+
+// SetIntegralBounds is SetIntegralLimits taking a Limits.
+func (pid *PID) SetIntegralBounds(l Limits) error {
+	if err := l.Validate(); err != nil {
+		return errors.New("integral limits: " + err.Error())
+	}
+	return pid.SetIntegralLimits(l.Min, l.Max)
+}
+
+// IntegralBounds returns the limits of the integral accumulator.
+func (pid *PID) IntegralBounds() Limits {
+	pid.mu.Lock()
+	defer pid.mu.Unlock()
+	return Limits{Min: pid.integralMin, Max: pid.integralMax}
+}
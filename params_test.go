@@ -0,0 +1,68 @@
+package pidpool_test
+
+import (
+	"encoding/json"
+	"math"
+	"testing"
+
+	"github.com/ankur-anand/go-pidpool"
+)
+
+func TestNew_Params(t *testing.T) {
+	out := pidpool.Limits{Min: 0, Max: 100}
+	p, err := pidpool.New(pidpool.Params{
+		Gains:        pidpool.Gains{Kp: 2, Ki: 0.5},
+		OutputLimits: &out,
+	})
+	if err != nil {
+		t.Fatalf("New err: %v", err)
+	}
+	if g := p.Gains(); g != (pidpool.Gains{Kp: 2, Ki: 0.5}) {
+		t.Fatalf("unexpected gains %+v", g)
+	}
+	if b := p.OutputBounds(); b != out {
+		t.Fatalf("unexpected output bounds %+v", b)
+	}
+	if b := p.IntegralBounds(); b.Min != -100 || b.Max != 100 {
+		t.Fatalf("integral bounds must keep defaults, got %+v", b)
+	}
+
+	if _, err := pidpool.New(pidpool.Params{Gains: pidpool.Gains{Kp: math.NaN()}}); err == nil {
+		t.Fatalf("expected error for NaN gain")
+	}
+	if err := p.SetOutputBounds(pidpool.Limits{Min: 1, Max: 0}); err == nil {
+		t.Fatalf("expected error for inverted limits")
+	}
+}
+
+func TestParams_JSON(t *testing.T) {
+	data := []byte(`{"gains":{"kp":1,"ki":2,"kd":3},"outputLimits":{"min":0,"max":null}}`)
+	var p pidpool.Params
+	if err := json.Unmarshal(data, &p); err != nil {
+		t.Fatalf("Unmarshal err: %v", err)
+	}
+	if p.Gains.Kd != 3 || p.OutputLimits.Min != 0 || !math.IsInf(p.OutputLimits.Max, 1) {
+		t.Fatalf("unexpected params %+v %+v", p, p.OutputLimits)
+	}
+
+	enc, err := json.Marshal(pidpool.Unbounded())
+	if err != nil || string(enc) != `{"min":null,"max":null}` {
+		t.Fatalf("unexpected encoding %s (%v)", enc, err)
+	}
+}
+
+func TestUpdateGains(t *testing.T) {
+	pid := pidpool.NewPID(1, 2, 3, 0)
+	if err := pid.UpdateGains(func(g pidpool.Gains) pidpool.Gains { g.Ki = 5; return g }); err != nil {
+		t.Fatalf("UpdateGains err: %v", err)
+	}
+	if g := pid.Gains(); g != (pidpool.Gains{Kp: 1, Ki: 5, Kd: 3}) {
+		t.Fatalf("unexpected gains %+v", g)
+	}
+	if err := pid.UpdateGains(func(g pidpool.Gains) pidpool.Gains { g.Kd = math.NaN(); return g }); err == nil {
+		t.Fatalf("expected an error for a NaN gain")
+	}
+	if g := pid.Gains(); g.Kd != 3 {
+		t.Fatalf("invalid gains applied: %+v", g)
+	}
+}
@@ -0,0 +1,197 @@
+package pidpool
+
+import (
+	"errors"
+	"math"
+	"sync"
+	"time"
+)
+
+// PerformanceReport summarizes the loop performance since the last
+// setpoint change. Errors are SetPoint - Value and time is the sum of the
+// update dt, in seconds.
+type PerformanceReport struct {
+	SetPoint float64 `json:"setPoint"`
+	// Step is the setpoint change the report measures the response to. The
+	// first report measures the response to the initial error.
+	Step float64 `json:"step"`
+	// Elapsed is the time since the setpoint change.
+	Elapsed time.Duration `json:"elapsed"`
+	Samples int           `json:"samples"`
+
+	// IAE, ISE and ITAE are the integrals of |e|, e² and t·|e|.
+	IAE  float64 `json:"iae"`
+	ISE  float64 `json:"ise"`
+	ITAE float64 `json:"itae"`
+
+	// RiseTime is the time the value took to go from 10% to 90% of the
+	// step, valid once Risen.
+	RiseTime time.Duration `json:"riseTime"`
+	Risen    bool          `json:"risen"`
+
+	// Overshoot is the largest excursion of the value past the setpoint in
+	// the direction of Step, zero if it never crossed, and PeakTime when it
+	// occurred.
+	Overshoot float64       `json:"overshoot"`
+	PeakTime  time.Duration `json:"peakTime"`
+	// OvershootRatio is Overshoot relative to |Step|.
+	OvershootRatio float64 `json:"overshootRatio"`
+
+	// Settled reports whether the error is inside the settling band, and
+	// SettlingTime when it entered the band for the last time.
+	Settled      bool          `json:"settled"`
+	SettlingTime time.Duration `json:"settlingTime"`
+}
+
+// PerformanceConfig configures a Performance accumulator.
+type PerformanceConfig struct {
+	// Band is the settling band as a fraction of |Step|. Defaults to 0.02.
+	Band float64
+	// MinBand is the smallest settling band in measurement units, used for
+	// small or zero steps.
+	MinBand float64
+
+	// OnSettled is called with the report so far whenever the response
+	// enters the settling band. An overshooting response may leave the band
+	// again; the last call before OnComplete carries the final settling
+	// time.
+	OnSettled func(PerformanceReport)
+	// OnComplete is called with the final report of a response when a
+	// setpoint change starts the next one.
+	OnComplete func(PerformanceReport)
+}
+
+// Performance accumulates loop performance indices from update events.
+// Attach it to a controller or feed it events with Observe. Updates with
+// Bad quality are ignored.
+type Performance struct {
+	mu sync.Mutex
+
+	cfg     PerformanceConfig
+	started bool
+	elapsed float64
+	rise10  float64
+	rose10  bool
+	rep     PerformanceReport
+}
+
+// NewPerformance returns a Performance accumulator.
+func NewPerformance(cfg PerformanceConfig) (*Performance, error) {
+	if cfg.Band < 0 || cfg.MinBand < 0 {
+		return nil, errors.New("settling band must not be negative")
+	}
+	if cfg.Band == 0 {
+		cfg.Band = 0.02
+	}
+
+	return &Performance{cfg: cfg}, nil
+}
+
+// Observe accumulates ev. A setpoint change restarts the measurement.
+func (p *Performance) Observe(ev UpdateEvent) {
+	if ev.Quality.IsBad() {
+		return
+	}
+	p.mu.Lock()
+	complete, settled := p.observeLocked(ev)
+	p.mu.Unlock()
+
+	if complete != nil && p.cfg.OnComplete != nil {
+		p.cfg.OnComplete(*complete)
+	}
+	if settled != nil && p.cfg.OnSettled != nil {
+		p.cfg.OnSettled(*settled)
+	}
+}
+
+// observeLocked accumulates ev and returns the reports to notify: the
+// completed previous response and the entry into the settling band.
+func (p *Performance) observeLocked(ev UpdateEvent) (complete, settled *PerformanceReport) {
+	e := ev.SetPoint - ev.Value
+	switch {
+	case !p.started:
+		p.restartLocked(ev.SetPoint, e)
+	case ev.SetPoint != p.rep.SetPoint:
+		prev := p.rep
+		complete = &prev
+		p.restartLocked(ev.SetPoint, ev.SetPoint-p.rep.SetPoint)
+	}
+
+	abs := math.Abs(e)
+	p.elapsed += ev.DT
+	// the sample time, zero for the update that saw the setpoint change.
+	now := p.elapsed - ev.DT
+	p.rep.Samples++
+	p.rep.Elapsed = time.Duration(p.elapsed * float64(time.Second))
+	p.rep.IAE += abs * ev.DT
+	p.rep.ISE += e * e * ev.DT
+	p.rep.ITAE += p.elapsed * abs * ev.DT
+
+	if p.rep.Step != 0 {
+		// past the setpoint in the direction of the step, e has the
+		// opposite sign of the step.
+		if over := -math.Copysign(1, p.rep.Step) * e; over > p.rep.Overshoot {
+			p.rep.Overshoot = over
+			p.rep.OvershootRatio = over / math.Abs(p.rep.Step)
+			p.rep.PeakTime = seconds(now)
+		}
+
+		progress := 1 - e/p.rep.Step
+		if !p.rose10 && progress >= 0.1 {
+			p.rose10, p.rise10 = true, now
+		}
+		if p.rose10 && !p.rep.Risen && progress >= 0.9 {
+			p.rep.Risen = true
+			p.rep.RiseTime = seconds(now - p.rise10)
+		}
+	}
+
+	band := math.Max(p.cfg.Band*math.Abs(p.rep.Step), p.cfg.MinBand)
+	inside := abs <= band
+	entered := inside && !p.rep.Settled
+	if entered {
+		p.rep.SettlingTime = seconds(now)
+	}
+	p.rep.Settled = inside
+	if entered {
+		rep := p.rep
+		settled = &rep
+	}
+
+	return complete, settled
+}
+
+func (p *Performance) restartLocked(setPoint, step float64) {
+	p.started = true
+	p.elapsed = 0
+	p.rise10, p.rose10 = 0, false
+	p.rep = PerformanceReport{SetPoint: setPoint, Step: step}
+}
+
+func seconds(s float64) time.Duration {
+	return time.Duration(s * float64(time.Second))
+}
+
+// Report returns the indices accumulated since the last setpoint change.
+func (p *Performance) Report() PerformanceReport {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return p.rep
+}
+
+// Reset discards the accumulated indices; the next event starts a new
+// measurement.
+func (p *Performance) Reset() {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.started = false
+	p.elapsed = 0
+	p.rise10, p.rose10 = 0, false
+	p.rep = PerformanceReport{}
+}
+
+// Attach feeds the updates of pid to p. It returns a function that
+// detaches it.
+func (p *Performance) Attach(pid *PID) (remove func()) {
+	return pid.OnUpdateWith(p.Observe, HookOptions{Name: "performance"})
+}
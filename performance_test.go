@@ -0,0 +1,94 @@
+package pidpool_test
+
+import (
+	"math"
+	"testing"
+	"time"
+
+	"github.com/ankur-anand/go-pidpool"
+)
+
+func TestPerformance_StepResponse(t *testing.T) {
+	perf, err := pidpool.NewPerformance(pidpool.PerformanceConfig{Band: 0.05})
+	if err != nil {
+		t.Fatalf("NewPerformance err: %v", err)
+	}
+	p := pidpool.NewPI(0.8, 2)
+	defer perf.Attach(p)()
+
+	// a first-order plant with unit gain, 0.1 s steps.
+	y := 0.0
+	p.SetSetPoint(0)
+	p.UpdateDuration(y, 0.1)
+	p.SetSetPoint(10)
+	for i := 0; i < 400; i++ {
+		u := p.UpdateDuration(y, 0.1)
+		y += 0.1 * (u - y)
+	}
+
+	r := perf.Report()
+	if r.Step != 10 || r.SetPoint != 10 || r.Samples != 400 {
+		t.Fatalf("unexpected step %+v", r)
+	}
+	if r.Elapsed != 40*time.Second {
+		t.Fatalf("unexpected elapsed %v", r.Elapsed)
+	}
+	if !r.Settled || r.SettlingTime <= 0 || r.SettlingTime >= r.Elapsed {
+		t.Fatalf("unexpected settling %+v", r)
+	}
+	if r.Overshoot <= 0 || math.Abs(r.OvershootRatio-r.Overshoot/10) > 1e-12 {
+		t.Fatalf("expected overshoot, got %+v", r)
+	}
+	if !(r.ISE > r.IAE && r.IAE > 1) || r.ITAE <= 0 {
+		t.Fatalf("unexpected indices %+v", r)
+	}
+
+	perf.Reset()
+	if r := perf.Report(); r.Samples != 0 || r.IAE != 0 {
+		t.Fatalf("Reset did not clear %+v", r)
+	}
+}
+
+func TestPerformance_RiseAndPeakTime(t *testing.T) {
+	var settled, complete []pidpool.PerformanceReport
+	perf, err := pidpool.NewPerformance(pidpool.PerformanceConfig{
+		OnSettled:  func(r pidpool.PerformanceReport) { settled = append(settled, r) },
+		OnComplete: func(r pidpool.PerformanceReport) { complete = append(complete, r) },
+	})
+	if err != nil {
+		t.Fatalf("NewPerformance err: %v", err)
+	}
+	perf.Observe(pidpool.UpdateEvent{SetPoint: 0, Value: 0, DT: 0.001})
+
+	// the unit step response of a second-order system with damping 0.5
+	// and natural frequency 1 rad/s.
+	const zeta, dt = 0.5, 0.001
+	wd := math.Sqrt(1 - zeta*zeta)
+	for i := 0; i <= 20000; i++ {
+		ts := float64(i) * dt
+		y := 1 - math.Exp(-zeta*ts)*(math.Cos(wd*ts)+zeta/wd*math.Sin(wd*ts))
+		perf.Observe(pidpool.UpdateEvent{SetPoint: 1, Value: y, DT: dt})
+	}
+
+	r := perf.Report()
+	// peak at π/wd with an overshoot of exp(-π*zeta/wd).
+	if d := r.PeakTime - time.Duration(math.Pi/wd*float64(time.Second)); d < -2*time.Millisecond || d > 2*time.Millisecond {
+		t.Fatalf("unexpected peak time %v", r.PeakTime)
+	}
+	if math.Abs(r.Overshoot-math.Exp(-math.Pi*zeta/wd)) > 1e-4 {
+		t.Fatalf("unexpected overshoot %v", r.Overshoot)
+	}
+	// the 10-90% rise time of this system is about 1.64 s.
+	if !r.Risen || r.RiseTime < 1630*time.Millisecond || r.RiseTime > 1650*time.Millisecond {
+		t.Fatalf("unexpected rise time %v", r.RiseTime)
+	}
+
+	// the overshoot leaves the band after the first entry.
+	if len(settled) < 3 || settled[1].SettlingTime >= r.SettlingTime || settled[len(settled)-1].SettlingTime != r.SettlingTime {
+		t.Fatalf("unexpected settling notifications: %+v", settled)
+	}
+	perf.Observe(pidpool.UpdateEvent{SetPoint: 2, Value: 1, DT: dt})
+	if len(complete) != 2 || complete[1].RiseTime != r.RiseTime || complete[1].Samples != r.Samples {
+		t.Fatalf("expected the completed report, got %+v", complete)
+	}
+}
@@ -26,10 +26,90 @@ type PID struct {
 	prevError  float64
 	lastUpdate time.Time
 	deadBand   float64
+
+	adaptiveDeadBand *AdaptiveDeadBand
+	deadBandNoise    *NoiseEstimator
+
+	// integral limits expressed as I-term contribution in output units.
+	termLimits bool
+	termMin    float64
+	termMax    float64
+
+	integralDecay time.Duration
+
+	mode         Mode
+	manualOutput float64
+	feedForward  float64
+
+	calibration Calibration
+	noise       *NoiseEstimator
+	health      *HealthMonitor
+
+	filter        MeasurementFilter
+	filterHistory []float64
+
+	qualityPolicy    QualityPolicy
+	hasQualityPolicy bool
+	sampleQuality    Quality
+	filterQuality    []Quality
+
+	hooks   []*updateHook
+	hookID  uint64
+	hookErr func(HookError)
+
+	watchers []*stateWatcher
+	bus      *eventBus
+	// changes queues the state copies of configuration changes in the
+	// order they were made; notifying is set while a caller delivers them.
+	changes   []stateChange
+	notifying bool
+
+	annotations map[string]string
+
+	history *eventRing
+	profile *profiler
+
+	gate       updateGate
+	lastOutput float64
+	lastStatus Status
+	stepErr    error
+
+	invalidPolicy InvalidInputPolicy
+	faulted       bool
+	fault         Fault
+	failSafe      FailSafe
+	lastGood      float64
+	hasLastGood   bool
+
+	feedback *positionState
+	// holdIntegral is set for one update by a Cascade while its inner loop
+	// is saturated and by a LagController while its knob is slew limited.
+	holdIntegral bool
+	// integralMaxDT caps the dt of the integral step for one update of a
+	// duty-cycled Runner, zero for no cap.
+	integralMaxDT float64
+
+	negative       *DirectionalParams
+	negativeActive bool
+
+	quantization   Quantization
+	outputDeadband float64
+
+	discretization  Discretization
+	derivativeState float64
+
+	circular    *Limits
+	inputRange  *Limits
+	outputRange *Limits
+	errorFunc   func(setPoint, value float64) float64
+
+	created  time.Time
+	counters LimitCounters
 }
 
 // NewPID returns a new PID controller with the given gains and dead-band.
 func NewPID(kp, ki, kd, deadBand float64) *PID {
+	now := time.Now()
 	return &PID{
 		kp:          kp,
 		ki:          ki,
@@ -39,7 +119,8 @@ func NewPID(kp, ki, kd, deadBand float64) *PID {
 		outputMax:   math.Inf(1),
 		integralMin: -100,
 		integralMax: 100,
-		lastUpdate:  time.Now(),
+		lastUpdate:  now,
+		created:     now,
 	}
 }
 
@@ -48,6 +129,7 @@ func (pid *PID) SetOutputLimits(min, max float64) error {
 	if min > max {
 		return errors.New("min output greater than max output")
 	}
+	defer pid.notifyChange()
 	pid.mu.Lock()
 	defer pid.mu.Unlock()
 	pid.outputMin, pid.outputMax = min, max
@@ -60,8 +142,16 @@ func (pid *PID) SetIntegralLimits(min, max float64) error {
 	if min > max {
 		return errors.New("min integral greater than max integral")
 	}
+	defer pid.notifyChange()
 	pid.mu.Lock()
 	defer pid.mu.Unlock()
+	pid.termLimits = false
+	pid.setIntegralLimitsLocked(min, max)
+
+	return nil
+}
+
+func (pid *PID) setIntegralLimitsLocked(min, max float64) {
 	pid.integralMin, pid.integralMax = min, max
 
 	// clamp.
@@ -70,12 +160,11 @@ func (pid *PID) SetIntegralLimits(min, max float64) error {
 	} else if pid.integral < pid.integralMin {
 		pid.integral = pid.integralMin
 	}
-
-	return nil
 }
 
 // SetSetPoint sets the PID setPoint.
 func (pid *PID) SetSetPoint(val float64) {
+	defer pid.notifyChange()
 	pid.mu.Lock()
 	defer pid.mu.Unlock()
 	pid.setPoint = val
@@ -90,9 +179,11 @@ func (pid *PID) GetSetPoint() float64 {
 
 // SetPID sets the PID gains.
 func (pid *PID) SetPID(kp, ki, kd float64) {
+	defer pid.notifyChange()
 	pid.mu.Lock()
 	defer pid.mu.Unlock()
 	pid.kp, pid.ki, pid.kd = kp, ki, kd
+	pid.applyTermLimitsLocked()
 }
 
 // GetPID returns the PID gains.
@@ -102,58 +193,230 @@ func (pid *PID) GetPID() (float64, float64, float64) {
 	return pid.kp, pid.ki, pid.kd
 }
 
+// SetErrorFunc replaces the setPoint - value error with fn, e.g. to shape
+// the error on a log scale or to weight it piecewise. fn replaces the
+// circular wrapping too, and is applied before the dead-band. It runs
+// with the controller locked and must not call back into it. Nil restores
+// the default. The error function is not part of State.
+func (pid *PID) SetErrorFunc(fn func(setPoint, value float64) float64) {
+	pid.mu.Lock()
+	defer pid.mu.Unlock()
+	pid.errorFunc = fn
+}
+
+// LastOutput returns the output of the most recent update.
+func (pid *PID) LastOutput() float64 {
+	pid.mu.Lock()
+	defer pid.mu.Unlock()
+	return pid.lastOutput
+}
+
 // Update runs the PID calculation. Uses wall time for dt.
 // You can also call UpdateDuration if you want to supply dt explicitly.
 func (pid *PID) Update(value float64) float64 {
-	pid.mu.Lock()
-	defer pid.mu.Unlock()
+	return pid.updateNow(value).Output
+}
+
+func (pid *PID) updateNow(value float64) UpdateEvent {
+	return pid.step(func() UpdateEvent { return pid.wallClockStep(value) })
+}
 
+func (pid *PID) wallClockStep(value float64) UpdateEvent {
 	now := time.Now()
 	dt := now.Sub(pid.lastUpdate).Seconds()
 	pid.lastUpdate = now
 
-	return pid.updateInternal(value, dt)
+	ev := pid.updateInternal(value, dt)
+	ev.Time = now
+
+	return ev
 }
 
 // UpdateDuration allows custom duration between updates.
 func (pid *PID) UpdateDuration(value float64, dt float64) float64 {
+	return pid.step(func() UpdateEvent {
+		ev := pid.updateInternal(value, dt)
+		ev.Time = time.Now()
+
+		return ev
+	}).Output
+}
+
+// step runs one update through tryStep. An update rejected by the
+// concurrency policy leaves the controller untouched and reports the last
+// output.
+func (pid *PID) step(fn func() UpdateEvent) UpdateEvent {
+	ev, err := pid.tryStep(fn)
+	if errors.Is(err, ErrConcurrentUpdate) {
+		pid.mu.Lock()
+		defer pid.mu.Unlock()
+		return UpdateEvent{Output: pid.lastOutput}
+	}
+
+	return ev
+}
+
+// tryStep admits the update according to the concurrency policy and runs
+// fn with pid.mu held. It then releases the lock and notifies the update
+// hooks, so hooks are free to call back into the controller. An input error
+// recorded by fn is returned along with its event.
+func (pid *PID) tryStep(fn func() UpdateEvent) (UpdateEvent, error) {
+	if err := pid.gate.enter(); err != nil {
+		return UpdateEvent{}, err
+	}
+	defer pid.gate.leave()
+
 	pid.mu.Lock()
-	defer pid.mu.Unlock()
-	return pid.updateInternal(value, dt)
+	prof := pid.profile.begin()
+	ev := fn()
+	err := pid.stepErr
+	pid.stepErr = nil
+	pid.lastOutput = ev.Output
+	st := ev.Status()
+	pid.countLimitsLocked(st, ev.DT)
+	pid.lastStatus = st
+	if pid.history != nil {
+		pid.history.add(ev)
+	}
+	if pid.health != nil {
+		pid.health.Observe(ev)
+	}
+	hooks := pid.hooks
+	pid.mu.Unlock()
+	pid.runHooks(hooks, ev)
+	prof.end()
+
+	return ev, err
 }
 
-func (pid *PID) updateInternal(value float64, dt float64) float64 {
+// updateInternal performs one controller step.
+//
+// The arithmetic is evaluated in a fixed, documented order and every
+// product is explicitly rounded to float64 before it is summed, which stops
+// the compiler from fusing multiply-adds on targets that support FMA. Given
+// the same state and the same (value, dt) sequence, every platform produces
+// bit-identical outputs.
+func (pid *PID) updateInternal(value float64, dt float64) UpdateEvent {
+	value, dt, ok := pid.checkInputLocked(value, dt)
+	if pid.faulted {
+		return pid.faultLocked(value, dt)
+	}
+	if !ok {
+		return pid.holdLocked(value, dt)
+	}
+
+	value = pid.calibration.Apply(value)
+	value = pid.unwrapLocked(value)
+	if pid.noise != nil {
+		pid.noise.Add(value)
+	}
+	value, rate, hasRate := pid.filterLocked(value, dt)
+	// the dead-band applies to the filtered error, so it is sized on the
+	// noise left after the filter.
+	pid.adaptDeadBandLocked(value)
+	quality := pid.filterQualityLocked(pid.sampleQuality)
 
 	// proportional gain.
-	err := pid.setPoint - value
+	err := pid.errorLocked(value)
 	if math.Abs(err) < pid.deadBand {
 		err = 0
 	}
 
+	if pid.mode == Manual {
+		ev := pid.manualInternal(value, err, dt)
+		ev.Value = pid.reportedValueLocked(value)
+		ev.Quality = quality
+		return ev
+	}
+
+	g := pid.gainsLocked(err)
+
 	// integral is total accumulated error over time.
-	pid.integral += err * dt
-	if pid.integral > pid.integralMax {
-		pid.integral = pid.integralMax
-	} else if pid.integral < pid.integralMin {
-		pid.integral = pid.integralMin
+	pid.decayIntegralLocked(dt)
+	before := pid.integral
+	clamped := pid.integralFrozenLocked()
+	if !clamped {
+		pid.integral += pid.integralStepLocked(err, dt)
+	}
+	if pid.integral > g.integralMax {
+		pid.integral = g.integralMax
+		clamped = true
+	} else if pid.integral < g.integralMin {
+		pid.integral = g.integralMin
+		clamped = true
 	}
 
 	derivative := 0.0
-	if dt > 0 {
+	if hasRate {
+		// the estimator tracks the rate of change of the measurement.
+		derivative = -rate
+	} else {
 		// derivative on Measurement
-		derivative = -(value - pid.prevValue) / dt
+		derivative = pid.derivativeLocked(value, dt)
 	}
 	pid.prevValue = value
 
-	output := pid.kp*err + pid.ki*pid.integral + pid.kd*derivative
+	// output = ((P + I) + D), each term rounded on its own.
+	pTerm := float64(g.kp * err)
+	iTerm := float64(g.ki * pid.integral)
+	dTerm := float64(g.kd * derivative)
+	raw := pTerm + iTerm
+	raw += dTerm
+	raw += pid.feedForward
+	raw += pid.outputBiasLocked()
 
-	if output > pid.outputMax {
-		output = pid.outputMax
-	} else if output < pid.outputMin {
-		output = pid.outputMin
+	output := raw
+	if output > g.outputMax {
+		output = g.outputMax
+	} else if output < g.outputMin {
+		output = g.outputMin
+	}
+	limited := output
+	output, beyond := pid.quantizeLocked(output, raw, g.outputMin, g.outputMax)
+	output = pid.holdOutputLocked(output, g.outputMin, g.outputMax)
+	if beyond && (output-raw)*float64(g.ki*err) < 0 && !clamped {
+		// stuck at an extreme position; integrating further only winds up.
+		pid.integral = before
+		clamped = true
 	}
 
 	pid.prevError = err
 
-	return output
+	return UpdateEvent{
+		SetPoint:    pid.setPoint,
+		Value:       pid.reportedValueLocked(value),
+		Error:       err,
+		DT:          dt,
+		P:           pTerm,
+		I:           iTerm,
+		D:           dTerm,
+		FeedForward: pid.feedForward,
+		RawOutput:   raw,
+		Output:      output,
+		Quality:     quality,
+
+		QuantizationError: output - limited,
+
+		IntegralClamped: clamped,
+	}
+}
+
+// manualInternal tracks the process while the output is held in Manual
+// mode, so the derivative and error history stay current.
+func (pid *PID) manualInternal(value, err, dt float64) UpdateEvent {
+	pid.prevValue = value
+	pid.prevError = err
+
+	limited := math.Max(pid.outputMin, math.Min(pid.outputMax, pid.manualOutput))
+	output, _ := pid.quantizeLocked(limited, limited, pid.outputMin, pid.outputMax)
+	return UpdateEvent{
+		SetPoint:  pid.setPoint,
+		Value:     value,
+		Error:     err,
+		DT:        dt,
+		RawOutput: pid.manualOutput,
+		Output:    output,
+
+		QuantizationError: output - limited,
+	}
 }
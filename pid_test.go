@@ -1,6 +1,7 @@
 package pidpool_test
 
 import (
+	"math"
 	"testing"
 
 	"github.com/ankur-anand/go-pidpool"
@@ -44,3 +45,80 @@ func TestNewPID_ProportionalControl_OutputLimit(t *testing.T) {
 		t.Fatalf("expected error for min>max")
 	}
 }
+
+func TestManualMode_BumplessTransfer(t *testing.T) {
+	for _, ff := range []float64{0, 10} {
+		p := pidpool.NewPID(1, 0.5, 0, 0)
+		p.SetSetPoint(10)
+		p.SetFeedForward(ff)
+		p.SetManualOutput(30)
+		if err := p.SetMode(pidpool.Manual); err != nil {
+			t.Fatalf("SetMode err: %v", err)
+		}
+		if out := p.UpdateDuration(6, 1); out != 30 {
+			t.Fatalf("manual output: expected 30, got %v", out)
+		}
+
+		if err := p.SetMode(pidpool.Auto); err != nil {
+			t.Fatalf("SetMode err: %v", err)
+		}
+		// P = 4, the feedforward and the back-calculated I = 26 - ff
+		// continue from the manual output, plus one more second of
+		// integration (0.5 * 4).
+		if out := p.UpdateDuration(6, 1); out != 32 {
+			t.Fatalf("feedforward %v: bump on transfer to auto: got %v", ff, out)
+		}
+	}
+}
+
+func TestInitializeOutput(t *testing.T) {
+	pid := pidpool.NewPID(2, 0.5, 1, 0)
+	pid.SetOutputLimits(0, 100)
+	pid.SetIntegralTermLimits(0, 100)
+	pid.SetSetPoint(60)
+
+	pid.InitializeOutput(35, 58)
+	if out := pid.UpdateDuration(58, 1); math.Abs(out-(35+0.5*2)) > 1e-9 {
+		t.Fatalf("expected the first output to continue from 35, got %v", out)
+	}
+
+	cold := pidpool.NewPID(2, 0.5, 1, 0)
+	cold.SetOutputLimits(0, 100)
+	cold.SetSetPoint(60)
+	if out := cold.UpdateDuration(58, 1); out != 0 {
+		t.Fatalf("expected a cold start to slam to 0, got %v", out)
+	}
+
+	// the error is taken the short way around a circular range.
+	heading := pidpool.NewPI(1, 1)
+	heading.SetInputRangeCircular(-180, 180)
+	heading.SetSetPoint(170)
+	heading.InitializeOutput(25, -170)
+	if out := heading.UpdateDuration(-170, 1); math.Abs(out-(25-20)) > 1e-9 {
+		t.Fatalf("expected the first output to continue from 25, got %v", out)
+	}
+}
+
+func TestSetErrorFunc(t *testing.T) {
+	pid := pidpool.NewP(1)
+	pid.SetSetPoint(7)
+	// weigh errors below the setpoint double.
+	pid.SetErrorFunc(func(sp, v float64) float64 {
+		e := sp - v
+		if e > 0 {
+			return 2 * e
+		}
+		return e
+	})
+	if out := pid.UpdateDuration(6, 1); out != 2 {
+		t.Fatalf("expected the shaped error, got %v", out)
+	}
+	if out := pid.UpdateDuration(8, 1); out != -1 {
+		t.Fatalf("expected the unweighted error, got %v", out)
+	}
+
+	pid.SetErrorFunc(nil)
+	if out := pid.UpdateDuration(6, 1); out != 1 {
+		t.Fatalf("expected the default error, got %v", out)
+	}
+}
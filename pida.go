@@ -0,0 +1,195 @@
+package pidpool
+
+import (
+	"errors"
+	"math"
+	"sync"
+	"time"
+)
+
+// PIDA is a PID controller with an acceleration term: a fourth gain Ka on
+// the second derivative of the measurement. In fast motion-control loops
+// it damps the plant's inertia ahead of the velocity, allowing higher
+// gains. Differentiating twice amplifies noise enormously, so the
+// acceleration passes through its own first-order filter, set with
+// SetAccelerationFilter.
+//
+// Like the D term, the A term acts on the measurement, not the error, so
+// setpoint steps cause no kick.
+type PIDA struct {
+	mu sync.Mutex
+
+	kp, ki, kd, ka float64
+	accelFilter    time.Duration
+
+	setPoint    float64
+	outputMin   float64
+	outputMax   float64
+	integralMin float64
+	integralMax float64
+
+	integral   float64
+	prevValue  float64
+	prevRate   float64
+	accel      float64
+	samples    int
+	lastUpdate time.Time
+	lastOutput float64
+}
+
+var _ Controller = (*PIDA)(nil)
+
+// NewPIDA returns a PIDA controller with the given gains, no acceleration
+// filter and the same default limits as NewPID.
+func NewPIDA(kp, ki, kd, ka float64) *PIDA {
+	return &PIDA{
+		kp:          kp,
+		ki:          ki,
+		kd:          kd,
+		ka:          ka,
+		outputMin:   math.Inf(-1),
+		outputMax:   math.Inf(1),
+		integralMin: -100,
+		integralMax: 100,
+		lastUpdate:  time.Now(),
+	}
+}
+
+// SetPIDA sets the gains.
+func (c *PIDA) SetPIDA(kp, ki, kd, ka float64) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.kp, c.ki, c.kd, c.ka = kp, ki, kd, ka
+}
+
+// GetPIDA returns the gains.
+func (c *PIDA) GetPIDA() (kp, ki, kd, ka float64) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.kp, c.ki, c.kd, c.ka
+}
+
+// SetAccelerationFilter sets the time constant of the low-pass filter on
+// the acceleration estimate. Zero disables the filter.
+func (c *PIDA) SetAccelerationFilter(tau time.Duration) error {
+	if tau < 0 {
+		return errors.New("acceleration filter time constant must not be negative")
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.accelFilter = tau
+
+	return nil
+}
+
+// SetOutputLimits sets min and max output.
+func (c *PIDA) SetOutputLimits(min, max float64) error {
+	if min > max {
+		return errors.New("min output greater than max output")
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.outputMin, c.outputMax = min, max
+
+	return nil
+}
+
+// SetIntegralLimits clamps the running sum (anti-windup).
+func (c *PIDA) SetIntegralLimits(min, max float64) error {
+	if min > max {
+		return errors.New("min integral greater than max integral")
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.integralMin, c.integralMax = min, max
+	c.integral = clamp(c.integral, min, max)
+
+	return nil
+}
+
+// SetSetPoint implements Controller.
+func (c *PIDA) SetSetPoint(val float64) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.setPoint = val
+}
+
+// GetSetPoint returns the current setpoint.
+func (c *PIDA) GetSetPoint() float64 {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.setPoint
+}
+
+// LastOutput returns the output of the most recent update.
+func (c *PIDA) LastOutput() float64 {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.lastOutput
+}
+
+// Update implements Controller. Uses wall time for dt.
+func (c *PIDA) Update(value float64) float64 {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	now := time.Now()
+	dt := now.Sub(c.lastUpdate).Seconds()
+	c.lastUpdate = now
+
+	return c.updateLocked(value, dt)
+}
+
+// UpdateDuration allows custom duration between updates.
+func (c *PIDA) UpdateDuration(value, dt float64) float64 {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.updateLocked(value, dt)
+}
+
+// updateLocked runs one step. The rate is available from the second update
+// and the acceleration from the third.
+func (c *PIDA) updateLocked(value, dt float64) float64 {
+	err := c.setPoint - value
+
+	c.integral += float64(err * dt)
+	c.integral = clamp(c.integral, c.integralMin, c.integralMax)
+
+	rate := 0.0
+	if c.samples > 0 && dt > 0 {
+		rate = (value - c.prevValue) / dt
+	}
+	if c.samples > 1 && dt > 0 {
+		accel := (rate - c.prevRate) / dt
+		alpha := 1.0
+		if c.accelFilter > 0 {
+			alpha = 1 - math.Exp(-dt/c.accelFilter.Seconds())
+		}
+		c.accel += float64(alpha * (accel - c.accel))
+	}
+	c.samples = min(c.samples+1, 2)
+	c.prevValue, c.prevRate = value, rate
+
+	// output = (((P + I) + D) + A), each term rounded on its own.
+	pTerm := float64(c.kp * err)
+	iTerm := float64(c.ki * c.integral)
+	dTerm := float64(c.kd * -rate)
+	aTerm := float64(c.ka * -c.accel)
+	out := pTerm + iTerm
+	out += dTerm
+	out += aTerm
+
+	c.lastOutput = clamp(out, c.outputMin, c.outputMax)
+	return c.lastOutput
+}
+
+// Reset implements Controller. The next Update measures dt from the time of
+// the reset.
+func (c *PIDA) Reset() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.integral = 0
+	c.prevValue, c.prevRate, c.accel = 0, 0, 0
+	c.samples = 0
+	c.lastOutput = 0
+	c.lastUpdate = time.Now()
+}
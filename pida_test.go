@@ -0,0 +1,54 @@
+package pidpool_test
+
+import (
+	"math"
+	"testing"
+	"time"
+
+	"github.com/ankur-anand/go-pidpool"
+)
+
+func TestPIDA_AccelerationTerm(t *testing.T) {
+	c := pidpool.NewPIDA(0, 0, 0, 1)
+	// x = t², an acceleration of 2.
+	c.UpdateDuration(0, 1)
+	if out := c.UpdateDuration(1, 1); out != 0 {
+		t.Fatalf("expected no acceleration from two samples, got %v", out)
+	}
+	if out := c.UpdateDuration(4, 1); out != -2 {
+		t.Fatalf("expected -Ka*2, got %v", out)
+	}
+
+	c.Reset()
+	if err := c.SetAccelerationFilter(time.Second); err != nil {
+		t.Fatalf("SetAccelerationFilter err: %v", err)
+	}
+	c.UpdateDuration(0, 0.1)
+	c.UpdateDuration(0.01, 0.1)
+	out := c.UpdateDuration(0.04, 0.1)
+	if want := -2 * (1 - math.Exp(-0.1)); math.Abs(out-want) > 1e-9 {
+		t.Fatalf("expected the filtered acceleration %v, got %v", want, out)
+	}
+	if err := c.SetAccelerationFilter(-time.Second); err == nil {
+		t.Fatalf("expected error for a negative time constant")
+	}
+}
+
+func TestPIDA_PositionsMass(t *testing.T) {
+	c := pidpool.NewPIDA(4, 0.5, 4, 0.2)
+	c.SetAccelerationFilter(20 * time.Millisecond)
+	c.SetOutputLimits(-20, 20)
+	c.SetSetPoint(1)
+
+	// a unit mass with a little friction.
+	const dt = 0.01
+	x, v := 0.0, 0.0
+	for i := 0; i < 6000; i++ {
+		u := c.UpdateDuration(x, dt)
+		v += (u - 0.1*v) * dt
+		x += v * dt
+	}
+	if math.Abs(x-1) > 1e-2 {
+		t.Fatalf("expected the mass at 1, got %v", x)
+	}
+}
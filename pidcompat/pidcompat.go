@@ -0,0 +1,142 @@
+// Package pidcompat checks that a controller still behaves as it did under
+// an earlier version of the package.
+//
+// A Fingerprint pairs a controller state and a golden input trace with the
+// outputs an earlier version produced for them. Compare replays the trace
+// with the current code and reports the numeric drift, so a deployment can
+// verify an upgrade before rolling it out:
+//
+//	fp, _ := pidcompat.Load("testdata/oven-v1.json")
+//	d, _ := fp.Compare()
+//	if !d.Within(0) {
+//		log.Fatalf("behavior changed: %v", d)
+//	}
+//
+// Replay is bit-exact across platforms, so any non-zero drift is a real
+// behavior change.
+package pidcompat
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"os"
+
+	"github.com/ankur-anand/go-pidpool"
+)
+
+// Fingerprint is the recorded behavior of a controller on a golden trace.
+type Fingerprint struct {
+	// Version labels the package version that produced Outputs.
+	Version string           `json:"version"`
+	State   pidpool.State    `json:"state"`
+	Samples []pidpool.Sample `json:"samples"`
+	Outputs []float64        `json:"outputs"`
+}
+
+// Record replays samples through a controller restored from st with the
+// current code and returns the fingerprint labelled with version.
+func Record(version string, st pidpool.State, samples []pidpool.Sample) (Fingerprint, error) {
+	out, err := pidpool.Replay(st, samples)
+	if err != nil {
+		return Fingerprint{}, err
+	}
+	return Fingerprint{Version: version, State: st, Samples: samples, Outputs: out}, nil
+}
+
+// Drift summarizes the differences between recorded and current outputs.
+type Drift struct {
+	Samples int
+	// Changed is the number of outputs that differ at all.
+	Changed int
+	// First is the index of the first changed output, or -1.
+	First  int
+	MaxAbs float64
+	// MaxRel is the largest difference relative to the recorded output;
+	// differences at a recorded zero count as absolute.
+	MaxRel float64
+}
+
+// Within reports whether no output drifted by more than tol.
+func (d Drift) Within(tol float64) bool {
+	return d.MaxAbs <= tol
+}
+
+func (d Drift) String() string {
+	if d.Changed == 0 {
+		return fmt.Sprintf("no drift over %d samples", d.Samples)
+	}
+	return fmt.Sprintf("%d of %d outputs changed, first at %d, max abs %g, max rel %g",
+		d.Changed, d.Samples, d.First, d.MaxAbs, d.MaxRel)
+}
+
+// Compare replays the fingerprint with the current code.
+func (f Fingerprint) Compare() (Drift, error) {
+	if len(f.Outputs) != len(f.Samples) {
+		return Drift{}, errors.New("fingerprint outputs do not match samples")
+	}
+	out, err := pidpool.Replay(f.State, f.Samples)
+	if err != nil {
+		return Drift{}, err
+	}
+
+	d := Drift{Samples: len(out), First: -1}
+	for i, got := range out {
+		want := f.Outputs[i]
+		if got == want || math.IsNaN(got) && math.IsNaN(want) {
+			continue
+		}
+		d.Changed++
+		if d.First < 0 {
+			d.First = i
+		}
+		abs := math.Abs(got - want)
+		rel := abs
+		if want != 0 {
+			rel = abs / math.Abs(want)
+		}
+		d.MaxAbs = math.Max(d.MaxAbs, abs)
+		d.MaxRel = math.Max(d.MaxRel, rel)
+	}
+
+	return d, nil
+}
+
+// Write encodes the fingerprint as JSON.
+func (f Fingerprint) Write(w io.Writer) error {
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(f)
+}
+
+// Read decodes a fingerprint written by Write.
+func Read(r io.Reader) (Fingerprint, error) {
+	var f Fingerprint
+	err := json.NewDecoder(r).Decode(&f)
+	return f, err
+}
+
+// Load reads a fingerprint from a file.
+func Load(path string) (Fingerprint, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return Fingerprint{}, err
+	}
+	defer file.Close()
+	return Read(file)
+}
+
+// Save writes a fingerprint to a file.
+func (f Fingerprint) Save(path string) error {
+	file, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	if err := f.Write(file); err != nil {
+		file.Close()
+		return err
+	}
+	return file.Close()
+}
@@ -0,0 +1,79 @@
+package pidcompat_test
+
+import (
+	"flag"
+	"math"
+	"testing"
+	"time"
+
+	"github.com/ankur-anand/go-pidpool"
+	"github.com/ankur-anand/go-pidpool/pidcompat"
+)
+
+var update = flag.Bool("update", false, "rewrite the golden fingerprints")
+
+const golden = "testdata/oven.json"
+
+// goldenTrace is a step change followed by a noisy approach to the
+// setpoint, exercising every term, the limits and the dead-band.
+func goldenTrace() (pidpool.State, []pidpool.Sample) {
+	p := pidpool.NewPID(2.5, 0.4, 0.8, 0.05)
+	p.SetSetPoint(180)
+	_ = p.SetOutputLimits(0, 100)
+	_ = p.SetIntegralLimits(-50, 50)
+	st := p.State()
+	t0 := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	st.LastUpdate = t0
+
+	samples := make([]pidpool.Sample, 300)
+	v := 20.0
+	for i := range samples {
+		v += (180 - v) * 0.02
+		noise := 0.3 * math.Sin(float64(i)*1.7)
+		samples[i] = pidpool.Sample{Value: v + noise, Time: t0.Add(time.Duration(i+1) * 250 * time.Millisecond)}
+	}
+
+	return st, samples
+}
+
+func TestGolden(t *testing.T) {
+	if *update {
+		st, samples := goldenTrace()
+		fp, err := pidcompat.Record("v1", st, samples)
+		if err != nil {
+			t.Fatalf("Record err: %v", err)
+		}
+		if err := fp.Save(golden); err != nil {
+			t.Fatalf("Save err: %v", err)
+		}
+	}
+
+	fp, err := pidcompat.Load(golden)
+	if err != nil {
+		t.Fatalf("Load err: %v", err)
+	}
+	d, err := fp.Compare()
+	if err != nil {
+		t.Fatalf("Compare err: %v", err)
+	}
+	if !d.Within(0) {
+		t.Fatalf("behavior drifted from %s: %v", fp.Version, d)
+	}
+}
+
+func TestCompare_ReportsDrift(t *testing.T) {
+	st, samples := goldenTrace()
+	fp, err := pidcompat.Record("v1", st, samples)
+	if err != nil {
+		t.Fatalf("Record err: %v", err)
+	}
+	fp.Outputs[10] += 0.5
+
+	d, err := fp.Compare()
+	if err != nil {
+		t.Fatalf("Compare err: %v", err)
+	}
+	if d.Changed != 1 || d.First != 10 || d.MaxAbs != 0.5 || d.Within(0.1) {
+		t.Fatalf("unexpected drift %+v", d)
+	}
+}
@@ -0,0 +1,372 @@
+// Package pidconfig builds PID controllers from a declarative JSON or YAML
+// configuration file. YAML uses the same field names as JSON.
+//
+// Example:
+//
+//	{
+//	  "controllers": [
+//	    {
+//	      "name": "oven",
+//	      "gains": {"kp": 2, "ki": 0.5, "kd": 0.1},
+//	      "setPoint": 180,
+//	      "deadBand": 0.5,
+//	      "outputLimits": {"min": 0, "max": 100},
+//	      "integralLimits": {"min": -50, "max": 50},
+//	      "sampleTime": "100ms",
+//	      "antiWindup": "clamp",
+//	      "direction": "direct"
+//	    }
+//	  ]
+//	}
+//
+// The same configuration in YAML:
+//
+//	controllers:
+//	  - name: oven
+//	    gains: {kp: 2, ki: 0.5, kd: 0.1}
+//	    setPoint: 180
+//	    sampleTime: 100ms
+package pidconfig
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"gopkg.in/yaml.v3"
+
+	"github.com/ankur-anand/go-pidpool"
+)
+
+// Config describes a set of controllers.
+type Config struct {
+	Controllers []Controller `json:"controllers"`
+}
+
+// Controller describes a single controller.
+type Controller struct {
+	Name     string        `json:"name"`
+	Gains    pidpool.Gains `json:"gains"`
+	SetPoint float64       `json:"setPoint"`
+	DeadBand float64       `json:"deadBand"`
+	// OutputLimits and IntegralLimits keep the defaults of pidpool.NewPID
+	// when absent. A null bound is unbounded.
+	OutputLimits   *pidpool.Limits `json:"outputLimits,omitempty"`
+	IntegralLimits *pidpool.Limits `json:"integralLimits,omitempty"`
+	// SampleTime is the control period of the Runner built by
+	// Controller.Runner.
+	SampleTime Duration `json:"sampleTime"`
+	// AntiWindup is "clamp" (default), which bounds the integral
+	// accumulator by IntegralLimits, "term", which bounds the integral
+	// term's contribution in output units by IntegralLimits, which are
+	// then required, or "none".
+	AntiWindup string `json:"antiWindup,omitempty"`
+	// Direction is "direct" (default) or "reverse". A reverse acting
+	// controller drives its output down when the process value is below
+	// the setpoint; it is built by negating the gains.
+	Direction string `json:"direction,omitempty"`
+	// FeedForward wires other controllers into this controller's
+	// feedforward input when built with BuildManager.
+	FeedForward []FeedForward `json:"feedForward,omitempty"`
+}
+
+// FeedForward links a signal of another controller into a controller.
+type FeedForward struct {
+	From string  `json:"from"`
+	Gain float64 `json:"gain"`
+	// Signal is "output" (default) or "value".
+	Signal string `json:"signal,omitempty"`
+}
+
+// Duration is a time.Duration encoded as a Go duration string, e.g. "250ms".
+type Duration time.Duration
+
+// UnmarshalJSON implements json.Unmarshaler.
+func (d *Duration) UnmarshalJSON(b []byte) error {
+	var s string
+	if err := json.Unmarshal(b, &s); err != nil {
+		return errors.New(`duration must be a string such as "100ms"`)
+	}
+	v, err := time.ParseDuration(s)
+	if err != nil {
+		return err
+	}
+	*d = Duration(v)
+	return nil
+}
+
+// MarshalJSON implements json.Marshaler.
+func (d Duration) MarshalJSON() ([]byte, error) {
+	return json.Marshal(time.Duration(d).String())
+}
+
+// FieldError is a validation error pointing at the offending field, e.g.
+// "controllers[1].gains.kp".
+type FieldError struct {
+	Field string
+	Err   error
+}
+
+func (e *FieldError) Error() string {
+	return e.Field + ": " + e.Err.Error()
+}
+
+func (e *FieldError) Unwrap() error {
+	return e.Err
+}
+
+// Parse decodes and validates a configuration. Unknown fields are errors.
+func Parse(data []byte) (*Config, error) {
+	var c Config
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.DisallowUnknownFields()
+	if err := dec.Decode(&c); err != nil {
+		return nil, fmt.Errorf("decode config: %w", err)
+	}
+	if err := c.Validate(); err != nil {
+		return nil, err
+	}
+	return &c, nil
+}
+
+// ParseYAML decodes and validates a YAML configuration. Unknown fields are
+// errors.
+func ParseYAML(data []byte) (*Config, error) {
+	var v any
+	if err := yaml.Unmarshal(data, &v); err != nil {
+		return nil, fmt.Errorf("decode config: %w", err)
+	}
+	// the YAML document is checked and decoded by the JSON rules, so both
+	// formats accept exactly the same fields and values.
+	b, err := json.Marshal(v)
+	if err != nil {
+		return nil, fmt.Errorf("decode config: %w", err)
+	}
+	return Parse(b)
+}
+
+// Load reads and parses the configuration file at path. Files ending in
+// .yaml or .yml are parsed as YAML, all others as JSON.
+func Load(path string) (*Config, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".yaml", ".yml":
+		return ParseYAML(data)
+	}
+	return Parse(data)
+}
+
+// Validate checks every controller and returns all field errors joined.
+func (c *Config) Validate() error {
+	var errs []error
+	seen := make(map[string]bool)
+	for i, ctl := range c.Controllers {
+		prefix := fmt.Sprintf("controllers[%d]", i)
+		if ctl.Name != "" && seen[ctl.Name] {
+			errs = append(errs, &FieldError{prefix + ".name", fmt.Errorf("duplicate name %q", ctl.Name)})
+		}
+		seen[ctl.Name] = true
+		errs = append(errs, ctl.validate(prefix)...)
+	}
+	for i, ctl := range c.Controllers {
+		for j, ff := range ctl.FeedForward {
+			field := fmt.Sprintf("controllers[%d].feedForward[%d]", i, j)
+			switch {
+			case ff.From == ctl.Name:
+				errs = append(errs, &FieldError{field + ".from", errors.New("a controller cannot feed forward into itself")})
+			case !seen[ff.From]:
+				errs = append(errs, &FieldError{field + ".from", fmt.Errorf("unknown controller %q", ff.From)})
+			}
+		}
+	}
+	return errors.Join(errs...)
+}
+
+func (c Controller) validate(prefix string) []error {
+	var errs []error
+	fail := func(field string, format string, args ...any) {
+		errs = append(errs, &FieldError{prefix + "." + field, fmt.Errorf(format, args...)})
+	}
+	finite := func(field string, v float64) {
+		if math.IsNaN(v) || math.IsInf(v, 0) {
+			fail(field, "must be finite")
+		}
+	}
+
+	if c.Name == "" {
+		fail("name", "is required")
+	}
+	finite("gains.kp", c.Gains.Kp)
+	finite("gains.ki", c.Gains.Ki)
+	finite("gains.kd", c.Gains.Kd)
+	finite("setPoint", c.SetPoint)
+	finite("deadBand", c.DeadBand)
+	if c.DeadBand < 0 {
+		fail("deadBand", "must not be negative")
+	}
+	if c.SampleTime < 0 {
+		fail("sampleTime", "must not be negative")
+	}
+	limits := []struct {
+		field string
+		l     *pidpool.Limits
+	}{{"outputLimits", c.OutputLimits}, {"integralLimits", c.IntegralLimits}}
+	for _, f := range limits {
+		if f.l == nil {
+			continue
+		}
+		if err := f.l.Validate(); err != nil {
+			fail(f.field, "%v", err)
+		}
+	}
+	switch c.AntiWindup {
+	case "", "clamp", "term", "none":
+	default:
+		fail("antiWindup", "unknown mode %q, want clamp, term or none", c.AntiWindup)
+	}
+	if c.AntiWindup == "none" && c.IntegralLimits != nil {
+		fail("integralLimits", "not allowed with antiWindup none")
+	}
+	if c.AntiWindup == "term" && c.IntegralLimits == nil {
+		fail("integralLimits", "is required with antiWindup term")
+	}
+	switch c.Direction {
+	case "", "direct", "reverse":
+	default:
+		fail("direction", "unknown direction %q, want direct or reverse", c.Direction)
+	}
+	for i, ff := range c.FeedForward {
+		finite(fmt.Sprintf("feedForward[%d].gain", i), ff.Gain)
+		if _, err := ff.signal(); err != nil {
+			fail(fmt.Sprintf("feedForward[%d].signal", i), "%v", err)
+		}
+	}
+
+	return errs
+}
+
+// Build constructs the controller described by c.
+func (c Controller) Build() (*pidpool.PID, error) {
+	if errs := c.validate(c.Name); len(errs) > 0 {
+		return nil, errors.Join(errs...)
+	}
+
+	g := c.Gains
+	if c.Direction == "reverse" {
+		g = pidpool.Gains{Kp: -g.Kp, Ki: -g.Ki, Kd: -g.Kd}
+	}
+	pid := pidpool.NewPID(g.Kp, g.Ki, g.Kd, c.DeadBand)
+	pid.SetSetPoint(c.SetPoint)
+	if c.OutputLimits != nil {
+		if err := pid.SetOutputBounds(*c.OutputLimits); err != nil {
+			return nil, err
+		}
+	}
+
+	var err error
+	switch c.AntiWindup {
+	case "", "clamp":
+		if c.IntegralLimits != nil {
+			err = pid.SetIntegralBounds(*c.IntegralLimits)
+		}
+	case "term":
+		err = pid.SetIntegralTermLimits(c.IntegralLimits.Min, c.IntegralLimits.Max)
+	case "none":
+		err = pid.SetIntegralLimits(math.Inf(-1), math.Inf(1))
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return pid, nil
+}
+
+// Runner builds the controller described by c and returns a Runner that
+// drives it every SampleTime, which must be set.
+func (c Controller) Runner(source pidpool.Source, sink pidpool.Sink) (*pidpool.Runner, error) {
+	if c.SampleTime == 0 {
+		return nil, &FieldError{c.Name + ".sampleTime", errors.New("is required to run the controller")}
+	}
+	pid, err := c.Build()
+	if err != nil {
+		return nil, err
+	}
+	return pidpool.NewRunner(pid, time.Duration(c.SampleTime), source, sink), nil
+}
+
+// Build constructs every controller, keyed by name.
+func (c *Config) Build() (map[string]*pidpool.PID, error) {
+	if err := c.Validate(); err != nil {
+		return nil, err
+	}
+	pids := make(map[string]*pidpool.PID, len(c.Controllers))
+	for _, ctl := range c.Controllers {
+		pid, err := ctl.Build()
+		if err != nil {
+			return nil, err
+		}
+		pids[ctl.Name] = pid
+	}
+	return pids, nil
+}
+
+func (f FeedForward) signal() (pidpool.LinkSignal, error) {
+	switch f.Signal {
+	case "", "output":
+		return pidpool.LinkOutput, nil
+	case "value":
+		return pidpool.LinkValue, nil
+	}
+	return 0, fmt.Errorf("unknown signal %q, want output or value", f.Signal)
+}
+
+// Links returns the feedforward links declared by the configuration.
+func (c *Config) Links() []pidpool.Link {
+	var links []pidpool.Link
+	for _, ctl := range c.Controllers {
+		for _, ff := range ctl.FeedForward {
+			sig, _ := ff.signal()
+			links = append(links, pidpool.Link{From: ff.From, To: ctl.Name, Gain: ff.Gain, Signal: sig})
+		}
+	}
+	return links
+}
+
+// BuildManager constructs a Manager holding every configured controller
+// with the declared feedforward links wired. Keys that are not configured
+// are created by fallback, or as zero-gain controllers when fallback is
+// nil.
+func (c *Config) BuildManager(fallback func(key string) *pidpool.PID) (*pidpool.Manager, error) {
+	pids, err := c.Build()
+	if err != nil {
+		return nil, err
+	}
+	if fallback == nil {
+		fallback = func(string) *pidpool.PID { return pidpool.NewPID(0, 0, 0, 0) }
+	}
+
+	m := pidpool.NewManager(func(key string) *pidpool.PID {
+		if pid, ok := pids[key]; ok {
+			return pid
+		}
+		return fallback(key)
+	})
+	for _, ctl := range c.Controllers {
+		m.Get(ctl.Name)
+	}
+	for _, l := range c.Links() {
+		if _, err := m.Link(l); err != nil {
+			return nil, err
+		}
+	}
+	return m, nil
+}
@@ -0,0 +1,186 @@
+package pidconfig_test
+
+import (
+	"context"
+	"errors"
+	"math"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/ankur-anand/go-pidpool"
+	"github.com/ankur-anand/go-pidpool/pidconfig"
+)
+
+const valid = `{
+  "controllers": [
+    {
+      "name": "oven",
+      "gains": {"kp": 2, "ki": 0.5, "kd": 0.1},
+      "setPoint": 180,
+      "outputLimits": {"min": 0, "max": 100},
+      "integralLimits": {"min": -50, "max": 50},
+      "sampleTime": "100ms"
+    },
+    {
+      "name": "chiller",
+      "gains": {"kp": 1},
+      "antiWindup": "none",
+      "direction": "reverse"
+    }
+  ]
+}`
+
+func TestParseAndBuild(t *testing.T) {
+	c, err := pidconfig.Parse([]byte(valid))
+	if err != nil {
+		t.Fatalf("Parse err: %v", err)
+	}
+	if time.Duration(c.Controllers[0].SampleTime) != 100*time.Millisecond {
+		t.Fatalf("unexpected sample time %v", c.Controllers[0].SampleTime)
+	}
+
+	pids, err := c.Build()
+	if err != nil {
+		t.Fatalf("Build err: %v", err)
+	}
+	oven := pids["oven"].State()
+	if oven.Kp != 2 || oven.SetPoint != 180 || oven.OutputMax != 100 || oven.IntegralMax != 50 {
+		t.Fatalf("unexpected oven state: %+v", oven)
+	}
+	chiller := pids["chiller"].State()
+	if chiller.Kp != -1 || !math.IsInf(chiller.IntegralMax, 1) {
+		t.Fatalf("unexpected chiller state: %+v", chiller)
+	}
+}
+
+func TestParse_FieldErrors(t *testing.T) {
+	bad := `{"controllers": [
+	  {"name": "a", "gains": {"kp": 1}},
+	  {"name": "b", "gains": {"kp": 1}, "outputLimits": {"min": 5, "max": 1}, "direction": "sideways"},
+	  {"name": "c", "gains": {"kp": 1, "ki": 1}, "antiWindup": "term"}
+	]}`
+	_, err := pidconfig.Parse([]byte(bad))
+	if err == nil {
+		t.Fatalf("expected validation error")
+	}
+
+	var fe *pidconfig.FieldError
+	if !errors.As(err, &fe) {
+		t.Fatalf("expected a FieldError, got %T", err)
+	}
+	for _, want := range []string{"controllers[1].outputLimits", "controllers[1].direction", "controllers[2].integralLimits"} {
+		if !strings.Contains(err.Error(), want) {
+			t.Fatalf("error %q does not mention %s", err, want)
+		}
+	}
+
+	if _, err := pidconfig.Parse([]byte(`{"controllers": [{"name": "a", "gain": {}}]}`)); err == nil {
+		t.Fatalf("expected error for unknown field")
+	}
+}
+
+func TestBuildManager_FeedForward(t *testing.T) {
+	cfg := `{"controllers": [
+	  {"name": "flow", "gains": {"kp": 1}, "setPoint": 10},
+	  {"name": "temp", "gains": {"kp": 1}, "feedForward": [{"from": "flow", "gain": 0.5, "signal": "value"}]}
+	]}`
+	c, err := pidconfig.Parse([]byte(cfg))
+	if err != nil {
+		t.Fatalf("Parse err: %v", err)
+	}
+	m, err := c.BuildManager(nil)
+	if err != nil {
+		t.Fatalf("BuildManager err: %v", err)
+	}
+
+	flow, _ := m.Lookup("flow")
+	temp, _ := m.Lookup("temp")
+	flow.UpdateDuration(8, 1)
+	if ff := temp.GetFeedForward(); ff != 4 {
+		t.Fatalf("expected feedforward 4, got %v", ff)
+	}
+	if out := temp.UpdateDuration(0, 1); out != 4 {
+		t.Fatalf("feedforward not applied to output: %v", out)
+	}
+
+	bad := `{"controllers": [{"name": "temp", "feedForward": [{"from": "nope"}]}]}`
+	if _, err := pidconfig.Parse([]byte(bad)); err == nil || !strings.Contains(err.Error(), "controllers[0].feedForward[0].from") {
+		t.Fatalf("expected unknown controller error, got %v", err)
+	}
+}
+
+func TestParseYAML(t *testing.T) {
+	doc := `
+controllers:
+  - name: oven
+    gains: {kp: 2, ki: 0.5, kd: 0.1}
+    setPoint: 180
+    outputLimits: {min: 0, max: 100}
+    integralLimits: {min: -50, max: 50}
+    sampleTime: 100ms
+  - name: chiller
+    gains: {kp: 1}
+    antiWindup: none
+    direction: reverse
+`
+	path := filepath.Join(t.TempDir(), "pids.yml")
+	if err := os.WriteFile(path, []byte(doc), 0o600); err != nil {
+		t.Fatalf("WriteFile err: %v", err)
+	}
+	got, err := pidconfig.Load(path)
+	if err != nil {
+		t.Fatalf("Load err: %v", err)
+	}
+	want, _ := pidconfig.Parse([]byte(valid))
+	if !reflect.DeepEqual(got, want) {
+		t.Fatalf("YAML and JSON differ: %+v vs %+v", got, want)
+	}
+
+	_, err = pidconfig.ParseYAML([]byte("controllers:\n  - name: a\n    gain: {kp: 1}\n"))
+	if err == nil || !strings.Contains(err.Error(), "gain") {
+		t.Fatalf("expected unknown field error, got %v", err)
+	}
+}
+
+func TestController_Runner(t *testing.T) {
+	c, err := pidconfig.Parse([]byte(valid))
+	if err != nil {
+		t.Fatalf("Parse err: %v", err)
+	}
+	if _, err := c.Controllers[1].Runner(nil, nil); err == nil || !strings.Contains(err.Error(), "chiller.sampleTime") {
+		t.Fatalf("expected sampleTime error, got %v", err)
+	}
+
+	writes := make(chan float64, 16)
+	source := pidpool.SourceFunc(func(context.Context) (float64, error) { return 170, nil })
+	sink := pidpool.SinkFunc(func(_ context.Context, out float64) error {
+		select {
+		case writes <- out:
+		default:
+		}
+		return nil
+	})
+	r, err := c.Controllers[0].Runner(source, sink)
+	if err != nil {
+		t.Fatalf("Runner err: %v", err)
+	}
+	start := time.Now()
+	if err := r.Start(); err != nil {
+		t.Fatalf("Start err: %v", err)
+	}
+	defer r.Stop()
+	for i := 0; i < 2; i++ {
+		select {
+		case <-writes:
+		case <-time.After(2 * time.Second):
+			t.Fatalf("runner did not tick")
+		}
+	}
+	if d := time.Since(start); d < 150*time.Millisecond {
+		t.Fatalf("expected ticks every 100ms, two took %v", d)
+	}
+}
@@ -0,0 +1,80 @@
+// Package pidexpvar publishes PID controller state through the standard
+// expvar package, for services that expose /debug/vars instead of running
+// Prometheus.
+package pidexpvar
+
+import (
+	"expvar"
+	"sync"
+
+	"github.com/ankur-anand/go-pidpool"
+)
+
+// Vars is the value published for a controller.
+type Vars struct {
+	Kp       float64 `json:"kp"`
+	Ki       float64 `json:"ki"`
+	Kd       float64 `json:"kd"`
+	SetPoint float64 `json:"setPoint"`
+	Output   float64 `json:"output"`
+	Integral float64 `json:"integral"`
+	// Events counts the lifecycle events of the controller since Publish;
+	// Read leaves it zero.
+	Events EventCounts `json:"events"`
+}
+
+// EventCounts counts controller events by kind.
+type EventCounts struct {
+	SetPointChanges uint64 `json:"setPointChanges"`
+	GainsChanges    uint64 `json:"gainsChanges"`
+	ModeChanges     uint64 `json:"modeChanges"`
+	Saturations     uint64 `json:"saturations"`
+	Faults          uint64 `json:"faults"`
+}
+
+// Read returns the current published values of p.
+func Read(p *pidpool.PID) Vars {
+	st := p.State()
+	return Vars{
+		Kp:       st.Kp,
+		Ki:       st.Ki,
+		Kd:       st.Kd,
+		SetPoint: st.SetPoint,
+		Output:   p.LastOutput(),
+		Integral: st.Integral,
+	}
+}
+
+// Publish exposes the gains, setpoint, last output and integral of p under
+// name, along with the counts of the events p emits from now on. Values
+// are read on every request to the expvar endpoint. Like expvar.Publish,
+// it panics if name is already registered.
+func Publish(name string, p *pidpool.PID) {
+	var (
+		mu     sync.Mutex
+		counts EventCounts
+	)
+	p.Subscribe(func(ev pidpool.Event) {
+		mu.Lock()
+		defer mu.Unlock()
+		switch ev.Kind {
+		case pidpool.EventSetPointChanged:
+			counts.SetPointChanges++
+		case pidpool.EventGainsChanged:
+			counts.GainsChanges++
+		case pidpool.EventModeChanged:
+			counts.ModeChanges++
+		case pidpool.EventSaturationEntered:
+			counts.Saturations++
+		case pidpool.EventFaultRaised:
+			counts.Faults++
+		}
+	})
+	expvar.Publish(name, expvar.Func(func() any {
+		v := Read(p)
+		mu.Lock()
+		v.Events = counts
+		mu.Unlock()
+		return v
+	}))
+}
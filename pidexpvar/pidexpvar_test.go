@@ -0,0 +1,34 @@
+package pidexpvar_test
+
+import (
+	"encoding/json"
+	"expvar"
+	"testing"
+
+	"github.com/ankur-anand/go-pidpool"
+	"github.com/ankur-anand/go-pidpool/pidexpvar"
+)
+
+func TestPublish(t *testing.T) {
+	p := pidpool.NewPID(2, 0.5, 0, 0)
+	p.SetSetPoint(2)
+	pidexpvar.Publish("pidexpvar_test", p)
+	p.SetSetPoint(3)
+	p.SetOutputLimits(0, 10)
+	p.UpdateDuration(1, 1)
+	p.Trip("test")
+
+	v := expvar.Get("pidexpvar_test")
+	if v == nil {
+		t.Fatalf("variable not published")
+	}
+	var got pidexpvar.Vars
+	if err := json.Unmarshal([]byte(v.String()), &got); err != nil {
+		t.Fatalf("Unmarshal err: %v", err)
+	}
+	want := pidexpvar.Vars{Kp: 2, Ki: 0.5, SetPoint: 3, Output: 5, Integral: 2,
+		Events: pidexpvar.EventCounts{SetPointChanges: 1, Faults: 1}}
+	if got != want {
+		t.Fatalf("got %+v, want %+v", got, want)
+	}
+}
@@ -0,0 +1,171 @@
+// Package pidgc tunes the Go garbage collector with a PID loop.
+//
+// A Governor samples the heap size and adjusts debug.SetGCPercent so the
+// heap stays at a target fraction of a memory budget: a process far below
+// its budget collects less often and spends less CPU on GC, one close to it
+// collects more aggressively.
+//
+//	g, _ := pidgc.New(pidgc.Config{
+//		PID:    pidpool.NewPI(200, 50),
+//		Budget: 512 << 20,
+//		Target: 0.7,
+//	})
+//	defer g.Restore()
+//	go g.Run(ctx)
+package pidgc
+
+import (
+	"context"
+	"errors"
+	"math"
+	"runtime/debug"
+	"runtime/metrics"
+	"sync"
+	"time"
+
+	"github.com/ankur-anand/go-pidpool"
+)
+
+// heapMetric is the memory occupied by live and not yet swept heap objects.
+const heapMetric = "/memory/classes/heap/objects:bytes"
+
+// Config configures a Governor.
+type Config struct {
+	// PID computes the GC percent from the heap size as a fraction of
+	// Budget. A heap above the target should lower the GC percent, so the
+	// gains are positive. It is governed over [MinPercent, MaxPercent],
+	// see pidpool.PID.Govern.
+	PID *pidpool.PID
+	// Budget is the memory budget in bytes.
+	Budget uint64
+	// Target is the heap fraction of Budget to hold, in (0, 1].
+	Target float64
+
+	// MinPercent and MaxPercent bound the GC percent. They default to 10
+	// and 500.
+	MinPercent int
+	MaxPercent int
+	// Interval is the sampling period of Run. Defaults to one second.
+	Interval time.Duration
+
+	// ReadHeap returns the heap size in bytes. Defaults to the runtime's
+	// heap object bytes.
+	ReadHeap func() uint64
+	// SetGCPercent applies the GC percent and returns the previous one.
+	// Defaults to debug.SetGCPercent.
+	SetGCPercent func(percent int) int
+}
+
+// Governor adjusts the GC percent.
+type Governor struct {
+	cfg Config
+
+	mu      sync.Mutex
+	percent int
+	// original is the GC percent before the first change, valid when
+	// saved is set; it is negative when GC was off.
+	original int
+	saved    bool
+	last     time.Time
+}
+
+// New returns a governor. It does not change the GC percent until the first
+// Step.
+func New(cfg Config) (*Governor, error) {
+	if cfg.PID == nil {
+		return nil, errors.New("pid is required")
+	}
+	if cfg.Budget == 0 {
+		return nil, errors.New("budget is required")
+	}
+	if !(cfg.Target > 0 && cfg.Target <= 1) {
+		return nil, errors.New("target must be in (0, 1]")
+	}
+	if cfg.MinPercent == 0 && cfg.MaxPercent == 0 {
+		cfg.MinPercent, cfg.MaxPercent = 10, 500
+	}
+	if cfg.MinPercent < 1 || cfg.MaxPercent < cfg.MinPercent {
+		return nil, errors.New("percents must satisfy 1 <= min <= max")
+	}
+	if cfg.Interval < 0 {
+		return nil, errors.New("interval must not be negative")
+	}
+	if cfg.Interval == 0 {
+		cfg.Interval = time.Second
+	}
+	if cfg.ReadHeap == nil {
+		cfg.ReadHeap = readHeap
+	}
+	if cfg.SetGCPercent == nil {
+		cfg.SetGCPercent = debug.SetGCPercent
+	}
+	if err := cfg.PID.Govern(cfg.Target, float64(cfg.MinPercent), float64(cfg.MaxPercent)); err != nil {
+		return nil, err
+	}
+
+	return &Governor{cfg: cfg, last: time.Now()}, nil
+}
+
+func readHeap() uint64 {
+	s := []metrics.Sample{{Name: heapMetric}}
+	metrics.Read(s)
+	if s[0].Value.Kind() != metrics.KindUint64 {
+		return 0
+	}
+	return s[0].Value.Uint64()
+}
+
+// Step samples the heap, updates the controller and applies the new GC
+// percent, which it returns.
+func (g *Governor) Step() int {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	now := time.Now()
+	frac := float64(g.cfg.ReadHeap()) / float64(g.cfg.Budget)
+	out := g.cfg.PID.UpdateDuration(frac, now.Sub(g.last).Seconds())
+	g.last = now
+
+	p := int(math.Round(out))
+	if p != g.percent {
+		prev := g.cfg.SetGCPercent(p)
+		if !g.saved {
+			g.original, g.saved = prev, true
+		}
+		g.percent = p
+	}
+
+	return g.percent
+}
+
+// Percent returns the GC percent last applied, zero before the first Step.
+func (g *Governor) Percent() int {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	return g.percent
+}
+
+// Run steps every interval until ctx is done.
+func (g *Governor) Run(ctx context.Context) {
+	t := time.NewTicker(g.cfg.Interval)
+	defer t.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-t.C:
+			g.Step()
+		}
+	}
+}
+
+// Restore puts back the GC percent that was in effect before the first
+// Step. Stop Run first.
+func (g *Governor) Restore() {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	if g.saved {
+		g.cfg.SetGCPercent(g.original)
+		g.saved, g.percent = false, 0
+	}
+}
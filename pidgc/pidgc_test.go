@@ -0,0 +1,80 @@
+package pidgc_test
+
+import (
+	"runtime/debug"
+	"testing"
+	"time"
+
+	"github.com/ankur-anand/go-pidpool"
+	"github.com/ankur-anand/go-pidpool/pidgc"
+)
+
+func TestGovernor_LowersPercentNearBudget(t *testing.T) {
+	heap := uint64(100)
+	percent := 100
+	g, err := pidgc.New(pidgc.Config{
+		PID:          pidpool.NewP(2000),
+		Budget:       1000,
+		Target:       0.5,
+		ReadHeap:     func() uint64 { return heap },
+		SetGCPercent: func(p int) int { prev := percent; percent = p; return prev },
+	})
+	if err != nil {
+		t.Fatalf("New err: %v", err)
+	}
+
+	// far below the budget: collect rarely.
+	if p := g.Step(); p != 500 || percent != 500 {
+		t.Fatalf("expected max percent, got %d", p)
+	}
+	// above the target: collect aggressively.
+	heap = 900
+	if p := g.Step(); p != 10 || percent != 10 {
+		t.Fatalf("expected min percent, got %d", p)
+	}
+
+	g.Restore()
+	if percent != 100 || g.Percent() != 0 {
+		t.Fatalf("original percent not restored: %d", percent)
+	}
+}
+
+func TestGovernor_Runtime(t *testing.T) {
+	orig := debug.SetGCPercent(100)
+	defer debug.SetGCPercent(orig)
+
+	g, err := pidgc.New(pidgc.Config{PID: pidpool.NewP(1), Budget: 1 << 40, Target: 0.5, Interval: time.Millisecond})
+	if err != nil {
+		t.Fatalf("New err: %v", err)
+	}
+	if p := g.Step(); p != 10 {
+		t.Fatalf("unexpected percent %d", p)
+	}
+	if prev := debug.SetGCPercent(100); prev != 10 {
+		t.Fatalf("runtime GC percent not set: %d", prev)
+	}
+	g.Restore()
+}
+
+func TestGovernor_RestoresGCOff(t *testing.T) {
+	heap := uint64(100)
+	percent := -1
+	g, err := pidgc.New(pidgc.Config{
+		PID:          pidpool.NewP(2000),
+		Budget:       1000,
+		Target:       0.5,
+		ReadHeap:     func() uint64 { return heap },
+		SetGCPercent: func(p int) int { prev := percent; percent = p; return prev },
+	})
+	if err != nil {
+		t.Fatalf("New err: %v", err)
+	}
+	g.Step()
+	heap = 900
+	g.Step()
+
+	g.Restore()
+	if percent != -1 {
+		t.Fatalf("expected GC restored to off, got %d", percent)
+	}
+}
@@ -0,0 +1,193 @@
+package pidhttp
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// LeaseHeader carries the lease token on modifying requests.
+const LeaseHeader = "X-Lease-Token"
+
+// Option configures a handler.
+type Option func(*handler)
+
+// WithLeases requires a client to hold a lease on a controller before it
+// may modify it, so two operators cannot fight over the same loop. Leases
+// expire after their TTL, which is capped at maxTTL.
+//
+// Routes added:
+//
+//	POST   /{name}/lease  acquire, renew, or take over a lease
+//	DELETE /{name}/lease  release the lease
+func WithLeases(maxTTL time.Duration) Option {
+	return WithLeaseTable(NewLeases(maxTTL))
+}
+
+// WithLeaseTable is WithLeases with a lease table shared with other
+// front ends, e.g. a Modbus or MQTT bridge that refuses writes to leased
+// controllers.
+func WithLeaseTable(l *Leases) Option {
+	return func(h *handler) {
+		h.leases = l
+	}
+}
+
+// Lease is an exclusive right to modify a controller.
+type Lease struct {
+	Holder  string    `json:"holder"`
+	Token   string    `json:"token,omitempty"`
+	Expires time.Time `json:"expires"`
+}
+
+// LeaseRequest is the body of POST /{name}/lease.
+type LeaseRequest struct {
+	Holder string `json:"holder"`
+	// TTL is a Go duration string; empty selects the maximum.
+	TTL string `json:"ttl,omitempty"`
+	// Takeover replaces a lease held by someone else.
+	Takeover bool `json:"takeover,omitempty"`
+	// Token renews the caller's existing lease.
+	Token string `json:"token,omitempty"`
+}
+
+var (
+	errLeaseHeld     = errors.New("controller is leased by another client")
+	errLeaseRequired = errors.New("a lease is required to modify this controller")
+)
+
+// Leases is a table of leases on controllers, keyed by name.
+type Leases struct {
+	mu     sync.Mutex
+	maxTTL time.Duration
+	held   map[string]Lease
+}
+
+// NewLeases returns an empty lease table whose leases last at most maxTTL.
+func NewLeases(maxTTL time.Duration) *Leases {
+	return &Leases{maxTTL: maxTTL, held: make(map[string]Lease)}
+}
+
+// Leased reports whether the named controller is under an active lease.
+func (l *Leases) Leased(name string) bool {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	cur, ok := l.held[name]
+	return ok && time.Now().Before(cur.Expires)
+}
+
+func (l *Leases) acquire(name string, req LeaseRequest, now time.Time) (Lease, error) {
+	ttl := l.maxTTL
+	if req.TTL != "" {
+		d, err := time.ParseDuration(req.TTL)
+		if err != nil || d <= 0 {
+			return Lease{}, errors.New("ttl must be a positive duration")
+		}
+		ttl = min(d, l.maxTTL)
+	}
+	if req.Holder == "" {
+		return Lease{}, errors.New("holder is required")
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	cur, ok := l.held[name]
+	active := ok && now.Before(cur.Expires)
+	if active && cur.Token != req.Token && !req.Takeover {
+		return Lease{Holder: cur.Holder, Expires: cur.Expires}, errLeaseHeld
+	}
+
+	token := req.Token
+	if !active || cur.Token != req.Token {
+		token = newToken()
+	}
+	lease := Lease{Holder: req.Holder, Token: token, Expires: now.Add(ttl)}
+	l.held[name] = lease
+
+	return lease, nil
+}
+
+func (l *Leases) release(name, token string) error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	cur, ok := l.held[name]
+	if !ok {
+		return nil
+	}
+	if cur.Token != token {
+		return errLeaseHeld
+	}
+	delete(l.held, name)
+	return nil
+}
+
+func (l *Leases) check(name, token string, now time.Time) error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	cur, ok := l.held[name]
+	if !ok || !now.Before(cur.Expires) {
+		return errLeaseRequired
+	}
+	if cur.Token != token {
+		return errLeaseHeld
+	}
+	return nil
+}
+
+func newToken() string {
+	b := make([]byte, 16)
+	_, _ = rand.Read(b)
+	return hex.EncodeToString(b)
+}
+
+func (h *handler) acquireLease(w http.ResponseWriter, r *http.Request) {
+	name := r.PathValue("name")
+	if _, ok := h.reg.Lookup(name); !ok {
+		writeError(w, http.StatusNotFound, errNotFound(name))
+		return
+	}
+	var req LeaseRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeError(w, http.StatusBadRequest, err)
+		return
+	}
+
+	lease, err := h.leases.acquire(name, req, time.Now())
+	switch {
+	case errors.Is(err, errLeaseHeld):
+		writeJSON(w, http.StatusConflict, lease)
+	case err != nil:
+		writeError(w, http.StatusBadRequest, err)
+	default:
+		writeJSON(w, http.StatusOK, lease)
+	}
+}
+
+func (h *handler) releaseLease(w http.ResponseWriter, r *http.Request) {
+	if err := h.leases.release(r.PathValue("name"), r.Header.Get(LeaseHeader)); err != nil {
+		writeError(w, http.StatusConflict, err)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// checkLease reports whether the request may modify the named controller
+// and writes the error response when it may not.
+func (h *handler) checkLease(w http.ResponseWriter, r *http.Request, name string) bool {
+	if h.leases == nil {
+		return true
+	}
+	switch err := h.leases.check(name, r.Header.Get(LeaseHeader), time.Now()); {
+	case errors.Is(err, errLeaseRequired):
+		writeError(w, http.StatusPreconditionRequired, err)
+		return false
+	case err != nil:
+		writeError(w, http.StatusConflict, err)
+		return false
+	}
+	return true
+}
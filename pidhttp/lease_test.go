@@ -0,0 +1,81 @@
+package pidhttp_test
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/ankur-anand/go-pidpool"
+	"github.com/ankur-anand/go-pidpool/pidhttp"
+)
+
+func do(t *testing.T, method, url, token, body string) (*http.Response, pidhttp.Lease) {
+	t.Helper()
+	req, err := http.NewRequest(method, url, strings.NewReader(body))
+	if err != nil {
+		t.Fatalf("NewRequest err: %v", err)
+	}
+	if token != "" {
+		req.Header.Set(pidhttp.LeaseHeader, token)
+	}
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatalf("%s %s err: %v", method, url, err)
+	}
+	defer resp.Body.Close()
+	var l pidhttp.Lease
+	_ = json.NewDecoder(resp.Body).Decode(&l)
+	return resp, l
+}
+
+func TestLeases(t *testing.T) {
+	m := pidpool.NewManager(func(string) *pidpool.PID { return pidpool.NewPID(1, 0, 0, 0) })
+	m.Get("oven")
+	srv := httptest.NewServer(pidhttp.NewHandler(m, pidhttp.WithLeases(time.Minute)))
+	defer srv.Close()
+	url := srv.URL + "/oven"
+
+	if resp, _ := do(t, "POST", url, "", `{"setPoint":1}`); resp.StatusCode != http.StatusPreconditionRequired {
+		t.Fatalf("expected 428 without a lease, got %d", resp.StatusCode)
+	}
+
+	resp, alice := do(t, "POST", url+"/lease", "", `{"holder":"alice"}`)
+	if resp.StatusCode != http.StatusOK || alice.Token == "" {
+		t.Fatalf("alice lease: %d %+v", resp.StatusCode, alice)
+	}
+	resp, held := do(t, "POST", url+"/lease", "", `{"holder":"bob"}`)
+	if resp.StatusCode != http.StatusConflict || held.Holder != "alice" || held.Token != "" {
+		t.Fatalf("bob must be refused: %d %+v", resp.StatusCode, held)
+	}
+
+	if resp, _ := do(t, "POST", url, alice.Token, `{"setPoint":2}`); resp.StatusCode != http.StatusOK {
+		t.Fatalf("alice update: %d", resp.StatusCode)
+	}
+
+	resp, bob := do(t, "POST", url+"/lease", "", `{"holder":"bob","takeover":true}`)
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("takeover: %d", resp.StatusCode)
+	}
+	if resp, _ := do(t, "POST", url, alice.Token, `{"setPoint":3}`); resp.StatusCode != http.StatusConflict {
+		t.Fatalf("alice must lose the lease after takeover, got %d", resp.StatusCode)
+	}
+	if resp, _ := do(t, "DELETE", url+"/lease", bob.Token, ""); resp.StatusCode != http.StatusNoContent {
+		t.Fatalf("release: %d", resp.StatusCode)
+	}
+
+	resp, short := do(t, "POST", url+"/lease", "", `{"holder":"carol","ttl":"20ms"}`)
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("short lease: %d", resp.StatusCode)
+	}
+	time.Sleep(40 * time.Millisecond)
+	if resp, _ := do(t, "POST", url, short.Token, `{"setPoint":4}`); resp.StatusCode != http.StatusPreconditionRequired {
+		t.Fatalf("expired lease must not allow updates, got %d", resp.StatusCode)
+	}
+
+	if p, _ := m.Lookup("oven"); p.GetSetPoint() != 2 {
+		t.Fatalf("unexpected setpoint %v", p.GetSetPoint())
+	}
+}
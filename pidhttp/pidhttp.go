@@ -0,0 +1,232 @@
+// Package pidhttp provides an HTTP admin handler for live tuning of named
+// PID controllers.
+//
+// Routes, relative to where the handler is mounted:
+//
+//	GET  /        list controller names
+//	GET  /{name}  read gains, setpoint, limits, mode and live state
+//	POST /{name}  update any of gains, setpoint, limits, mode, manual output
+//	              and annotations
+//
+// Request and response bodies are JSON. Unbounded limits are encoded as
+// null.
+package pidhttp
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"net/http"
+
+	"github.com/ankur-anand/go-pidpool"
+)
+
+// Registry resolves controllers by name. *pidpool.Registry and
+// *pidpool.Manager implement it.
+type Registry interface {
+	Lookup(name string) (*pidpool.PID, bool)
+	Keys() []string
+}
+
+var (
+	_ Registry = (*pidpool.Registry)(nil)
+	_ Registry = (*pidpool.Manager)(nil)
+)
+
+// Controller is the response body for a single controller.
+type Controller struct {
+	Name           string         `json:"name"`
+	Gains          pidpool.Gains  `json:"gains"`
+	SetPoint       float64        `json:"setPoint"`
+	OutputLimits   pidpool.Limits `json:"outputLimits"`
+	IntegralLimits pidpool.Limits `json:"integralLimits"`
+	Mode           pidpool.Mode   `json:"mode"`
+	ManualOutput   float64        `json:"manualOutput"`
+	Integral       float64        `json:"integral"`
+	// Value is the last measurement, after the measurement filter.
+	Value  float64 `json:"value"`
+	Output float64 `json:"output"`
+
+	Annotations map[string]string `json:"annotations,omitempty"`
+}
+
+// Update is the request body of POST /{name}. Absent fields are left
+// unchanged.
+type Update struct {
+	Gains          *pidpool.Gains  `json:"gains,omitempty"`
+	SetPoint       *float64        `json:"setPoint,omitempty"`
+	OutputLimits   *pidpool.Limits `json:"outputLimits,omitempty"`
+	IntegralLimits *pidpool.Limits `json:"integralLimits,omitempty"`
+	Mode           *pidpool.Mode   `json:"mode,omitempty"`
+	ManualOutput   *float64        `json:"manualOutput,omitempty"`
+
+	// Annotations are merged into the existing ones; a null value removes
+	// the annotation.
+	Annotations map[string]*string `json:"annotations,omitempty"`
+}
+
+type handler struct {
+	reg    Registry
+	mux    *http.ServeMux
+	leases *Leases
+}
+
+// NewHandler returns an http.Handler serving the controllers of reg.
+func NewHandler(reg Registry, opts ...Option) http.Handler {
+	h := &handler{reg: reg, mux: http.NewServeMux()}
+	for _, opt := range opts {
+		opt(h)
+	}
+	h.mux.HandleFunc("GET /{$}", h.list)
+	h.mux.HandleFunc("GET /{name}", h.get)
+	h.mux.HandleFunc("POST /{name}", h.update)
+	if h.leases != nil {
+		h.mux.HandleFunc("POST /{name}/lease", h.acquireLease)
+		h.mux.HandleFunc("DELETE /{name}/lease", h.releaseLease)
+	}
+
+	return h
+}
+
+func (h *handler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	h.mux.ServeHTTP(w, r)
+}
+
+func (h *handler) list(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, http.StatusOK, h.reg.Keys())
+}
+
+func (h *handler) get(w http.ResponseWriter, r *http.Request) {
+	name := r.PathValue("name")
+	pid, ok := h.reg.Lookup(name)
+	if !ok {
+		writeError(w, http.StatusNotFound, errNotFound(name))
+		return
+	}
+	writeJSON(w, http.StatusOK, view(name, pid))
+}
+
+func (h *handler) update(w http.ResponseWriter, r *http.Request) {
+	name := r.PathValue("name")
+	pid, ok := h.reg.Lookup(name)
+	if !ok {
+		writeError(w, http.StatusNotFound, errNotFound(name))
+		return
+	}
+
+	if !h.checkLease(w, r, name) {
+		return
+	}
+
+	var u Update
+	dec := json.NewDecoder(r.Body)
+	dec.DisallowUnknownFields()
+	if err := dec.Decode(&u); err != nil {
+		writeError(w, http.StatusBadRequest, err)
+		return
+	}
+	if err := Apply(pid, u); err != nil {
+		writeError(w, http.StatusBadRequest, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, view(name, pid))
+}
+
+// Apply validates u and applies it to pid. Nothing is applied when any
+// field is invalid.
+func Apply(pid *pidpool.PID, u Update) error {
+	if u.Gains != nil {
+		if err := u.Gains.Validate(); err != nil {
+			return errors.New("gains: " + err.Error())
+		}
+	}
+	if u.SetPoint != nil && (math.IsNaN(*u.SetPoint) || math.IsInf(*u.SetPoint, 0)) {
+		return errors.New("setPoint: must be finite")
+	}
+	if u.OutputLimits != nil {
+		if err := u.OutputLimits.Validate(); err != nil {
+			return errors.New("outputLimits: " + err.Error())
+		}
+	}
+	if u.IntegralLimits != nil {
+		if err := u.IntegralLimits.Validate(); err != nil {
+			return errors.New("integralLimits: " + err.Error())
+		}
+	}
+
+	for k := range u.Annotations {
+		if k == "" {
+			return errors.New("annotations: key must not be empty")
+		}
+	}
+
+	if u.Gains != nil {
+		if err := pid.SetGains(*u.Gains); err != nil {
+			return err
+		}
+	}
+	if u.SetPoint != nil {
+		pid.SetSetPoint(*u.SetPoint)
+	}
+	if u.OutputLimits != nil {
+		if err := pid.SetOutputBounds(*u.OutputLimits); err != nil {
+			return err
+		}
+	}
+	if u.IntegralLimits != nil {
+		if err := pid.SetIntegralBounds(*u.IntegralLimits); err != nil {
+			return err
+		}
+	}
+	if u.ManualOutput != nil {
+		pid.SetManualOutput(*u.ManualOutput)
+	}
+	if u.Mode != nil {
+		if err := pid.SetMode(*u.Mode); err != nil {
+			return err
+		}
+	}
+	for k, v := range u.Annotations {
+		if v == nil {
+			pid.DeleteAnnotation(k)
+			continue
+		}
+		if err := pid.SetAnnotation(k, *v); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func view(name string, pid *pidpool.PID) Controller {
+	st := pid.State()
+	return Controller{
+		Name:           name,
+		Gains:          pidpool.Gains{Kp: st.Kp, Ki: st.Ki, Kd: st.Kd},
+		SetPoint:       st.SetPoint,
+		OutputLimits:   pidpool.Limits{Min: st.OutputMin, Max: st.OutputMax},
+		IntegralLimits: pidpool.Limits{Min: st.IntegralMin, Max: st.IntegralMax},
+		Mode:           st.Mode,
+		ManualOutput:   st.ManualOutput,
+		Integral:       st.Integral,
+		Value:          st.PrevValue,
+		Output:         pid.LastOutput(),
+		Annotations:    st.Annotations,
+	}
+}
+
+func errNotFound(name string) error {
+	return fmt.Errorf("controller %q not found", name)
+}
+
+func writeJSON(w http.ResponseWriter, status int, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	_ = json.NewEncoder(w).Encode(v)
+}
+
+func writeError(w http.ResponseWriter, status int, err error) {
+	writeJSON(w, status, map[string]string{"error": err.Error()})
+}
@@ -0,0 +1,101 @@
+package pidhttp_test
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/ankur-anand/go-pidpool"
+	"github.com/ankur-anand/go-pidpool/pidhttp"
+)
+
+func newServer(t *testing.T) (*httptest.Server, *pidpool.Manager) {
+	t.Helper()
+	m := pidpool.NewManager(func(string) *pidpool.PID { return pidpool.NewPID(1, 0, 0, 0) })
+	m.Get("oven")
+	srv := httptest.NewServer(pidhttp.NewHandler(m))
+	t.Cleanup(srv.Close)
+	return srv, m
+}
+
+func TestHandler_GetAndUpdate(t *testing.T) {
+	srv, m := newServer(t)
+
+	body := `{"gains":{"kp":2,"ki":0.5,"kd":0},"setPoint":180,"outputLimits":{"min":0,"max":100},"mode":"manual","manualOutput":40,"annotations":{"asset":"oven-3","note":"new element"}}`
+	resp, err := http.Post(srv.URL+"/oven", "application/json", strings.NewReader(body))
+	if err != nil {
+		t.Fatalf("POST err: %v", err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("POST status %d", resp.StatusCode)
+	}
+
+	resp, err = http.Get(srv.URL + "/oven")
+	if err != nil {
+		t.Fatalf("GET err: %v", err)
+	}
+	defer resp.Body.Close()
+	var c pidhttp.Controller
+	if err := json.NewDecoder(resp.Body).Decode(&c); err != nil {
+		t.Fatalf("decode err: %v", err)
+	}
+	if c.Gains.Kp != 2 || c.SetPoint != 180 || c.Mode != pidpool.Manual || c.OutputLimits.Max != 100 {
+		t.Fatalf("unexpected controller: %+v", c)
+	}
+	if c.Annotations["asset"] != "oven-3" || c.Annotations["note"] != "new element" {
+		t.Fatalf("unexpected annotations: %v", c.Annotations)
+	}
+	if c.IntegralLimits.Min != -100 {
+		t.Fatalf("unexpected integral limits: %+v", c.IntegralLimits)
+	}
+
+	p, _ := m.Lookup("oven")
+	if err := pidhttp.Apply(p, pidhttp.Update{Annotations: map[string]*string{"note": nil}}); err != nil {
+		t.Fatalf("Apply err: %v", err)
+	}
+	if _, ok := p.Annotation("note"); ok {
+		t.Fatalf("null annotation not removed")
+	}
+	if out := p.UpdateDuration(20, 1); out != 40 {
+		t.Fatalf("manual output not applied: %v", out)
+	}
+}
+
+func TestHandler_Errors(t *testing.T) {
+	srv, m := newServer(t)
+
+	resp, err := http.Get(srv.URL + "/missing")
+	if err != nil {
+		t.Fatalf("GET err: %v", err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusNotFound {
+		t.Fatalf("expected 404, got %d", resp.StatusCode)
+	}
+
+	body := `{"setPoint":5,"outputLimits":{"min":10,"max":0}}`
+	resp, err = http.Post(srv.URL+"/oven", "application/json", strings.NewReader(body))
+	if err != nil {
+		t.Fatalf("POST err: %v", err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusBadRequest {
+		t.Fatalf("expected 400, got %d", resp.StatusCode)
+	}
+	if p, _ := m.Lookup("oven"); p.GetSetPoint() != 0 {
+		t.Fatalf("partial update applied")
+	}
+
+	resp, err = http.Get(srv.URL + "/")
+	if err != nil {
+		t.Fatalf("GET err: %v", err)
+	}
+	defer resp.Body.Close()
+	var names []string
+	if err := json.NewDecoder(resp.Body).Decode(&names); err != nil || len(names) != 1 || names[0] != "oven" {
+		t.Fatalf("unexpected list %v (%v)", names, err)
+	}
+}
@@ -0,0 +1,201 @@
+// Package pidk8s feeds a PID-computed replica recommendation to the
+// Kubernetes HorizontalPodAutoscaler through the external metrics API.
+//
+// A Recommender reads an arbitrary metric and computes the desired replica
+// count with a PID loop. Handler serves it as an external metric, which an
+// HPA consumes with an AverageValue target of 1, so the HPA scales to the
+// recommended count instead of applying its own proportional rule:
+//
+//	metrics:
+//	- type: External
+//	  external:
+//	    metric:
+//	      name: checkout-replicas
+//	    target:
+//	      type: AverageValue
+//	      averageValue: "1"
+//
+// The HPA still applies its tolerance, 10% by default
+// (--horizontal-pod-autoscaler-tolerance): it ignores a recommendation
+// within 10% of the current count. Below 10 replicas every change of one
+// replica exceeds it; above that, small corrections are held back until
+// they add up, which acts as a dead-band on the loop's output. Lower the
+// tolerance, or set a per-HPA tolerance in its behavior where the
+// HPAConfigurableTolerance feature is enabled, when the loop must move in
+// single replicas at scale.
+//
+// The handler must be served behind an APIService registration for
+// external.metrics.k8s.io, with the TLS setup that implies.
+package pidk8s
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"maps"
+	"math"
+	"net/http"
+	"slices"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/ankur-anand/go-pidpool"
+)
+
+// GroupVersion is the API group and version served by Handler.
+const GroupVersion = "external.metrics.k8s.io/v1beta1"
+
+// Config configures a Recommender.
+type Config struct {
+	// PID computes the replica count from the metric. When more replicas
+	// lower the metric, e.g. per-pod latency or queue backlog, the gains
+	// are negative. The recommender governs it over [MinReplicas,
+	// MaxReplicas], see pidpool.PID.Govern.
+	PID    *pidpool.PID
+	Metric pidpool.Source
+	Target float64
+
+	MinReplicas int
+	MaxReplicas int
+}
+
+// Recommender computes a desired replica count.
+type Recommender struct {
+	cfg Config
+
+	mu       sync.Mutex
+	replicas int
+	updated  time.Time
+}
+
+// NewRecommender returns a recommender starting at MinReplicas.
+func NewRecommender(cfg Config) (*Recommender, error) {
+	if cfg.PID == nil || cfg.Metric == nil {
+		return nil, errors.New("pid and metric are required")
+	}
+	if cfg.MinReplicas < 1 || cfg.MaxReplicas < cfg.MinReplicas {
+		return nil, errors.New("replicas must satisfy 1 <= min <= max")
+	}
+	if err := cfg.PID.Govern(cfg.Target, float64(cfg.MinReplicas), float64(cfg.MaxReplicas)); err != nil {
+		return nil, err
+	}
+
+	return &Recommender{cfg: cfg, replicas: cfg.MinReplicas, updated: time.Now()}, nil
+}
+
+// Step reads the metric and updates the recommendation, rounding up.
+func (r *Recommender) Step(ctx context.Context) (int, error) {
+	v, err := r.cfg.Metric.Read(ctx)
+	if err != nil {
+		return r.Replicas(), err
+	}
+	out := r.cfg.PID.Update(v)
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.replicas = int(math.Ceil(out))
+	r.updated = time.Now()
+
+	return r.replicas, nil
+}
+
+// Run steps every interval until ctx is done. A failed read keeps the last
+// recommendation.
+func (r *Recommender) Run(ctx context.Context, interval time.Duration) {
+	t := time.NewTicker(interval)
+	defer t.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-t.C:
+			_, _ = r.Step(ctx)
+		}
+	}
+}
+
+// Replicas returns the current recommendation.
+func (r *Recommender) Replicas() int {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.replicas
+}
+
+// ExternalMetricValue is an item of an ExternalMetricValueList.
+type ExternalMetricValue struct {
+	MetricName   string            `json:"metricName"`
+	MetricLabels map[string]string `json:"metricLabels"`
+	Timestamp    time.Time         `json:"timestamp"`
+	// Value is a Kubernetes quantity.
+	Value string `json:"value"`
+}
+
+// ExternalMetricValueList is the external metrics API response body.
+type ExternalMetricValueList struct {
+	Kind       string                `json:"kind"`
+	APIVersion string                `json:"apiVersion"`
+	Metadata   struct{}              `json:"metadata"`
+	Items      []ExternalMetricValue `json:"items"`
+}
+
+type apiResource struct {
+	Name       string   `json:"name"`
+	Namespaced bool     `json:"namespaced"`
+	Kind       string   `json:"kind"`
+	Verbs      []string `json:"verbs"`
+}
+
+type apiResourceList struct {
+	Kind         string        `json:"kind"`
+	APIVersion   string        `json:"apiVersion"`
+	GroupVersion string        `json:"groupVersion"`
+	Resources    []apiResource `json:"resources"`
+}
+
+// Handler serves the recommendations as external metrics, keyed by metric
+// name, in every namespace:
+//
+//	GET /apis/external.metrics.k8s.io/v1beta1
+//	GET /apis/external.metrics.k8s.io/v1beta1/namespaces/{namespace}/{metric}
+func Handler(metrics map[string]*Recommender) http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /apis/"+GroupVersion, func(w http.ResponseWriter, r *http.Request) {
+		list := apiResourceList{Kind: "APIResourceList", APIVersion: "v1", GroupVersion: GroupVersion}
+		for _, name := range slices.Sorted(maps.Keys(metrics)) {
+			list.Resources = append(list.Resources, apiResource{
+				Name: name, Namespaced: true, Kind: "ExternalMetricValueList", Verbs: []string{"get"},
+			})
+		}
+		writeJSON(w, http.StatusOK, list)
+	})
+	mux.HandleFunc("GET /apis/"+GroupVersion+"/namespaces/{namespace}/{metric}", func(w http.ResponseWriter, r *http.Request) {
+		name := r.PathValue("metric")
+		rec, ok := metrics[name]
+		if !ok {
+			writeJSON(w, http.StatusNotFound, map[string]string{"error": "unknown metric " + strconv.Quote(name)})
+			return
+		}
+		rec.mu.Lock()
+		item := ExternalMetricValue{
+			MetricName:   name,
+			MetricLabels: map[string]string{},
+			Timestamp:    rec.updated.UTC().Truncate(time.Second),
+			Value:        strconv.Itoa(rec.replicas),
+		}
+		rec.mu.Unlock()
+		writeJSON(w, http.StatusOK, ExternalMetricValueList{
+			Kind:       "ExternalMetricValueList",
+			APIVersion: GroupVersion,
+			Items:      []ExternalMetricValue{item},
+		})
+	})
+
+	return mux
+}
+
+func writeJSON(w http.ResponseWriter, status int, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	_ = json.NewEncoder(w).Encode(v)
+}
@@ -0,0 +1,54 @@
+package pidk8s_test
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/ankur-anand/go-pidpool"
+	"github.com/ankur-anand/go-pidpool/pidk8s"
+)
+
+func TestHandler_ServesRecommendation(t *testing.T) {
+	latency := 0.4
+	rec, err := pidk8s.NewRecommender(pidk8s.Config{
+		PID:         pidpool.NewP(-20),
+		Metric:      pidpool.SourceFunc(func(context.Context) (float64, error) { return latency, nil }),
+		Target:      0.2,
+		MinReplicas: 2,
+		MaxReplicas: 10,
+	})
+	if err != nil {
+		t.Fatalf("NewRecommender err: %v", err)
+	}
+	if n, err := rec.Step(context.Background()); err != nil || n != 4 {
+		t.Fatalf("expected 4 replicas, got %d (%v)", n, err)
+	}
+
+	srv := httptest.NewServer(pidk8s.Handler(map[string]*pidk8s.Recommender{"checkout-replicas": rec}))
+	defer srv.Close()
+
+	resp, err := http.Get(srv.URL + "/apis/external.metrics.k8s.io/v1beta1/namespaces/shop/checkout-replicas")
+	if err != nil {
+		t.Fatalf("GET err: %v", err)
+	}
+	defer resp.Body.Close()
+	var list pidk8s.ExternalMetricValueList
+	if err := json.NewDecoder(resp.Body).Decode(&list); err != nil {
+		t.Fatalf("decode err: %v", err)
+	}
+	if list.Kind != "ExternalMetricValueList" || len(list.Items) != 1 || list.Items[0].Value != "4" {
+		t.Fatalf("unexpected list %+v", list)
+	}
+
+	resp, err = http.Get(srv.URL + "/apis/external.metrics.k8s.io/v1beta1/namespaces/shop/missing")
+	if err != nil {
+		t.Fatalf("GET err: %v", err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusNotFound {
+		t.Fatalf("expected 404, got %d", resp.StatusCode)
+	}
+}
@@ -0,0 +1,238 @@
+// Package pidmodbus serves controllers over Modbus TCP, so PLCs, HMIs and
+// SCADA systems can read and write them like industrial hardware.
+//
+// Every controller is addressed by its unit identifier and exposes the
+// same register map: setpoint, gains, mode and manual output as holding
+// registers and the measurement, output, error and status as input
+// registers (see the Reg constants). The server implements function codes
+// 3 (read holding registers), 4 (read input registers), 6 (write single
+// register) and 16 (write multiple registers). Floats span two registers
+// and must be written with function code 16.
+//
+//	srv := pidmodbus.NewServer(map[byte]*pidpool.PID{1: oven, 2: chiller})
+//	l, _ := net.Listen("tcp", ":502")
+//	go srv.Serve(ctx, l)
+package pidmodbus
+
+import (
+	"context"
+	"encoding/binary"
+	"errors"
+	"io"
+	"net"
+	"sync"
+
+	"github.com/ankur-anand/go-pidpool"
+)
+
+// Function codes.
+const (
+	fcReadHolding   = 3
+	fcReadInput     = 4
+	fcWriteSingle   = 6
+	fcWriteMultiple = 16
+)
+
+// Exception codes.
+const (
+	exIllegalFunction = 1
+	exIllegalAddress  = 2
+	exIllegalValue    = 3
+	exDeviceBusy      = 6
+	exGatewayTarget   = 0x0B
+)
+
+// maxRead is the largest register count a read may request.
+const maxRead = 125
+
+// LeaseChecker reports whether a controller is leased by a client of
+// another front end. *pidhttp.Leases implements it.
+type LeaseChecker interface {
+	Leased(name string) bool
+}
+
+// Option configures a Server.
+type Option func(*Server)
+
+// WithLeases refuses writes to a unit, with exception 6 (server device
+// busy), while its controller is leased. names maps unit identifiers to
+// the controller names the leases are held under. Modbus has no way to
+// present a lease token, so reads stay open but writes are only accepted
+// while nobody holds a lease.
+func WithLeases(leases LeaseChecker, names map[byte]string) Option {
+	return func(s *Server) {
+		s.leases, s.names = leases, names
+	}
+}
+
+// Server serves controllers over Modbus TCP.
+type Server struct {
+	units  map[byte]*pidpool.PID
+	leases LeaseChecker
+	names  map[byte]string
+
+	mu    sync.Mutex
+	conns map[net.Conn]struct{}
+}
+
+// NewServer returns a server for the controllers keyed by unit identifier.
+func NewServer(units map[byte]*pidpool.PID, opts ...Option) *Server {
+	s := &Server{units: units, conns: map[net.Conn]struct{}{}}
+	for _, opt := range opts {
+		opt(s)
+	}
+	return s
+}
+
+// leased reports whether writes to unit are refused.
+func (s *Server) leased(unit byte) bool {
+	if s.leases == nil {
+		return false
+	}
+	name, ok := s.names[unit]
+	return ok && s.leases.Leased(name)
+}
+
+// Serve accepts connections on l until ctx is done, then closes l and every
+// open connection and returns ctx.Err().
+func (s *Server) Serve(ctx context.Context, l net.Listener) error {
+	go func() {
+		<-ctx.Done()
+		l.Close()
+		s.mu.Lock()
+		for c := range s.conns {
+			c.Close()
+		}
+		s.mu.Unlock()
+	}()
+
+	for {
+		conn, err := l.Accept()
+		if err != nil {
+			if ctx.Err() != nil {
+				return ctx.Err()
+			}
+			return err
+		}
+		s.mu.Lock()
+		s.conns[conn] = struct{}{}
+		s.mu.Unlock()
+		go func() {
+			defer func() {
+				s.mu.Lock()
+				delete(s.conns, conn)
+				s.mu.Unlock()
+				conn.Close()
+			}()
+			_ = s.ServeConn(conn)
+		}()
+	}
+}
+
+// ServeConn answers requests on rw until it is closed. It returns nil on a
+// clean end of stream.
+func (s *Server) ServeConn(rw io.ReadWriter) error {
+	var header [7]byte
+	for {
+		if _, err := io.ReadFull(rw, header[:]); err != nil {
+			if errors.Is(err, io.EOF) {
+				return nil
+			}
+			return err
+		}
+		// MBAP: transaction, protocol, length (unit id and PDU), unit id.
+		length := int(binary.BigEndian.Uint16(header[4:]))
+		if binary.BigEndian.Uint16(header[2:]) != 0 || length < 2 || length > 254 {
+			return errors.New("malformed mbap header")
+		}
+		pdu := make([]byte, length-1)
+		if _, err := io.ReadFull(rw, pdu); err != nil {
+			return err
+		}
+
+		resp := s.Handle(header[6], pdu)
+		out := make([]byte, 7, 7+len(resp))
+		copy(out, header[:4])
+		binary.BigEndian.PutUint16(out[4:], uint16(len(resp)+1))
+		out[6] = header[6]
+		if _, err := rw.Write(append(out, resp...)); err != nil {
+			return err
+		}
+	}
+}
+
+// Handle answers the request pdu addressed to unit and returns the
+// response PDU, which is an exception response on failure.
+func (s *Server) Handle(unit byte, pdu []byte) []byte {
+	if len(pdu) == 0 {
+		return []byte{0x80, exIllegalFunction}
+	}
+	fc := pdu[0]
+	pid, ok := s.units[unit]
+	if !ok {
+		return exception(fc, exGatewayTarget)
+	}
+
+	switch fc {
+	case fcReadHolding, fcReadInput:
+		if len(pdu) != 5 {
+			return exception(fc, exIllegalValue)
+		}
+		addr := int(binary.BigEndian.Uint16(pdu[1:]))
+		n := int(binary.BigEndian.Uint16(pdu[3:]))
+		if n < 1 || n > maxRead {
+			return exception(fc, exIllegalValue)
+		}
+		var regs []uint16
+		if fc == fcReadHolding {
+			regs = holding(pid)
+		} else {
+			regs = input(pid)
+		}
+		if addr+n > len(regs) {
+			return exception(fc, exIllegalAddress)
+		}
+		return append([]byte{fc, byte(2 * n)}, encode(regs[addr:addr+n])...)
+
+	case fcWriteSingle:
+		if len(pdu) != 5 {
+			return exception(fc, exIllegalValue)
+		}
+		if s.leased(unit) {
+			return exception(fc, exDeviceBusy)
+		}
+		addr := int(binary.BigEndian.Uint16(pdu[1:]))
+		if ex := writeHolding(pid, addr, []uint16{binary.BigEndian.Uint16(pdu[3:])}); ex != 0 {
+			return exception(fc, ex)
+		}
+		// the response echoes the request.
+		return append([]byte(nil), pdu...)
+
+	case fcWriteMultiple:
+		if len(pdu) < 6 {
+			return exception(fc, exIllegalValue)
+		}
+		addr := int(binary.BigEndian.Uint16(pdu[1:]))
+		n := int(binary.BigEndian.Uint16(pdu[3:]))
+		if n < 1 || int(pdu[5]) != 2*n || len(pdu) != 6+2*n {
+			return exception(fc, exIllegalValue)
+		}
+		if s.leased(unit) {
+			return exception(fc, exDeviceBusy)
+		}
+		vals := make([]uint16, n)
+		for i := range vals {
+			vals[i] = binary.BigEndian.Uint16(pdu[6+2*i:])
+		}
+		if ex := writeHolding(pid, addr, vals); ex != 0 {
+			return exception(fc, ex)
+		}
+		return append([]byte(nil), pdu[:5]...)
+	}
+
+	return exception(fc, exIllegalFunction)
+}
+
+func exception(fc, code byte) []byte {
+	return []byte{fc | 0x80, code}
+}
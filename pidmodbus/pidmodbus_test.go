@@ -0,0 +1,156 @@
+package pidmodbus_test
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"io"
+	"math"
+	"net"
+	"testing"
+
+	"github.com/ankur-anand/go-pidpool"
+	"github.com/ankur-anand/go-pidpool/pidhttp"
+	"github.com/ankur-anand/go-pidpool/pidmodbus"
+)
+
+var _ pidmodbus.LeaseChecker = (*pidhttp.Leases)(nil)
+
+func float32Regs(v float64) []byte {
+	b := make([]byte, 4)
+	binary.BigEndian.PutUint32(b, math.Float32bits(float32(v)))
+	return b
+}
+
+func TestHandle(t *testing.T) {
+	pid := pidpool.NewPID(2, 0.5, 0, 0)
+	srv := pidmodbus.NewServer(map[byte]*pidpool.PID{1: pid})
+
+	// write setpoint and kp in one request: registers 0..3.
+	req := []byte{16, 0, 0, 0, 4, 8}
+	req = append(req, float32Regs(40)...)
+	req = append(req, float32Regs(3)...)
+	if resp := srv.Handle(1, req); !bytes.Equal(resp, req[:5]) {
+		t.Fatalf("unexpected write response %v", resp)
+	}
+	if kp, ki, _ := pid.GetPID(); pid.GetSetPoint() != 40 || kp != 3 || ki != 0.5 {
+		t.Fatalf("write not applied: sp %v kp %v ki %v", pid.GetSetPoint(), kp, ki)
+	}
+
+	if resp := srv.Handle(1, []byte{6, 0, pidmodbus.RegMode, 0, 1}); resp[0] != 6 || pid.GetMode() != pidpool.Manual {
+		t.Fatalf("mode write failed: %v", resp)
+	}
+	if resp := srv.Handle(1, []byte{6, 0, pidmodbus.RegMode, 0, 7}); !bytes.Equal(resp, []byte{0x86, 3}) {
+		t.Fatalf("expected illegal value, got %v", resp)
+	}
+	if resp := srv.Handle(1, []byte{3, 0, 10, 0, 2}); !bytes.Equal(resp, []byte{0x83, 2}) {
+		t.Fatalf("expected illegal address, got %v", resp)
+	}
+	if resp := srv.Handle(9, []byte{3, 0, 0, 0, 2}); !bytes.Equal(resp, []byte{0x83, 0x0B}) {
+		t.Fatalf("expected gateway target failure, got %v", resp)
+	}
+	if resp := srv.Handle(1, []byte{5, 0, 0, 0xff, 0}); !bytes.Equal(resp, []byte{0x85, 1}) {
+		t.Fatalf("expected illegal function, got %v", resp)
+	}
+
+	// a NaN setpoint is rejected and nothing changes.
+	req = append([]byte{16, 0, 0, 0, 2, 4}, float32Regs(math.NaN())...)
+	if resp := srv.Handle(1, req); !bytes.Equal(resp, []byte{0x90, 3}) || pid.GetSetPoint() != 40 {
+		t.Fatalf("expected NaN rejected, got %v", resp)
+	}
+}
+
+func TestHandle_FloatWrites(t *testing.T) {
+	pid := pidpool.NewPID(2, 0.123456789, 0.987654321, 0)
+	pid.SetSetPoint(40)
+	srv := pidmodbus.NewServer(map[byte]*pidpool.PID{1: pid})
+
+	// half a float is refused, by either function code.
+	if resp := srv.Handle(1, []byte{6, 0, pidmodbus.RegSetPoint, 0x42, 0x48}); !bytes.Equal(resp, []byte{0x86, 2}) {
+		t.Fatalf("expected illegal address for half a float, got %v", resp)
+	}
+	req := []byte{16, 0, pidmodbus.RegSetPoint + 1, 0, 2, 4, 0, 0, 0x40, 0x40}
+	if resp := srv.Handle(1, req); !bytes.Equal(resp, []byte{0x90, 2}) {
+		t.Fatalf("expected illegal address for a misaligned float, got %v", resp)
+	}
+	if sp := pid.GetSetPoint(); sp != 40 {
+		t.Fatalf("partial write reached the controller: %v", sp)
+	}
+
+	// writing kp leaves ki and kd at full precision.
+	req = append([]byte{16, 0, pidmodbus.RegKp, 0, 2, 4}, float32Regs(3)...)
+	if resp := srv.Handle(1, req); !bytes.Equal(resp, req[:5]) {
+		t.Fatalf("unexpected write response %v", resp)
+	}
+	if kp, ki, kd := pid.GetPID(); kp != 3 || ki != 0.123456789 || kd != 0.987654321 {
+		t.Fatalf("unexpected gains %v %v %v", kp, ki, kd)
+	}
+}
+
+type leaseFunc func(name string) bool
+
+func (f leaseFunc) Leased(name string) bool { return f(name) }
+
+func TestHandle_Leases(t *testing.T) {
+	pid := pidpool.NewP(1)
+	leased := true
+	srv := pidmodbus.NewServer(map[byte]*pidpool.PID{1: pid},
+		pidmodbus.WithLeases(leaseFunc(func(name string) bool { return name == "oven" && leased }), map[byte]string{1: "oven"}))
+
+	req := append([]byte{16, 0, pidmodbus.RegSetPoint, 0, 2, 4}, float32Regs(40)...)
+	if resp := srv.Handle(1, req); !bytes.Equal(resp, []byte{0x90, 6}) || pid.GetSetPoint() != 0 {
+		t.Fatalf("expected a leased controller to refuse writes, got %v", resp)
+	}
+	if resp := srv.Handle(1, []byte{6, 0, pidmodbus.RegMode, 0, 1}); !bytes.Equal(resp, []byte{0x86, 6}) {
+		t.Fatalf("expected a leased controller to refuse writes, got %v", resp)
+	}
+	if resp := srv.Handle(1, []byte{3, 0, 0, 0, 2}); resp[0] != 3 {
+		t.Fatalf("expected reads to stay open, got %v", resp)
+	}
+
+	leased = false
+	if resp := srv.Handle(1, req); !bytes.Equal(resp, req[:5]) || pid.GetSetPoint() != 40 {
+		t.Fatalf("expected the write once the lease is gone, got %v", resp)
+	}
+}
+
+func TestServe(t *testing.T) {
+	pid := pidpool.NewP(2)
+	pid.SetSetPoint(10)
+	pid.UpdateDuration(4, 1)
+
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("listen err: %v", err)
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error, 1)
+	go func() { done <- pidmodbus.NewServer(map[byte]*pidpool.PID{1: pid}).Serve(ctx, l) }()
+
+	conn, err := net.Dial("tcp", l.Addr().String())
+	if err != nil {
+		t.Fatalf("dial err: %v", err)
+	}
+	defer conn.Close()
+
+	// read input registers 0..5: value, output, error.
+	if _, err := conn.Write([]byte{0, 7, 0, 0, 0, 6, 1, 4, 0, 0, 0, 6}); err != nil {
+		t.Fatalf("write err: %v", err)
+	}
+	resp := make([]byte, 9+12)
+	if _, err := io.ReadFull(conn, resp); err != nil {
+		t.Fatalf("read err: %v", err)
+	}
+	if !bytes.Equal(resp[:9], []byte{0, 7, 0, 0, 0, 15, 1, 4, 12}) {
+		t.Fatalf("unexpected header %v", resp[:9])
+	}
+	want := append(append(float32Regs(4), float32Regs(12)...), float32Regs(6)...)
+	if !bytes.Equal(resp[9:], want) {
+		t.Fatalf("expected registers %v, got %v", want, resp[9:])
+	}
+
+	cancel()
+	if err := <-done; err != context.Canceled {
+		t.Fatalf("expected context.Canceled, got %v", err)
+	}
+}
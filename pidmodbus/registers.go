@@ -0,0 +1,138 @@
+package pidmodbus
+
+import (
+	"encoding/binary"
+	"math"
+
+	"github.com/ankur-anand/go-pidpool"
+)
+
+// Holding registers, read/write. Floats are IEEE 754 float32 spanning two
+// registers, high word first.
+const (
+	RegSetPoint     = 0  // float32
+	RegKp           = 2  // float32
+	RegKi           = 4  // float32
+	RegKd           = 6  // float32
+	RegMode         = 8  // 0 auto, 1 manual
+	RegManualOutput = 9  // float32
+	holdingCount    = 11 // registers
+)
+
+// Input registers, read-only.
+const (
+	RegValue  = 0 // float32, last measurement after the filter
+	RegOutput = 2 // float32
+	RegError  = 4 // float32, setpoint minus value
+	// RegStatus holds bit 0 set while the output is saturated and bit 1
+	// while anti-windup holds the integral.
+	RegStatus  = 6
+	inputCount = 7
+)
+
+func putFloat(regs []uint16, at int, v float64) {
+	b := math.Float32bits(float32(v))
+	regs[at], regs[at+1] = uint16(b>>16), uint16(b)
+}
+
+func getFloat(regs []uint16, at int) float64 {
+	return float64(math.Float32frombits(uint32(regs[at])<<16 | uint32(regs[at+1])))
+}
+
+func holding(pid *pidpool.PID) []uint16 {
+	st := pid.State()
+	regs := make([]uint16, holdingCount)
+	putFloat(regs, RegSetPoint, st.SetPoint)
+	putFloat(regs, RegKp, st.Kp)
+	putFloat(regs, RegKi, st.Ki)
+	putFloat(regs, RegKd, st.Kd)
+	regs[RegMode] = uint16(st.Mode)
+	putFloat(regs, RegManualOutput, st.ManualOutput)
+	return regs
+}
+
+func input(pid *pidpool.PID) []uint16 {
+	st := pid.State()
+	regs := make([]uint16, inputCount)
+	putFloat(regs, RegValue, st.PrevValue)
+	putFloat(regs, RegOutput, pid.LastOutput())
+	putFloat(regs, RegError, st.SetPoint-st.PrevValue)
+	status := pid.LastStatus()
+	if status.Saturated {
+		regs[RegStatus] |= 1
+	}
+	if status.WindupClamped {
+		regs[RegStatus] |= 2
+	}
+	return regs
+}
+
+// writeHolding overlays vals at addr on the holding registers and applies
+// every field they touch. A float must be written whole, both registers in
+// one request: half a float would combine with the stale other half and
+// reach the controller as a garbage value. Nothing is applied when a field
+// is invalid.
+func writeHolding(pid *pidpool.PID, addr int, vals []uint16) byte {
+	if addr+len(vals) > holdingCount {
+		return exIllegalAddress
+	}
+	regs := holding(pid)
+	copy(regs[addr:], vals)
+	touched := func(at, n int) bool { return addr < at+n && at < addr+len(vals) }
+
+	floats := []int{RegSetPoint, RegKp, RegKi, RegKd, RegManualOutput}
+	for _, at := range floats {
+		if !touched(at, 2) {
+			continue
+		}
+		if at < addr || at+2 > addr+len(vals) {
+			return exIllegalAddress
+		}
+		if v := getFloat(regs, at); math.IsNaN(v) || math.IsInf(v, 0) {
+			return exIllegalValue
+		}
+	}
+	if touched(RegMode, 1) && regs[RegMode] > uint16(pidpool.Manual) {
+		return exIllegalValue
+	}
+
+	if touched(RegKp, 6) {
+		// only the gains written change; the others keep their full
+		// precision instead of a float32 round trip.
+		err := pid.UpdateGains(func(g pidpool.Gains) pidpool.Gains {
+			if touched(RegKp, 2) {
+				g.Kp = getFloat(regs, RegKp)
+			}
+			if touched(RegKi, 2) {
+				g.Ki = getFloat(regs, RegKi)
+			}
+			if touched(RegKd, 2) {
+				g.Kd = getFloat(regs, RegKd)
+			}
+			return g
+		})
+		if err != nil {
+			return exIllegalValue
+		}
+	}
+	if touched(RegSetPoint, 2) {
+		pid.SetSetPoint(getFloat(regs, RegSetPoint))
+	}
+	if touched(RegManualOutput, 2) {
+		pid.SetManualOutput(getFloat(regs, RegManualOutput))
+	}
+	if touched(RegMode, 1) {
+		if err := pid.SetMode(pidpool.Mode(regs[RegMode])); err != nil {
+			return exIllegalValue
+		}
+	}
+	return 0
+}
+
+func encode(regs []uint16) []byte {
+	b := make([]byte, 2*len(regs))
+	for i, r := range regs {
+		binary.BigEndian.PutUint16(b[2*i:], r)
+	}
+	return b
+}
@@ -0,0 +1,88 @@
+package pidmqtt
+
+import (
+	"encoding/json"
+	"regexp"
+)
+
+var unsafeID = regexp.MustCompile(`[^a-zA-Z0-9_-]`)
+
+type device struct {
+	Identifiers  []string `json:"identifiers"`
+	Name         string   `json:"name"`
+	Manufacturer string   `json:"manufacturer"`
+}
+
+type entity struct {
+	Name          string   `json:"name"`
+	UniqueID      string   `json:"unique_id"`
+	StateTopic    string   `json:"state_topic"`
+	ValueTemplate string   `json:"value_template"`
+	CommandTopic  string   `json:"command_topic,omitempty"`
+	Unit          string   `json:"unit_of_measurement,omitempty"`
+	Options       []string `json:"options,omitempty"`
+	Min           *float64 `json:"min,omitempty"`
+	Max           *float64 `json:"max,omitempty"`
+	Step          float64  `json:"step,omitempty"`
+	Mode          string   `json:"mode,omitempty"`
+	Device        device   `json:"device"`
+}
+
+// Discovery returns the retained Home Assistant discovery messages, or
+// nil when discovery is disabled. Topics follow
+// <prefix>/<component>/<node>/<object>/config with the node derived from
+// the controller name.
+func (b *Bridge) Discovery() []Message {
+	if b.cfg.DiscoveryPrefix == "" {
+		return nil
+	}
+	node := unsafeID.ReplaceAllString(b.cfg.Name, "_")
+	dev := device{Identifiers: []string{"pidpool_" + node}, Name: b.cfg.Name, Manufacturer: "go-pidpool"}
+	state := b.Topic("state")
+
+	sp := entity{
+		Name:          "Setpoint",
+		StateTopic:    state,
+		ValueTemplate: "{{ value_json.setPoint }}",
+		CommandTopic:  b.Topic("setpoint/set"),
+		Unit:          b.cfg.Unit,
+		Step:          b.cfg.SetPointStep,
+		Mode:          "box",
+	}
+	if b.cfg.SetPointMin != 0 || b.cfg.SetPointMax != 0 {
+		lo, hi := b.cfg.SetPointMin, b.cfg.SetPointMax
+		sp.Min, sp.Max = &lo, &hi
+	}
+
+	entities := []struct {
+		component, object string
+		e                 entity
+	}{
+		{"sensor", "value", entity{Name: "Process value", StateTopic: state, ValueTemplate: "{{ value_json.value }}", Unit: b.cfg.Unit}},
+		{"sensor", "output", entity{Name: "Output", StateTopic: state, ValueTemplate: "{{ value_json.output }}"}},
+		{"number", "setpoint", sp},
+		{"select", "mode", entity{
+			Name:          "Mode",
+			StateTopic:    state,
+			ValueTemplate: "{{ value_json.mode }}",
+			CommandTopic:  b.Topic("mode/set"),
+			Options:       []string{"auto", "manual"},
+		}},
+	}
+
+	msgs := make([]Message, 0, len(entities))
+	for _, e := range entities {
+		e.e.UniqueID = "pidpool_" + node + "_" + e.object
+		e.e.Device = dev
+		payload, err := json.Marshal(e.e)
+		if err != nil {
+			// entity holds only strings and the finite numbers New checked.
+			panic(err)
+		}
+		msgs = append(msgs, Message{
+			Topic:   b.cfg.DiscoveryPrefix + "/" + e.component + "/" + node + "/" + e.object + "/config",
+			Payload: payload,
+		})
+	}
+	return msgs
+}
@@ -0,0 +1,320 @@
+// Package pidmqtt bridges a controller to MQTT, so it can be watched and
+// driven from home and industrial automation stacks such as Home
+// Assistant or Node-RED.
+//
+// Topics, relative to Config.Prefix:
+//
+//	state       telemetry published after updates: {"value", "setPoint", "output", "mode"}
+//	setpoint/set  a number sets the setpoint
+//	gains/set     JSON {"kp", "ki", "kd"}; absent gains are left unchanged
+//	mode/set      "auto" or "manual"
+//	manual/set    a number sets the manual output
+//
+// Commands with non-finite numbers are rejected. While Config.Leases
+// reports the controller as leased, every command is rejected.
+//
+// When Config.DiscoveryPrefix is set, Start also publishes retained Home
+// Assistant MQTT discovery payloads: sensors for the process value and the
+// output, a number for the setpoint and a select for the mode.
+//
+// The package has no MQTT dependency; Client is small enough to wrap any
+// client library in a few lines.
+package pidmqtt
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/ankur-anand/go-pidpool"
+)
+
+// Client is the subset of an MQTT client the bridge needs.
+type Client interface {
+	Publish(ctx context.Context, topic string, payload []byte, retain bool) error
+	// Subscribe calls handler with the payload of every message on topic.
+	Subscribe(ctx context.Context, topic string, handler func(payload []byte)) error
+}
+
+// Config configures a Bridge.
+type Config struct {
+	Client Client
+	// Name identifies the controller in discovery payloads.
+	Name string
+	// Prefix is the topic prefix. Defaults to "pidpool/" + Name.
+	Prefix string
+	// MinInterval publishes telemetry at most once per interval. Zero
+	// publishes after every update.
+	MinInterval time.Duration
+
+	// DiscoveryPrefix enables Home Assistant discovery under this prefix,
+	// usually "homeassistant".
+	DiscoveryPrefix string
+	// Unit is the unit of measurement of the process value and setpoint.
+	Unit string
+	// SetPointMin and SetPointMax bound the setpoint entity; both zero
+	// leaves Home Assistant's defaults.
+	SetPointMin float64
+	SetPointMax float64
+	// SetPointStep is the setpoint increment. Defaults to 0.1.
+	SetPointStep float64
+
+	// Leases, when set, rejects commands while the controller is leased
+	// under Name, e.g. by an operator tuning it through pidhttp, whose
+	// *Leases implements it. MQTT has no way to present a lease token.
+	Leases interface {
+		Leased(name string) bool
+	}
+
+	// OnError receives publish failures and rejected commands.
+	OnError func(error)
+}
+
+// errLeased is reported for commands rejected by a lease.
+var errLeased = errors.New("controller is leased by another client")
+
+// errQueueFull is reported for telemetry dropped while the publisher is
+// behind.
+var errQueueFull = errors.New("telemetry queue full, update dropped")
+
+// State is the telemetry payload.
+type State struct {
+	Value    float64      `json:"value"`
+	SetPoint float64      `json:"setPoint"`
+	Output   float64      `json:"output"`
+	Mode     pidpool.Mode `json:"mode"`
+}
+
+// Message is an MQTT message.
+type Message struct {
+	Topic   string
+	Payload []byte
+}
+
+// Bridge publishes the telemetry of a controller and applies the commands
+// it receives to it.
+type Bridge struct {
+	pid *pidpool.PID
+	cfg Config
+
+	mu     sync.Mutex
+	ctx    context.Context
+	last   time.Time
+	remove func()
+	// queue hands updates from the controller to the publishing
+	// goroutine, so a slow broker never stalls the loop.
+	queue chan pidpool.UpdateEvent
+}
+
+// New returns a bridge for pid. Nothing is published or subscribed until
+// Start.
+func New(pid *pidpool.PID, cfg Config) (*Bridge, error) {
+	if pid == nil || cfg.Client == nil {
+		return nil, errors.New("pid and client are required")
+	}
+	if cfg.Name == "" {
+		return nil, errors.New("name is required")
+	}
+	if cfg.MinInterval < 0 {
+		return nil, errors.New("min interval must not be negative")
+	}
+	for _, v := range []float64{cfg.SetPointMin, cfg.SetPointMax, cfg.SetPointStep} {
+		if math.IsNaN(v) || math.IsInf(v, 0) {
+			return nil, errors.New("setpoint bounds and step must be finite")
+		}
+	}
+	if cfg.SetPointMin > cfg.SetPointMax || cfg.SetPointStep < 0 {
+		return nil, errors.New("setpoint min greater than max or step negative")
+	}
+	if cfg.SetPointStep == 0 {
+		cfg.SetPointStep = 0.1
+	}
+	if cfg.Prefix == "" {
+		cfg.Prefix = "pidpool/" + cfg.Name
+	}
+	cfg.Prefix = strings.TrimSuffix(cfg.Prefix, "/")
+
+	return &Bridge{pid: pid, cfg: cfg}, nil
+}
+
+// Topic returns the full topic for suffix, e.g. "state".
+func (b *Bridge) Topic(suffix string) string {
+	return b.cfg.Prefix + "/" + suffix
+}
+
+// Start publishes the discovery payloads, subscribes to the command topics
+// and starts publishing telemetry. ctx bounds the whole life of the bridge
+// and is passed to every publish; once it is done the bridge closes.
+func (b *Bridge) Start(ctx context.Context) error {
+	for _, m := range b.Discovery() {
+		if err := b.cfg.Client.Publish(ctx, m.Topic, m.Payload, true); err != nil {
+			return err
+		}
+	}
+	commands := map[string]func([]byte) error{
+		"setpoint/set": b.setPoint,
+		"gains/set":    b.gains,
+		"mode/set":     b.mode,
+		"manual/set":   b.manual,
+	}
+	for suffix, apply := range commands {
+		topic := b.Topic(suffix)
+		if err := b.cfg.Client.Subscribe(ctx, topic, func(payload []byte) {
+			err := errLeased
+			if b.cfg.Leases == nil || !b.cfg.Leases.Leased(b.cfg.Name) {
+				err = apply(payload)
+			}
+			if err != nil {
+				b.report(fmt.Errorf("%s: %w", topic, err))
+			}
+		}); err != nil {
+			return err
+		}
+	}
+
+	queue := make(chan pidpool.UpdateEvent, 64)
+	b.mu.Lock()
+	b.ctx = ctx
+	b.queue = queue
+	b.remove = b.pid.SubscribeWith(func(ev pidpool.Event) {
+		if ev.Kind != pidpool.EventUpdated {
+			return
+		}
+		// an update in flight may still deliver after Close removed the
+		// subscriber; the queue check keeps it off the closed channel.
+		b.mu.Lock()
+		open, sent := b.queue == queue, false
+		if open {
+			select {
+			case queue <- ev.Update:
+				sent = true
+			default:
+			}
+		}
+		b.mu.Unlock()
+		if open && !sent {
+			b.report(errQueueFull)
+		}
+	}, pidpool.SubscribeOptions{Updates: true})
+	b.mu.Unlock()
+	go func() {
+		for ev := range queue {
+			b.publish(ev)
+		}
+	}()
+	context.AfterFunc(ctx, b.Close)
+
+	return nil
+}
+
+// Close stops publishing telemetry. Unsubscribing is left to the client.
+func (b *Bridge) Close() {
+	b.mu.Lock()
+	remove, queue := b.remove, b.queue
+	b.remove, b.queue = nil, nil
+	b.mu.Unlock()
+	if remove != nil {
+		remove()
+		close(queue)
+	}
+}
+
+func (b *Bridge) publish(ev pidpool.UpdateEvent) {
+	b.mu.Lock()
+	if b.cfg.MinInterval > 0 && !b.last.IsZero() && ev.Time.Sub(b.last) < b.cfg.MinInterval {
+		b.mu.Unlock()
+		return
+	}
+	b.last = ev.Time
+	ctx := b.ctx
+	b.mu.Unlock()
+	if ctx.Err() != nil {
+		// closing; the hook is being removed.
+		return
+	}
+
+	payload, err := json.Marshal(State{
+		Value:    ev.Value,
+		SetPoint: ev.SetPoint,
+		Output:   ev.Output,
+		Mode:     b.pid.GetMode(),
+	})
+	if err == nil {
+		err = b.cfg.Client.Publish(ctx, b.Topic("state"), payload, false)
+	}
+	if err != nil {
+		b.report(err)
+	}
+}
+
+func (b *Bridge) report(err error) {
+	if b.cfg.OnError != nil {
+		b.cfg.OnError(err)
+	}
+}
+
+func (b *Bridge) setPoint(payload []byte) error {
+	v, err := parseFloat(payload)
+	if err != nil {
+		return err
+	}
+	if math.IsNaN(v) || math.IsInf(v, 0) {
+		return errors.New("setpoint must be finite")
+	}
+	b.pid.SetSetPoint(v)
+	return nil
+}
+
+func (b *Bridge) manual(payload []byte) error {
+	v, err := parseFloat(payload)
+	if err != nil {
+		return err
+	}
+	if math.IsNaN(v) || math.IsInf(v, 0) {
+		return errors.New("manual output must be finite")
+	}
+	b.pid.SetManualOutput(v)
+	return nil
+}
+
+func (b *Bridge) mode(payload []byte) error {
+	var m pidpool.Mode
+	if err := m.UnmarshalText([]byte(strings.ToLower(strings.TrimSpace(string(payload))))); err != nil {
+		return err
+	}
+	return b.pid.SetMode(m)
+}
+
+func (b *Bridge) gains(payload []byte) error {
+	var g struct {
+		Kp *float64 `json:"kp"`
+		Ki *float64 `json:"ki"`
+		Kd *float64 `json:"kd"`
+	}
+	if err := json.Unmarshal(payload, &g); err != nil {
+		return err
+	}
+	// absent gains are filled in under the controller lock, so a
+	// concurrent change of another gain is not lost.
+	return b.pid.UpdateGains(func(cur pidpool.Gains) pidpool.Gains {
+		for _, p := range []struct {
+			src *float64
+			dst *float64
+		}{{g.Kp, &cur.Kp}, {g.Ki, &cur.Ki}, {g.Kd, &cur.Kd}} {
+			if p.src != nil {
+				*p.dst = *p.src
+			}
+		}
+		return cur
+	})
+}
+
+func parseFloat(payload []byte) (float64, error) {
+	return strconv.ParseFloat(strings.TrimSpace(string(payload)), 64)
+}
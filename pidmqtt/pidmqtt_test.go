@@ -0,0 +1,171 @@
+package pidmqtt_test
+
+import (
+	"context"
+	"encoding/json"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/ankur-anand/go-pidpool"
+	"github.com/ankur-anand/go-pidpool/pidmqtt"
+)
+
+type fakeClient struct {
+	mu        sync.Mutex
+	published map[string][]byte
+	retained  map[string]bool
+	handlers  map[string]func([]byte)
+}
+
+func newFakeClient() *fakeClient {
+	return &fakeClient{
+		published: map[string][]byte{},
+		retained:  map[string]bool{},
+		handlers:  map[string]func([]byte){},
+	}
+}
+
+func (c *fakeClient) Publish(_ context.Context, topic string, payload []byte, retain bool) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.published[topic] = payload
+	c.retained[topic] = retain
+	return nil
+}
+
+func (c *fakeClient) Subscribe(_ context.Context, topic string, handler func([]byte)) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.handlers[topic] = handler
+	return nil
+}
+
+func (c *fakeClient) send(topic, payload string) {
+	c.mu.Lock()
+	h := c.handlers[topic]
+	c.mu.Unlock()
+	h([]byte(payload))
+}
+
+func (c *fakeClient) get(topic string) ([]byte, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	p, ok := c.published[topic]
+	return p, ok
+}
+
+func TestBridge(t *testing.T) {
+	client := newFakeClient()
+	pid := pidpool.NewPI(2, 0)
+	var errs []error
+	b, err := pidmqtt.New(pid, pidmqtt.Config{
+		Client:          client,
+		Name:            "kiln 1",
+		DiscoveryPrefix: "homeassistant",
+		Unit:            "°C",
+		SetPointMax:     1200,
+		OnError:         func(err error) { errs = append(errs, err) },
+	})
+	if err != nil {
+		t.Fatalf("New err: %v", err)
+	}
+	if err := b.Start(context.Background()); err != nil {
+		t.Fatalf("Start err: %v", err)
+	}
+	defer b.Close()
+
+	cfg, ok := client.get("homeassistant/number/kiln_1/setpoint/config")
+	if !ok || !client.retained["homeassistant/number/kiln_1/setpoint/config"] {
+		t.Fatalf("setpoint discovery not published retained")
+	}
+	var sp map[string]any
+	if err := json.Unmarshal(cfg, &sp); err != nil {
+		t.Fatalf("discovery payload: %v", err)
+	}
+	if sp["command_topic"] != "pidpool/kiln 1/setpoint/set" || sp["max"] != 1200.0 || sp["unique_id"] != "pidpool_kiln_1_setpoint" {
+		t.Fatalf("unexpected setpoint discovery %s", cfg)
+	}
+	if len(b.Discovery()) != 4 {
+		t.Fatalf("expected 4 discovery messages, got %d", len(b.Discovery()))
+	}
+
+	client.send(b.Topic("setpoint/set"), " 50 ")
+	client.send(b.Topic("gains/set"), `{"ki":0.5}`)
+	client.send(b.Topic("manual/set"), "30")
+	client.send(b.Topic("mode/set"), "MANUAL")
+	client.send(b.Topic("setpoint/set"), "hot")
+	if pid.GetSetPoint() != 50 || pid.GetMode() != pidpool.Manual || pid.GetManualOutput() != 30 {
+		t.Fatalf("commands not applied")
+	}
+	if kp, ki, _ := pid.GetPID(); kp != 2 || ki != 0.5 {
+		t.Fatalf("expected gains 2, 0.5, got %v, %v", kp, ki)
+	}
+	if len(errs) != 1 || !strings.Contains(errs[0].Error(), "setpoint/set") {
+		t.Fatalf("expected one setpoint error, got %v", errs)
+	}
+
+	pid.UpdateDuration(20, 1)
+	deadline := time.Now().Add(time.Second)
+	for {
+		if payload, ok := client.get(b.Topic("state")); ok {
+			var st pidmqtt.State
+			if err := json.Unmarshal(payload, &st); err != nil {
+				t.Fatalf("state payload: %v", err)
+			}
+			if st.Value != 20 || st.SetPoint != 50 || st.Output != 30 || st.Mode != pidpool.Manual {
+				t.Fatalf("unexpected state %+v", st)
+			}
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("state not published")
+		}
+		time.Sleep(time.Millisecond)
+	}
+}
+
+type leaseFunc func(name string) bool
+
+func (f leaseFunc) Leased(name string) bool { return f(name) }
+
+func TestBridge_RejectsAndStops(t *testing.T) {
+	client := newFakeClient()
+	pid := pidpool.NewPI(2, 0)
+	var errs []error
+	leased := false
+	b, err := pidmqtt.New(pid, pidmqtt.Config{
+		Client:  client,
+		Name:    "kiln",
+		Leases:  leaseFunc(func(name string) bool { return name == "kiln" && leased }),
+		OnError: func(err error) { errs = append(errs, err) },
+	})
+	if err != nil {
+		t.Fatalf("New err: %v", err)
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	if err := b.Start(ctx); err != nil {
+		t.Fatalf("Start err: %v", err)
+	}
+
+	client.send(b.Topic("manual/set"), "NaN")
+	client.send(b.Topic("manual/set"), "-Inf")
+	if pid.GetManualOutput() != 0 || len(errs) != 2 {
+		t.Fatalf("expected non-finite manual outputs rejected, got %v", errs)
+	}
+
+	leased = true
+	client.send(b.Topic("setpoint/set"), "50")
+	if pid.GetSetPoint() != 0 || len(errs) != 3 {
+		t.Fatalf("expected a leased controller to reject commands, got %v", errs)
+	}
+	leased = false
+
+	cancel()
+	pid.UpdateDuration(20, 1)
+	time.Sleep(20 * time.Millisecond)
+	if _, ok := client.get(b.Topic("state")); ok {
+		t.Fatalf("telemetry published after the context was done")
+	}
+}
@@ -0,0 +1,112 @@
+package pidnotify
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/smtp"
+	"strings"
+)
+
+// ErrChannelFull is returned by a channel notifier whose channel is full.
+var ErrChannelFull = errors.New("notification channel full")
+
+// Channel returns a Notifier that sends to ch without blocking.
+func Channel(ch chan<- Notification) Notifier {
+	return Func(func(ctx context.Context, n Notification) error {
+		select {
+		case ch <- n:
+			return nil
+		default:
+			return ErrChannelFull
+		}
+	})
+}
+
+// Webhook posts notifications as JSON to URL.
+type Webhook struct {
+	URL string
+	// Client defaults to http.DefaultClient.
+	Client *http.Client
+}
+
+// Notify implements Notifier.
+func (w Webhook) Notify(ctx context.Context, n Notification) error {
+	body, err := json.Marshal(n)
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, w.URL, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	client := w.Client
+	if client == nil {
+		client = http.DefaultClient
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+	resp.Body.Close()
+	if resp.StatusCode/100 != 2 {
+		return fmt.Errorf("webhook returned %s", resp.Status)
+	}
+
+	return nil
+}
+
+// Email sends notifications through an SMTP server.
+type Email struct {
+	// Addr is the host:port of the SMTP server.
+	Addr string
+	Auth smtp.Auth
+	From string
+	To   []string
+}
+
+// Notify implements Notifier. The context is not honoured by net/smtp.
+func (e Email) Notify(ctx context.Context, n Notification) error {
+	subject := fmt.Sprintf("[%s] %s: %s", n.Severity, n.Controller, n.Kind)
+	var msg strings.Builder
+	fmt.Fprintf(&msg, "From: %s\r\n", e.From)
+	fmt.Fprintf(&msg, "To: %s\r\n", strings.Join(e.To, ", "))
+	fmt.Fprintf(&msg, "Subject: %s\r\n\r\n", subject)
+	fmt.Fprintf(&msg, "%s\r\n\r\ntime: %s\r\n", n.Message, n.Time.Format("2006-01-02T15:04:05Z07:00"))
+	for k, v := range n.Fields {
+		fmt.Fprintf(&msg, "%s: %s\r\n", k, v)
+	}
+
+	return smtp.SendMail(e.Addr, e.Auth, e.From, e.To, []byte(msg.String()))
+}
+
+// Publisher publishes a payload on a topic, e.g. an MQTT client.
+type Publisher interface {
+	Publish(ctx context.Context, topic string, payload []byte) error
+}
+
+// Topic publishes notifications as JSON through a Publisher. Topic may
+// contain {controller}, {kind} and {severity} placeholders.
+type Topic struct {
+	Publisher Publisher
+	Topic     string
+}
+
+// Notify implements Notifier.
+func (t Topic) Notify(ctx context.Context, n Notification) error {
+	payload, err := json.Marshal(n)
+	if err != nil {
+		return err
+	}
+	topic := strings.NewReplacer(
+		"{controller}", n.Controller,
+		"{kind}", n.Kind,
+		"{severity}", n.Severity.String(),
+	).Replace(t.Topic)
+
+	return t.Publisher.Publish(ctx, topic, payload)
+}
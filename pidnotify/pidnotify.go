@@ -0,0 +1,405 @@
+// Package pidnotify routes controller alarms and faults to notifiers.
+//
+// A Router holds a list of routes. Every route has a minimum severity, a
+// Notifier and an optional rate limit, so a critical fault can page
+// someone while warnings only go to a chat channel, and a flapping loop
+// cannot flood either. Notify only queues, so it is safe to call from a
+// control loop; a goroutine of the Router delivers:
+//
+//	r, _ := pidnotify.NewRouter(
+//		pidnotify.Route{Name: "pager", MinSeverity: pidnotify.Critical, Notifier: pidnotify.Webhook{URL: pagerURL}},
+//		pidnotify.Route{Name: "log", Notifier: pidnotify.Func(logNotification), Burst: 5, Interval: time.Minute},
+//	)
+//	monitor, _ := pidpool.NewActuatorMonitor(pidpool.ActuatorMonitorConfig{
+//		...
+//		OnFault: func(f pidpool.ActuatorFault) { r.Notify(ctx, pidnotify.ActuatorFault("oven", f)) },
+//	})
+//	r.Watch("oven", pid) // faults and saturation from the event bus
+//	defer r.Close()
+package pidnotify
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/ankur-anand/go-pidpool"
+)
+
+// Severity ranks notifications.
+type Severity int
+
+const (
+	// Info is informational, e.g. a recovery.
+	Info Severity = iota
+	// Warning needs attention but not immediately.
+	Warning
+	// Critical needs immediate attention.
+	Critical
+)
+
+// String implements fmt.Stringer.
+func (s Severity) String() string {
+	switch s {
+	case Info:
+		return "info"
+	case Warning:
+		return "warning"
+	case Critical:
+		return "critical"
+	}
+	return fmt.Sprintf("Severity(%d)", int(s))
+}
+
+// MarshalText implements encoding.TextMarshaler.
+func (s Severity) MarshalText() ([]byte, error) {
+	return []byte(s.String()), nil
+}
+
+// UnmarshalText implements encoding.TextUnmarshaler.
+func (s *Severity) UnmarshalText(text []byte) error {
+	switch string(text) {
+	case "info":
+		*s = Info
+	case "warning":
+		*s = Warning
+	case "critical":
+		*s = Critical
+	default:
+		return fmt.Errorf("unknown severity %q", text)
+	}
+	return nil
+}
+
+// Notification is an alarm or fault event.
+type Notification struct {
+	Time     time.Time `json:"time"`
+	Severity Severity  `json:"severity"`
+	// Controller names the loop the event is about.
+	Controller string `json:"controller"`
+	// Kind classifies the event, e.g. "actuator_fault".
+	Kind    string            `json:"kind"`
+	Message string            `json:"message"`
+	Fields  map[string]string `json:"fields,omitempty"`
+}
+
+// Notifier delivers notifications.
+type Notifier interface {
+	Notify(ctx context.Context, n Notification) error
+}
+
+// Func adapts a function to the Notifier interface.
+type Func func(ctx context.Context, n Notification) error
+
+// Notify implements Notifier.
+func (f Func) Notify(ctx context.Context, n Notification) error { return f(ctx, n) }
+
+// Route sends notifications of at least MinSeverity to Notifier. When Burst
+// is positive, at most Burst notifications per controller and kind are
+// sent per Interval; the rest are dropped.
+type Route struct {
+	Name        string
+	MinSeverity Severity
+	Notifier    Notifier
+	Burst       int
+	Interval    time.Duration
+}
+
+// ErrRateLimited is reported for notifications a route dropped.
+var ErrRateLimited = errors.New("rate limited")
+
+// ErrQueueFull is returned by Notify when the delivery queue is full; the
+// notification is dropped and counted.
+var ErrQueueFull = errors.New("notification queue full")
+
+// ErrRouterClosed is returned by Notify after Close.
+var ErrRouterClosed = errors.New("router closed")
+
+// RouteError reports a notification a route did not deliver.
+type RouteError struct {
+	Route string
+	Err   error
+}
+
+func (e *RouteError) Error() string {
+	return fmt.Sprintf("route %q: %v", e.Route, e.Err)
+}
+
+func (e *RouteError) Unwrap() error { return e.Err }
+
+// RouterConfig configures the delivery of a Router.
+type RouterConfig struct {
+	// QueueSize bounds the notifications waiting for delivery. Defaults to
+	// 256.
+	QueueSize int
+	// OnError is called from the delivery goroutine with the joined
+	// RouteErrors of a notification some route failed or rate limited.
+	OnError func(n Notification, err error)
+}
+
+// Router fans notifications out to routes.
+type Router struct {
+	routes  []Route
+	onError func(Notification, error)
+
+	mu      sync.Mutex
+	buckets map[bucketKey]*bucket
+
+	// qmu guards closing the queue against concurrent sends.
+	qmu     sync.RWMutex
+	closed  bool
+	queue   chan queued
+	done    chan struct{}
+	dropped atomic.Uint64
+}
+
+type queued struct {
+	ctx context.Context
+	n   Notification
+}
+
+type bucketKey struct {
+	route            int
+	controller, kind string
+}
+
+// bucket is a token bucket refilled at Burst tokens per Interval.
+type bucket struct {
+	tokens float64
+	last   time.Time
+}
+
+// NewRouter returns a Router for the given routes with the default
+// RouterConfig.
+func NewRouter(routes ...Route) (*Router, error) {
+	return NewRouterConfig(RouterConfig{}, routes...)
+}
+
+// NewRouterConfig returns a Router for the given routes and starts its
+// delivery goroutine, which runs until Close.
+func NewRouterConfig(cfg RouterConfig, routes ...Route) (*Router, error) {
+	for _, r := range routes {
+		if r.Notifier == nil {
+			return nil, fmt.Errorf("route %q: notifier required", r.Name)
+		}
+		if r.Burst < 0 || r.Burst > 0 && r.Interval <= 0 {
+			return nil, fmt.Errorf("route %q: rate limit needs a positive burst and interval", r.Name)
+		}
+	}
+	if cfg.QueueSize < 0 {
+		return nil, errors.New("queue size must not be negative")
+	}
+	if cfg.QueueSize == 0 {
+		cfg.QueueSize = 256
+	}
+	r := &Router{
+		routes:  routes,
+		onError: cfg.OnError,
+		buckets: make(map[bucketKey]*bucket),
+		queue:   make(chan queued, cfg.QueueSize),
+		done:    make(chan struct{}),
+	}
+	go r.deliver()
+
+	return r, nil
+}
+
+// Notify queues n for delivery and returns without waiting for the
+// notifiers. A zero Time is set to now. When the queue is full n is
+// dropped, counted in Dropped, and ErrQueueFull returned. Cancelling ctx
+// does not withdraw a queued notification; its values are passed on to
+// the notifiers.
+func (r *Router) Notify(ctx context.Context, n Notification) error {
+	if n.Time.IsZero() {
+		n.Time = time.Now()
+	}
+	r.qmu.RLock()
+	defer r.qmu.RUnlock()
+	if r.closed {
+		return ErrRouterClosed
+	}
+	select {
+	case r.queue <- queued{ctx: context.WithoutCancel(ctx), n: n}:
+		return nil
+	default:
+		r.dropped.Add(1)
+		return ErrQueueFull
+	}
+}
+
+// Dropped returns the number of notifications dropped on a full queue.
+func (r *Router) Dropped() uint64 {
+	return r.dropped.Load()
+}
+
+// Close stops accepting notifications and waits until the queued ones are
+// delivered.
+func (r *Router) Close() {
+	r.qmu.Lock()
+	if !r.closed {
+		r.closed = true
+		close(r.queue)
+	}
+	r.qmu.Unlock()
+	<-r.done
+}
+
+func (r *Router) deliver() {
+	defer close(r.done)
+	for q := range r.queue {
+		if err := r.Send(q.ctx, q.n); err != nil && r.onError != nil {
+			r.onError(q.n, err)
+		}
+	}
+}
+
+// Send delivers n to every route it matches, in route order, and waits for
+// the notifiers. A zero Time is set to now. It returns the joined
+// RouteErrors of the routes that failed or dropped n.
+func (r *Router) Send(ctx context.Context, n Notification) error {
+	if n.Time.IsZero() {
+		n.Time = time.Now()
+	}
+
+	var errs []error
+	for i, route := range r.routes {
+		if n.Severity < route.MinSeverity {
+			continue
+		}
+		if !r.allow(i, route, n) {
+			errs = append(errs, &RouteError{Route: route.Name, Err: ErrRateLimited})
+			continue
+		}
+		if err := route.Notifier.Notify(ctx, n); err != nil {
+			errs = append(errs, &RouteError{Route: route.Name, Err: err})
+		}
+	}
+
+	return errors.Join(errs...)
+}
+
+func (r *Router) allow(i int, route Route, n Notification) bool {
+	if route.Burst == 0 {
+		return true
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	key := bucketKey{route: i, controller: n.Controller, kind: n.Kind}
+	b, ok := r.buckets[key]
+	if !ok {
+		b = &bucket{tokens: float64(route.Burst), last: n.Time}
+		r.buckets[key] = b
+	}
+	if elapsed := n.Time.Sub(b.last); elapsed > 0 {
+		b.tokens += float64(route.Burst) * elapsed.Seconds() / route.Interval.Seconds()
+		b.tokens = min(b.tokens, float64(route.Burst))
+		b.last = n.Time
+	}
+	if b.tokens < 1 {
+		return false
+	}
+	b.tokens--
+
+	return true
+}
+
+// ActuatorFault returns the notification for an actuator fault.
+func ActuatorFault(controller string, f pidpool.ActuatorFault) Notification {
+	return Notification{
+		Time:       f.Time,
+		Severity:   Critical,
+		Controller: controller,
+		Kind:       "actuator_fault",
+		Message: fmt.Sprintf("process did not respond to an output change of %g within %v",
+			f.OutputChange, f.Duration),
+	}
+}
+
+// PositionMismatch returns the notification for an actuator position
+// mismatch.
+func PositionMismatch(controller string, m pidpool.PositionMismatch) Notification {
+	return Notification{
+		Time:       m.Time,
+		Severity:   Critical,
+		Controller: controller,
+		Kind:       "position_mismatch",
+		Message: fmt.Sprintf("actuator at %g, commanded %g, for %v",
+			m.Position, m.Command, m.Duration),
+	}
+}
+
+// HookError returns the notification for a failing update hook.
+func HookError(controller string, e pidpool.HookError) Notification {
+	return Notification{
+		Severity:   Warning,
+		Controller: controller,
+		Kind:       "hook_error",
+		Message:    e.Error(),
+	}
+}
+
+// Alarm returns the notification for an alarm event: critical for a
+// raised high-high or low-low alarm, a warning for a raised high or low
+// alarm and informational when an alarm clears.
+func Alarm(controller string, e pidpool.AlarmEvent) Notification {
+	n := Notification{
+		Time:       e.Time,
+		Severity:   Info,
+		Controller: controller,
+		Kind:       "alarm",
+		Fields: map[string]string{
+			"signal": e.Limit.Signal.String(),
+			"level":  e.Limit.Level.String(),
+			"limit":  fmt.Sprintf("%g", e.Limit.Limit),
+			"value":  fmt.Sprintf("%g", e.Value),
+		},
+	}
+	if !e.Active {
+		n.Message = fmt.Sprintf("%s %s alarm cleared at %g", e.Limit.Signal, e.Limit.Level, e.Value)
+		return n
+	}
+	n.Severity = Warning
+	if e.Limit.Level == pidpool.AlarmHighHigh || e.Limit.Level == pidpool.AlarmLowLow {
+		n.Severity = Critical
+	}
+	n.Message = fmt.Sprintf("%s %s alarm: %g beyond limit %g", e.Limit.Signal, e.Limit.Level, e.Value, e.Limit.Limit)
+	return n
+}
+
+// Event returns the notification for a controller event: critical for a
+// raised fault, a warning for saturation and informational when either
+// ends. Configuration events have no notification.
+func Event(controller string, ev pidpool.Event) (Notification, bool) {
+	n := Notification{Time: ev.Time, Controller: controller}
+	switch ev.Kind {
+	case pidpool.EventFaultRaised:
+		n.Severity, n.Kind = Critical, "fault"
+		n.Message = "controller faulted: " + ev.Fault.Reason
+	case pidpool.EventFaultCleared:
+		n.Severity, n.Kind = Info, "fault"
+		n.Message = "controller fault cleared"
+	case pidpool.EventSaturationEntered:
+		n.Severity, n.Kind = Warning, "saturation"
+		n.Message = fmt.Sprintf("output saturated at %g, wanted %g", ev.Update.Output, ev.Update.RawOutput)
+	case pidpool.EventSaturationLeft:
+		n.Severity, n.Kind = Info, "saturation"
+		n.Message = fmt.Sprintf("output left saturation at %g", ev.Update.Output)
+	default:
+		return Notification{}, false
+	}
+	return n, true
+}
+
+// Watch notifies r of the fault and saturation events of pid, under the
+// given controller name. Notifications only queue, so the control loop is
+// never held up by a notifier. The returned function stops watching.
+func (r *Router) Watch(controller string, pid *pidpool.PID) (stop func()) {
+	return pid.Subscribe(func(ev pidpool.Event) {
+		if n, ok := Event(controller, ev); ok {
+			_ = r.Notify(context.Background(), n)
+		}
+	})
+}
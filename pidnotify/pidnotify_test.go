@@ -0,0 +1,169 @@
+package pidnotify_test
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/ankur-anand/go-pidpool"
+	"github.com/ankur-anand/go-pidpool/pidnotify"
+)
+
+func TestRouter_SeverityAndRateLimit(t *testing.T) {
+	pager := make(chan pidnotify.Notification, 10)
+	chat := make(chan pidnotify.Notification, 10)
+	r, err := pidnotify.NewRouter(
+		pidnotify.Route{Name: "pager", MinSeverity: pidnotify.Critical, Notifier: pidnotify.Channel(pager)},
+		pidnotify.Route{Name: "chat", Notifier: pidnotify.Channel(chat), Burst: 2, Interval: time.Minute},
+	)
+	if err != nil {
+		t.Fatalf("NewRouter err: %v", err)
+	}
+	defer r.Close()
+
+	t0 := time.Unix(0, 0)
+	ctx := context.Background()
+	for i := 0; i < 3; i++ {
+		err = r.Send(ctx, pidnotify.Notification{Time: t0, Severity: pidnotify.Warning, Controller: "oven", Kind: "x"})
+	}
+	if !errors.Is(err, pidnotify.ErrRateLimited) {
+		t.Fatalf("expected third warning to be rate limited, got %v", err)
+	}
+	if len(chat) != 2 || len(pager) != 0 {
+		t.Fatalf("unexpected deliveries chat=%d pager=%d", len(chat), len(pager))
+	}
+
+	// another controller has its own budget; the bucket refills with time.
+	if err := r.Send(ctx, pidnotify.Notification{Time: t0, Severity: pidnotify.Critical, Controller: "chiller", Kind: "x"}); err != nil {
+		t.Fatalf("Notify err: %v", err)
+	}
+	if err := r.Send(ctx, pidnotify.Notification{Time: t0.Add(30 * time.Second), Controller: "oven", Kind: "x"}); err != nil {
+		t.Fatalf("expected a refilled token, got %v", err)
+	}
+	if len(pager) != 1 || len(chat) != 4 {
+		t.Fatalf("unexpected deliveries chat=%d pager=%d", len(chat), len(pager))
+	}
+}
+
+func TestWebhook(t *testing.T) {
+	got := make(chan pidnotify.Notification, 1)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var n pidnotify.Notification
+		if err := json.NewDecoder(r.Body).Decode(&n); err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+		}
+		got <- n
+	}))
+	defer srv.Close()
+
+	n := pidnotify.Notification{Severity: pidnotify.Critical, Controller: "oven", Kind: "actuator_fault", Message: "stuck"}
+	if err := (pidnotify.Webhook{URL: srv.URL}).Notify(context.Background(), n); err != nil {
+		t.Fatalf("Notify err: %v", err)
+	}
+	if m := <-got; m.Kind != "actuator_fault" || m.Severity != pidnotify.Critical {
+		t.Fatalf("unexpected payload %+v", m)
+	}
+}
+
+func TestAlarm(t *testing.T) {
+	e := pidpool.AlarmEvent{
+		Limit:  pidpool.AlarmLimit{Signal: pidpool.AlarmValue, Level: pidpool.AlarmHighHigh, Limit: 250},
+		Value:  260,
+		Active: true,
+	}
+	if n := pidnotify.Alarm("oven", e); n.Severity != pidnotify.Critical || n.Fields["level"] != "high-high" {
+		t.Fatalf("unexpected notification %+v", n)
+	}
+	e.Active = false
+	if n := pidnotify.Alarm("oven", e); n.Severity != pidnotify.Info {
+		t.Fatalf("expected a cleared alarm to be informational, got %v", n.Severity)
+	}
+}
+
+func TestRouter_Queue(t *testing.T) {
+	release := make(chan struct{})
+	delivered := make(chan pidnotify.Notification, 10)
+	slow := pidnotify.Func(func(ctx context.Context, n pidnotify.Notification) error {
+		<-release
+		delivered <- n
+		if n.Kind == "bad" {
+			return errors.New("refused")
+		}
+		return nil
+	})
+	var failed []string
+	r, err := pidnotify.NewRouterConfig(pidnotify.RouterConfig{
+		QueueSize: 2,
+		OnError:   func(n pidnotify.Notification, err error) { failed = append(failed, n.Kind) },
+	}, pidnotify.Route{Name: "slow", Notifier: slow})
+	if err != nil {
+		t.Fatalf("NewRouterConfig err: %v", err)
+	}
+
+	// a blocked notifier does not block the caller.
+	ctx, cancel := context.WithCancel(context.Background())
+	var errs []error
+	for _, kind := range []string{"a", "bad", "c", "d", "e"} {
+		errs = append(errs, r.Notify(ctx, pidnotify.Notification{Kind: kind}))
+	}
+	cancel()
+	// the first is taken by the delivery goroutine at some point, so at
+	// least two and at most three fit.
+	var full int
+	for _, err := range errs {
+		if errors.Is(err, pidnotify.ErrQueueFull) {
+			full++
+		}
+	}
+	if full < 2 || full > 3 || r.Dropped() != uint64(full) {
+		t.Fatalf("expected 2 or 3 dropped, got %v (Dropped %d)", errs, r.Dropped())
+	}
+
+	close(release)
+	r.Close()
+	if len(delivered) != 5-full {
+		t.Fatalf("expected %d deliveries, got %d", 5-full, len(delivered))
+	}
+	if len(failed) != 1 || failed[0] != "bad" {
+		t.Fatalf("expected the refused notification reported, got %v", failed)
+	}
+	if err := r.Notify(context.Background(), pidnotify.Notification{}); !errors.Is(err, pidnotify.ErrRouterClosed) {
+		t.Fatalf("expected ErrRouterClosed, got %v", err)
+	}
+}
+
+func TestRouter_Watch(t *testing.T) {
+	got := make(chan pidnotify.Notification, 10)
+	r, err := pidnotify.NewRouter(pidnotify.Route{Name: "all", Notifier: pidnotify.Channel(got)})
+	if err != nil {
+		t.Fatalf("NewRouter err: %v", err)
+	}
+	pid := pidpool.NewP(1)
+	pid.SetOutputLimits(0, 10)
+	stop := r.Watch("oven", pid)
+
+	pid.SetSetPoint(50)
+	pid.UpdateDuration(0, 1)
+	pid.Trip("sensor lost")
+	stop()
+	pid.ClearFault()
+	r.Close()
+
+	want := []struct {
+		kind     string
+		severity pidnotify.Severity
+	}{{"saturation", pidnotify.Warning}, {"fault", pidnotify.Critical}}
+	if len(got) != len(want) {
+		t.Fatalf("expected %d notifications, got %d", len(want), len(got))
+	}
+	for _, w := range want {
+		n := <-got
+		if n.Kind != w.kind || n.Severity != w.severity || n.Controller != "oven" {
+			t.Fatalf("expected %s %v, got %+v", w.kind, w.severity, n)
+		}
+	}
+}
@@ -0,0 +1,84 @@
+// Package pidperiph wires a controller to hardware through periph.io, for
+// boards and sensors that pidsysfs does not reach:
+//
+//   - PWM drives a periph.io PWM-capable pin and is a pidpool.Sink.
+//   - Pin drives a periph.io output pin and is a pidpool.Switch, e.g. for a
+//     relay driven by pidpool.PWM.
+//   - Sensor reads the temperature of any periph.io environmental sensor,
+//     such as the ds18b20 or bmxx80 drivers, and is a pidpool.Source.
+//
+// A temperature loop on a Raspberry Pi, with the host drivers loaded by
+// host.Init:
+//
+//	bus, _ := netlink.New(0x00)
+//	probe, _ := ds18b20.New(bus, addr, 10)
+//	heater := pidperiph.NewPWM(rpi.P1_12, 1*physic.KiloHertz)
+//	pid := pidpool.NewPI(8, 0.2)
+//	pid.SetOutputLimits(0, 100)
+//	pid.SetSetPoint(55)
+//	pidpool.NewRunner(pid, time.Second, pidperiph.Sensor{Sensor: probe}, heater).Start()
+package pidperiph
+
+import (
+	"context"
+
+	"periph.io/x/conn/v3/gpio"
+	"periph.io/x/conn/v3/physic"
+
+	"github.com/ankur-anand/go-pidpool"
+)
+
+var (
+	_ pidpool.Source = Sensor{}
+	_ pidpool.Sink   = (*PWM)(nil)
+	_ pidpool.Switch = Pin{}
+)
+
+// Sensor reads the temperature, in °C, of an environmental sensor.
+type Sensor struct {
+	Sensor physic.SenseEnv
+}
+
+// Read implements pidpool.Source.
+func (s Sensor) Read(context.Context) (float64, error) {
+	var env physic.Env
+	if err := s.Sensor.Sense(&env); err != nil {
+		return 0, err
+	}
+	return env.Temperature.Celsius(), nil
+}
+
+// PWM drives a pin with hardware PWM support at a fixed frequency.
+type PWM struct {
+	Pin gpio.PinOut
+	// Frequency is the PWM frequency; zero lets the pin pick.
+	Frequency physic.Frequency
+	// Max is the output that maps to a 100% duty cycle.
+	Max float64
+}
+
+// NewPWM returns a PWM on pin at frequency taking outputs in percent.
+func NewPWM(pin gpio.PinOut, frequency physic.Frequency) *PWM {
+	return &PWM{Pin: pin, Frequency: frequency, Max: 100}
+}
+
+// Write implements pidpool.Sink. The output is clamped to [0, Max].
+func (p *PWM) Write(_ context.Context, output float64) error {
+	duty := min(max(output/p.Max, 0), 1)
+	return p.Pin.PWM(gpio.Duty(duty*float64(gpio.DutyMax)), p.Frequency)
+}
+
+// Close drives the pin low.
+func (p *PWM) Close() error {
+	return p.Pin.Out(gpio.Low)
+}
+
+// Pin is an output pin.
+type Pin struct {
+	Pin gpio.PinOut
+}
+
+// Set implements pidpool.Switch.
+func (p Pin) Set(_ context.Context, on bool) error {
+	return p.Pin.Out(gpio.Level(on))
+}
@@ -0,0 +1,75 @@
+package pidperiph_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"periph.io/x/conn/v3/gpio"
+	"periph.io/x/conn/v3/physic"
+
+	"github.com/ankur-anand/go-pidpool/pidperiph"
+)
+
+type fakePin struct {
+	gpio.PinOut
+	duty  gpio.Duty
+	freq  physic.Frequency
+	level gpio.Level
+}
+
+func (p *fakePin) PWM(d gpio.Duty, f physic.Frequency) error {
+	p.duty, p.freq = d, f
+	return nil
+}
+
+func (p *fakePin) Out(l gpio.Level) error {
+	p.level = l
+	return nil
+}
+
+type fakeSensor struct {
+	physic.SenseEnv
+	t   physic.Temperature
+	err error
+}
+
+func (s fakeSensor) Sense(env *physic.Env) error {
+	env.Temperature = s.t
+	return s.err
+}
+
+func TestPWM(t *testing.T) {
+	pin := &fakePin{}
+	p := pidperiph.NewPWM(pin, physic.KiloHertz)
+	ctx := context.Background()
+	for _, c := range []struct {
+		out  float64
+		want gpio.Duty
+	}{{25, gpio.DutyMax / 4}, {150, gpio.DutyMax}, {-5, 0}} {
+		if err := p.Write(ctx, c.out); err != nil {
+			t.Fatalf("Write err: %v", err)
+		}
+		if pin.duty != c.want || pin.freq != physic.KiloHertz {
+			t.Fatalf("output %v: expected duty %v, got %v at %v", c.out, c.want, pin.duty, pin.freq)
+		}
+	}
+
+	if err := (pidperiph.Pin{Pin: pin}).Set(ctx, true); err != nil || pin.level != gpio.High {
+		t.Fatalf("expected the pin high, got %v (%v)", pin.level, err)
+	}
+	if err := p.Close(); err != nil || pin.level != gpio.Low {
+		t.Fatalf("expected the pin low after Close, got %v (%v)", pin.level, err)
+	}
+}
+
+func TestSensor(t *testing.T) {
+	s := pidperiph.Sensor{Sensor: fakeSensor{t: physic.ZeroCelsius + 55*physic.Celsius}}
+	if v, err := s.Read(context.Background()); err != nil || v != 55 {
+		t.Fatalf("expected 55°C, got %v (%v)", v, err)
+	}
+	s.Sensor = fakeSensor{err: errors.New("crc")}
+	if _, err := s.Read(context.Background()); err == nil {
+		t.Fatalf("expected the sensor error")
+	}
+}
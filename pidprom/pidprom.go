@@ -0,0 +1,369 @@
+// Package pidprom exposes PID controller state as Prometheus metrics.
+//
+// The Collector is a prometheus.Collector, to be registered with a
+// Prometheus registry, and also renders the text exposition format itself
+// and serves it over HTTP, for programs that do not run a registry.
+package pidprom
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"math"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/prometheus/client_golang/prometheus"
+
+	"github.com/ankur-anand/go-pidpool"
+)
+
+// Collector collects metrics for a set of named controllers.
+type Collector struct {
+	mu     sync.Mutex
+	loops  map[string]*loop
+	prefix string
+	descs  []*prometheus.Desc
+}
+
+var _ prometheus.Collector = (*Collector)(nil)
+
+type loop struct {
+	pid    *pidpool.PID
+	remove func()
+
+	mu        sync.Mutex
+	last      pidpool.UpdateEvent
+	updates   uint64
+	saturated uint64
+}
+
+// New returns an empty Collector. Metric names are prefixed with "pid_".
+func New() *Collector {
+	c := &Collector{loops: make(map[string]*loop), prefix: "pid_"}
+	for _, m := range metrics {
+		c.descs = append(c.descs, prometheus.NewDesc(c.prefix+m.name, m.help, []string{"controller"}, nil))
+	}
+	return c
+}
+
+// Add starts collecting metrics for p under the given controller name,
+// replacing any controller previously added with that name. The returned
+// function stops collecting.
+func (c *Collector) Add(name string, p *pidpool.PID) (remove func()) {
+	l := &loop{pid: p}
+	l.remove = p.SubscribeWith(l.record, pidpool.SubscribeOptions{Updates: true})
+
+	c.mu.Lock()
+	if old, ok := c.loops[name]; ok {
+		old.remove()
+	}
+	c.loops[name] = l
+	c.mu.Unlock()
+
+	return func() {
+		c.mu.Lock()
+		defer c.mu.Unlock()
+		if c.loops[name] == l {
+			delete(c.loops, name)
+			l.remove()
+		}
+	}
+}
+
+// Track collects metrics for every controller of r, following later
+// registrations and removals. The returned function stops tracking and
+// removes the tracked controllers.
+func (c *Collector) Track(r *pidpool.Registry) (stop func()) {
+	var mu sync.Mutex
+	removes := make(map[string]func())
+	cancel := r.Watch(func(name string, p *pidpool.PID) {
+		mu.Lock()
+		defer mu.Unlock()
+		if rm, ok := removes[name]; ok {
+			rm()
+			delete(removes, name)
+		}
+		if p != nil {
+			removes[name] = c.Add(name, p)
+		}
+	})
+
+	return func() {
+		cancel()
+		mu.Lock()
+		defer mu.Unlock()
+		for name, rm := range removes {
+			rm()
+			delete(removes, name)
+		}
+	}
+}
+
+func (l *loop) record(e pidpool.Event) {
+	if e.Kind != pidpool.EventUpdated {
+		return
+	}
+	ev := e.Update
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.last = ev
+	l.updates++
+	if ev.Status().Saturated {
+		l.saturated++
+	}
+}
+
+type metric struct {
+	name, help, typ string
+	value           func(l *loop, st pidpool.State) (float64, bool)
+}
+
+var metrics = []metric{
+	{"setpoint", "Current controller setpoint.", "gauge",
+		func(l *loop, st pidpool.State) (float64, bool) { return st.SetPoint, true }},
+	{"process_value", "Process value passed to the last update.", "gauge",
+		func(l *loop, st pidpool.State) (float64, bool) { return l.last.Value, l.updates > 0 }},
+	{"error", "Control error of the last update.", "gauge",
+		func(l *loop, st pidpool.State) (float64, bool) { return l.last.Error, l.updates > 0 }},
+	{"output", "Controller output of the last update.", "gauge",
+		func(l *loop, st pidpool.State) (float64, bool) { return l.last.Output, l.updates > 0 }},
+	{"integral", "Integral accumulator.", "gauge",
+		func(l *loop, st pidpool.State) (float64, bool) { return st.Integral, true }},
+	{"saturated", "Whether the last output was clamped to the output limits.", "gauge",
+		func(l *loop, st pidpool.State) (float64, bool) {
+			return boolValue(l.last.Status().Saturated), l.updates > 0
+		}},
+	{"updates_total", "Number of controller updates.", "counter",
+		func(l *loop, st pidpool.State) (float64, bool) { return float64(l.updates), true }},
+	{"saturated_updates_total", "Number of updates whose output was clamped.", "counter",
+		func(l *loop, st pidpool.State) (float64, bool) { return float64(l.saturated), true }},
+	{"saturations_total", "Number of times the output became clamped to the output limits.", "counter",
+		func(l *loop, st pidpool.State) (float64, bool) {
+			return float64(l.pid.LimitCounters().Saturations), true
+		}},
+	{"saturated_seconds_total", "Time the output spent clamped to the output limits.", "counter",
+		func(l *loop, st pidpool.State) (float64, bool) {
+			return l.pid.LimitCounters().SaturatedTime.Seconds(), true
+		}},
+	{"windups_total", "Number of times anti-windup started holding the integral back.", "counter",
+		func(l *loop, st pidpool.State) (float64, bool) { return float64(l.pid.LimitCounters().Windups), true }},
+	{"windup_seconds_total", "Time anti-windup held the integral back.", "counter",
+		func(l *loop, st pidpool.State) (float64, bool) {
+			return l.pid.LimitCounters().WindupTime.Seconds(), true
+		}},
+	{"health_score", "Rolling loop health score in [0, 1], higher being better.", "gauge",
+		func(l *loop, st pidpool.State) (float64, bool) {
+			h, ok := l.pid.Health()
+			return h.Score, ok && h.Ready
+		}},
+	{"harris_index", "Minimum-variance benchmark over the error variance.", "gauge",
+		func(l *loop, st pidpool.State) (float64, bool) {
+			h, ok := l.pid.Health()
+			return h.HarrisIndex, ok && h.Ready
+		}},
+	{"noise_snr_db", "Estimated measurement signal-to-noise ratio in dB.", "gauge",
+		func(l *loop, st pidpool.State) (float64, bool) {
+			ns, ok := l.pid.NoiseStats()
+			return ns.SNR, ok
+		}},
+}
+
+func boolValue(b bool) float64 {
+	if b {
+		return 1
+	}
+	return 0
+}
+
+type sample struct {
+	name string
+	st   pidpool.State
+	l    *loop
+}
+
+// sample snapshots every controller, sorted by name.
+func (c *Collector) sample() []sample {
+	c.mu.Lock()
+	samples := make([]sample, 0, len(c.loops))
+	for name, l := range c.loops {
+		samples = append(samples, sample{name: name, l: l})
+	}
+	c.mu.Unlock()
+	sort.Slice(samples, func(i, j int) bool { return samples[i].name < samples[j].name })
+
+	for i, s := range samples {
+		l := s.l
+		samples[i].st = l.pid.State()
+		l.mu.Lock()
+		samples[i].l = &loop{pid: l.pid, last: l.last, updates: l.updates, saturated: l.saturated}
+		l.mu.Unlock()
+	}
+	return samples
+}
+
+// Describe implements prometheus.Collector. It sends no descriptors, which
+// registers the Collector as unchecked: the labels of the info metric
+// follow the controllers' annotations and cannot be described up front.
+func (c *Collector) Describe(chan<- *prometheus.Desc) {}
+
+// Collect implements prometheus.Collector.
+func (c *Collector) Collect(ch chan<- prometheus.Metric) {
+	samples := c.sample()
+	for i, m := range metrics {
+		typ := prometheus.GaugeValue
+		if m.typ == "counter" {
+			typ = prometheus.CounterValue
+		}
+		for _, s := range samples {
+			if v, ok := m.value(s.l, s.st); ok {
+				sendMetric(ch, c.descs[i], typ, v, s.name)
+			}
+		}
+	}
+
+	// a metric family needs one label set, so every controller carries
+	// the union of the annotation labels, empty where it has none.
+	labels := make([]map[string]string, len(samples))
+	union := map[string]bool{}
+	for i, s := range samples {
+		labels[i] = map[string]string{}
+		for _, l := range infoLabels(s.name, s.st.Annotations)[1:] {
+			labels[i][l[0]] = l[1]
+			union[l[0]] = true
+		}
+	}
+	names := []string{"controller"}
+	for name := range union {
+		names = append(names, name)
+	}
+	sort.Strings(names[1:])
+	desc := prometheus.NewDesc(c.prefix+"info", "Controller annotations as labels.", names, nil)
+	for i, s := range samples {
+		values := []string{s.name}
+		for _, name := range names[1:] {
+			values = append(values, labels[i][name])
+		}
+		sendMetric(ch, desc, prometheus.GaugeValue, 1, values...)
+	}
+}
+
+// sendMetric sends a constant metric, dropping it when a label value is
+// not valid UTF-8: names and annotations can be set remotely, and one bad
+// value must not fail the whole scrape.
+func sendMetric(ch chan<- prometheus.Metric, desc *prometheus.Desc, typ prometheus.ValueType, v float64, labels ...string) {
+	if m, err := prometheus.NewConstMetric(desc, typ, v, labels...); err == nil {
+		ch <- m
+	}
+}
+
+// WriteTo writes all metrics in the Prometheus text exposition format.
+func (c *Collector) WriteTo(w io.Writer) (int64, error) {
+	samples := c.sample()
+
+	bw := bufio.NewWriter(w)
+	cw := &countingWriter{w: bw}
+	for _, m := range metrics {
+		fmt.Fprintf(cw, "# HELP %s%s %s\n", c.prefix, m.name, m.help)
+		fmt.Fprintf(cw, "# TYPE %s%s %s\n", c.prefix, m.name, m.typ)
+		for _, s := range samples {
+			v, ok := m.value(s.l, s.st)
+			if !ok {
+				continue
+			}
+			fmt.Fprintf(cw, "%s%s{controller=%s} %s\n", c.prefix, m.name, quote(s.name), formatValue(v))
+		}
+	}
+	fmt.Fprintf(cw, "# HELP %sinfo Controller annotations as labels.\n", c.prefix)
+	fmt.Fprintf(cw, "# TYPE %sinfo gauge\n", c.prefix)
+	for _, s := range samples {
+		pairs := make([]string, 0, len(s.st.Annotations)+1)
+		for _, l := range infoLabels(s.name, s.st.Annotations) {
+			pairs = append(pairs, l[0]+"="+quote(l[1]))
+		}
+		fmt.Fprintf(cw, "%sinfo{%s} 1\n", c.prefix, strings.Join(pairs, ","))
+	}
+	err := bw.Flush()
+
+	return cw.n, err
+}
+
+// ServeHTTP implements http.Handler.
+func (c *Collector) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "text/plain; version=0.0.4; charset=utf-8")
+	_, _ = c.WriteTo(w)
+}
+
+// infoLabels returns the controller label followed by one label per
+// annotation, as name and value pairs. Annotation keys are sanitized into
+// valid label names; a key that collides with an earlier label, such as
+// the controller label, or that leaves no name is dropped.
+func infoLabels(name string, annotations map[string]string) [][2]string {
+	keys := make([]string, 0, len(annotations))
+	for k := range annotations {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	labels := [][2]string{{"controller", name}}
+	seen := map[string]bool{"controller": true}
+	for _, k := range keys {
+		label := labelName(k)
+		if label == "" || seen[label] {
+			continue
+		}
+		seen[label] = true
+		labels = append(labels, [2]string{label, annotations[k]})
+	}
+
+	return labels
+}
+
+// labelName sanitizes an annotation key into a label name. Names starting
+// with "__" are reserved by Prometheus, so a leading run of underscores is
+// cut to one.
+func labelName(s string) string {
+	b := []byte(s)
+	for i, c := range b {
+		ok := c == '_' || 'a' <= c && c <= 'z' || 'A' <= c && c <= 'Z' || i > 0 && '0' <= c && c <= '9'
+		if !ok {
+			b[i] = '_'
+		}
+	}
+	name := string(b)
+	if strings.HasPrefix(name, "__") {
+		name = "_" + strings.TrimLeft(name, "_")
+	}
+	return name
+}
+
+func quote(s string) string {
+	r := strings.NewReplacer(`\`, `\\`, "\n", `\n`, `"`, `\"`)
+	return `"` + r.Replace(s) + `"`
+}
+
+func formatValue(v float64) string {
+	switch {
+	case math.IsInf(v, 1):
+		return "+Inf"
+	case math.IsInf(v, -1):
+		return "-Inf"
+	case math.IsNaN(v):
+		return "NaN"
+	}
+	return strconv.FormatFloat(v, 'g', -1, 64)
+}
+
+type countingWriter struct {
+	w io.Writer
+	n int64
+}
+
+func (c *countingWriter) Write(p []byte) (int, error) {
+	n, err := c.w.Write(p)
+	c.n += int64(n)
+	return n, err
+}
@@ -0,0 +1,173 @@
+package pidprom_test
+
+import (
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/prometheus/client_golang/prometheus"
+
+	"github.com/ankur-anand/go-pidpool"
+	"github.com/ankur-anand/go-pidpool/pidprom"
+)
+
+func TestCollector_Exposition(t *testing.T) {
+	p := pidpool.NewPID(1, 0, 0, 0)
+	if err := p.SetOutputLimits(0, 5); err != nil {
+		t.Fatalf("SetOutputLimits err: %v", err)
+	}
+	p.SetSetPoint(10)
+	if err := p.SetAnnotation("asset-id", "A17"); err != nil {
+		t.Fatalf("SetAnnotation err: %v", err)
+	}
+
+	c := pidprom.New()
+	remove := c.Add("oven", p)
+	p.UpdateDuration(2, 1)
+
+	rec := httptest.NewRecorder()
+	c.ServeHTTP(rec, httptest.NewRequest("GET", "/metrics", nil))
+	body := rec.Body.String()
+
+	for _, want := range []string{
+		"# TYPE pid_setpoint gauge",
+		`pid_setpoint{controller="oven"} 10`,
+		`pid_process_value{controller="oven"} 2`,
+		`pid_output{controller="oven"} 5`,
+		`pid_saturated{controller="oven"} 1`,
+		`pid_updates_total{controller="oven"} 1`,
+		`pid_saturations_total{controller="oven"} 1`,
+		`pid_saturated_seconds_total{controller="oven"} 1`,
+		`pid_info{controller="oven",asset_id="A17"} 1`,
+	} {
+		if !strings.Contains(body, want) {
+			t.Fatalf("missing %q in:\n%s", want, body)
+		}
+	}
+	if strings.Contains(body, "pid_health_score{") {
+		t.Fatalf("health metric must be omitted when monitoring is disabled")
+	}
+	if strings.Contains(body, "pid_noise_snr_db{") {
+		t.Fatalf("noise metric must be omitted when estimation is disabled")
+	}
+
+	remove()
+	rec = httptest.NewRecorder()
+	c.ServeHTTP(rec, httptest.NewRequest("GET", "/metrics", nil))
+	if strings.Contains(rec.Body.String(), "oven") {
+		t.Fatalf("removed controller still exported")
+	}
+}
+
+func TestCollector_Track(t *testing.T) {
+	r := pidpool.NewRegistry()
+	_ = r.Register("oven", pidpool.NewPID(1, 0, 0, 0))
+
+	c := pidprom.New()
+	stop := c.Track(r)
+	_ = r.Register("chiller", pidpool.NewPID(1, 0, 0, 0))
+	r.Unregister("oven")
+
+	var b strings.Builder
+	_, _ = c.WriteTo(&b)
+	if !strings.Contains(b.String(), `controller="chiller"`) || strings.Contains(b.String(), `controller="oven"`) {
+		t.Fatalf("registry not tracked:\n%s", b.String())
+	}
+
+	stop()
+	b.Reset()
+	_, _ = c.WriteTo(&b)
+	if strings.Contains(b.String(), "chiller") {
+		t.Fatalf("stopped tracking but still exported")
+	}
+}
+
+func TestCollector_Registry(t *testing.T) {
+	oven := pidpool.NewP(1)
+	oven.SetOutputLimits(0, 5)
+	oven.SetSetPoint(10)
+	oven.SetAnnotation("asset-id", "A17")
+	chiller := pidpool.NewP(1)
+	chiller.SetOutputLimits(0, 100)
+	// quantization and the output deadband are not saturation.
+	chiller.SetOutputQuantization(pidpool.Quantization{Step: 1})
+	chiller.SetSetPoint(2.4)
+
+	c := pidprom.New()
+	c.Add("oven", oven)
+	c.Add("chiller", chiller)
+	oven.UpdateDuration(2, 1)
+	chiller.UpdateDuration(0, 1)
+
+	reg := prometheus.NewPedanticRegistry()
+	if err := reg.Register(c); err != nil {
+		t.Fatalf("Register err: %v", err)
+	}
+	families, err := reg.Gather()
+	if err != nil {
+		t.Fatalf("Gather err: %v", err)
+	}
+	values := map[string]float64{}
+	for _, f := range families {
+		for _, m := range f.GetMetric() {
+			key := f.GetName()
+			for _, l := range m.GetLabel() {
+				if l.GetName() == "controller" {
+					key += "/" + l.GetValue()
+				}
+			}
+			switch {
+			case m.Gauge != nil:
+				values[key] = m.GetGauge().GetValue()
+			case m.Counter != nil:
+				values[key] = m.GetCounter().GetValue()
+			}
+		}
+	}
+	for key, want := range map[string]float64{
+		"pid_setpoint/oven":                   10,
+		"pid_saturated/oven":                  1,
+		"pid_saturated/chiller":               0,
+		"pid_saturated_updates_total/chiller": 0,
+		"pid_updates_total/chiller":           1,
+		"pid_info/chiller":                    1,
+	} {
+		if got, ok := values[key]; !ok || got != want {
+			t.Fatalf("%s: expected %v, got %v (present %v)", key, want, got, ok)
+		}
+	}
+}
+
+func TestCollector_ReservedAnnotationKeys(t *testing.T) {
+	p := pidpool.NewP(1)
+	for _, k := range []string{"__asset", "::site"} {
+		if err := p.SetAnnotation(k, "x"); err != nil {
+			t.Fatalf("SetAnnotation err: %v", err)
+		}
+	}
+	c := pidprom.New()
+	c.Add("oven", p)
+
+	reg := prometheus.NewPedanticRegistry()
+	if err := reg.Register(c); err != nil {
+		t.Fatalf("Register err: %v", err)
+	}
+	families, err := reg.Gather()
+	if err != nil {
+		t.Fatalf("Gather err: %v", err)
+	}
+	for _, f := range families {
+		if f.GetName() != "pid_info" {
+			continue
+		}
+		names := map[string]bool{}
+		for _, l := range f.GetMetric()[0].GetLabel() {
+			names[l.GetName()] = true
+		}
+		if !names["_asset"] || !names["_site"] {
+			t.Fatalf("expected the reserved prefix cut to one underscore, got %v", names)
+		}
+		return
+	}
+	t.Fatalf("pid_info missing")
+}
@@ -0,0 +1,64 @@
+// Package pidrate drives a golang.org/x/time/rate limiter with a PID loop,
+// so clients can self-throttle against a struggling backend.
+//
+//	lim := rate.NewLimiter(100, 10)
+//	r, _ := pidrate.New(lim, pidrate.Config{
+//		PID:     pidpool.NewPI(500, 2000),
+//		Signal:  errorRate, // a pidpool.Source, e.g. the 5xx ratio
+//		Target:  0.01,
+//		MinRate: 1,
+//		MaxRate: 1000,
+//	})
+//	r.Start()
+package pidrate
+
+import (
+	"errors"
+	"time"
+
+	"golang.org/x/time/rate"
+
+	"github.com/ankur-anand/go-pidpool"
+)
+
+// Config configures the loop, see pidpool.RateControl.
+type Config struct {
+	// PID computes the limit in events per second from the signal. A
+	// signal above Target should lower the rate, so the gains are positive.
+	PID    *pidpool.PID
+	Signal pidpool.Source
+	Target float64
+
+	MinRate float64
+	MaxRate float64
+	// Interval is the update interval. Defaults to one second.
+	Interval time.Duration
+
+	// Burst returns the burst for a new limit, e.g. a tenth of a second's
+	// worth of events. If nil the limiter's burst is left unchanged.
+	Burst func(limit float64) int
+}
+
+// New returns a pidpool.Runner that sets the limit, and the burst if
+// configured, of lim every interval. Call Start to begin.
+func New(lim *rate.Limiter, cfg Config) (*pidpool.Runner, error) {
+	if lim == nil {
+		return nil, errors.New("limiter is required")
+	}
+	burst := cfg.Burst
+
+	return pidpool.NewRateControl(pidpool.RateControl{
+		PID:      cfg.PID,
+		Signal:   cfg.Signal,
+		Target:   cfg.Target,
+		MinRate:  cfg.MinRate,
+		MaxRate:  cfg.MaxRate,
+		Interval: cfg.Interval,
+		SetLimit: func(limit float64) {
+			if burst != nil {
+				lim.SetBurst(burst(limit))
+			}
+			lim.SetLimit(rate.Limit(limit))
+		},
+	})
+}
@@ -0,0 +1,47 @@
+package pidrate_test
+
+import (
+	"context"
+	"testing"
+
+	"golang.org/x/time/rate"
+
+	"github.com/ankur-anand/go-pidpool"
+	"github.com/ankur-anand/go-pidpool/pidrate"
+)
+
+func TestNew_BacksOffLimiter(t *testing.T) {
+	lim := rate.NewLimiter(500, 50)
+	// the backend fails a growing share of requests above 100/s.
+	errorRate := pidpool.SourceFunc(func(context.Context) (float64, error) {
+		return max(0, (float64(lim.Limit())-100)/1000), nil
+	})
+	r, err := pidrate.New(lim, pidrate.Config{
+		PID:     pidpool.NewPI(200, 1000),
+		Signal:  errorRate,
+		Target:  0.01,
+		MinRate: 1,
+		MaxRate: 1000,
+		Burst:   func(l float64) int { return max(1, int(l/10)) },
+	})
+	if err != nil {
+		t.Fatalf("New err: %v", err)
+	}
+
+	for i := 0; i < 20; i++ {
+		if err := r.RunOnce(context.Background()); err != nil {
+			t.Fatalf("RunOnce err: %v", err)
+		}
+	}
+	l := float64(lim.Limit())
+	if l >= 100 || l < 1 {
+		t.Fatalf("limit did not back off into [1, 100): %v", l)
+	}
+	if want := max(1, int(l/10)); lim.Burst() != want {
+		t.Fatalf("expected burst %d, got %d", want, lim.Burst())
+	}
+
+	if _, err := pidrate.New(nil, pidrate.Config{PID: pidpool.NewP(1)}); err == nil {
+		t.Fatalf("expected error without limiter")
+	}
+}
@@ -0,0 +1,219 @@
+// Package pidshed provides net/http middleware for adaptive load shedding.
+//
+// A Shedder admits each request with a probability adjusted every interval
+// by a PID loop holding request latency or the in-flight count at a target;
+// shed requests get 503 Service Unavailable. The first request of every
+// interval is always admitted as a probe, so the latency keeps being
+// measured, and the shedder recovers, even at a drop probability of 1. LimitHandler instead caps
+// concurrency with a pidpool.AdaptiveLimiter.
+package pidshed
+
+import (
+	"context"
+	"errors"
+	"math/rand/v2"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/ankur-anand/go-pidpool"
+)
+
+// Signal selects what a Shedder holds at its target.
+type Signal int
+
+const (
+	// SignalLatency is the mean latency, in seconds, of the requests
+	// completed during the last interval.
+	SignalLatency Signal = iota
+	// SignalInFlight is the number of requests being served.
+	SignalInFlight
+)
+
+// Adjustment describes one control step of a Shedder.
+type Adjustment struct {
+	Time time.Time
+	// Signal is the measured latency or in-flight count.
+	Signal float64
+	// DropProbability is the new probability of shedding a request.
+	DropProbability float64
+}
+
+// Config configures a Shedder.
+type Config struct {
+	// PID computes the drop probability from the signal. A signal above
+	// Target should shed more, so the gains are negative. The shedder
+	// governs it over [0, MaxDrop], see pidpool.PID.Govern.
+	PID    *pidpool.PID
+	Signal Signal
+	Target float64
+	// MaxDrop is the largest drop probability. Defaults to 1.
+	MaxDrop float64
+	// Interval is the control period. Defaults to one second.
+	Interval time.Duration
+
+	// OnAdjust is called after every control step.
+	OnAdjust func(Adjustment)
+	// OnShed is called for every shed request, before the 503 is written.
+	OnShed func(*http.Request)
+}
+
+// Shedder is probabilistic admission control middleware.
+type Shedder struct {
+	cfg Config
+
+	mu       sync.Mutex
+	drop     float64
+	probed   bool
+	inFlight int
+	latSum   float64
+	latN     int
+
+	stop chan struct{}
+	done chan struct{}
+	once sync.Once
+}
+
+// New returns a Shedder and starts its control loop.
+func New(cfg Config) (*Shedder, error) {
+	if cfg.PID == nil {
+		return nil, errors.New("pid is required")
+	}
+	if cfg.MaxDrop < 0 || cfg.MaxDrop > 1 {
+		return nil, errors.New("max drop must be in [0, 1]")
+	}
+	if cfg.Interval < 0 {
+		return nil, errors.New("interval must not be negative")
+	}
+	if cfg.MaxDrop == 0 {
+		cfg.MaxDrop = 1
+	}
+	if cfg.Interval == 0 {
+		cfg.Interval = time.Second
+	}
+	if err := cfg.PID.Govern(cfg.Target, 0, cfg.MaxDrop); err != nil {
+		return nil, err
+	}
+
+	s := &Shedder{cfg: cfg, stop: make(chan struct{}), done: make(chan struct{})}
+	go s.loop()
+
+	return s, nil
+}
+
+// Handler wraps next with admission control.
+func (s *Shedder) Handler(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		s.mu.Lock()
+		drop := s.drop
+		admit := drop <= 0 || !s.probed || rand.Float64() >= drop
+		if admit {
+			s.inFlight++
+			s.probed = true
+		}
+		s.mu.Unlock()
+		if !admit {
+			if s.cfg.OnShed != nil {
+				s.cfg.OnShed(r)
+			}
+			http.Error(w, "overloaded", http.StatusServiceUnavailable)
+			return
+		}
+
+		start := time.Now()
+		defer func() {
+			s.mu.Lock()
+			s.inFlight--
+			s.latSum += time.Since(start).Seconds()
+			s.latN++
+			s.mu.Unlock()
+		}()
+		next.ServeHTTP(w, r)
+	})
+}
+
+// DropProbability returns the current probability of shedding a request.
+func (s *Shedder) DropProbability() float64 {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.drop
+}
+
+// Close stops the control loop. The drop probability stays where it is.
+func (s *Shedder) Close() {
+	s.once.Do(func() { close(s.stop) })
+	<-s.done
+}
+
+func (s *Shedder) loop() {
+	defer close(s.done)
+	t := time.NewTicker(s.cfg.Interval)
+	defer t.Stop()
+	last := time.Now()
+	for {
+		select {
+		case <-s.stop:
+			return
+		case now := <-t.C:
+			s.control(now, now.Sub(last))
+			last = now
+		}
+	}
+}
+
+// control runs one control step. With SignalLatency, an interval without
+// completed requests leaves the probability unchanged.
+func (s *Shedder) control(now time.Time, dt time.Duration) {
+	s.mu.Lock()
+	signal := float64(s.inFlight)
+	n := s.latN
+	if s.cfg.Signal == SignalLatency && n > 0 {
+		signal = s.latSum / float64(n)
+	}
+	s.latSum, s.latN = 0, 0
+	s.probed = false
+	s.mu.Unlock()
+	if s.cfg.Signal == SignalLatency && n == 0 {
+		return
+	}
+
+	drop := s.cfg.PID.UpdateDuration(signal, dt.Seconds())
+	s.mu.Lock()
+	s.drop = drop
+	s.mu.Unlock()
+	if s.cfg.OnAdjust != nil {
+		s.cfg.OnAdjust(Adjustment{Time: now, Signal: signal, DropProbability: drop})
+	}
+}
+
+// LimitOptions configures LimitHandler.
+type LimitOptions struct {
+	// MaxWait is how long a request may wait for a slot before it is shed.
+	// Zero sheds requests that find no free slot.
+	MaxWait time.Duration
+	// OnShed is called for every shed request, before the 503 is written.
+	OnShed func(*http.Request)
+}
+
+// LimitHandler wraps next with the concurrency cap of l. Requests that get
+// no slot within MaxWait are shed with 503; the in-flight time of the
+// others is reported to l.
+func LimitHandler(l *pidpool.AdaptiveLimiter, next http.Handler, opts LimitOptions) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		// a zero MaxWait yields an expired context: only a free slot is
+		// taken.
+		ctx, cancel := context.WithTimeout(r.Context(), opts.MaxWait)
+		defer cancel()
+
+		err := l.Do(ctx, func() error {
+			next.ServeHTTP(w, r)
+			return nil
+		})
+		if err != nil {
+			if opts.OnShed != nil {
+				opts.OnShed(r)
+			}
+			http.Error(w, "overloaded", http.StatusServiceUnavailable)
+		}
+	})
+}
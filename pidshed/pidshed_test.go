@@ -0,0 +1,124 @@
+package pidshed_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/ankur-anand/go-pidpool"
+	"github.com/ankur-anand/go-pidpool/pidshed"
+)
+
+func TestShedder_ShedsWhenSlow(t *testing.T) {
+	var adjustments atomic.Int32
+	var shed atomic.Int32
+	s, err := pidshed.New(pidshed.Config{
+		PID:      pidpool.NewPI(-50, -500),
+		Signal:   pidshed.SignalLatency,
+		Target:   0.002,
+		MaxDrop:  0.9,
+		Interval: 10 * time.Millisecond,
+		OnAdjust: func(pidshed.Adjustment) { adjustments.Add(1) },
+		OnShed:   func(*http.Request) { shed.Add(1) },
+	})
+	if err != nil {
+		t.Fatalf("New err: %v", err)
+	}
+	defer s.Close()
+
+	slow := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		time.Sleep(10 * time.Millisecond)
+	})
+	h := s.Handler(slow)
+	deadline := time.Now().Add(2 * time.Second)
+	for s.DropProbability() == 0 && time.Now().Before(deadline) {
+		h.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", "/", nil))
+	}
+	if adjustments.Load() == 0 || s.DropProbability() == 0 {
+		t.Fatalf("no shedding: %v", s.DropProbability())
+	}
+
+	codes := map[int]int{}
+	for i := 0; i < 200; i++ {
+		rec := httptest.NewRecorder()
+		h.ServeHTTP(rec, httptest.NewRequest("GET", "/", nil))
+		codes[rec.Code]++
+	}
+	if codes[http.StatusServiceUnavailable] == 0 || int(shed.Load()) < codes[http.StatusServiceUnavailable] {
+		t.Fatalf("unexpected responses %v, %d shed", codes, shed.Load())
+	}
+}
+
+func TestShedder_RecoversFromFullDrop(t *testing.T) {
+	s, err := pidshed.New(pidshed.Config{
+		PID:      pidpool.NewPI(-100, -5000),
+		Signal:   pidshed.SignalLatency,
+		Target:   0.002,
+		Interval: 10 * time.Millisecond,
+	})
+	if err != nil {
+		t.Fatalf("New err: %v", err)
+	}
+	defer s.Close()
+
+	var delay atomic.Int64
+	delay.Store(int64(10 * time.Millisecond))
+	h := s.Handler(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		time.Sleep(time.Duration(delay.Load()))
+	}))
+	serve := func() { h.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", "/", nil)) }
+
+	deadline := time.Now().Add(2 * time.Second)
+	for s.DropProbability() < 1 && time.Now().Before(deadline) {
+		serve()
+	}
+	if p := s.DropProbability(); p != 1 {
+		t.Fatalf("expected every request shed, got %v", p)
+	}
+
+	// the backend recovers; only the probes can tell.
+	delay.Store(0)
+	deadline = time.Now().Add(2 * time.Second)
+	for s.DropProbability() == 1 && time.Now().Before(deadline) {
+		serve()
+		time.Sleep(time.Millisecond)
+	}
+	if p := s.DropProbability(); p == 1 {
+		t.Fatalf("shedder stuck at drop probability 1")
+	}
+}
+
+func TestLimitHandler_ShedsBeyondCap(t *testing.T) {
+	l, err := pidpool.NewAdaptiveLimiter(pidpool.AdaptiveLimiterConfig{
+		PID: pidpool.NewP(1), Target: time.Second, MinLimit: 1, MaxLimit: 1,
+	})
+	if err != nil {
+		t.Fatalf("NewAdaptiveLimiter err: %v", err)
+	}
+	defer l.Close()
+
+	release := make(chan struct{})
+	started := make(chan struct{})
+	h := pidshed.LimitHandler(l, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		close(started)
+		<-release
+	}), pidshed.LimitOptions{})
+
+	var wg sync.WaitGroup
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		h.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", "/", nil))
+	}()
+	<-started
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, httptest.NewRequest("GET", "/", nil))
+	if rec.Code != http.StatusServiceUnavailable {
+		t.Fatalf("expected 503, got %d", rec.Code)
+	}
+	close(release)
+	wg.Wait()
+}
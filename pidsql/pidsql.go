@@ -0,0 +1,155 @@
+// Package pidsql sizes a database/sql connection pool with a PID loop.
+//
+// A Governor periodically samples sql.DBStats and adjusts SetMaxOpenConns
+// and SetMaxIdleConns so the mean time requests wait for a connection stays
+// at a target:
+//
+//	g, _ := pidsql.New(db, pidsql.Config{
+//		PID:     pidpool.NewPI(-100, -500),
+//		Target:  5 * time.Millisecond,
+//		MinOpen: 4,
+//		MaxOpen: 64,
+//	})
+//	go g.Run(ctx)
+package pidsql
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"math"
+	"sync"
+	"time"
+
+	"github.com/ankur-anand/go-pidpool"
+)
+
+// DB is the part of *sql.DB the governor uses.
+type DB interface {
+	Stats() sql.DBStats
+	SetMaxOpenConns(n int)
+	SetMaxIdleConns(n int)
+}
+
+var _ DB = (*sql.DB)(nil)
+
+// Config configures a Governor.
+type Config struct {
+	// PID computes the open connection limit from the mean wait, in
+	// seconds. More connections shorten the wait, so the gains are
+	// negative. It is governed over [MinOpen, MaxOpen], see
+	// pidpool.PID.Govern.
+	PID *pidpool.PID
+	// Target is the mean time a request that had to wait for a connection
+	// should wait.
+	Target time.Duration
+
+	MinOpen int
+	MaxOpen int
+	// IdleRatio sets the idle connection limit as a fraction of the open
+	// limit, rounded up. Defaults to 0.5.
+	IdleRatio float64
+	// Hysteresis is the smallest change of the open limit that is applied,
+	// so the pool is not resized for every small fluctuation. Defaults to 1.
+	Hysteresis int
+	// Interval is the sampling period of Run. Defaults to ten seconds.
+	Interval time.Duration
+}
+
+// Governor adjusts the connection limits of a DB.
+type Governor struct {
+	db  DB
+	cfg Config
+
+	mu       sync.Mutex
+	maxOpen  int
+	last     sql.DBStats
+	lastTime time.Time
+}
+
+// New returns a governor for db. The open limit starts at MinOpen.
+func New(db DB, cfg Config) (*Governor, error) {
+	if cfg.PID == nil {
+		return nil, errors.New("pid is required")
+	}
+	if cfg.MinOpen < 1 || cfg.MaxOpen < cfg.MinOpen {
+		return nil, errors.New("open limits must satisfy 1 <= min <= max")
+	}
+	if cfg.Target <= 0 {
+		return nil, errors.New("target must be positive")
+	}
+	if cfg.IdleRatio < 0 || cfg.IdleRatio > 1 {
+		return nil, errors.New("idle ratio must be in [0, 1]")
+	}
+	if cfg.Hysteresis < 0 || cfg.Interval < 0 {
+		return nil, errors.New("hysteresis and interval must not be negative")
+	}
+	if cfg.IdleRatio == 0 {
+		cfg.IdleRatio = 0.5
+	}
+	if cfg.Hysteresis == 0 {
+		cfg.Hysteresis = 1
+	}
+	if cfg.Interval == 0 {
+		cfg.Interval = 10 * time.Second
+	}
+	if err := cfg.PID.Govern(cfg.Target.Seconds(), float64(cfg.MinOpen), float64(cfg.MaxOpen)); err != nil {
+		return nil, err
+	}
+
+	g := &Governor{db: db, cfg: cfg, last: db.Stats(), lastTime: time.Now()}
+	g.applyLocked(cfg.MinOpen)
+
+	return g, nil
+}
+
+// MaxOpen returns the open connection limit last applied.
+func (g *Governor) MaxOpen() int {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	return g.maxOpen
+}
+
+// Run samples the pool every interval until ctx is done.
+func (g *Governor) Run(ctx context.Context) {
+	t := time.NewTicker(g.cfg.Interval)
+	defer t.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-t.C:
+			g.Step()
+		}
+	}
+}
+
+// Step samples the pool statistics once, updates the controller and
+// applies the new limits. It returns the open connection limit.
+func (g *Governor) Step() int {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	now, st := time.Now(), g.db.Stats()
+	waits := st.WaitCount - g.last.WaitCount
+	wait := 0.0
+	if waits > 0 {
+		wait = (st.WaitDuration - g.last.WaitDuration).Seconds() / float64(waits)
+	}
+	dt := now.Sub(g.lastTime).Seconds()
+	g.last, g.lastTime = st, now
+
+	n := int(math.Round(g.cfg.PID.UpdateDuration(wait, dt)))
+	if d := n - g.maxOpen; d >= g.cfg.Hysteresis || -d >= g.cfg.Hysteresis {
+		g.applyLocked(n)
+	}
+
+	return g.maxOpen
+}
+
+func (g *Governor) applyLocked(n int) {
+	g.maxOpen = n
+	// set the open limit first: database/sql caps the idle limit at it.
+	g.db.SetMaxOpenConns(n)
+	g.db.SetMaxIdleConns(int(math.Ceil(g.cfg.IdleRatio * float64(n))))
+}
@@ -0,0 +1,63 @@
+package pidsql_test
+
+import (
+	"database/sql"
+	"testing"
+	"time"
+
+	"github.com/ankur-anand/go-pidpool"
+	"github.com/ankur-anand/go-pidpool/pidsql"
+)
+
+// fakeDB models a pool where each missing connection below 20 adds 1ms to
+// the wait of 100 requests per sample.
+type fakeDB struct {
+	stats         sql.DBStats
+	open, idle    int
+	setOpenCalled int
+}
+
+func (db *fakeDB) Stats() sql.DBStats { return db.stats }
+func (db *fakeDB) SetMaxOpenConns(n int) {
+	db.open = n
+	db.setOpenCalled++
+}
+func (db *fakeDB) SetMaxIdleConns(n int) { db.idle = n }
+
+func (db *fakeDB) serve() {
+	if short := 20 - db.open; short > 0 {
+		db.stats.WaitCount += 100
+		db.stats.WaitDuration += 100 * time.Duration(short) * time.Millisecond
+	}
+}
+
+func TestGovernor_GrowsUntilWaitsStop(t *testing.T) {
+	db := &fakeDB{}
+	g, err := pidsql.New(db, pidsql.Config{
+		PID:        pidpool.NewPI(-500, -20000),
+		Target:     time.Millisecond,
+		MinOpen:    2,
+		MaxOpen:    50,
+		Hysteresis: 2,
+	})
+	if err != nil {
+		t.Fatalf("New err: %v", err)
+	}
+	if db.open != 2 || db.idle != 1 {
+		t.Fatalf("initial limits not applied: open %d, idle %d", db.open, db.idle)
+	}
+
+	for i := 0; i < 200; i++ {
+		db.serve()
+		time.Sleep(time.Millisecond)
+		g.Step()
+	}
+	if db.open < 18 || db.open > 22 || g.MaxOpen() != db.open || db.idle != (db.open+1)/2 {
+		t.Fatalf("unexpected limits: open %d, idle %d", db.open, db.idle)
+	}
+
+	// settled, changes below the hysteresis are not applied.
+	if db.setOpenCalled > 50 {
+		t.Fatalf("pool resized on %d of 200 samples", db.setOpenCalled)
+	}
+}
@@ -0,0 +1,188 @@
+// Package pidsysfs wires a controller to hardware through the Linux sysfs
+// interfaces available on a Raspberry Pi and similar boards, with no
+// dependencies beyond the standard library:
+//
+//   - DS18B20 reads 1-Wire temperature sensors through the w1-therm
+//     driver and is a pidpool.Source.
+//   - PWM drives a hardware PWM channel and is a pidpool.Sink.
+//   - GPIO drives an output pin and is a pidpool.Switch, e.g. for a relay
+//     driven by pidpool.PWM.
+//
+// A temperature loop on a Pi with the 1-Wire and PWM overlays enabled:
+//
+//	sensors, _ := pidsysfs.FindDS18B20(pidsysfs.W1Devices)
+//	heater, _ := pidsysfs.OpenPWM(pidsysfs.PWMClass+"/pwmchip0", 0, time.Millisecond)
+//	defer heater.Close()
+//	pid := pidpool.NewPI(8, 0.2)
+//	pid.SetOutputLimits(0, 100)
+//	pid.SetSetPoint(55)
+//	pidpool.NewRunner(pid, time.Second, pidsysfs.DS18B20{Path: sensors[0]}, heater).Start()
+//
+// Package pidperiph offers the same adapters on top of periph.io drivers.
+package pidsysfs
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/ankur-anand/go-pidpool"
+)
+
+// Default sysfs locations.
+const (
+	W1Devices = "/sys/bus/w1/devices"
+	PWMClass  = "/sys/class/pwm"
+	GPIOClass = "/sys/class/gpio"
+)
+
+// ErrCRC is returned when a 1-Wire reading fails its checksum.
+var ErrCRC = errors.New("1-wire CRC check failed")
+
+var (
+	_ pidpool.Source = DS18B20{}
+	_ pidpool.Sink   = (*PWM)(nil)
+	_ pidpool.Switch = (*GPIO)(nil)
+)
+
+// FindDS18B20 returns the device directories of the DS18B20 sensors under
+// dir, usually W1Devices.
+func FindDS18B20(dir string) ([]string, error) {
+	return filepath.Glob(filepath.Join(dir, "28-*"))
+}
+
+// DS18B20 is a 1-Wire temperature sensor. Path is its device directory.
+type DS18B20 struct {
+	Path string
+}
+
+// Read implements pidpool.Source. It returns the temperature in degrees
+// Celsius.
+func (s DS18B20) Read(context.Context) (float64, error) {
+	b, err := os.ReadFile(filepath.Join(s.Path, "w1_slave"))
+	if err != nil {
+		return 0, err
+	}
+	// 72 01 4b 46 7f ff 0e 10 57 : crc=57 YES
+	// 72 01 4b 46 7f ff 0e 10 57 t=23125
+	lines := strings.Split(strings.TrimSpace(string(b)), "\n")
+	if len(lines) != 2 {
+		return 0, fmt.Errorf("%s: malformed reading %q", s.Path, b)
+	}
+	if !strings.HasSuffix(strings.TrimSpace(lines[0]), "YES") {
+		return 0, fmt.Errorf("%s: %w", s.Path, ErrCRC)
+	}
+	_, milli, ok := strings.Cut(lines[1], "t=")
+	if !ok {
+		return 0, fmt.Errorf("%s: malformed reading %q", s.Path, b)
+	}
+	v, err := strconv.Atoi(strings.TrimSpace(milli))
+	if err != nil {
+		return 0, fmt.Errorf("%s: %w", s.Path, err)
+	}
+
+	return float64(v) / 1000, nil
+}
+
+// PWM is a hardware PWM channel.
+type PWM struct {
+	dir    string
+	period time.Duration
+	// Max is the output that means 100% duty. Defaults to 100.
+	Max float64
+}
+
+// OpenPWM exports channel of the PWM chip directory chip, e.g.
+// PWMClass+"/pwmchip0", sets its period and enables it at 0% duty.
+func OpenPWM(chip string, channel int, period time.Duration) (*PWM, error) {
+	if period <= 0 {
+		return nil, errors.New("period must be positive")
+	}
+	dir := filepath.Join(chip, fmt.Sprintf("pwm%d", channel))
+	if err := export(filepath.Join(chip, "export"), channel, dir); err != nil {
+		return nil, err
+	}
+	p := &PWM{dir: dir, period: period, Max: 100}
+	if err := p.write("duty_cycle", 0); err != nil {
+		return nil, err
+	}
+	if err := p.write("period", period.Nanoseconds()); err != nil {
+		return nil, err
+	}
+	if err := p.write("enable", 1); err != nil {
+		return nil, err
+	}
+
+	return p, nil
+}
+
+// Write implements pidpool.Sink. The output is clamped to [0, Max].
+func (p *PWM) Write(_ context.Context, output float64) error {
+	duty := min(max(output/p.Max, 0), 1)
+	return p.write("duty_cycle", int64(duty*float64(p.period.Nanoseconds())))
+}
+
+// Close disables the channel.
+func (p *PWM) Close() error {
+	return p.write("enable", 0)
+}
+
+func (p *PWM) write(attr string, v int64) error {
+	return writeAttr(filepath.Join(p.dir, attr), strconv.FormatInt(v, 10))
+}
+
+// GPIO is an output pin.
+type GPIO struct {
+	dir string
+}
+
+// OpenGPIO exports pin of the GPIO class directory dir, usually GPIOClass,
+// and configures it as an output driven low.
+func OpenGPIO(dir string, pin int) (*GPIO, error) {
+	pinDir := filepath.Join(dir, fmt.Sprintf("gpio%d", pin))
+	if err := export(filepath.Join(dir, "export"), pin, pinDir); err != nil {
+		return nil, err
+	}
+	// "low" sets the direction and the level at once, without a glitch.
+	if err := writeAttr(filepath.Join(pinDir, "direction"), "low"); err != nil {
+		return nil, err
+	}
+
+	return &GPIO{dir: pinDir}, nil
+}
+
+// Set implements pidpool.Switch.
+func (g *GPIO) Set(_ context.Context, on bool) error {
+	v := "0"
+	if on {
+		v = "1"
+	}
+	return writeAttr(filepath.Join(g.dir, "value"), v)
+}
+
+// export writes n to the export file unless dir already exists, and waits
+// for the kernel and udev to create dir with usable permissions.
+func export(file string, n int, dir string) error {
+	if _, err := os.Stat(dir); err == nil {
+		return nil
+	}
+	if err := writeAttr(file, strconv.Itoa(n)); err != nil {
+		return err
+	}
+	for i := 0; i < 50; i++ {
+		if _, err := os.Stat(dir); err == nil {
+			return nil
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	return fmt.Errorf("%s did not appear after export", dir)
+}
+
+func writeAttr(path, v string) error {
+	return os.WriteFile(path, []byte(v), 0)
+}
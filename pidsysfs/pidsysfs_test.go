@@ -0,0 +1,87 @@
+package pidsysfs_test
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/ankur-anand/go-pidpool/pidsysfs"
+)
+
+func writeFile(t *testing.T, path, content string) {
+	t.Helper()
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		t.Fatalf("mkdir err: %v", err)
+	}
+	if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+		t.Fatalf("write err: %v", err)
+	}
+}
+
+func readFile(t *testing.T, path string) string {
+	t.Helper()
+	b, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("read err: %v", err)
+	}
+	return string(b)
+}
+
+func TestDS18B20(t *testing.T) {
+	dir := t.TempDir()
+	writeFile(t, filepath.Join(dir, "28-0316a2795fff", "w1_slave"),
+		"72 01 4b 46 7f ff 0e 10 57 : crc=57 YES\n72 01 4b 46 7f ff 0e 10 57 t=23125\n")
+	writeFile(t, filepath.Join(dir, "28-bad", "w1_slave"),
+		"72 01 4b 46 7f ff 0e 10 57 : crc=58 NO\n72 01 4b 46 7f ff 0e 10 57 t=23125\n")
+
+	paths, err := pidsysfs.FindDS18B20(dir)
+	if err != nil || len(paths) != 2 {
+		t.Fatalf("FindDS18B20: %v (%v)", paths, err)
+	}
+	if v, err := (pidsysfs.DS18B20{Path: paths[0]}).Read(context.Background()); err != nil || v != 23.125 {
+		t.Fatalf("expected 23.125, got %v (%v)", v, err)
+	}
+	if _, err := (pidsysfs.DS18B20{Path: paths[1]}).Read(context.Background()); !errors.Is(err, pidsysfs.ErrCRC) {
+		t.Fatalf("expected ErrCRC, got %v", err)
+	}
+}
+
+func TestPWMAndGPIO(t *testing.T) {
+	chip := t.TempDir()
+	for _, a := range []string{"period", "duty_cycle", "enable"} {
+		writeFile(t, filepath.Join(chip, "pwm1", a), "0")
+	}
+	p, err := pidsysfs.OpenPWM(chip, 1, time.Millisecond)
+	if err != nil {
+		t.Fatalf("OpenPWM err: %v", err)
+	}
+	if err := p.Write(context.Background(), 40); err != nil {
+		t.Fatalf("Write err: %v", err)
+	}
+	if got := readFile(t, filepath.Join(chip, "pwm1", "duty_cycle")); got != "400000" {
+		t.Fatalf("unexpected duty cycle %q", got)
+	}
+	if got := readFile(t, filepath.Join(chip, "pwm1", "period")); got != "1000000" {
+		t.Fatalf("unexpected period %q", got)
+	}
+	if err := p.Close(); err != nil || readFile(t, filepath.Join(chip, "pwm1", "enable")) != "0" {
+		t.Fatalf("Close did not disable: %v", err)
+	}
+
+	gpio := t.TempDir()
+	writeFile(t, filepath.Join(gpio, "gpio17", "direction"), "in")
+	writeFile(t, filepath.Join(gpio, "gpio17", "value"), "0")
+	g, err := pidsysfs.OpenGPIO(gpio, 17)
+	if err != nil {
+		t.Fatalf("OpenGPIO err: %v", err)
+	}
+	if err := g.Set(context.Background(), true); err != nil || readFile(t, filepath.Join(gpio, "gpio17", "value")) != "1" {
+		t.Fatalf("Set did not drive the pin: %v", err)
+	}
+	if readFile(t, filepath.Join(gpio, "gpio17", "direction")) != "low" {
+		t.Fatalf("direction not set")
+	}
+}
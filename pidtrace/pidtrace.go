@@ -0,0 +1,148 @@
+// Package pidtrace replays recorded measurements through a controller and
+// exports the resulting control trace for plotting in external tools.
+//
+// A typical offline tuning session reads a production log, replays it with
+// candidate gains and compares the traces:
+//
+//	samples, _ := pidtrace.ReadCSV(logFile)
+//	pid := pidpool.NewPID(2, 0.5, 0, 0)
+//	pid.SetSetPoint(180)
+//	trace, _ := pidtrace.Replay(pid.State(), samples)
+//	pidtrace.WriteCSV(out, trace)
+package pidtrace
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/ankur-anand/go-pidpool"
+)
+
+// Point is one step of a control trace.
+type Point struct {
+	Time        time.Time `json:"time"`
+	SetPoint    float64   `json:"setPoint"`
+	Value       float64   `json:"value"`
+	Error       float64   `json:"error"`
+	Output      float64   `json:"output"`
+	P           float64   `json:"p"`
+	I           float64   `json:"i"`
+	D           float64   `json:"d"`
+	FeedForward float64   `json:"feedForward"`
+}
+
+// FromEvent returns the trace point of an update event.
+func FromEvent(ev pidpool.UpdateEvent) Point {
+	return Point{
+		Time:        ev.Time,
+		SetPoint:    ev.SetPoint,
+		Value:       ev.Value,
+		Error:       ev.Error,
+		Output:      ev.Output,
+		P:           ev.P,
+		I:           ev.I,
+		D:           ev.D,
+		FeedForward: ev.FeedForward,
+	}
+}
+
+// ReadCSV reads (timestamp, value) records. The timestamp is either
+// RFC 3339 or Unix seconds, possibly fractional. A first record whose value
+// is not a number is taken as a header and skipped; further columns are
+// ignored.
+func ReadCSV(r io.Reader) ([]pidpool.Sample, error) {
+	cr := csv.NewReader(r)
+	cr.FieldsPerRecord = -1
+	cr.TrimLeadingSpace = true
+
+	var samples []pidpool.Sample
+	for line := 1; ; line++ {
+		rec, err := cr.Read()
+		if errors.Is(err, io.EOF) {
+			return samples, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+		if len(rec) < 2 {
+			return nil, fmt.Errorf("line %d: expected timestamp and value", line)
+		}
+		v, err := strconv.ParseFloat(strings.TrimSpace(rec[1]), 64)
+		if err != nil {
+			if line == 1 {
+				continue
+			}
+			return nil, fmt.Errorf("line %d: %w", line, err)
+		}
+		t, err := parseTime(strings.TrimSpace(rec[0]))
+		if err != nil {
+			return nil, fmt.Errorf("line %d: %w", line, err)
+		}
+		samples = append(samples, pidpool.Sample{Value: v, Time: t})
+	}
+}
+
+func parseTime(s string) (time.Time, error) {
+	if secs, err := strconv.ParseFloat(s, 64); err == nil {
+		whole, frac := math.Modf(secs)
+		return time.Unix(int64(whole), int64(math.Round(frac*1e9))).UTC(), nil
+	}
+	return time.Parse(time.RFC3339Nano, s)
+}
+
+// Replay runs the samples through a controller restored from st and
+// returns the trace. dt is derived from the sample timestamps; st.LastUpdate
+// is ignored, so the first sample is applied with dt 0.
+func Replay(st pidpool.State, samples []pidpool.Sample) ([]Point, error) {
+	st.LastUpdate = time.Time{}
+	pid := pidpool.NewPID(0, 0, 0, 0)
+	if err := pid.RestoreState(st); err != nil {
+		return nil, err
+	}
+
+	trace := make([]Point, 0, len(samples))
+	pid.OnUpdateWith(func(ev pidpool.UpdateEvent) {
+		trace = append(trace, FromEvent(ev))
+	}, pidpool.HookOptions{Name: "pidtrace"})
+	for _, s := range samples {
+		pid.UpdateAt(s.Value, s.Time)
+	}
+
+	return trace, nil
+}
+
+var csvHeader = []string{"time", "setpoint", "value", "error", "output", "p", "i", "d", "feedforward"}
+
+// WriteCSV writes the trace as CSV with a header row. Times are RFC 3339.
+func WriteCSV(w io.Writer, trace []Point) error {
+	cw := csv.NewWriter(w)
+	if err := cw.Write(csvHeader); err != nil {
+		return err
+	}
+	f := func(v float64) string { return strconv.FormatFloat(v, 'g', -1, 64) }
+	for _, p := range trace {
+		rec := []string{
+			p.Time.Format(time.RFC3339Nano),
+			f(p.SetPoint), f(p.Value), f(p.Error), f(p.Output),
+			f(p.P), f(p.I), f(p.D), f(p.FeedForward),
+		}
+		if err := cw.Write(rec); err != nil {
+			return err
+		}
+	}
+	cw.Flush()
+
+	return cw.Error()
+}
+
+// WriteJSON writes the trace as a JSON array.
+func WriteJSON(w io.Writer, trace []Point) error {
+	return json.NewEncoder(w).Encode(trace)
+}
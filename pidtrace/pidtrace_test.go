@@ -0,0 +1,63 @@
+package pidtrace_test
+
+import (
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/ankur-anand/go-pidpool"
+	"github.com/ankur-anand/go-pidpool/pidtrace"
+)
+
+const log = `timestamp,pv
+1700000000,20
+1700000000.5,21
+2023-11-14T22:13:21Z,23
+`
+
+func TestReplayAndExport(t *testing.T) {
+	samples, err := pidtrace.ReadCSV(strings.NewReader(log))
+	if err != nil {
+		t.Fatalf("ReadCSV err: %v", err)
+	}
+	if len(samples) != 3 || samples[1].Time.Sub(samples[0].Time) != 500*time.Millisecond ||
+		samples[2].Time.Sub(samples[0].Time) != time.Second {
+		t.Fatalf("unexpected samples %+v", samples)
+	}
+
+	pid := pidpool.NewPI(2, 1)
+	pid.SetSetPoint(25)
+	trace, err := pidtrace.Replay(pid.State(), samples)
+	if err != nil || len(trace) != 3 {
+		t.Fatalf("unexpected trace length %d", len(trace))
+	}
+	// the second sample integrates error 4 over 0.5s.
+	if p := trace[1]; p.Value != 21 || p.P != 8 || p.I != 2 || p.Output != 10 {
+		t.Fatalf("unexpected point %+v", p)
+	}
+
+	var buf bytes.Buffer
+	if err := pidtrace.WriteCSV(&buf, trace); err != nil {
+		t.Fatalf("WriteCSV err: %v", err)
+	}
+	recs, err := csv.NewReader(&buf).ReadAll()
+	if err != nil || len(recs) != 4 || recs[0][4] != "output" || recs[2][4] != "10" {
+		t.Fatalf("unexpected CSV %v (%v)", recs, err)
+	}
+
+	buf.Reset()
+	if err := pidtrace.WriteJSON(&buf, trace); err != nil {
+		t.Fatalf("WriteJSON err: %v", err)
+	}
+	var back []pidtrace.Point
+	if err := json.Unmarshal(buf.Bytes(), &back); err != nil || back[1] != trace[1] {
+		t.Fatalf("JSON round trip: %+v (%v)", back, err)
+	}
+
+	if _, err := pidtrace.ReadCSV(strings.NewReader("1,2\n3,x\n")); err == nil {
+		t.Fatalf("expected error for bad value")
+	}
+}
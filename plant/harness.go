@@ -0,0 +1,48 @@
+package plant
+
+import (
+	"errors"
+	"time"
+
+	"github.com/ankur-anand/go-pidpool"
+)
+
+// Sample is one step of a closed-loop run.
+type Sample struct {
+	// Time is the time since the start of the run.
+	Time     time.Duration
+	SetPoint float64
+	// Value is the process value the controller saw.
+	Value float64
+	// Output is the controller output applied over the next interval.
+	Output float64
+}
+
+// Run closes the loop between pid and m for the given duration, updating
+// the controller every dt, and returns one sample per update. The run is
+// fully simulated, it does not sleep, and starts from m's current state.
+func Run(pid *pidpool.PID, m Model, duration, dt time.Duration) ([]Sample, error) {
+	if dt <= 0 {
+		return nil, errors.New("dt must be positive")
+	}
+	if duration < 0 {
+		return nil, errors.New("duration must not be negative")
+	}
+
+	h := dt.Seconds()
+	n := int(duration / dt)
+	samples := make([]Sample, 0, n)
+	y := m.Step(0, 0)
+	for i := 0; i < n; i++ {
+		u := pid.UpdateDuration(y, h)
+		samples = append(samples, Sample{
+			Time:     time.Duration(i) * dt,
+			SetPoint: pid.GetSetPoint(),
+			Value:    y,
+			Output:   u,
+		})
+		y = m.Step(u, h)
+	}
+
+	return samples, nil
+}
@@ -0,0 +1,193 @@
+// Package plant provides standard process models for testing and tuning
+// controllers offline.
+//
+// Every model starts at rest, with zero input and output, and is advanced
+// by Step, which applies the input u for dt seconds and returns the process
+// value at the end of the interval; a Step with dt zero reads the process
+// value without advancing it. Time constants and dead times are in seconds.
+package plant
+
+import (
+	"math"
+	"math/rand"
+
+	"github.com/ankur-anand/go-pidpool"
+)
+
+// Model is a simulated process.
+type Model interface {
+	Step(u, dt float64) float64
+	Reset()
+}
+
+// FirstOrder is the process Gain / (TimeConstant*s + 1).
+type FirstOrder struct {
+	Gain         float64
+	TimeConstant float64
+
+	y float64
+}
+
+// Step implements Model. The model is discretized exactly for an input held
+// over dt.
+func (m *FirstOrder) Step(u, dt float64) float64 {
+	m.y = firstOrder(m.y, m.Gain*u, m.TimeConstant, dt)
+	return m.y
+}
+
+// Reset implements Model.
+func (m *FirstOrder) Reset() { m.y = 0 }
+
+func firstOrder(y, target, tau, dt float64) float64 {
+	if tau <= 0 {
+		return target
+	}
+	return target + (y-target)*math.Exp(-dt/tau)
+}
+
+// FOPDT is the process Gain * exp(-DeadTime*s) / (TimeConstant*s + 1).
+type FOPDT struct {
+	Gain         float64
+	TimeConstant float64
+	DeadTime     float64
+
+	y     float64
+	delay delayLine
+}
+
+// NewFOPDT returns a plant for an identified model, e.g. the result of
+// pidpool.IdentifyFOPDT.
+func NewFOPDT(m pidpool.FOPDT) *FOPDT {
+	return &FOPDT{Gain: m.Gain, TimeConstant: m.TimeConstant, DeadTime: m.DeadTime}
+}
+
+// Step implements Model.
+func (m *FOPDT) Step(u, dt float64) float64 {
+	u = m.delay.step(u, dt, m.DeadTime)
+	m.y = firstOrder(m.y, m.Gain*u, m.TimeConstant, dt)
+	return m.y
+}
+
+// Reset implements Model.
+func (m *FOPDT) Reset() {
+	m.y = 0
+	m.delay = delayLine{}
+}
+
+// SecondOrder is the process
+//
+//	Gain * w² / (s² + 2*Damping*w*s + w²)
+//
+// with natural frequency w = NaturalFrequency in rad/s. A Damping below 1
+// gives an underdamped, oscillating step response.
+type SecondOrder struct {
+	Gain             float64
+	NaturalFrequency float64
+	Damping          float64
+
+	y, dy float64
+}
+
+// maxSubstep bounds w*h of the internal integration steps.
+const maxSubstep = 0.01
+
+// Step implements Model. The model is integrated with semi-implicit Euler
+// substeps short enough to keep it stable and accurate.
+func (m *SecondOrder) Step(u, dt float64) float64 {
+	w := m.NaturalFrequency
+	n := int(math.Ceil(w * dt / maxSubstep))
+	if n < 1 {
+		n = 1
+	}
+	h := dt / float64(n)
+	for i := 0; i < n; i++ {
+		m.dy += h * (w*w*(m.Gain*u-m.y) - 2*m.Damping*w*m.dy)
+		m.y += h * m.dy
+	}
+	return m.y
+}
+
+// Reset implements Model.
+func (m *SecondOrder) Reset() { m.y, m.dy = 0, 0 }
+
+// IntegratorDeadTime is the process Gain * exp(-DeadTime*s) / s, e.g. a
+// tank level driven by a net inflow. It has no steady state for a non-zero
+// input.
+type IntegratorDeadTime struct {
+	Gain     float64
+	DeadTime float64
+
+	y     float64
+	delay delayLine
+}
+
+// Step implements Model.
+func (m *IntegratorDeadTime) Step(u, dt float64) float64 {
+	u = m.delay.step(u, dt, m.DeadTime)
+	m.y += m.Gain * u * dt
+	return m.y
+}
+
+// Reset implements Model.
+func (m *IntegratorDeadTime) Reset() {
+	m.y = 0
+	m.delay = delayLine{}
+}
+
+// Noisy adds white Gaussian measurement noise with standard deviation
+// StdDev to the output of Model. The noise does not affect the process
+// itself. Rand defaults to a source seeded with 1, so runs are repeatable.
+type Noisy struct {
+	Model  Model
+	StdDev float64
+	Rand   *rand.Rand
+}
+
+// Step implements Model.
+func (m *Noisy) Step(u, dt float64) float64 {
+	if m.Rand == nil {
+		m.Rand = rand.New(rand.NewSource(1))
+	}
+	return m.Model.Step(u, dt) + m.StdDev*m.Rand.NormFloat64()
+}
+
+// Reset implements Model. The noise sequence continues.
+func (m *Noisy) Reset() { m.Model.Reset() }
+
+// delayLine delays an input by a dead time. Before the first input has
+// travelled through, it returns zero.
+type delayLine struct {
+	now     float64
+	pending []timedInput
+}
+
+type timedInput struct {
+	at float64
+	u  float64
+}
+
+// step records u, applied from now on, advances dt seconds and returns the
+// input that reaches the process over that interval. Arrivals are rounded
+// to the nearest interval.
+func (d *delayLine) step(u, dt, delay float64) float64 {
+	if delay <= 0 {
+		return u
+	}
+	d.pending = append(d.pending, timedInput{at: d.now + delay, u: u})
+	mid := d.now + dt/2
+	d.now += dt
+
+	out := 0.0
+	i := 0
+	for ; i < len(d.pending) && d.pending[i].at <= mid; i++ {
+		out = d.pending[i].u
+	}
+	if i == 0 {
+		return 0
+	}
+	// keep the input in effect so it holds until the next one arrives.
+	d.pending = append(d.pending[:0], d.pending[i-1:]...)
+	d.pending[0].at = math.Inf(-1)
+
+	return out
+}
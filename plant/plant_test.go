@@ -0,0 +1,57 @@
+package plant_test
+
+import (
+	"math"
+	"testing"
+	"time"
+
+	"github.com/ankur-anand/go-pidpool"
+	"github.com/ankur-anand/go-pidpool/plant"
+)
+
+func TestModels_StepResponse(t *testing.T) {
+	for name, tc := range map[string]struct {
+		m    plant.Model
+		at   int // steps of 0.1 s
+		want float64
+	}{
+		"first-order one tau":  {&plant.FirstOrder{Gain: 2, TimeConstant: 1}, 10, 2 * (1 - math.Exp(-1))},
+		"fopdt before delay":   {&plant.FOPDT{Gain: 2, TimeConstant: 1, DeadTime: 0.5}, 5, 0},
+		"fopdt after delay":    {&plant.FOPDT{Gain: 2, TimeConstant: 1, DeadTime: 0.5}, 15, 2 * (1 - math.Exp(-1))},
+		"second-order settled": {&plant.SecondOrder{Gain: 3, NaturalFrequency: 2, Damping: 0.7}, 100, 3},
+		"integrator ramp":      {&plant.IntegratorDeadTime{Gain: 0.5, DeadTime: 1}, 30, 1},
+	} {
+		y := 0.0
+		for i := 0; i < tc.at; i++ {
+			y = tc.m.Step(1, 0.1)
+		}
+		if math.Abs(y-tc.want) > 1e-3 {
+			t.Fatalf("%s: got %v, want %v", name, y, tc.want)
+		}
+		tc.m.Reset()
+		if y := tc.m.Step(0, 0); y != 0 {
+			t.Fatalf("%s: Reset left %v", name, y)
+		}
+	}
+}
+
+func TestRun_ClosedLoop(t *testing.T) {
+	m := &plant.Noisy{Model: &plant.FOPDT{Gain: 2, TimeConstant: 5, DeadTime: 1}, StdDev: 0.01}
+	kp, ki, kd, err := pidpool.FOPDT{Gain: 2, TimeConstant: 5, DeadTime: 1}.SIMC(0)
+	if err != nil {
+		t.Fatalf("SIMC err: %v", err)
+	}
+	pid := pidpool.NewPID(kp, ki, kd, 0)
+	pid.SetSetPoint(10)
+
+	samples, err := plant.Run(pid, m, 60*time.Second, 100*time.Millisecond)
+	if err != nil {
+		t.Fatalf("Run err: %v", err)
+	}
+	if len(samples) != 600 || samples[599].Time != 59900*time.Millisecond {
+		t.Fatalf("unexpected samples: %d", len(samples))
+	}
+	if last := samples[len(samples)-1]; math.Abs(last.Value-10) > 0.1 {
+		t.Fatalf("loop did not settle: %+v", last)
+	}
+}
@@ -0,0 +1,241 @@
+package pidpool
+
+import (
+	"context"
+	"errors"
+	"math"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// PoolMetric selects the signal a WorkerPool holds at its target.
+type PoolMetric int
+
+const (
+	// PoolQueueLatency is the mean time, in seconds, tasks waited in the
+	// queue during the last interval.
+	PoolQueueLatency PoolMetric = iota
+	// PoolQueueDepth is the number of queued tasks.
+	PoolQueueDepth
+)
+
+// ErrPoolClosed is returned by Submit after Close.
+var ErrPoolClosed = errors.New("worker pool closed")
+
+// WorkerPoolConfig configures a WorkerPool.
+type WorkerPoolConfig struct {
+	// PID computes the worker count. More workers lower the metric, so the
+	// gains are negative. The pool governs it over [MinWorkers,
+	// MaxWorkers], see PID.Govern.
+	PID    *PID
+	Metric PoolMetric
+	Target float64
+
+	MinWorkers int
+	MaxWorkers int
+	// QueueSize is the capacity of the task queue. Defaults to 1024.
+	QueueSize int
+	// Interval is the control period. Defaults to one second.
+	Interval time.Duration
+	// Cooldown is the minimum time between two changes of the worker count.
+	Cooldown time.Duration
+}
+
+// WorkerPool runs submitted tasks on a set of workers whose size is
+// governed by a PID loop tracking queue latency or queue depth. Workers
+// retired by a scale-down finish their current task before they exit.
+type WorkerPool struct {
+	cfg   WorkerPoolConfig
+	queue chan queuedTask
+	// retire carries one token per worker that should exit.
+	retire chan struct{}
+
+	mu        sync.RWMutex
+	closed    bool
+	target    int
+	lastScale time.Time
+	// the wait statistics are updated by the workers without mu.
+	waitSum atomic.Int64
+	waitN   atomic.Int64
+
+	// submits counts the Submit calls between their closed check and
+	// their send, which Close waits for before it closes the queue.
+	submits sync.WaitGroup
+	workers sync.WaitGroup
+	stop    chan struct{}
+	done    chan struct{}
+}
+
+type queuedTask struct {
+	fn     func()
+	queued time.Time
+}
+
+// NewWorkerPool starts a pool with MinWorkers workers and its control loop.
+func NewWorkerPool(cfg WorkerPoolConfig) (*WorkerPool, error) {
+	if cfg.PID == nil {
+		return nil, errors.New("pid is required")
+	}
+	if cfg.MinWorkers < 1 || cfg.MaxWorkers < cfg.MinWorkers {
+		return nil, errors.New("workers must satisfy 1 <= min <= max")
+	}
+	if cfg.QueueSize < 0 || cfg.Interval < 0 || cfg.Cooldown < 0 {
+		return nil, errors.New("queue size, interval and cooldown must not be negative")
+	}
+	if cfg.QueueSize == 0 {
+		cfg.QueueSize = 1024
+	}
+	if cfg.Interval == 0 {
+		cfg.Interval = time.Second
+	}
+	if err := cfg.PID.Govern(cfg.Target, float64(cfg.MinWorkers), float64(cfg.MaxWorkers)); err != nil {
+		return nil, err
+	}
+
+	p := &WorkerPool{
+		cfg:    cfg,
+		queue:  make(chan queuedTask, cfg.QueueSize),
+		retire: make(chan struct{}, cfg.MaxWorkers),
+		stop:   make(chan struct{}),
+		done:   make(chan struct{}),
+	}
+	p.mu.Lock()
+	p.scaleLocked(cfg.MinWorkers)
+	p.mu.Unlock()
+	go p.loop()
+
+	return p, nil
+}
+
+// Submit queues fn, blocking while the queue is full. It returns
+// ErrPoolClosed if the pool is closed while it waits.
+func (p *WorkerPool) Submit(ctx context.Context, fn func()) error {
+	p.mu.RLock()
+	if p.closed {
+		p.mu.RUnlock()
+		return ErrPoolClosed
+	}
+	p.submits.Add(1)
+	p.mu.RUnlock()
+	defer p.submits.Done()
+
+	select {
+	case p.queue <- queuedTask{fn: fn, queued: time.Now()}:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-p.stop:
+		return ErrPoolClosed
+	}
+}
+
+// Workers returns the current target number of workers.
+func (p *WorkerPool) Workers() int {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+	return p.target
+}
+
+// QueueLen returns the number of queued tasks.
+func (p *WorkerPool) QueueLen() int {
+	return len(p.queue)
+}
+
+// Close stops accepting tasks, waits for the queued tasks to run and for
+// all workers to exit.
+func (p *WorkerPool) Close() {
+	p.mu.Lock()
+	if p.closed {
+		p.mu.Unlock()
+		return
+	}
+	p.closed = true
+	close(p.stop)
+	p.mu.Unlock()
+
+	// blocked submitters return on stop; none start after closed is set.
+	p.submits.Wait()
+	close(p.queue)
+	<-p.done
+	p.workers.Wait()
+}
+
+// scaleLocked moves the worker count to n. Surplus workers are retired
+// through tokens, so a busy worker finishes its task first.
+func (p *WorkerPool) scaleLocked(n int) {
+	for ; p.target < n; p.target++ {
+		select {
+		case <-p.retire:
+			// a pending retirement is cancelled instead.
+		default:
+			p.workers.Add(1)
+			go p.work()
+		}
+	}
+	for ; p.target > n; p.target-- {
+		p.retire <- struct{}{}
+	}
+}
+
+func (p *WorkerPool) work() {
+	defer p.workers.Done()
+	for {
+		select {
+		case <-p.retire:
+			return
+		case t, ok := <-p.queue:
+			if !ok {
+				return
+			}
+			p.waitSum.Add(int64(time.Since(t.queued)))
+			p.waitN.Add(1)
+			t.fn()
+		}
+	}
+}
+
+func (p *WorkerPool) loop() {
+	defer close(p.done)
+	t := time.NewTicker(p.cfg.Interval)
+	defer t.Stop()
+	last := time.Now()
+	for {
+		select {
+		case <-p.stop:
+			return
+		case now := <-t.C:
+			p.control(now, now.Sub(last))
+			last = now
+		}
+	}
+}
+
+// control runs one control step.
+func (p *WorkerPool) control(now time.Time, dt time.Duration) {
+	waitN := p.waitN.Swap(0)
+	waitSum := time.Duration(p.waitSum.Swap(0))
+	metric := float64(len(p.queue))
+	if p.cfg.Metric == PoolQueueLatency {
+		switch {
+		case waitN > 0:
+			metric = (waitSum / time.Duration(waitN)).Seconds()
+		case len(p.queue) > 0:
+			// nothing was dequeued: the head has waited at least this long.
+			metric = dt.Seconds()
+		default:
+			metric = 0
+		}
+	}
+
+	out := p.cfg.PID.UpdateDuration(metric, dt.Seconds())
+	n := int(math.Round(out))
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.closed || n == p.target || now.Sub(p.lastScale) < p.cfg.Cooldown {
+		return
+	}
+	p.scaleLocked(n)
+	p.lastScale = now
+}
@@ -0,0 +1,146 @@
+package pidpool_test
+
+import (
+	"context"
+	"errors"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/ankur-anand/go-pidpool"
+)
+
+func TestWorkerPool_ScalesWithQueueDepth(t *testing.T) {
+	p, err := pidpool.NewWorkerPool(pidpool.WorkerPoolConfig{
+		PID:        pidpool.NewPI(-0.5, -20),
+		Metric:     pidpool.PoolQueueDepth,
+		Target:     2,
+		MinWorkers: 1,
+		MaxWorkers: 16,
+		Interval:   5 * time.Millisecond,
+	})
+	if err != nil {
+		t.Fatalf("NewWorkerPool err: %v", err)
+	}
+
+	var ran atomic.Int32
+	peak := 0
+	for i := 0; i < 300; i++ {
+		if err := p.Submit(context.Background(), func() {
+			time.Sleep(2 * time.Millisecond)
+			ran.Add(1)
+		}); err != nil {
+			t.Fatalf("Submit err: %v", err)
+		}
+		peak = max(peak, p.Workers())
+	}
+	for p.QueueLen() > 0 {
+		peak = max(peak, p.Workers())
+		time.Sleep(time.Millisecond)
+	}
+	if peak <= 1 {
+		t.Fatalf("pool did not scale up")
+	}
+
+	// an empty queue scales the pool back down.
+	deadline := time.Now().Add(5 * time.Second)
+	for p.Workers() > 1 && time.Now().Before(deadline) {
+		time.Sleep(5 * time.Millisecond)
+	}
+	if n := p.Workers(); n != 1 {
+		t.Fatalf("pool did not scale down: %d workers", n)
+	}
+
+	p.Close()
+	if n := ran.Load(); n != 300 {
+		t.Fatalf("expected all tasks to run, got %d", n)
+	}
+	if err := p.Submit(context.Background(), func() {}); err != pidpool.ErrPoolClosed {
+		t.Fatalf("expected ErrPoolClosed, got %v", err)
+	}
+}
+
+func TestWorkerPool_ConcurrentSubmittersOnFullQueue(t *testing.T) {
+	p, err := pidpool.NewWorkerPool(pidpool.WorkerPoolConfig{
+		PID:        pidpool.NewPI(-0.5, -1),
+		Metric:     pidpool.PoolQueueLatency,
+		MinWorkers: 1,
+		MaxWorkers: 1,
+		QueueSize:  1,
+		Interval:   time.Millisecond,
+	})
+	if err != nil {
+		t.Fatalf("NewWorkerPool err: %v", err)
+	}
+	defer p.Close()
+
+	// a deadlocked pool fails the submits instead of hanging the test.
+	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+	defer cancel()
+	var ran atomic.Int32
+	errs := make(chan error, 8)
+	for i := 0; i < 8; i++ {
+		go func() {
+			errs <- p.Submit(ctx, func() {
+				time.Sleep(5 * time.Millisecond)
+				ran.Add(1)
+			})
+		}()
+	}
+	for i := 0; i < 8; i++ {
+		if err := <-errs; err != nil {
+			t.Fatalf("Submit err: %v", err)
+		}
+	}
+
+	deadline := time.Now().Add(2 * time.Second)
+	for ran.Load() < 8 && time.Now().Before(deadline) {
+		time.Sleep(time.Millisecond)
+	}
+	if n := ran.Load(); n != 8 {
+		t.Fatalf("expected all 8 tasks to run, got %d", n)
+	}
+}
+
+func TestWorkerPool_ScalesUpWhileSubmittersBlock(t *testing.T) {
+	p, err := pidpool.NewWorkerPool(pidpool.WorkerPoolConfig{
+		PID:        pidpool.NewP(-10),
+		Metric:     pidpool.PoolQueueDepth,
+		MinWorkers: 1,
+		MaxWorkers: 4,
+		QueueSize:  1,
+		Interval:   time.Millisecond,
+	})
+	if err != nil {
+		t.Fatalf("NewWorkerPool err: %v", err)
+	}
+
+	// every task blocks until released, so only a scale-up drains the
+	// queue behind the submitters.
+	release := make(chan struct{})
+	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+	defer cancel()
+	errs := make(chan error, 5)
+	for i := 0; i < 5; i++ {
+		go func() { errs <- p.Submit(ctx, func() { <-release }) }()
+	}
+	for i := 0; i < 3; i++ {
+		if err := <-errs; err != nil {
+			t.Fatalf("Submit err: %v", err)
+		}
+	}
+	if n := p.Workers(); n < 2 {
+		t.Fatalf("expected the pool to scale up, got %d workers", n)
+	}
+
+	close(release)
+	for i := 0; i < 2; i++ {
+		if err := <-errs; err != nil {
+			t.Fatalf("Submit err: %v", err)
+		}
+	}
+	p.Close()
+	if err := p.Submit(ctx, func() {}); !errors.Is(err, pidpool.ErrPoolClosed) {
+		t.Fatalf("expected ErrPoolClosed, got %v", err)
+	}
+}
@@ -0,0 +1,156 @@
+package pidpool
+
+import (
+	"errors"
+	"runtime/metrics"
+	"sync"
+	"time"
+)
+
+// LoopCost is the sampled cost of a controller's updates.
+type LoopCost struct {
+	// Updates is the number of updates since profiling was enabled.
+	Updates uint64
+	// Samples is the number of updates that were measured.
+	Samples uint64
+	// UpdateTime is the mean time spent in a sampled update, including
+	// filters and synchronous hooks. The update path never blocks, so this
+	// tracks the CPU time of the loop.
+	UpdateTime time.Duration
+	// AllocBytes is the mean number of heap bytes allocated during a
+	// sampled update. Allocation counters are process wide, so concurrent
+	// goroutines can inflate individual samples; treat it as an estimate.
+	AllocBytes float64
+}
+
+type profiler struct {
+	every   uint64
+	updates uint64 // guarded by the controller lock.
+
+	mu         sync.Mutex
+	samples    uint64
+	totalTime  time.Duration
+	totalAlloc uint64
+}
+
+type profileSample struct {
+	p     *profiler
+	start time.Time
+	alloc uint64
+}
+
+const allocMetric = "/gc/heap/allocs:bytes"
+
+func heapAllocs() uint64 {
+	s := [1]metrics.Sample{{Name: allocMetric}}
+	metrics.Read(s[:])
+	if s[0].Value.Kind() != metrics.KindUint64 {
+		return 0
+	}
+	return s[0].Value.Uint64()
+}
+
+// begin must be called with the controller lock held.
+func (p *profiler) begin() profileSample {
+	if p == nil {
+		return profileSample{}
+	}
+	p.updates++
+	if (p.updates-1)%p.every != 0 {
+		return profileSample{}
+	}
+
+	return profileSample{p: p, alloc: heapAllocs(), start: time.Now()}
+}
+
+func (s profileSample) end() {
+	if s.p == nil {
+		return
+	}
+	elapsed := time.Since(s.start)
+	alloc := heapAllocs() - s.alloc
+
+	s.p.mu.Lock()
+	defer s.p.mu.Unlock()
+	s.p.samples++
+	s.p.totalTime += elapsed
+	s.p.totalAlloc += alloc
+}
+
+// SetProfiling measures the time and allocations of every Nth update.
+// Passing 0 disables profiling and discards the collected samples.
+func (pid *PID) SetProfiling(every int) error {
+	if every < 0 {
+		return errors.New("profiling interval must not be negative")
+	}
+	pid.mu.Lock()
+	defer pid.mu.Unlock()
+	if every == 0 {
+		pid.profile = nil
+		return nil
+	}
+	pid.profile = &profiler{every: uint64(every)}
+
+	return nil
+}
+
+// Cost returns the sampled update cost. The boolean is false when
+// profiling is not enabled.
+func (pid *PID) Cost() (LoopCost, bool) {
+	pid.mu.Lock()
+	p := pid.profile
+	if p == nil {
+		pid.mu.Unlock()
+		return LoopCost{}, false
+	}
+	c := LoopCost{Updates: p.updates}
+	pid.mu.Unlock()
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	c.Samples = p.samples
+	if p.samples > 0 {
+		c.UpdateTime = p.totalTime / time.Duration(p.samples)
+		c.AllocBytes = float64(p.totalAlloc) / float64(p.samples)
+	}
+
+	return c, true
+}
+
+// EnableProfiling turns on sampled cost profiling (see PID.SetProfiling)
+// for every current and future controller of the manager.
+func (m *Manager) EnableProfiling(every int) error {
+	if every < 0 {
+		return errors.New("profiling interval must not be negative")
+	}
+	m.mu.Lock()
+	m.profileEvery = every
+	pids := make([]*PID, 0, len(m.pids))
+	for _, pid := range m.pids {
+		pids = append(pids, pid)
+	}
+	m.mu.Unlock()
+
+	for _, pid := range pids {
+		if err := pid.SetProfiling(every); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// Costs returns the sampled cost of every profiled controller by key, so
+// the expensive loops among hundreds can be found.
+func (m *Manager) Costs() map[string]LoopCost {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	costs := make(map[string]LoopCost, len(m.pids))
+	for k, pid := range m.pids {
+		if c, ok := pid.Cost(); ok {
+			costs[k] = c
+		}
+	}
+
+	return costs
+}
@@ -0,0 +1,38 @@
+package pidpool_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/ankur-anand/go-pidpool"
+)
+
+func TestManager_CostsFindExpensiveLoop(t *testing.T) {
+	m := pidpool.NewManager(newTenantPID)
+	m.Get("cheap")
+	if err := m.EnableProfiling(2); err != nil {
+		t.Fatalf("EnableProfiling err: %v", err)
+	}
+	m.Get("slow").OnUpdate(func(pidpool.UpdateEvent) { time.Sleep(2 * time.Millisecond) })
+
+	for i := 0; i < 10; i++ {
+		m.Get("cheap").UpdateDuration(1, 1)
+		m.Get("slow").UpdateDuration(1, 1)
+	}
+
+	costs := m.Costs()
+	cheap, slow := costs["cheap"], costs["slow"]
+	if cheap.Updates != 10 || cheap.Samples != 5 {
+		t.Fatalf("unexpected sampling: %+v", cheap)
+	}
+	if slow.UpdateTime < 2*time.Millisecond || slow.UpdateTime <= cheap.UpdateTime {
+		t.Fatalf("slow loop not attributed: cheap %v slow %v", cheap.UpdateTime, slow.UpdateTime)
+	}
+}
+
+func TestPID_CostDisabled(t *testing.T) {
+	p := pidpool.NewPID(1, 0, 0, 0)
+	if _, ok := p.Cost(); ok {
+		t.Fatalf("expected profiling to be disabled by default")
+	}
+}
@@ -0,0 +1,144 @@
+package pidpool
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+)
+
+// Switch is an on/off actuator, e.g. a relay or a solid state relay.
+type Switch interface {
+	Set(ctx context.Context, on bool) error
+}
+
+// SwitchFunc adapts a function to the Switch interface.
+type SwitchFunc func(ctx context.Context, on bool) error
+
+// Set implements Switch.
+func (f SwitchFunc) Set(ctx context.Context, on bool) error { return f(ctx, on) }
+
+// PWMConfig configures a PWM output.
+type PWMConfig struct {
+	Switch Switch
+	// Cycle is the period over which the output is time-proportioned.
+	Cycle time.Duration
+	// MinOn and MinOff are the shortest on and off pulses, sparing
+	// mechanical relays. A shorter pulse is skipped and carried over to
+	// later cycles, so the average duty is kept.
+	MinOn  time.Duration
+	MinOff time.Duration
+	// Max is the output that means always on; zero selects 100. An output
+	// of zero or less means always off.
+	Max float64
+}
+
+// PWM converts a continuous controller output into a time-proportioned
+// on/off signal: an output of 40% turns the switch on for 40% of every
+// cycle. It is a Sink, so a Runner can drive it, while Run switches the
+// output.
+type PWM struct {
+	cfg PWMConfig
+
+	mu    sync.Mutex
+	duty  float64
+	carry time.Duration
+}
+
+// NewPWM returns a PWM output. It starts off; call Run to drive the switch.
+func NewPWM(cfg PWMConfig) (*PWM, error) {
+	if cfg.Switch == nil {
+		return nil, errors.New("switch is required")
+	}
+	if cfg.Cycle <= 0 {
+		return nil, errors.New("cycle must be positive")
+	}
+	if cfg.MinOn < 0 || cfg.MinOff < 0 || cfg.MinOn+cfg.MinOff > cfg.Cycle {
+		return nil, errors.New("min on and off times must fit into the cycle")
+	}
+	if cfg.Max < 0 {
+		return nil, errors.New("max must not be negative")
+	}
+	if cfg.Max == 0 {
+		cfg.Max = 100
+	}
+
+	return &PWM{cfg: cfg}, nil
+}
+
+// Write implements Sink. The new duty applies from the next cycle.
+func (p *PWM) Write(_ context.Context, output float64) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.duty = clamp(output/p.cfg.Max, 0, 1)
+	return nil
+}
+
+// Duty returns the duty cycle in [0, 1].
+func (p *PWM) Duty() float64 {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return p.duty
+}
+
+// nextOn returns the on time of the next cycle.
+func (p *PWM) nextOn() time.Duration {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	cycle := p.cfg.Cycle
+	want := time.Duration(p.duty*float64(cycle)) + p.carry
+	on := min(max(want, 0), cycle)
+	switch {
+	case on < p.cfg.MinOn:
+		on = 0
+	case cycle-on < p.cfg.MinOff:
+		on = cycle
+	}
+	p.carry = want - on
+	// do not build up a debt that can never be paid back.
+	p.carry = min(max(p.carry, -cycle), cycle)
+
+	return on
+}
+
+// Run drives the switch until ctx is done or the switch fails. The switch
+// is turned off on return.
+func (p *PWM) Run(ctx context.Context) error {
+	defer func() { _ = p.cfg.Switch.Set(context.WithoutCancel(ctx), false) }()
+
+	t := time.NewTimer(0)
+	defer t.Stop()
+	<-t.C
+	wait := func(d time.Duration) bool {
+		t.Reset(d)
+		select {
+		case <-ctx.Done():
+			return false
+		case <-t.C:
+			return true
+		}
+	}
+
+	on := false
+	for {
+		onTime := p.nextOn()
+		if want := onTime > 0; want != on {
+			if err := p.cfg.Switch.Set(ctx, want); err != nil {
+				return err
+			}
+			on = want
+		}
+		if onTime > 0 && onTime < p.cfg.Cycle {
+			if !wait(onTime) {
+				return nil
+			}
+			if err := p.cfg.Switch.Set(ctx, false); err != nil {
+				return err
+			}
+			on = false
+		}
+		if !wait(p.cfg.Cycle - onTime%p.cfg.Cycle) {
+			return nil
+		}
+	}
+}
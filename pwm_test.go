@@ -0,0 +1,61 @@
+package pidpool_test
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/ankur-anand/go-pidpool"
+)
+
+func TestPWM_MinOnTimeKeepsAverage(t *testing.T) {
+	var mu sync.Mutex
+	var pulses []time.Duration
+	var onSince time.Time
+	sw := pidpool.SwitchFunc(func(_ context.Context, on bool) error {
+		mu.Lock()
+		defer mu.Unlock()
+		if on {
+			onSince = time.Now()
+		} else if !onSince.IsZero() {
+			pulses = append(pulses, time.Since(onSince))
+			onSince = time.Time{}
+		}
+		return nil
+	})
+	p, err := pidpool.NewPWM(pidpool.PWMConfig{Switch: sw, Cycle: 20 * time.Millisecond, MinOn: 8 * time.Millisecond})
+	if err != nil {
+		t.Fatalf("NewPWM err: %v", err)
+	}
+	if err := p.Write(context.Background(), 250); err != nil || p.Duty() != 1 {
+		t.Fatalf("output not clamped: %v", p.Duty())
+	}
+	// 25% of a 20ms cycle is 5ms, below the minimum on time: every other
+	// cycle gets a 10ms pulse instead.
+	_ = p.Write(context.Background(), 25)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 205*time.Millisecond)
+	defer cancel()
+	if err := p.Run(ctx); err != nil {
+		t.Fatalf("Run err: %v", err)
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+	if len(pulses) < 3 || len(pulses) > 6 {
+		t.Fatalf("expected about 5 pulses, got %v", pulses)
+	}
+	for _, d := range pulses[:len(pulses)-1] {
+		if d < 8*time.Millisecond {
+			t.Fatalf("pulse shorter than the minimum on time: %v", pulses)
+		}
+	}
+	if !onSince.IsZero() {
+		t.Fatalf("switch left on")
+	}
+
+	if _, err := pidpool.NewPWM(pidpool.PWMConfig{Switch: sw, Cycle: time.Second, MinOn: time.Second, MinOff: time.Second}); err == nil {
+		t.Fatalf("expected error for min times longer than the cycle")
+	}
+}
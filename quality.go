@@ -0,0 +1,234 @@
+package pidpool
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"time"
+)
+
+// Quality is the quality code a source reports with a measurement. The high
+// byte is the major quality, Good, Uncertain or Bad, the low byte an
+// optional sub-code, mirroring the status codes of OPC and similar
+// industrial protocols. A higher major quality is worse.
+type Quality uint16
+
+// Major qualities.
+const (
+	QualityGood      Quality = 0x000
+	QualityUncertain Quality = 0x100
+	QualityBad       Quality = 0x200
+)
+
+// Good sub-codes.
+const (
+	QualityGoodLocalOverride Quality = QualityGood | iota + 1
+)
+
+// Uncertain sub-codes.
+const (
+	QualityUncertainLastUsable Quality = QualityUncertain | iota + 1
+	QualityUncertainSensorCal
+	QualityUncertainSubNormal
+)
+
+// Bad sub-codes.
+const (
+	QualityBadConfigError Quality = QualityBad | iota + 1
+	QualityBadNotConnected
+	QualityBadDeviceFailure
+	QualityBadSensorFailure
+	QualityBadCommFailure
+	QualityBadOutOfService
+)
+
+// Major returns the major quality without the sub-code.
+func (q Quality) Major() Quality { return q &^ 0xff }
+
+// IsGood reports whether the major quality is Good.
+func (q Quality) IsGood() bool { return q.Major() == QualityGood }
+
+// IsUncertain reports whether the major quality is Uncertain.
+func (q Quality) IsUncertain() bool { return q.Major() == QualityUncertain }
+
+// IsBad reports whether the major quality is Bad or unknown.
+func (q Quality) IsBad() bool { return q.Major() >= QualityBad }
+
+var qualityNames = map[Quality]string{
+	QualityGood:                "good",
+	QualityUncertain:           "uncertain",
+	QualityBad:                 "bad",
+	QualityGoodLocalOverride:   "good/local-override",
+	QualityUncertainLastUsable: "uncertain/last-usable",
+	QualityUncertainSensorCal:  "uncertain/sensor-cal",
+	QualityUncertainSubNormal:  "uncertain/sub-normal",
+	QualityBadConfigError:      "bad/config-error",
+	QualityBadNotConnected:     "bad/not-connected",
+	QualityBadDeviceFailure:    "bad/device-failure",
+	QualityBadSensorFailure:    "bad/sensor-failure",
+	QualityBadCommFailure:      "bad/comm-failure",
+	QualityBadOutOfService:     "bad/out-of-service",
+}
+
+// String implements fmt.Stringer.
+func (q Quality) String() string {
+	if name, ok := qualityNames[q]; ok {
+		return name
+	}
+	return fmt.Sprintf("Quality(%#04x)", uint16(q))
+}
+
+// worse returns the worse of two qualities.
+func worse(a, b Quality) Quality {
+	if b.Major() > a.Major() {
+		return b
+	}
+	return a
+}
+
+// QualityAction is what a controller does with a measurement of a given
+// quality.
+type QualityAction int
+
+const (
+	// QualityUse runs the update as usual, ignoring the quality.
+	QualityUse QualityAction = iota
+	// QualityHold skips the update and holds the last output. The
+	// measurement does not enter the filter, integral or derivative.
+	QualityHold
+	// QualityFailSafe skips the update and outputs the fail-safe value.
+	QualityFailSafe
+)
+
+// QualityPolicy maps measurement quality to an action. The zero value uses
+// every measurement.
+type QualityPolicy struct {
+	Uncertain QualityAction
+	Bad       QualityAction
+	// FailSafeOutput is the output of QualityFailSafe, clamped to the
+	// output limits.
+	FailSafeOutput float64
+}
+
+// DefaultQualityPolicy uses uncertain measurements and holds the output on
+// bad ones.
+var DefaultQualityPolicy = QualityPolicy{Uncertain: QualityUse, Bad: QualityHold}
+
+func (p QualityPolicy) action(q Quality) QualityAction {
+	switch {
+	case q.IsBad():
+		return p.Bad
+	case q.IsUncertain():
+		return p.Uncertain
+	}
+	return QualityUse
+}
+
+// SetQualityPolicy sets how UpdateWithQuality treats measurements that are
+// not Good.
+func (pid *PID) SetQualityPolicy(p QualityPolicy) {
+	pid.mu.Lock()
+	defer pid.mu.Unlock()
+	pid.qualityPolicy = p
+	pid.hasQualityPolicy = true
+}
+
+// GetQualityPolicy returns the quality policy.
+func (pid *PID) GetQualityPolicy() QualityPolicy {
+	pid.mu.Lock()
+	defer pid.mu.Unlock()
+	return pid.qualityPolicyLocked()
+}
+
+func (pid *PID) qualityPolicyLocked() QualityPolicy {
+	if !pid.hasQualityPolicy {
+		return DefaultQualityPolicy
+	}
+	return pid.qualityPolicy
+}
+
+// UpdateWithQuality runs the PID calculation for a measurement of quality q
+// according to the quality policy. Uses wall time for dt. It returns the
+// output and the quality of the value that drove it: the worst quality
+// still inside the SMA window, or q itself for the other filters.
+func (pid *PID) UpdateWithQuality(value float64, q Quality) (float64, Quality) {
+	ev := pid.step(func() UpdateEvent {
+		now := time.Now()
+		dt := now.Sub(pid.lastUpdate).Seconds()
+		pid.lastUpdate = now
+
+		ev := pid.qualityStepLocked(value, q, dt)
+		ev.Time = now
+
+		return ev
+	})
+
+	return ev.Output, ev.Quality
+}
+
+func (pid *PID) qualityStepLocked(value float64, q Quality, dt float64) UpdateEvent {
+	if pid.faulted {
+		// the fault state overrides the quality policy.
+		return pid.updateInternal(value, dt)
+	}
+	policy := pid.qualityPolicyLocked()
+	switch policy.action(q) {
+	case QualityHold:
+		return UpdateEvent{
+			SetPoint:  pid.setPoint,
+			Value:     value,
+			DT:        dt,
+			RawOutput: pid.lastOutput,
+			Output:    pid.lastOutput,
+			Quality:   q,
+		}
+	case QualityFailSafe:
+		return UpdateEvent{
+			SetPoint:  pid.setPoint,
+			Value:     value,
+			DT:        dt,
+			RawOutput: policy.FailSafeOutput,
+			Output:    math.Max(pid.outputMin, math.Min(pid.outputMax, policy.FailSafeOutput)),
+			Quality:   q,
+		}
+	}
+
+	pid.sampleQuality = q
+	defer func() { pid.sampleQuality = QualityGood }()
+
+	return pid.updateInternal(value, dt)
+}
+
+// filterQualityLocked returns the quality of the filtered value given the
+// quality of the sample just fed to the filter.
+func (pid *PID) filterQualityLocked(q Quality) Quality {
+	if pid.filter.Kind != FilterSMA {
+		pid.filterQuality = nil
+		return q
+	}
+	if len(pid.filterQuality) == pid.filter.Window {
+		copy(pid.filterQuality, pid.filterQuality[1:])
+		pid.filterQuality = pid.filterQuality[:len(pid.filterQuality)-1]
+	}
+	pid.filterQuality = append(pid.filterQuality, q)
+	worst := QualityGood
+	for _, fq := range pid.filterQuality {
+		worst = worse(worst, fq)
+	}
+
+	return worst
+}
+
+// Reading is a measurement together with its quality.
+type Reading struct {
+	Value   float64
+	Quality Quality
+}
+
+// QualitySource is a Source that also reports measurement quality. A Runner
+// whose source implements it updates the controller with
+// UpdateWithQuality.
+type QualitySource interface {
+	Source
+	ReadQuality(ctx context.Context) (Reading, error)
+}
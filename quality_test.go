@@ -0,0 +1,79 @@
+package pidpool_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/ankur-anand/go-pidpool"
+)
+
+func TestQuality_Codes(t *testing.T) {
+	if !pidpool.QualityBadSensorFailure.IsBad() || pidpool.QualityBadSensorFailure.Major() != pidpool.QualityBad {
+		t.Fatalf("sub-code lost its major quality")
+	}
+	if s := pidpool.QualityUncertainLastUsable.String(); s != "uncertain/last-usable" {
+		t.Fatalf("unexpected name %q", s)
+	}
+}
+
+func TestUpdateWithQuality_Policy(t *testing.T) {
+	p := pidpool.NewP(1)
+	p.SetSetPoint(10)
+	if out, q := p.UpdateWithQuality(4, pidpool.QualityGood); out != 6 || !q.IsGood() {
+		t.Fatalf("unexpected good update %v %v", out, q)
+	}
+
+	// the default policy holds the output on bad measurements.
+	if out, q := p.UpdateWithQuality(-1000, pidpool.QualityBadCommFailure); out != 6 || q != pidpool.QualityBadCommFailure {
+		t.Fatalf("bad measurement not held: %v %v", out, q)
+	}
+
+	p.SetQualityPolicy(pidpool.QualityPolicy{Uncertain: pidpool.QualityFailSafe, Bad: pidpool.QualityHold, FailSafeOutput: -3})
+	if out, _ := p.UpdateWithQuality(5, pidpool.QualityUncertainSensorCal); out != -3 {
+		t.Fatalf("expected fail-safe output, got %v", out)
+	}
+}
+
+func TestUpdateWithQuality_SMAPropagation(t *testing.T) {
+	p := pidpool.NewP(1)
+	_ = p.SetMeasurementFilter(pidpool.MeasurementFilter{Kind: pidpool.FilterSMA, Window: 2})
+	p.SetQualityPolicy(pidpool.QualityPolicy{})
+
+	qualities := []pidpool.Quality{pidpool.QualityGood, pidpool.QualityUncertainSubNormal, pidpool.QualityGood, pidpool.QualityGood}
+	want := []pidpool.Quality{pidpool.QualityGood, pidpool.QualityUncertainSubNormal, pidpool.QualityUncertainSubNormal, pidpool.QualityGood}
+	for i, q := range qualities {
+		if _, got := p.UpdateWithQuality(1, q); got != want[i] {
+			t.Fatalf("step %d: expected %v, got %v", i, want[i], got)
+		}
+	}
+}
+
+type qualitySource struct{ r pidpool.Reading }
+
+func (s qualitySource) Read(context.Context) (float64, error) { return s.r.Value, nil }
+
+func (s qualitySource) ReadQuality(context.Context) (pidpool.Reading, error) { return s.r, nil }
+
+func TestRunner_QualitySource(t *testing.T) {
+	p := pidpool.NewP(1)
+	p.SetSetPoint(10)
+	src := qualitySource{pidpool.Reading{Value: 4, Quality: pidpool.QualityBadSensorFailure}}
+	wrote := make(chan float64, 1)
+	sink := pidpool.SinkFunc(func(_ context.Context, out float64) error {
+		select {
+		case wrote <- out:
+		default:
+		}
+		return nil
+	})
+	r := pidpool.NewRunner(p, time.Millisecond, src, sink)
+	if err := r.Start(); err != nil {
+		t.Fatalf("Start err: %v", err)
+	}
+	defer r.Stop()
+
+	if out := <-wrote; out != 0 {
+		t.Fatalf("bad reading must hold the initial output, got %v", out)
+	}
+}
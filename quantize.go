@@ -0,0 +1,109 @@
+package pidpool
+
+import (
+	"errors"
+	"math"
+)
+
+// Quantization snaps the output to the discrete positions an actuator
+// accepts, multiples of Step inside the output limits.
+type Quantization struct {
+	// Step is the distance between positions. Zero disables quantization.
+	Step float64 `json:"step"`
+	// Hysteresis widens the anti-chatter band: the output only moves to
+	// another position once the continuous output is more than
+	// Step/2 + Hysteresis away from the current one, so it does not
+	// toggle between two positions while hovering halfway.
+	Hysteresis float64 `json:"hysteresis,omitempty"`
+}
+
+// Validate reports whether the quantization is well formed.
+func (q Quantization) Validate() error {
+	if !(q.Step >= 0) || math.IsInf(q.Step, 0) {
+		return errors.New("quantization step must be finite and not negative")
+	}
+	if !(q.Hysteresis >= 0) || math.IsInf(q.Hysteresis, 0) {
+		return errors.New("quantization hysteresis must be finite and not negative")
+	}
+	return nil
+}
+
+// SetOutputQuantization makes the controller output discrete positions.
+// The quantized output feeds back into anti-windup: while it sits at the
+// highest or lowest position and the error pushes further out, the
+// integral stops accumulating, as it does at the integral limits.
+func (pid *PID) SetOutputQuantization(q Quantization) error {
+	if err := q.Validate(); err != nil {
+		return err
+	}
+	defer pid.notifyChange()
+	pid.mu.Lock()
+	defer pid.mu.Unlock()
+	pid.quantization = q
+
+	return nil
+}
+
+// GetOutputQuantization returns the output quantization.
+func (pid *PID) GetOutputQuantization() Quantization {
+	pid.mu.Lock()
+	defer pid.mu.Unlock()
+	return pid.quantization
+}
+
+// quantizeLocked snaps the limited output u onto a position inside
+// [min, max]. It reports whether the raw output, before the limits, lies
+// beyond the extreme positions.
+func (pid *PID) quantizeLocked(u, raw, min, max float64) (q float64, beyond bool) {
+	step := pid.quantization.Step
+	if step == 0 {
+		return u, false
+	}
+	lo, hi := math.Ceil(min/step)*step, math.Floor(max/step)*step
+	if lo > hi {
+		// no position inside the limits; hold the nearest bound.
+		return clamp(u, min, max), false
+	}
+	snap := func(v float64) float64 { return clamp(math.Round(v/step)*step, lo, hi) }
+
+	q = snap(pid.lastOutput)
+	if math.Abs(u-q) > step/2+pid.quantization.Hysteresis {
+		q = snap(u)
+	}
+	return q, raw > hi || raw < lo
+}
+
+// SetOutputDeadband holds the output until the newly computed value
+// differs from the last output by more than delta, so valves and dampers
+// are not worn by constant small moves. A move onto an output limit is
+// always made, so the actuator can close fully. The hold applies after
+// quantization and only in Auto mode. Zero disables it.
+func (pid *PID) SetOutputDeadband(delta float64) error {
+	if !(delta >= 0) || math.IsInf(delta, 0) {
+		return errors.New("output deadband must be finite and not negative")
+	}
+	defer pid.notifyChange()
+	pid.mu.Lock()
+	defer pid.mu.Unlock()
+	pid.outputDeadband = delta
+
+	return nil
+}
+
+// GetOutputDeadband returns the output deadband.
+func (pid *PID) GetOutputDeadband() float64 {
+	pid.mu.Lock()
+	defer pid.mu.Unlock()
+	return pid.outputDeadband
+}
+
+// holdOutputLocked applies the output deadband to u.
+func (pid *PID) holdOutputLocked(u, min, max float64) float64 {
+	if pid.outputDeadband == 0 || u == min || u == max {
+		return u
+	}
+	if math.Abs(u-pid.lastOutput) <= pid.outputDeadband {
+		return pid.lastOutput
+	}
+	return u
+}
@@ -0,0 +1,106 @@
+package pidpool_test
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/ankur-anand/go-pidpool"
+)
+
+func TestOutputQuantization(t *testing.T) {
+	p := pidpool.NewP(1)
+	p.SetOutputLimits(0, 10)
+	if err := p.SetOutputQuantization(pidpool.Quantization{Step: 1, Hysteresis: 0.1}); err != nil {
+		t.Fatalf("SetOutputQuantization err: %v", err)
+	}
+
+	cases := []struct{ u, want float64 }{
+		{3.4, 3},
+		{3.55, 3}, // inside the anti-chatter band
+		{3.7, 4},
+		{3.45, 4},
+		{3.35, 3},
+		{12, 10},
+	}
+	for _, c := range cases {
+		p.SetSetPoint(c.u)
+		if got := p.UpdateDuration(0, 1); got != c.want {
+			t.Fatalf("u=%v: expected %v, got %v", c.u, c.want, got)
+		}
+	}
+	if st := p.LastStatus(); !st.Saturated {
+		t.Fatalf("expected saturation at the top position")
+	}
+	p.SetSetPoint(3.7)
+	p.UpdateDuration(0, 1)
+	if st := p.LastStatus(); st.Saturated {
+		t.Fatalf("quantization alone is not saturation")
+	}
+
+	b, _ := json.Marshal(p.State())
+	var st pidpool.State
+	if err := json.Unmarshal(b, &st); err != nil || st.Quantization.Step != 1 {
+		t.Fatalf("quantization lost in round trip: %s (%v)", b, err)
+	}
+	if err := p.SetOutputQuantization(pidpool.Quantization{Step: -1}); err == nil {
+		t.Fatalf("expected error for negative step")
+	}
+}
+
+func TestOutputQuantization_AntiWindup(t *testing.T) {
+	for _, max := range []float64{10, 10.5} {
+		// 0..10 in integer steps puts the top position on the output limit;
+		// with 10.5 it lies below it.
+		p := pidpool.NewPI(0, 1)
+		p.SetOutputLimits(0, max)
+		p.SetIntegralLimits(-1000, 1000)
+		p.SetOutputQuantization(pidpool.Quantization{Step: 1})
+		p.SetSetPoint(100)
+		for i := 0; i < 100; i++ {
+			p.UpdateDuration(0, 1)
+		}
+		if i := p.State().Integral; i > 110 {
+			t.Fatalf("max %v: integral wound up to %v at the top position", max, i)
+		}
+		// it unwinds promptly once the error reverses.
+		p.SetSetPoint(-100)
+		p.UpdateDuration(0, 1)
+		if out := p.LastOutput(); out >= 10 {
+			t.Fatalf("max %v: expected the output to leave the top position, got %v", max, out)
+		}
+	}
+}
+
+func TestOutputDeadband(t *testing.T) {
+	p := pidpool.NewP(1)
+	p.SetOutputLimits(0, 100)
+	if err := p.SetOutputDeadband(2); err != nil {
+		t.Fatalf("SetOutputDeadband err: %v", err)
+	}
+
+	cases := []struct{ u, want float64 }{
+		{50, 50},
+		{51.5, 50},
+		{48.2, 50},
+		{52.5, 52.5},
+		{0.5, 0.5},
+		{-3, 0}, // a limit is always reached
+	}
+	for _, c := range cases {
+		p.SetSetPoint(c.u)
+		if got := p.UpdateDuration(0, 1); got != c.want {
+			t.Fatalf("u=%v: expected %v, got %v", c.u, c.want, got)
+		}
+	}
+	p.SetSetPoint(1)
+	p.UpdateDuration(0, 1)
+	if st := p.LastStatus(); st.Saturated {
+		t.Fatalf("a held output is not saturation")
+	}
+	if p.State().OutputDeadband != 2 {
+		t.Fatalf("expected output deadband in state")
+	}
+	if err := p.SetOutputDeadband(-1); err == nil {
+		t.Fatalf("expected error for negative deadband")
+	}
+}
@@ -0,0 +1,73 @@
+package pidpool
+
+import (
+	"context"
+	"errors"
+	"time"
+)
+
+// QueueGovernorConfig configures a QueueGovernor.
+type QueueGovernorConfig struct {
+	// PID computes the rate from the queue depth. When the rate is a
+	// consumer rate, a deep queue should raise it, so the gains are
+	// negative; for a producer rate they are positive. It is governed over
+	// [MinRate, MaxRate], see PID.Govern.
+	PID *PID
+	// Depth returns the current queue depth, see ChanDepth.
+	Depth  func() int
+	Target int
+	// SetRate applies the new rate.
+	SetRate func(rate float64)
+
+	MinRate float64
+	MaxRate float64
+	// Interval is the control period. Defaults to one second.
+	Interval time.Duration
+}
+
+// QueueGovernor keeps the depth of a queue, e.g. a channel, a job queue or
+// a pipeline stage, at a target by driving a producer or consumer rate.
+type QueueGovernor struct {
+	*Runner
+}
+
+// NewQueueGovernor returns a governor for cfg. Call Start to begin.
+func NewQueueGovernor(cfg QueueGovernorConfig) (*QueueGovernor, error) {
+	if cfg.PID == nil || cfg.Depth == nil || cfg.SetRate == nil {
+		return nil, errors.New("pid, depth and set rate are required")
+	}
+	if cfg.MaxRate < cfg.MinRate {
+		return nil, errors.New("min rate greater than max rate")
+	}
+	if cfg.Interval < 0 {
+		return nil, errors.New("interval must not be negative")
+	}
+	if cfg.Interval == 0 {
+		cfg.Interval = time.Second
+	}
+	if err := cfg.PID.Govern(float64(cfg.Target), cfg.MinRate, cfg.MaxRate); err != nil {
+		return nil, err
+	}
+
+	depth, set := cfg.Depth, cfg.SetRate
+	src := SourceFunc(func(context.Context) (float64, error) {
+		return float64(depth()), nil
+	})
+	sink := SinkFunc(func(_ context.Context, rate float64) error {
+		set(rate)
+		return nil
+	})
+
+	return &QueueGovernor{NewRunner(cfg.PID, cfg.Interval, src, sink)}, nil
+}
+
+// Rate returns the rate last applied. The boolean is false before the
+// first control step.
+func (g *QueueGovernor) Rate() (float64, bool) {
+	return g.LastOutput()
+}
+
+// ChanDepth returns a depth function for a buffered channel.
+func ChanDepth[T any](ch chan T) func() int {
+	return func() int { return len(ch) }
+}
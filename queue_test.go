@@ -0,0 +1,54 @@
+package pidpool_test
+
+import (
+	"math"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/ankur-anand/go-pidpool"
+)
+
+func TestQueueGovernor_HoldsChannelDepth(t *testing.T) {
+	ch := make(chan int, 1000)
+	var rate atomic.Uint64 // consumer rate in items per tick, as float64 bits
+	g, err := pidpool.NewQueueGovernor(pidpool.QueueGovernorConfig{
+		PID:      pidpool.NewPI(-0.05, -2),
+		Depth:    pidpool.ChanDepth(ch),
+		Target:   100,
+		SetRate:  func(r float64) { rate.Store(math.Float64bits(r)) },
+		MinRate:  0,
+		MaxRate:  20,
+		Interval: 5 * time.Millisecond,
+	})
+	if err != nil {
+		t.Fatalf("NewQueueGovernor err: %v", err)
+	}
+	if err := g.Start(); err != nil {
+		t.Fatalf("Start err: %v", err)
+	}
+	defer g.Stop()
+
+	// a producer adds 5 items per millisecond; the consumer takes rate
+	// items per millisecond.
+	for i := 0; i < 600; i++ {
+		for j := 0; j < 5; j++ {
+			select {
+			case ch <- j:
+			default:
+			}
+		}
+		n := int(math.Round(math.Float64frombits(rate.Load())))
+		for j := 0; j < n && len(ch) > 0; j++ {
+			<-ch
+		}
+		time.Sleep(time.Millisecond)
+	}
+
+	if d := len(ch); d < 30 || d > 300 {
+		t.Fatalf("depth not held near 100: %d", d)
+	}
+	if r, ok := g.Rate(); !ok || r < 2 || r > 10 {
+		t.Fatalf("unexpected rate %v (%v)", r, ok)
+	}
+}
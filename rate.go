@@ -0,0 +1,58 @@
+package pidpool
+
+import (
+	"context"
+	"errors"
+	"time"
+)
+
+// RateControl configures a loop that adjusts a request rate limit, so
+// clients can self-throttle against a struggling backend.
+//
+// SetLimit is called with the new limit in events per second, so any
+// limiter can be driven. Package pidrate adapts a golang.org/x/time/rate
+// limiter.
+type RateControl struct {
+	// PID computes the limit from the signal. A signal above Target, e.g.
+	// a rising error rate or queue lag, should lower the rate, so the
+	// gains are positive. The loop governs it over [MinRate, MaxRate], see
+	// PID.Govern.
+	PID    *PID
+	Signal Source
+	Target float64
+
+	MinRate float64
+	MaxRate float64
+	// Interval is the update interval. Defaults to one second.
+	Interval time.Duration
+
+	SetLimit func(limit float64)
+}
+
+// NewRateControl returns a Runner that reads the signal and applies the new
+// limit every interval. Call Start to begin.
+func NewRateControl(cfg RateControl) (*Runner, error) {
+	if cfg.PID == nil || cfg.Signal == nil || cfg.SetLimit == nil {
+		return nil, errors.New("pid, signal and set limit are required")
+	}
+	if cfg.MinRate < 0 || cfg.MaxRate < cfg.MinRate {
+		return nil, errors.New("rates must satisfy 0 <= min <= max")
+	}
+	if cfg.Interval < 0 {
+		return nil, errors.New("interval must not be negative")
+	}
+	if cfg.Interval == 0 {
+		cfg.Interval = time.Second
+	}
+	if err := cfg.PID.Govern(cfg.Target, cfg.MinRate, cfg.MaxRate); err != nil {
+		return nil, err
+	}
+
+	set := cfg.SetLimit
+	sink := SinkFunc(func(_ context.Context, limit float64) error {
+		set(limit)
+		return nil
+	})
+
+	return NewRunner(cfg.PID, cfg.Interval, cfg.Signal, sink), nil
+}
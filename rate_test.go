@@ -0,0 +1,40 @@
+package pidpool_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/ankur-anand/go-pidpool"
+)
+
+func TestRateControl_BacksOff(t *testing.T) {
+	// the backend fails a growing share of requests above 100/s.
+	limit := 500.0
+	errorRate := pidpool.SourceFunc(func(context.Context) (float64, error) {
+		return max(0, (limit-100)/1000), nil
+	})
+	r, err := pidpool.NewRateControl(pidpool.RateControl{
+		PID:      pidpool.NewPI(200, 1000),
+		Signal:   errorRate,
+		Target:   0.01,
+		MinRate:  1,
+		MaxRate:  1000,
+		SetLimit: func(l float64) { limit = l },
+	})
+	if err != nil {
+		t.Fatalf("NewRateControl err: %v", err)
+	}
+
+	for i := 0; i < 20; i++ {
+		if err := r.RunOnce(context.Background()); err != nil {
+			t.Fatalf("RunOnce err: %v", err)
+		}
+	}
+	if limit >= 100 || limit < 1 {
+		t.Fatalf("limit did not back off into [1, 100): %v", limit)
+	}
+
+	if _, err := pidpool.NewRateControl(pidpool.RateControl{PID: pidpool.NewP(1)}); err == nil {
+		t.Fatalf("expected error without signal")
+	}
+}
@@ -0,0 +1,171 @@
+package pidpool
+
+import (
+	"errors"
+	"sort"
+	"sync"
+)
+
+// ErrNameTaken is returned by Registry.Register when the name is in use.
+var ErrNameTaken = errors.New("controller name already registered")
+
+// Registry stores controllers by name. Unlike a Manager it never creates
+// controllers; they are registered explicitly. It is the bookkeeping the
+// HTTP and metrics adapters consume, so each integration point does not
+// have to keep its own map.
+type Registry struct {
+	mu sync.RWMutex
+
+	pids     map[string]*PID
+	watchers map[uint64]func(name string, pid *PID)
+	watchID  uint64
+}
+
+// NewRegistry returns an empty Registry.
+func NewRegistry() *Registry {
+	return &Registry{
+		pids:     make(map[string]*PID),
+		watchers: make(map[uint64]func(string, *PID)),
+	}
+}
+
+// Register adds pid under name.
+func (r *Registry) Register(name string, pid *PID) error {
+	if pid == nil {
+		return errors.New("controller must not be nil")
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if _, ok := r.pids[name]; ok {
+		return ErrNameTaken
+	}
+	r.pids[name] = pid
+	r.notifyLocked(name, pid)
+
+	return nil
+}
+
+// Unregister removes the controller registered under name and reports
+// whether there was one.
+func (r *Registry) Unregister(name string) bool {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if _, ok := r.pids[name]; !ok {
+		return false
+	}
+	delete(r.pids, name)
+	r.notifyLocked(name, nil)
+
+	return true
+}
+
+// Get returns the controller registered under name.
+func (r *Registry) Get(name string) (*PID, bool) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	pid, ok := r.pids[name]
+	return pid, ok
+}
+
+// Lookup is Get under the name the adapters expect, so a Registry can be
+// used wherever a Manager is.
+func (r *Registry) Lookup(name string) (*PID, bool) {
+	return r.Get(name)
+}
+
+// Range calls fn for every controller in name order until fn returns
+// false. The registry is not locked while fn runs.
+func (r *Registry) Range(fn func(name string, pid *PID) bool) {
+	for _, name := range r.Keys() {
+		pid, ok := r.Get(name)
+		if !ok {
+			continue
+		}
+		if !fn(name, pid) {
+			return
+		}
+	}
+}
+
+// Keys returns the sorted names of all controllers.
+func (r *Registry) Keys() []string {
+	r.mu.RLock()
+	keys := make([]string, 0, len(r.pids))
+	for k := range r.pids {
+		keys = append(keys, k)
+	}
+	r.mu.RUnlock()
+	sort.Strings(keys)
+
+	return keys
+}
+
+// Len returns the number of controllers.
+func (r *Registry) Len() int {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	return len(r.pids)
+}
+
+// Watch calls fn for every registered controller and then on every
+// Register and Unregister, with a nil pid for the latter. Calls are made
+// with the registry locked, so fn must not call back into it. The returned
+// function stops watching.
+func (r *Registry) Watch(fn func(name string, pid *PID)) (cancel func()) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.watchID++
+	id := r.watchID
+	r.watchers[id] = fn
+	for name, pid := range r.pids {
+		fn(name, pid)
+	}
+
+	return func() {
+		r.mu.Lock()
+		defer r.mu.Unlock()
+		delete(r.watchers, id)
+	}
+}
+
+func (r *Registry) notifyLocked(name string, pid *PID) {
+	for _, fn := range r.watchers {
+		fn(name, pid)
+	}
+}
+
+// UpdateAll updates the controller registered under each name with its
+// value and returns the outputs by name. Names that are not registered are
+// skipped. The registry is read-locked once for the whole batch; each
+// controller still takes its own lock.
+func (r *Registry) UpdateAll(values map[string]float64) map[string]float64 {
+	return r.updateAll(values, func(pid *PID, v float64) float64 { return pid.Update(v) })
+}
+
+// UpdateAllDuration is UpdateAll with an explicit dt for every controller.
+func (r *Registry) UpdateAllDuration(values map[string]float64, dt float64) map[string]float64 {
+	return r.updateAll(values, func(pid *PID, v float64) float64 { return pid.UpdateDuration(v, dt) })
+}
+
+func (r *Registry) updateAll(values map[string]float64, update func(*PID, float64) float64) map[string]float64 {
+	type job struct {
+		name string
+		pid  *PID
+		v    float64
+	}
+	jobs := make([]job, 0, len(values))
+	r.mu.RLock()
+	for name, v := range values {
+		if pid, ok := r.pids[name]; ok {
+			jobs = append(jobs, job{name, pid, v})
+		}
+	}
+	r.mu.RUnlock()
+
+	out := make(map[string]float64, len(jobs))
+	for _, j := range jobs {
+		out[j.name] = update(j.pid, j.v)
+	}
+
+	return out
+}
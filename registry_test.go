@@ -0,0 +1,72 @@
+package pidpool_test
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/ankur-anand/go-pidpool"
+)
+
+func TestRegistry(t *testing.T) {
+	r := pidpool.NewRegistry()
+	oven := pidpool.NewPID(1, 0, 0, 0)
+	if err := r.Register("oven", oven); err != nil {
+		t.Fatalf("Register err: %v", err)
+	}
+	if err := r.Register("oven", pidpool.NewPID(1, 0, 0, 0)); !errors.Is(err, pidpool.ErrNameTaken) {
+		t.Fatalf("expected ErrNameTaken, got %v", err)
+	}
+
+	seen := map[string]bool{}
+	cancel := r.Watch(func(name string, pid *pidpool.PID) { seen[name] = pid != nil })
+	_ = r.Register("chiller", pidpool.NewPID(1, 0, 0, 0))
+	if !seen["oven"] || !seen["chiller"] {
+		t.Fatalf("watcher missed registrations: %v", seen)
+	}
+
+	var names []string
+	r.Range(func(name string, pid *pidpool.PID) bool {
+		names = append(names, name)
+		return false
+	})
+	if len(names) != 1 || names[0] != "chiller" {
+		t.Fatalf("Range must visit in name order and stop early, got %v", names)
+	}
+
+	if !r.Unregister("oven") || seen["oven"] {
+		t.Fatalf("Unregister not observed")
+	}
+	cancel()
+	if _, ok := r.Get("oven"); ok || r.Len() != 1 {
+		t.Fatalf("unexpected registry contents: %v", r.Keys())
+	}
+}
+
+func TestRegistry_UpdateAll(t *testing.T) {
+	r := pidpool.NewRegistry()
+	for _, name := range []string{"dev-1", "dev-2"} {
+		p := pidpool.NewP(2)
+		p.SetSetPoint(10)
+		_ = r.Register(name, p)
+	}
+
+	out := r.UpdateAllDuration(map[string]float64{"dev-1": 4, "dev-2": 9, "dev-3": 0}, 1)
+	if len(out) != 2 || out["dev-1"] != 12 || out["dev-2"] != 2 {
+		t.Fatalf("unexpected outputs: %v", out)
+	}
+}
+
+func BenchmarkRegistry_UpdateAll(b *testing.B) {
+	r := pidpool.NewRegistry()
+	values := make(map[string]float64, 500)
+	for i := 0; i < 500; i++ {
+		name := fmt.Sprintf("dev-%03d", i)
+		_ = r.Register(name, pidpool.NewPID(1, 0.1, 0.01, 0))
+		values[name] = float64(i)
+	}
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		r.UpdateAllDuration(values, 0.01)
+	}
+}
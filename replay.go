@@ -0,0 +1,58 @@
+package pidpool
+
+import "time"
+
+// Sample is a timestamped measurement.
+type Sample struct {
+	Value float64   `json:"value"`
+	Time  time.Time `json:"time"`
+}
+
+// updateAt runs one step with dt derived from the sample timestamp instead
+// of the wall clock. Samples that are older than the last update yield dt 0.
+func (pid *PID) updateAt(value float64, t time.Time) UpdateEvent {
+	dt := 0.0
+	if !pid.lastUpdate.IsZero() && t.After(pid.lastUpdate) {
+		dt = t.Sub(pid.lastUpdate).Seconds()
+	}
+	if t.After(pid.lastUpdate) {
+		pid.lastUpdate = t
+	}
+
+	ev := pid.updateInternal(value, dt)
+	ev.Time = t
+
+	return ev
+}
+
+// UpdateAt runs the PID calculation for a sample measured at t, deriving dt
+// from the sample timestamps rather than the wall clock, so queueing delays
+// between measurement and arrival do not distort dt. A sample older than the
+// previous one is applied with dt 0 and does not move the timestamp back.
+func (pid *PID) UpdateAt(value float64, t time.Time) float64 {
+	return pid.step(func() UpdateEvent { return pid.updateAt(value, t) }).Output
+}
+
+// Replay runs a recorded trace through a controller restored from st and
+// returns one output per sample.
+//
+// Replay never reads the wall clock: dt is derived only from the sample
+// timestamps, and the update arithmetic is evaluated in a fixed order (see
+// updateInternal), so the same state and trace reproduce bit-identical
+// outputs on every platform. This makes it suitable for incident audits.
+func Replay(st State, samples []Sample) ([]float64, error) {
+	pid := &PID{}
+	if err := pid.RestoreState(st); err != nil {
+		return nil, err
+	}
+
+	out := make([]float64, len(samples))
+	for i, s := range samples {
+		// as in tryStep, the output is kept for the output dead-band and
+		// the quantizer of the next step.
+		out[i] = pid.updateAt(s.Value, s.Time).Output
+		pid.lastOutput = out[i]
+	}
+
+	return out, nil
+}
@@ -0,0 +1,103 @@
+package pidpool_test
+
+import (
+	"math"
+	"testing"
+	"time"
+
+	"github.com/ankur-anand/go-pidpool"
+)
+
+func TestReplay_MatchesLiveController(t *testing.T) {
+	start := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	p := pidpool.NewPID(0.8, 0.3, 0.05, 0)
+	p.SetSetPoint(10)
+	st := p.State()
+	st.LastUpdate = start
+
+	samples := make([]pidpool.Sample, 100)
+	want := make([]float64, len(samples))
+	live := pidpool.NewPID(0, 0, 0, 0)
+	if err := live.RestoreState(st); err != nil {
+		t.Fatalf("RestoreState err: %v", err)
+	}
+	for i := range samples {
+		dt := time.Duration(50+i%7) * time.Millisecond
+		prev := start
+		if i > 0 {
+			prev = samples[i-1].Time
+		}
+		samples[i] = pidpool.Sample{Value: 10 * math.Sin(float64(i)/10), Time: prev.Add(dt)}
+		want[i] = live.UpdateDuration(samples[i].Value, dt.Seconds())
+	}
+
+	for run := 0; run < 2; run++ {
+		got, err := pidpool.Replay(st, samples)
+		if err != nil {
+			t.Fatalf("Replay err: %v", err)
+		}
+		for i := range got {
+			if math.Float64bits(got[i]) != math.Float64bits(want[i]) {
+				t.Fatalf("run %d sample %d: got %v, want %v", run, i, got[i], want[i])
+			}
+		}
+	}
+}
+
+func TestReplay_OutputDeadbandFromCheckpoint(t *testing.T) {
+	start := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	live := pidpool.NewPID(1, 0.2, 0, 0)
+	live.SetSetPoint(10)
+	live.SetOutputLimits(0, 100)
+	if err := live.SetOutputDeadband(0.5); err != nil {
+		t.Fatalf("SetOutputDeadband err: %v", err)
+	}
+	live.UpdateAt(0, start)
+
+	// small moves of the measurement are held by the output dead-band,
+	// which compares every output with the previous one.
+	samples := make([]pidpool.Sample, 40)
+	for i := range samples {
+		samples[i] = pidpool.Sample{Value: 0.1 * float64(i), Time: start.Add(time.Duration(i+1) * 100 * time.Millisecond)}
+	}
+	for _, s := range samples[:10] {
+		live.UpdateAt(s.Value, s.Time)
+	}
+	checkpoint := live.State()
+	want := make([]float64, 0, 30)
+	for _, s := range samples[10:] {
+		want = append(want, live.UpdateAt(s.Value, s.Time))
+	}
+
+	got, err := pidpool.Replay(checkpoint, samples[10:])
+	if err != nil {
+		t.Fatalf("Replay err: %v", err)
+	}
+	for i := range got {
+		if math.Float64bits(got[i]) != math.Float64bits(want[i]) {
+			t.Fatalf("sample %d: got %v, want %v (all %v, want %v)", i, got[i], want[i], got, want)
+		}
+	}
+}
+
+func TestUpdateAt_UsesSampleTime(t *testing.T) {
+	start := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	p := pidpool.NewPI(0, 1)
+	p.SetSetPoint(1)
+	st := p.State()
+	st.LastUpdate = start
+	if err := p.RestoreState(st); err != nil {
+		t.Fatalf("RestoreState err: %v", err)
+	}
+
+	// samples arrive late and in a burst; dt must follow their timestamps.
+	if out := p.UpdateAt(0, start.Add(2*time.Second)); out != 2 {
+		t.Fatalf("expected integral over 2s, got %v", out)
+	}
+	if out := p.UpdateAt(0, start.Add(time.Second)); out != 2 {
+		t.Fatalf("stale sample must not integrate, got %v", out)
+	}
+	if out := p.UpdateAt(0, start.Add(3*time.Second)); out != 3 {
+		t.Fatalf("expected integral over 3s, got %v", out)
+	}
+}
@@ -0,0 +1,202 @@
+package pidpool
+
+import (
+	"errors"
+	"math"
+	"sort"
+	"sync"
+	"time"
+)
+
+// RetentionTier keeps aggregates of the given resolution for the given
+// duration.
+type RetentionTier struct {
+	Resolution time.Duration
+	Retention  time.Duration
+}
+
+// Aggregate summarizes the update events of one time bucket.
+type Aggregate struct {
+	Start      time.Time
+	Resolution time.Duration
+	Count      int
+
+	MinValue  float64
+	MaxValue  float64
+	MeanValue float64
+
+	MeanSetPoint float64
+	MeanError    float64
+	MeanAbsError float64
+
+	MinOutput  float64
+	MaxOutput  float64
+	MeanOutput float64
+}
+
+type bucket struct {
+	agg                             Aggregate
+	sumValue, sumSP, sumErr, sumAbs float64
+	sumOutput                       float64
+}
+
+func (b *bucket) add(ev UpdateEvent) {
+	a := &b.agg
+	if a.Count == 0 {
+		a.MinValue, a.MaxValue = ev.Value, ev.Value
+		a.MinOutput, a.MaxOutput = ev.Output, ev.Output
+	}
+	a.Count++
+	a.MinValue = math.Min(a.MinValue, ev.Value)
+	a.MaxValue = math.Max(a.MaxValue, ev.Value)
+	a.MinOutput = math.Min(a.MinOutput, ev.Output)
+	a.MaxOutput = math.Max(a.MaxOutput, ev.Output)
+	b.sumValue += ev.Value
+	b.sumSP += ev.SetPoint
+	b.sumErr += ev.Error
+	b.sumAbs += math.Abs(ev.Error)
+	b.sumOutput += ev.Output
+}
+
+func (b *bucket) aggregate() Aggregate {
+	a := b.agg
+	n := float64(a.Count)
+	a.MeanValue = b.sumValue / n
+	a.MeanSetPoint = b.sumSP / n
+	a.MeanError = b.sumErr / n
+	a.MeanAbsError = b.sumAbs / n
+	a.MeanOutput = b.sumOutput / n
+	return a
+}
+
+type tier struct {
+	RetentionTier
+	buckets []*bucket
+}
+
+// TieredHistory records update events at full rate for a short window and
+// downsamples them into coarser tiers for longer windows, so an edge device
+// can answer "what did this loop do last night" without external storage.
+type TieredHistory struct {
+	mu sync.Mutex
+
+	rawRetention time.Duration
+	raw          []UpdateEvent
+	tiers        []*tier
+}
+
+// NewTieredHistory returns a history keeping full rate events for raw and
+// the given tiers, which must have increasing resolutions.
+func NewTieredHistory(raw time.Duration, tiers ...RetentionTier) (*TieredHistory, error) {
+	if raw < 0 {
+		return nil, errors.New("raw retention must not be negative")
+	}
+	h := &TieredHistory{rawRetention: raw}
+	for i, t := range tiers {
+		if t.Resolution <= 0 || t.Retention < t.Resolution {
+			return nil, errors.New("tier retention must cover at least one positive resolution")
+		}
+		if i > 0 && t.Resolution <= tiers[i-1].Resolution {
+			return nil, errors.New("tier resolutions must increase")
+		}
+		h.tiers = append(h.tiers, &tier{RetentionTier: t})
+	}
+
+	return h, nil
+}
+
+// NewDefaultTieredHistory returns a history keeping full rate events for
+// 10 minutes, 1-second aggregates for 6 hours and 1-minute aggregates for
+// 7 days.
+func NewDefaultTieredHistory() *TieredHistory {
+	h, _ := NewTieredHistory(10*time.Minute,
+		RetentionTier{Resolution: time.Second, Retention: 6 * time.Hour},
+		RetentionTier{Resolution: time.Minute, Retention: 7 * 24 * time.Hour},
+	)
+	return h
+}
+
+// Attach records every update of pid. The returned function detaches it.
+func (h *TieredHistory) Attach(pid *PID) (remove func()) {
+	return pid.OnUpdateWith(h.Record, HookOptions{Name: "tiered-history"})
+}
+
+// Record adds an update event. Events are expected in time order.
+func (h *TieredHistory) Record(ev UpdateEvent) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	if h.rawRetention > 0 {
+		h.raw = append(h.raw, ev)
+		cut := ev.Time.Add(-h.rawRetention)
+		i := sort.Search(len(h.raw), func(i int) bool { return !h.raw[i].Time.Before(cut) })
+		h.raw = h.raw[i:]
+	}
+
+	for _, t := range h.tiers {
+		start := ev.Time.Truncate(t.Resolution)
+		n := len(t.buckets)
+		if n == 0 || t.buckets[n-1].agg.Start.Before(start) {
+			t.buckets = append(t.buckets, &bucket{agg: Aggregate{Start: start, Resolution: t.Resolution}})
+			n++
+		}
+		t.buckets[n-1].add(ev)
+
+		cut := start.Add(-t.Retention)
+		i := sort.Search(n, func(i int) bool { return t.buckets[i].agg.Start.After(cut) })
+		t.buckets = t.buckets[i:]
+	}
+}
+
+// Raw returns the full rate events in [from, to).
+func (h *TieredHistory) Raw(from, to time.Time) []UpdateEvent {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	var out []UpdateEvent
+	for _, ev := range h.raw {
+		if !ev.Time.Before(from) && ev.Time.Before(to) {
+			out = append(out, ev)
+		}
+	}
+	return out
+}
+
+// Aggregates returns the buckets of the tier with the given resolution
+// whose start lies in [from, to).
+func (h *TieredHistory) Aggregates(from, to time.Time, resolution time.Duration) ([]Aggregate, error) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	for _, t := range h.tiers {
+		if t.Resolution == resolution {
+			return t.query(from, to), nil
+		}
+	}
+	return nil, errors.New("no tier with that resolution")
+}
+
+// Query returns aggregates for [from, to) from the finest tier that still
+// retains from.
+func (h *TieredHistory) Query(from, to time.Time) []Aggregate {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	for _, t := range h.tiers {
+		if len(t.buckets) > 0 && !t.buckets[0].agg.Start.After(from.Truncate(t.Resolution)) {
+			return t.query(from, to)
+		}
+	}
+	if n := len(h.tiers); n > 0 {
+		return h.tiers[n-1].query(from, to)
+	}
+	return nil
+}
+
+func (t *tier) query(from, to time.Time) []Aggregate {
+	var out []Aggregate
+	for _, b := range t.buckets {
+		s := b.agg.Start
+		if !s.Before(from.Truncate(t.Resolution)) && s.Before(to) {
+			out = append(out, b.aggregate())
+		}
+	}
+	return out
+}
@@ -0,0 +1,60 @@
+package pidpool_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/ankur-anand/go-pidpool"
+)
+
+func TestTieredHistory_Downsampling(t *testing.T) {
+	h, err := pidpool.NewTieredHistory(2*time.Second,
+		pidpool.RetentionTier{Resolution: time.Second, Retention: time.Minute},
+		pidpool.RetentionTier{Resolution: time.Minute, Retention: time.Hour},
+	)
+	if err != nil {
+		t.Fatalf("NewTieredHistory err: %v", err)
+	}
+
+	start := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	// 10 Hz for 3 minutes.
+	for i := 0; i < 1800; i++ {
+		h.Record(pidpool.UpdateEvent{
+			Time:   start.Add(time.Duration(i) * 100 * time.Millisecond),
+			Value:  float64(i % 10),
+			Output: 1,
+		})
+	}
+	end := start.Add(3 * time.Minute)
+
+	if raw := h.Raw(start, end); len(raw) != 21 {
+		t.Fatalf("expected 2s of raw events, got %d", len(raw))
+	}
+
+	secs, err := h.Aggregates(start, end, time.Second)
+	if err != nil {
+		t.Fatalf("Aggregates err: %v", err)
+	}
+	if len(secs) != 60 {
+		t.Fatalf("expected 60 one-second buckets, got %d", len(secs))
+	}
+	if a := secs[0]; a.Count != 10 || a.MinValue != 0 || a.MaxValue != 9 || a.MeanValue != 4.5 {
+		t.Fatalf("unexpected second bucket: %+v", a)
+	}
+
+	// the 1s tier no longer covers the start, so Query falls back to minutes.
+	mins := h.Query(start, end)
+	if len(mins) != 3 || mins[0].Resolution != time.Minute || mins[0].Count != 600 {
+		t.Fatalf("unexpected minute buckets: %+v", mins)
+	}
+}
+
+func TestTieredHistory_InvalidTiers(t *testing.T) {
+	_, err := pidpool.NewTieredHistory(time.Minute,
+		pidpool.RetentionTier{Resolution: time.Minute, Retention: time.Hour},
+		pidpool.RetentionTier{Resolution: time.Second, Retention: time.Hour},
+	)
+	if err == nil {
+		t.Fatalf("expected error for decreasing resolutions")
+	}
+}
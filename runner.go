@@ -0,0 +1,391 @@
+package pidpool
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+)
+
+// Source provides process measurements to a Runner.
+type Source interface {
+	Read(ctx context.Context) (float64, error)
+}
+
+// Sink receives controller outputs from a Runner.
+type Sink interface {
+	Write(ctx context.Context, output float64) error
+}
+
+// SourceFunc adapts a function to the Source interface.
+type SourceFunc func(ctx context.Context) (float64, error)
+
+// Read implements Source.
+func (f SourceFunc) Read(ctx context.Context) (float64, error) { return f(ctx) }
+
+// SinkFunc adapts a function to the Sink interface.
+type SinkFunc func(ctx context.Context, output float64) error
+
+// Write implements Sink.
+func (f SinkFunc) Write(ctx context.Context, output float64) error { return f(ctx, output) }
+
+// SinkPolicy controls how a Runner reacts to sink write failures. A failing
+// sink never stops the loop: the controller keeps computing every tick and
+// the actuator holds the last value it accepted until a write succeeds.
+type SinkPolicy struct {
+	// Retries is the number of times a failed write is re-sent within the
+	// same tick.
+	Retries int
+	// RetryDelay is the pause between retries.
+	RetryDelay time.Duration
+	// FailureThreshold is the number of consecutive failed ticks after which
+	// OnFailure is called. Zero disables the event.
+	FailureThreshold int
+	// OnFailure is called once when the consecutive failures reach
+	// FailureThreshold, with the last error.
+	OnFailure func(consecutive int, err error)
+	// OnRecover is called when a write succeeds after OnFailure fired.
+	OnRecover func()
+}
+
+// ErrRunnerStarted is returned by Start when the runner is already running.
+var ErrRunnerStarted = errors.New("runner already started")
+
+// ErrRunnerNotRunning is returned by Pause and Resume when the runner is
+// not in the state they leave.
+var ErrRunnerNotRunning = errors.New("runner not running")
+
+// RunnerState is the lifecycle state of a Runner.
+type RunnerState int
+
+const (
+	// RunnerStopped is the state before Start and after Stop.
+	RunnerStopped RunnerState = iota
+	// RunnerRunning ticks every interval.
+	RunnerRunning
+	// RunnerPaused keeps the goroutine but skips every tick: the source is
+	// not read, the controller not updated and the sink not written.
+	RunnerPaused
+)
+
+// String implements fmt.Stringer.
+func (s RunnerState) String() string {
+	switch s {
+	case RunnerStopped:
+		return "stopped"
+	case RunnerRunning:
+		return "running"
+	case RunnerPaused:
+		return "paused"
+	}
+	return fmt.Sprintf("RunnerState(%d)", int(s))
+}
+
+// Runner owns the goroutine that drives a controller: every interval it
+// reads the source, updates the controller and writes the output to the
+// sink.
+type Runner struct {
+	pid      *PID
+	interval time.Duration
+	source   Source
+	sink     Sink
+
+	mu      sync.Mutex
+	policy  SinkPolicy
+	monitor *ActuatorMonitor
+	duty    DutyCycle
+
+	position   Source
+	calibrator Calibrator
+	cancel     context.CancelFunc
+	done       chan struct{}
+	state      RunnerState
+	onState    []func(from, to RunnerState)
+
+	// tickMu is held for the whole of every tick, so Pause and Stop can
+	// wait for one in flight.
+	tickMu sync.Mutex
+
+	lastOutput   float64
+	hasOutput    bool
+	sinkFailures int
+}
+
+// NewRunner returns a Runner for pid. It does nothing until Start is called.
+func NewRunner(pid *PID, interval time.Duration, source Source, sink Sink) *Runner {
+	return &Runner{
+		pid:      pid,
+		interval: interval,
+		source:   source,
+		sink:     sink,
+	}
+}
+
+// SetSinkPolicy sets the sink failure policy.
+func (r *Runner) SetSinkPolicy(p SinkPolicy) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.policy = p
+}
+
+// SetActuatorMonitor checks every tick that the outputs accepted by the
+// sink actually move the process value read from the source. Nil disables
+// the check.
+func (r *Runner) SetActuatorMonitor(m *ActuatorMonitor) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.monitor = m
+}
+
+// SetPositionSource makes the runner read the actuator position from src
+// after every successful write and report it to the controller with
+// PID.ReportPosition. Configure the check with PID.SetPositionFeedback.
+func (r *Runner) SetPositionSource(src Source) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.position = src
+}
+
+// Start launches the control goroutine.
+func (r *Runner) Start() error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.cancel != nil {
+		return ErrRunnerStarted
+	}
+	if r.interval <= 0 {
+		return errors.New("interval must be positive")
+	}
+
+	if err := r.restore(r.duty); err != nil {
+		return err
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	r.cancel = cancel
+	r.done = make(chan struct{})
+	notify := r.setStateLocked(RunnerRunning)
+	go r.loop(ctx, r.done)
+	defer notify()
+
+	return nil
+}
+
+// Stop stops the control goroutine and waits for it to exit. Once Stop
+// returns no tick is running and none will start, until the next Start.
+func (r *Runner) Stop() {
+	r.mu.Lock()
+	cancel, done := r.cancel, r.done
+	r.cancel, r.done = nil, nil
+	r.mu.Unlock()
+	if cancel == nil {
+		return
+	}
+	cancel()
+	<-done
+
+	r.mu.Lock()
+	notify := func() {}
+	if r.cancel == nil {
+		// not restarted meanwhile.
+		notify = r.setStateLocked(RunnerStopped)
+	}
+	r.mu.Unlock()
+	notify()
+}
+
+// Pause suspends the ticks without stopping the goroutine. Once Pause
+// returns no tick is running and none will start until Resume, so the
+// actuator can be driven by hand in the meantime.
+func (r *Runner) Pause() error {
+	r.mu.Lock()
+	if r.state != RunnerRunning {
+		r.mu.Unlock()
+		return ErrRunnerNotRunning
+	}
+	notify := r.setStateLocked(RunnerPaused)
+	r.mu.Unlock()
+
+	// wait for a tick in flight.
+	r.tickMu.Lock()
+	r.tickMu.Unlock()
+	notify()
+
+	return nil
+}
+
+// Resume restarts the ticks after Pause. The controller measures the next
+// dt from the resume, not from the last tick before the pause.
+func (r *Runner) Resume() error {
+	r.mu.Lock()
+	if r.state != RunnerPaused {
+		r.mu.Unlock()
+		return ErrRunnerNotRunning
+	}
+	r.pid.mu.Lock()
+	r.pid.lastUpdate = time.Now()
+	r.pid.mu.Unlock()
+	notify := r.setStateLocked(RunnerRunning)
+	r.mu.Unlock()
+	notify()
+
+	return nil
+}
+
+// State returns the lifecycle state.
+func (r *Runner) State() RunnerState {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.state
+}
+
+// OnStateChange registers fn to be called after every lifecycle
+// transition, on the goroutine that caused it.
+func (r *Runner) OnStateChange(fn func(from, to RunnerState)) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.onState = append(r.onState, fn)
+}
+
+// setStateLocked moves to state and returns the function notifying the
+// callbacks, to be called once r.mu is released.
+func (r *Runner) setStateLocked(state RunnerState) func() {
+	from := r.state
+	r.state = state
+	callbacks := r.onState
+	return func() {
+		for _, fn := range callbacks {
+			fn(from, state)
+		}
+	}
+}
+
+// LastOutput returns the last output the sink accepted. The boolean is
+// false when no write has succeeded yet.
+func (r *Runner) LastOutput() (float64, bool) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.lastOutput, r.hasOutput
+}
+
+// SinkFailures returns the number of consecutive failed sink writes.
+func (r *Runner) SinkFailures() int {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.sinkFailures
+}
+
+func (r *Runner) loop(ctx context.Context, done chan struct{}) {
+	defer close(done)
+	t := time.NewTicker(r.interval)
+	defer t.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-t.C:
+			r.runTick(ctx)
+		}
+	}
+}
+
+// runTick runs a tick unless the runner is paused or stopping, and saves
+// the duty cycle state after it.
+func (r *Runner) runTick(ctx context.Context) {
+	r.tickMu.Lock()
+	defer r.tickMu.Unlock()
+	r.mu.Lock()
+	paused, duty := r.state == RunnerPaused, r.duty
+	r.mu.Unlock()
+	if paused || ctx.Err() != nil {
+		return
+	}
+
+	if r.tick(ctx) != nil {
+		return
+	}
+	if err := r.save(duty); err != nil && duty.OnError != nil {
+		duty.OnError(err)
+	}
+}
+
+// tick runs one read-update-write cycle. It returns the source error when
+// no measurement was available, in which case the controller is untouched.
+func (r *Runner) tick(ctx context.Context) error {
+	r.recalibrate(ctx)
+	reading, err := r.read(ctx)
+	if err != nil {
+		return err
+	}
+	value := reading.Value
+
+	r.mu.Lock()
+	maxDT := r.duty.MaxDT
+	r.mu.Unlock()
+	output := r.pid.updateCapped(value, reading.Quality, maxDT)
+
+	r.mu.Lock()
+	policy, monitor := r.policy, r.monitor
+	applied, hasApplied := r.lastOutput, r.hasOutput
+	r.mu.Unlock()
+	if monitor != nil && hasApplied && reading.Quality.IsGood() {
+		// value was measured while the last accepted output was in effect.
+		monitor.Observe(time.Now(), applied, value)
+	}
+
+	err = r.sink.Write(ctx, output)
+	for i := 0; err != nil && i < policy.Retries && ctx.Err() == nil; i++ {
+		if policy.RetryDelay > 0 {
+			select {
+			case <-ctx.Done():
+			case <-time.After(policy.RetryDelay):
+			}
+		}
+		err = r.sink.Write(ctx, output)
+	}
+	r.recordWrite(policy, output, err)
+
+	r.mu.Lock()
+	position := r.position
+	r.mu.Unlock()
+	if err == nil && position != nil {
+		if pos, err := position.Read(ctx); err == nil {
+			r.pid.ReportPosition(pos)
+		}
+	}
+
+	return nil
+}
+
+// read reads the source, with quality when it reports one.
+func (r *Runner) read(ctx context.Context) (Reading, error) {
+	if qs, ok := r.source.(QualitySource); ok {
+		return qs.ReadQuality(ctx)
+	}
+	v, err := r.source.Read(ctx)
+	return Reading{Value: v}, err
+}
+
+func (r *Runner) recordWrite(policy SinkPolicy, output float64, err error) {
+	r.mu.Lock()
+	var notify func()
+	if err == nil {
+		if policy.FailureThreshold > 0 && r.sinkFailures >= policy.FailureThreshold && policy.OnRecover != nil {
+			notify = policy.OnRecover
+		}
+		r.lastOutput, r.hasOutput = output, true
+		r.sinkFailures = 0
+	} else {
+		r.sinkFailures++
+		if r.sinkFailures == policy.FailureThreshold && policy.OnFailure != nil {
+			n := r.sinkFailures
+			notify = func() { policy.OnFailure(n, err) }
+		}
+	}
+	r.mu.Unlock()
+
+	if notify != nil {
+		notify()
+	}
+}
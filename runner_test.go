@@ -0,0 +1,143 @@
+package pidpool_test
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/ankur-anand/go-pidpool"
+)
+
+func TestRunner_SinkFailureHoldsAndRecovers(t *testing.T) {
+	p := pidpool.NewPID(1, 0, 0, 0)
+	p.SetSetPoint(10)
+
+	var failing atomic.Bool
+	failing.Store(true)
+	var writes atomic.Int32
+	sink := pidpool.SinkFunc(func(context.Context, float64) error {
+		writes.Add(1)
+		if failing.Load() {
+			return errors.New("serial port hiccup")
+		}
+		return nil
+	})
+	source := pidpool.SourceFunc(func(context.Context) (float64, error) { return 4, nil })
+
+	var mu sync.Mutex
+	var failedAt int
+	recovered := make(chan struct{})
+	r := pidpool.NewRunner(p, time.Millisecond, source, sink)
+	r.SetSinkPolicy(pidpool.SinkPolicy{
+		Retries:          1,
+		FailureThreshold: 3,
+		OnFailure: func(n int, err error) {
+			mu.Lock()
+			defer mu.Unlock()
+			failedAt = n
+			failing.Store(false)
+		},
+		OnRecover: func() { close(recovered) },
+	})
+	if err := r.Start(); err != nil {
+		t.Fatalf("Start err: %v", err)
+	}
+	defer r.Stop()
+	if err := r.Start(); err != pidpool.ErrRunnerStarted {
+		t.Fatalf("expected ErrRunnerStarted, got %v", err)
+	}
+
+	select {
+	case <-recovered:
+	case <-time.After(2 * time.Second):
+		t.Fatalf("runner did not recover")
+	}
+	r.Stop()
+
+	mu.Lock()
+	defer mu.Unlock()
+	if failedAt != 3 {
+		t.Fatalf("expected failure event after 3 ticks, got %d", failedAt)
+	}
+	if writes.Load() < 7 {
+		t.Fatalf("expected retries to re-send outputs, got %d writes", writes.Load())
+	}
+	if out, ok := r.LastOutput(); !ok || out != 6 {
+		t.Fatalf("unexpected last output %v (%v)", out, ok)
+	}
+}
+
+func TestRunner_PauseResumeStop(t *testing.T) {
+	p := pidpool.NewP(1)
+	var ticks atomic.Int32
+	source := pidpool.SourceFunc(func(context.Context) (float64, error) {
+		ticks.Add(1)
+		return 0, nil
+	})
+	sink := pidpool.SinkFunc(func(context.Context, float64) error { return nil })
+	r := pidpool.NewRunner(p, time.Millisecond, source, sink)
+
+	var mu sync.Mutex
+	var transitions []string
+	r.OnStateChange(func(from, to pidpool.RunnerState) {
+		mu.Lock()
+		defer mu.Unlock()
+		transitions = append(transitions, from.String()+"->"+to.String())
+	})
+
+	if err := r.Pause(); !errors.Is(err, pidpool.ErrRunnerNotRunning) {
+		t.Fatalf("expected ErrRunnerNotRunning, got %v", err)
+	}
+	if err := r.Start(); err != nil {
+		t.Fatalf("Start err: %v", err)
+	}
+	waitTicks := func(n int32) {
+		t.Helper()
+		deadline := time.Now().Add(2 * time.Second)
+		for ticks.Load() < n {
+			if time.Now().After(deadline) {
+				t.Fatalf("expected %d ticks, got %d", n, ticks.Load())
+			}
+			time.Sleep(time.Millisecond)
+		}
+	}
+	waitTicks(2)
+
+	if err := r.Pause(); err != nil {
+		t.Fatalf("Pause err: %v", err)
+	}
+	if r.State() != pidpool.RunnerPaused {
+		t.Fatalf("expected paused, got %v", r.State())
+	}
+	paused := ticks.Load()
+	time.Sleep(20 * time.Millisecond)
+	if ticks.Load() != paused {
+		t.Fatalf("ticked while paused")
+	}
+
+	if err := r.Resume(); err != nil {
+		t.Fatalf("Resume err: %v", err)
+	}
+	waitTicks(paused + 2)
+	r.Stop()
+	stopped := ticks.Load()
+	time.Sleep(20 * time.Millisecond)
+	if ticks.Load() != stopped || r.State() != pidpool.RunnerStopped {
+		t.Fatalf("ticked after Stop or not stopped: %v", r.State())
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+	want := []string{"stopped->running", "running->paused", "paused->running", "running->stopped"}
+	if len(transitions) != len(want) {
+		t.Fatalf("expected transitions %v, got %v", want, transitions)
+	}
+	for i := range want {
+		if transitions[i] != want[i] {
+			t.Fatalf("expected transitions %v, got %v", want, transitions)
+		}
+	}
+}
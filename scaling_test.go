@@ -0,0 +1,58 @@
+package pidpool_test
+
+import (
+	"math"
+	"testing"
+
+	"github.com/ankur-anand/go-pidpool"
+)
+
+func TestInputOutputRange_SharedTuning(t *testing.T) {
+	// the same percent tuning on a 0-400 °C sensor driving a 4-20 mA valve
+	// and on a 0-100 °C sensor driving a 0-10 V valve.
+	a := pidpool.NewPI(2, 0.5)
+	a.SetInputRange(0, 400)
+	a.SetOutputRange(4, 20)
+	a.SetSetPoint(200)
+
+	b := pidpool.NewPI(2, 0.5)
+	b.SetInputRange(0, 100)
+	b.SetOutputRange(0, 10)
+	b.SetSetPoint(50)
+
+	// both 10% below the setpoint.
+	outA := a.UpdateDuration(160, 1)
+	outB := b.UpdateDuration(40, 1)
+	// 2*10% + 0.5*10%*1s = 25%.
+	if math.Abs(outA-(4+0.25*16)) > 1e-9 || math.Abs(outB-0.25*10) > 1e-9 {
+		t.Fatalf("expected 25%% of the output ranges, got %v and %v", outA, outB)
+	}
+
+	if lo, hi, ok := a.GetOutputRange(); !ok || lo != 4 || hi != 20 {
+		t.Fatalf("GetOutputRange: %v %v %v", lo, hi, ok)
+	}
+	if err := a.SetInputRange(1, 1); err == nil {
+		t.Fatalf("expected error for an empty range")
+	}
+
+	a.ClearInputRange()
+	a.ClearOutputRange()
+	a.Reset()
+	if out := a.UpdateDuration(160, 1); out != 2*40+0.5*40 {
+		t.Fatalf("expected engineering-unit gains after clearing, got %v", out)
+	}
+}
+
+func TestOutputRange_BumplessTransfer(t *testing.T) {
+	pid := pidpool.NewPI(1, 0.5)
+	pid.SetOutputRange(4, 20)
+	pid.SetSetPoint(10)
+	pid.SetManualOutput(12)
+	pid.SetMode(pidpool.Manual)
+	pid.UpdateDuration(10, 1)
+
+	pid.SetMode(pidpool.Auto)
+	if out := pid.UpdateDuration(10, 1); math.Abs(out-12) > 1e-9 {
+		t.Fatalf("bump on transfer to auto: got %v", out)
+	}
+}
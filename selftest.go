@@ -0,0 +1,123 @@
+package pidpool
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"time"
+)
+
+// Self-test failures.
+var (
+	// ErrNoResponse means the measurement did not move when the output
+	// did, e.g. a dead sensor or a disconnected actuator.
+	ErrNoResponse = errors.New("self-test: measurement did not respond")
+	// ErrReversedAction means the measurement moved the wrong way, e.g.
+	// reversed wiring.
+	ErrReversedAction = errors.New("self-test: measurement responded with the wrong sign")
+	// ErrUnexpectedGain means the response was far from the expected
+	// magnitude.
+	ErrUnexpectedGain = errors.New("self-test: response magnitude out of range")
+)
+
+// SelfTest configures a startup self-test. The test holds Baseline, steps
+// the output by Step, and checks that the measurement moves by roughly
+// ExpectedGain*Step before returning to Baseline.
+type SelfTest struct {
+	// Baseline is the output held before and after the step.
+	Baseline float64
+	// Step is the output change. The stepped output is clamped to
+	// [MinOutput, MaxOutput].
+	Step float64
+	// MinOutput and MaxOutput bound the outputs the test may write. Both
+	// zero means unbounded.
+	MinOutput float64
+	MaxOutput float64
+	// Settle is how long to wait after each output change before reading
+	// the measurement.
+	Settle time.Duration
+	// ExpectedGain is the expected change of the measurement per unit of
+	// output; its sign is the direction of action.
+	ExpectedGain float64
+	// Tolerance is the factor by which the measured gain may differ from
+	// ExpectedGain. Defaults to 3.
+	Tolerance float64
+	// MinResponse is the smallest measurement change that is not noise.
+	MinResponse float64
+}
+
+// SelfTestResult reports the measurements of a self-test.
+type SelfTestResult struct {
+	Before float64
+	After  float64
+	// Gain is the measured change of the measurement per unit of output.
+	Gain float64
+}
+
+// SelfTest runs the startup self-test against the runner's source and sink.
+// Call it before Start; automatic control should only be enabled when it
+// returns nil. The output is returned to Baseline whatever the outcome.
+func (r *Runner) SelfTest(ctx context.Context, cfg SelfTest) (SelfTestResult, error) {
+	if cfg.Step == 0 || cfg.ExpectedGain == 0 {
+		return SelfTestResult{}, errors.New("self-test step and expected gain must not be zero")
+	}
+	if cfg.Tolerance == 0 {
+		cfg.Tolerance = 3
+	}
+	if cfg.Tolerance < 1 {
+		return SelfTestResult{}, errors.New("self-test tolerance must be at least 1")
+	}
+	bound := func(v float64) float64 {
+		if cfg.MinOutput == 0 && cfg.MaxOutput == 0 {
+			return v
+		}
+		return math.Max(cfg.MinOutput, math.Min(cfg.MaxOutput, v))
+	}
+
+	baseline := bound(cfg.Baseline)
+	stepped := bound(cfg.Baseline + cfg.Step)
+	if stepped == baseline {
+		return SelfTestResult{}, errors.New("self-test step vanishes within the output bounds")
+	}
+
+	var res SelfTestResult
+	var err error
+	defer func() {
+		// leave the actuator at the baseline even when ctx is done.
+		_ = r.sink.Write(context.WithoutCancel(ctx), baseline)
+	}()
+	if res.Before, err = r.settleAndRead(ctx, baseline, cfg.Settle); err != nil {
+		return res, err
+	}
+	if res.After, err = r.settleAndRead(ctx, stepped, cfg.Settle); err != nil {
+		return res, err
+	}
+
+	delta := res.After - res.Before
+	res.Gain = delta / (stepped - baseline)
+	switch {
+	case math.Abs(delta) < cfg.MinResponse || delta == 0:
+		return res, ErrNoResponse
+	case math.Signbit(res.Gain) != math.Signbit(cfg.ExpectedGain):
+		return res, ErrReversedAction
+	case res.Gain/cfg.ExpectedGain > cfg.Tolerance || cfg.ExpectedGain/res.Gain > cfg.Tolerance:
+		return res, fmt.Errorf("%w: gain %g, expected %g", ErrUnexpectedGain, res.Gain, cfg.ExpectedGain)
+	}
+
+	return res, nil
+}
+
+func (r *Runner) settleAndRead(ctx context.Context, output float64, settle time.Duration) (float64, error) {
+	if err := r.sink.Write(ctx, output); err != nil {
+		return 0, err
+	}
+	t := time.NewTimer(settle)
+	defer t.Stop()
+	select {
+	case <-ctx.Done():
+		return 0, ctx.Err()
+	case <-t.C:
+	}
+	return r.source.Read(ctx)
+}
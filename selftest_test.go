@@ -0,0 +1,57 @@
+package pidpool_test
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+
+	"github.com/ankur-anand/go-pidpool"
+)
+
+// heater is a static plant: the measurement is 20 plus gain times the
+// output.
+type heater struct {
+	mu   sync.Mutex
+	gain float64
+	out  float64
+}
+
+func (h *heater) Read(context.Context) (float64, error) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	return 20 + h.gain*h.out, nil
+}
+
+func (h *heater) Write(_ context.Context, out float64) error {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.out = out
+	return nil
+}
+
+func TestRunner_SelfTest(t *testing.T) {
+	cfg := pidpool.SelfTest{Step: 20, MaxOutput: 10, ExpectedGain: 0.5, MinResponse: 0.1}
+	for _, tc := range []struct {
+		gain float64
+		want error
+	}{
+		{0.4, nil},
+		{-0.5, pidpool.ErrReversedAction},
+		{0, pidpool.ErrNoResponse},
+		{5, pidpool.ErrUnexpectedGain},
+	} {
+		h := &heater{gain: tc.gain}
+		r := pidpool.NewRunner(pidpool.NewP(1), 1, h, h)
+		res, err := r.SelfTest(context.Background(), cfg)
+		if !errors.Is(err, tc.want) {
+			t.Fatalf("gain %v: expected %v, got %v (%+v)", tc.gain, tc.want, err, res)
+		}
+		if h.out != 0 {
+			t.Fatalf("gain %v: output not returned to baseline: %v", tc.gain, h.out)
+		}
+		if tc.want == nil && res.Gain != 0.4 {
+			t.Fatalf("unexpected measured gain %v", res.Gain)
+		}
+	}
+}
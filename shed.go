@@ -0,0 +1,104 @@
+package pidpool
+
+import (
+	"errors"
+	"math/rand/v2"
+	"sync"
+)
+
+// Tier is a priority class used by PriorityShedder.
+type Tier struct {
+	// Name identifies the tier, e.g. "batch" or "interactive".
+	Name string
+	// Threshold is the controller output at which the tier starts shedding.
+	Threshold float64
+	// Span is the output range over which the tier goes from admitting
+	// everything to shedding everything. Zero means the tier is shed
+	// completely as soon as the output crosses Threshold.
+	Span float64
+}
+
+// PriorityShedder turns a PID output into per-tier drop probabilities.
+// Tiers are ordered from the lowest priority to the highest, so low
+// priority traffic is shed first and high priority traffic is only touched
+// once the output exceeds its (higher) threshold.
+type PriorityShedder struct {
+	mu sync.Mutex
+
+	tiers  []Tier
+	output float64
+}
+
+// NewPriorityShedder returns a shedder for the given tiers, lowest priority first.
+func NewPriorityShedder(tiers ...Tier) (*PriorityShedder, error) {
+	if len(tiers) == 0 {
+		return nil, errors.New("at least one tier is required")
+	}
+	for i, t := range tiers {
+		if t.Span < 0 {
+			return nil, errors.New("tier span must not be negative")
+		}
+		if i > 0 && t.Threshold < tiers[i-1].Threshold {
+			return nil, errors.New("tier thresholds must be non-decreasing")
+		}
+	}
+
+	return &PriorityShedder{tiers: append([]Tier(nil), tiers...)}, nil
+}
+
+// SetOutput records the latest controller output.
+func (s *PriorityShedder) SetOutput(output float64) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.output = output
+}
+
+// Output returns the latest controller output.
+func (s *PriorityShedder) Output() float64 {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.output
+}
+
+// Tiers returns the configured tiers.
+func (s *PriorityShedder) Tiers() []Tier {
+	return append([]Tier(nil), s.tiers...)
+}
+
+// DropProbability returns the fraction of requests of the given tier that
+// should be shed at the current output. Unknown tiers are never shed.
+func (s *PriorityShedder) DropProbability(tier int) float64 {
+	if tier < 0 || tier >= len(s.tiers) {
+		return 0
+	}
+	s.mu.Lock()
+	out := s.output
+	s.mu.Unlock()
+
+	t := s.tiers[tier]
+	if out < t.Threshold {
+		return 0
+	}
+	if t.Span == 0 {
+		return 1
+	}
+	p := (out - t.Threshold) / t.Span
+	if p > 1 {
+		p = 1
+	}
+
+	return p
+}
+
+// Admit reports whether a request of the given tier should be admitted.
+func (s *PriorityShedder) Admit(tier int) bool {
+	p := s.DropProbability(tier)
+	if p <= 0 {
+		return true
+	}
+	if p >= 1 {
+		return false
+	}
+
+	return rand.Float64() >= p
+}
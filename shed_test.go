@@ -0,0 +1,52 @@
+package pidpool_test
+
+import (
+	"testing"
+
+	"github.com/ankur-anand/go-pidpool"
+)
+
+func TestPriorityShedder_LowTiersFirst(t *testing.T) {
+	s, err := pidpool.NewPriorityShedder(
+		pidpool.Tier{Name: "batch", Threshold: 0, Span: 10},
+		pidpool.Tier{Name: "interactive", Threshold: 10, Span: 10},
+	)
+	if err != nil {
+		t.Fatalf("NewPriorityShedder err: %v", err)
+	}
+
+	s.SetOutput(5)
+	if p := s.DropProbability(0); p != 0.5 {
+		t.Fatalf("batch drop: expected 0.5, got %v", p)
+	}
+	if p := s.DropProbability(1); p != 0 {
+		t.Fatalf("interactive drop: expected 0, got %v", p)
+	}
+	if !s.Admit(1) {
+		t.Fatalf("interactive traffic must be admitted below its threshold")
+	}
+
+	s.SetOutput(25)
+	if p := s.DropProbability(0); p != 1 {
+		t.Fatalf("batch drop: expected 1, got %v", p)
+	}
+	if p := s.DropProbability(1); p != 1 {
+		t.Fatalf("interactive drop: expected 1, got %v", p)
+	}
+	if s.Admit(0) {
+		t.Fatalf("batch traffic must be shed at full output")
+	}
+}
+
+func TestPriorityShedder_InvalidTiers(t *testing.T) {
+	if _, err := pidpool.NewPriorityShedder(); err == nil {
+		t.Fatalf("expected error for no tiers")
+	}
+	_, err := pidpool.NewPriorityShedder(
+		pidpool.Tier{Threshold: 10},
+		pidpool.Tier{Threshold: 5},
+	)
+	if err == nil {
+		t.Fatalf("expected error for decreasing thresholds")
+	}
+}
@@ -0,0 +1,156 @@
+// Package sim provides building blocks for simulating PID loops offline.
+package sim
+
+import (
+	"math"
+	"time"
+)
+
+// Actuator models the element between the controller output and the plant
+// input. Apply receives the commanded output dt seconds after the previous
+// call and returns what actually reaches the plant.
+type Actuator interface {
+	Apply(command, dt float64) float64
+	Reset()
+}
+
+// Ideal passes the command through unchanged.
+type Ideal struct{}
+
+// Apply implements Actuator.
+func (Ideal) Apply(command, dt float64) float64 { return command }
+
+// Reset implements Actuator.
+func (Ideal) Reset() {}
+
+// RateLimit moves towards the command at no more than Rate units per
+// second, like a motorized valve.
+type RateLimit struct {
+	Rate float64
+
+	pos    float64
+	primed bool
+}
+
+// Apply implements Actuator.
+func (a *RateLimit) Apply(command, dt float64) float64 {
+	if !a.primed {
+		a.pos, a.primed = command, true
+		return a.pos
+	}
+	step := a.Rate * dt
+	a.pos += math.Max(-step, math.Min(step, command-a.pos))
+	return a.pos
+}
+
+// Reset implements Actuator.
+func (a *RateLimit) Reset() { a.pos, a.primed = 0, false }
+
+// DeadZone ignores commands whose magnitude is below Width, like a valve
+// that does not open until a minimum signal is applied.
+type DeadZone struct {
+	Width float64
+}
+
+// Apply implements Actuator.
+func (a DeadZone) Apply(command, dt float64) float64 {
+	if math.Abs(command) < a.Width {
+		return 0
+	}
+	return command
+}
+
+// Reset implements Actuator.
+func (DeadZone) Reset() {}
+
+// Backlash only moves once the command has travelled more than Width past
+// the current position, like a sticky valve or a gear train with play.
+type Backlash struct {
+	Width float64
+
+	pos float64
+}
+
+// Apply implements Actuator.
+func (a *Backlash) Apply(command, dt float64) float64 {
+	half := a.Width / 2
+	switch {
+	case command > a.pos+half:
+		a.pos = command - half
+	case command < a.pos-half:
+		a.pos = command + half
+	}
+	return a.pos
+}
+
+// Reset implements Actuator.
+func (a *Backlash) Reset() { a.pos = 0 }
+
+// Quantizer rounds the command to multiples of Step, like a stepper motor
+// or a DAC with limited resolution.
+type Quantizer struct {
+	Step float64
+}
+
+// Apply implements Actuator.
+func (a Quantizer) Apply(command, dt float64) float64 {
+	if a.Step <= 0 {
+		return command
+	}
+	return math.Round(command/a.Step) * a.Step
+}
+
+// Reset implements Actuator.
+func (Quantizer) Reset() {}
+
+// Delay applies the command after a transport delay. Until the first
+// command has travelled through, it outputs Initial.
+type Delay struct {
+	Delay   time.Duration
+	Initial float64
+
+	now     float64
+	pending []timed
+}
+
+type timed struct {
+	at, v float64
+}
+
+// Apply implements Actuator.
+func (a *Delay) Apply(command, dt float64) float64 {
+	a.now += dt
+	a.pending = append(a.pending, timed{at: a.now + a.Delay.Seconds(), v: command})
+
+	out := a.Initial
+	i := 0
+	for ; i < len(a.pending) && a.pending[i].at <= a.now+1e-12; i++ {
+		out = a.pending[i].v
+	}
+	if i > 0 {
+		a.Initial = out
+		a.pending = a.pending[i:]
+	}
+	return out
+}
+
+// Reset implements Actuator.
+func (a *Delay) Reset() { a.now, a.pending, a.Initial = 0, nil, 0 }
+
+// Chain applies actuators in order.
+type Chain []Actuator
+
+// Apply implements Actuator.
+func (c Chain) Apply(command, dt float64) float64 {
+	for _, a := range c {
+		command = a.Apply(command, dt)
+	}
+	return command
+}
+
+// Reset implements Actuator.
+func (c Chain) Reset() {
+	for _, a := range c {
+		a.Reset()
+	}
+}
@@ -0,0 +1,41 @@
+package sim_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/ankur-anand/go-pidpool/sim"
+)
+
+func TestActuators(t *testing.T) {
+	rl := &sim.RateLimit{Rate: 10}
+	rl.Apply(0, 0.1)
+	if got := rl.Apply(100, 0.1); got != 1 {
+		t.Fatalf("RateLimit: expected 1, got %v", got)
+	}
+
+	if got := (sim.DeadZone{Width: 5}).Apply(3, 0.1); got != 0 {
+		t.Fatalf("DeadZone: expected 0, got %v", got)
+	}
+
+	bl := &sim.Backlash{Width: 2}
+	bl.Apply(10, 0.1) // position 9.
+	if got := bl.Apply(9.5, 0.1); got != 9 {
+		t.Fatalf("Backlash: small reversal must not move, got %v", got)
+	}
+
+	if got := (sim.Quantizer{Step: 0.5}).Apply(1.3, 0.1); got != 1.5 {
+		t.Fatalf("Quantizer: expected 1.5, got %v", got)
+	}
+
+	d := &sim.Delay{Delay: 200 * time.Millisecond}
+	outs := []float64{d.Apply(1, 0.1), d.Apply(2, 0.1), d.Apply(3, 0.1), d.Apply(4, 0.1)}
+	if outs[0] != 0 || outs[1] != 0 || outs[2] != 1 || outs[3] != 2 {
+		t.Fatalf("Delay: unexpected outputs %v", outs)
+	}
+
+	chain := sim.Chain{sim.Quantizer{Step: 1}, sim.DeadZone{Width: 2}}
+	if got := chain.Apply(1.4, 0.1); got != 0 {
+		t.Fatalf("Chain: expected 0, got %v", got)
+	}
+}
@@ -0,0 +1,118 @@
+package sim
+
+import (
+	"errors"
+	"sort"
+	"time"
+
+	"github.com/ankur-anand/go-pidpool"
+	"github.com/ankur-anand/go-pidpool/plant"
+)
+
+// Change sets a scheduled quantity to Value from time At on.
+type Change struct {
+	At    time.Duration
+	Value float64
+}
+
+// Scenario is a schedule of setpoint steps and load disturbances for a
+// closed-loop simulation.
+type Scenario struct {
+	// Duration is the length of the run and DT the controller period.
+	Duration time.Duration
+	DT       time.Duration
+
+	// SetPoint is the initial setpoint, changed by SetPoints.
+	SetPoint  float64
+	SetPoints []Change
+	// Loads are step load disturbances, added to the plant input. The load
+	// is zero until the first change.
+	Loads []Change
+
+	// Actuator sits between the controller and the plant. Nil is Ideal.
+	Actuator Actuator
+}
+
+// Point is one controller period of a simulation.
+type Point struct {
+	// Time is the time since the start of the run.
+	Time     time.Duration
+	SetPoint float64
+	// Value is the process value the controller saw.
+	Value float64
+	// Output is the controller output and Applied what the actuator made
+	// of it; Load is the disturbance added to Applied.
+	Output  float64
+	Applied float64
+	Load    float64
+}
+
+// Trajectory is the result of a simulation, one point per period.
+type Trajectory []Point
+
+// IAE returns the integral of the absolute error over the trajectory.
+func (tr Trajectory) IAE() float64 {
+	sum := 0.0
+	for i := 1; i < len(tr); i++ {
+		e := tr[i].SetPoint - tr[i].Value
+		if e < 0 {
+			e = -e
+		}
+		sum += e * (tr[i].Time - tr[i-1].Time).Seconds()
+	}
+	return sum
+}
+
+// Simulate runs pid against p according to the scenario and returns the
+// full trajectory. The run is fully simulated and does not sleep. Neither
+// the controller nor the plant is reset first, so reset both to compare
+// runs; the controller's setpoint is left at the last scheduled value.
+func Simulate(pid *pidpool.PID, p plant.Model, sc Scenario) (Trajectory, error) {
+	if sc.DT <= 0 {
+		return nil, errors.New("dt must be positive")
+	}
+	if sc.Duration < 0 {
+		return nil, errors.New("duration must not be negative")
+	}
+	act := sc.Actuator
+	if act == nil {
+		act = Ideal{}
+	}
+	setPoints, loads := sorted(sc.SetPoints), sorted(sc.Loads)
+
+	h := sc.DT.Seconds()
+	n := int(sc.Duration / sc.DT)
+	tr := make(Trajectory, 0, n)
+	setPoint, load := sc.SetPoint, 0.0
+	y := p.Step(0, 0)
+	for i := 0; i < n; i++ {
+		now := time.Duration(i) * sc.DT
+		for len(setPoints) > 0 && setPoints[0].At <= now {
+			setPoint, setPoints = setPoints[0].Value, setPoints[1:]
+		}
+		for len(loads) > 0 && loads[0].At <= now {
+			load, loads = loads[0].Value, loads[1:]
+		}
+
+		pid.SetSetPoint(setPoint)
+		u := pid.UpdateDuration(y, h)
+		applied := act.Apply(u, h)
+		tr = append(tr, Point{
+			Time:     now,
+			SetPoint: setPoint,
+			Value:    y,
+			Output:   u,
+			Applied:  applied,
+			Load:     load,
+		})
+		y = p.Step(applied+load, h)
+	}
+
+	return tr, nil
+}
+
+func sorted(changes []Change) []Change {
+	out := append([]Change(nil), changes...)
+	sort.SliceStable(out, func(i, j int) bool { return out[i].At < out[j].At })
+	return out
+}
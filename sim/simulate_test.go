@@ -0,0 +1,51 @@
+package sim_test
+
+import (
+	"math"
+	"testing"
+	"time"
+
+	"github.com/ankur-anand/go-pidpool"
+	"github.com/ankur-anand/go-pidpool/plant"
+	"github.com/ankur-anand/go-pidpool/sim"
+)
+
+func TestSimulate_StepAndDisturbance(t *testing.T) {
+	sc := sim.Scenario{
+		Duration:  120 * time.Second,
+		DT:        100 * time.Millisecond,
+		SetPoints: []sim.Change{{At: 5 * time.Second, Value: 10}},
+		Loads:     []sim.Change{{At: 60 * time.Second, Value: -2}},
+	}
+	run := func(integralMax float64) sim.Trajectory {
+		pid := pidpool.NewPI(1, 0.5)
+		_ = pid.SetOutputLimits(0, 8)
+		_ = pid.SetIntegralLimits(-integralMax, integralMax)
+		tr, err := sim.Simulate(pid, &plant.FirstOrder{Gain: 2, TimeConstant: 5}, sc)
+		if err != nil {
+			t.Fatalf("Simulate err: %v", err)
+		}
+		return tr
+	}
+
+	tr := run(100)
+	if len(tr) != 1200 {
+		t.Fatalf("unexpected length %d", len(tr))
+	}
+	if p := tr[49]; p.SetPoint != 0 || p.Load != 0 {
+		t.Fatalf("change applied early: %+v", p)
+	}
+	if p := tr[600]; p.SetPoint != 10 || p.Load != -2 {
+		t.Fatalf("change not applied: %+v", p)
+	}
+	if v := tr[599].Value; math.Abs(v-10) > 0.05 {
+		t.Fatalf("not settled before the disturbance: %v", v)
+	}
+	if v := tr[len(tr)-1].Value; math.Abs(v-10) > 0.05 {
+		t.Fatalf("disturbance not rejected: %v", v)
+	}
+
+	if tr.IAE() <= 0 || run(100).IAE() != tr.IAE() {
+		t.Fatalf("unexpected IAE %v", tr.IAE())
+	}
+}
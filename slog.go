@@ -0,0 +1,87 @@
+package pidpool
+
+import (
+	"context"
+	"log/slog"
+	"sync"
+)
+
+// WithLogger logs the behavior of the controller to logger with structured
+// attributes, so it shows up in standard service logs:
+//
+//   - every everyN-th update at level, with the term breakdown; everyN <= 0
+//     disables the update records;
+//   - mode changes, and saturation entry and exit, at Info;
+//   - entering the fault state at Error, with its reason, and leaving it
+//     at Info.
+//
+// Attach identifying attributes with logger.With. The returned function
+// detaches the logger.
+func (pid *PID) WithLogger(logger *slog.Logger, level slog.Level, everyN int) (remove func()) {
+	l := &pidLogger{logger: logger, level: level, everyN: everyN}
+	return pid.SubscribeWith(l.logEvent, SubscribeOptions{Updates: everyN > 0})
+}
+
+type pidLogger struct {
+	logger *slog.Logger
+	level  slog.Level
+	everyN int
+
+	mu sync.Mutex
+	n  int
+}
+
+func (l *pidLogger) logEvent(ev Event) {
+	ctx := context.Background()
+	switch ev.Kind {
+	case EventUpdated:
+		l.logUpdate(ev.Update)
+	case EventModeChanged:
+		l.logger.LogAttrs(ctx, slog.LevelInfo, "pid mode changed",
+			slog.String("from", ev.Previous.Mode.String()),
+			slog.String("to", ev.State.Mode.String()),
+		)
+	case EventSaturationEntered, EventSaturationLeft:
+		msg := "pid output saturated"
+		if ev.Kind == EventSaturationLeft {
+			msg = "pid output left saturation"
+		}
+		l.logger.LogAttrs(ctx, slog.LevelInfo, msg,
+			slog.Float64("output", ev.Update.Output),
+			slog.Float64("raw_output", ev.Update.RawOutput),
+		)
+	case EventFaultRaised:
+		l.logger.LogAttrs(ctx, slog.LevelError, "pid fault",
+			slog.String("reason", ev.Fault.Reason),
+			slog.Time("since", ev.Fault.Time),
+		)
+	case EventFaultCleared:
+		l.logger.LogAttrs(ctx, slog.LevelInfo, "pid fault cleared")
+	}
+}
+
+func (l *pidLogger) logUpdate(ev UpdateEvent) {
+	l.mu.Lock()
+	l.n++
+	skip := l.n%l.everyN != 0
+	l.mu.Unlock()
+	if skip {
+		return
+	}
+
+	l.logger.LogAttrs(context.Background(), l.level, "pid update",
+		slog.Float64("setpoint", ev.SetPoint),
+		slog.Float64("value", ev.Value),
+		slog.Float64("error", ev.Error),
+		slog.Float64("dt", ev.DT),
+		slog.Float64("p", ev.P),
+		slog.Float64("i", ev.I),
+		slog.Float64("d", ev.D),
+		slog.Float64("feed_forward", ev.FeedForward),
+		slog.Float64("raw_output", ev.RawOutput),
+		slog.Float64("output", ev.Output),
+		slog.Bool("saturated", ev.Status().Saturated),
+		slog.Bool("integral_clamped", ev.IntegralClamped),
+		slog.String("quality", ev.Quality.String()),
+	)
+}
@@ -0,0 +1,77 @@
+package pidpool_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"log/slog"
+	"strings"
+	"testing"
+
+	"github.com/ankur-anand/go-pidpool"
+)
+
+func logRecords(t *testing.T, buf *bytes.Buffer) []map[string]any {
+	t.Helper()
+	var recs []map[string]any
+	for _, line := range strings.Split(strings.TrimSpace(buf.String()), "\n") {
+		if line == "" {
+			continue
+		}
+		var rec map[string]any
+		if err := json.Unmarshal([]byte(line), &rec); err != nil {
+			t.Fatalf("bad log line %q: %v", line, err)
+		}
+		recs = append(recs, rec)
+	}
+	buf.Reset()
+	return recs
+}
+
+func TestWithLogger(t *testing.T) {
+	var buf bytes.Buffer
+	logger := slog.New(slog.NewJSONHandler(&buf, &slog.HandlerOptions{Level: slog.LevelDebug}))
+
+	pid := pidpool.NewP(1)
+	pid.SetOutputLimits(-10, 10)
+	pid.SetSetPoint(5)
+	remove := pid.WithLogger(logger.With("loop", "oven"), slog.LevelDebug, 2)
+
+	pid.UpdateDuration(4, 1)
+	if recs := logRecords(t, &buf); len(recs) != 0 {
+		t.Fatalf("expected no record on the first update, got %v", recs)
+	}
+	pid.UpdateDuration(4, 1)
+	recs := logRecords(t, &buf)
+	if len(recs) != 1 || recs[0]["msg"] != "pid update" || recs[0]["level"] != "DEBUG" ||
+		recs[0]["loop"] != "oven" || recs[0]["error"] != 1.0 || recs[0]["output"] != 1.0 {
+		t.Fatalf("unexpected update record: %v", recs)
+	}
+
+	pid.SetSetPoint(100)
+	pid.UpdateDuration(4, 1)
+	if recs := logRecords(t, &buf); len(recs) != 1 || recs[0]["msg"] != "pid output saturated" || recs[0]["raw_output"] != 96.0 {
+		t.Fatalf("expected a saturation record, got %v", recs)
+	}
+
+	if err := pid.SetMode(pidpool.Manual); err != nil {
+		t.Fatalf("SetMode err: %v", err)
+	}
+	if recs := logRecords(t, &buf); len(recs) != 1 || recs[0]["msg"] != "pid mode changed" || recs[0]["to"] != "manual" {
+		t.Fatalf("expected a mode record, got %v", recs)
+	}
+
+	pid.Trip("door open")
+	recs = logRecords(t, &buf)
+	if len(recs) != 1 || recs[0]["msg"] != "pid fault" || recs[0]["level"] != "ERROR" || recs[0]["reason"] != "door open" {
+		t.Fatalf("expected a fault record, got %v", recs)
+	}
+
+	remove()
+	pid.ClearFault()
+	pid.SetMode(pidpool.Auto)
+	pid.UpdateDuration(4, 1)
+	pid.UpdateDuration(4, 1)
+	if recs := logRecords(t, &buf); len(recs) != 0 {
+		t.Fatalf("expected no records after remove, got %v", recs)
+	}
+}
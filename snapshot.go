@@ -0,0 +1,64 @@
+package pidpool
+
+import "time"
+
+// Snapshot is a consistent, read-only view of a controller's live values,
+// taken under a single lock acquisition. Unlike State it holds no slices or
+// maps, so taking one does not allocate; use it for monitoring and State
+// for persistence.
+type Snapshot struct {
+	Kp float64
+	Ki float64
+	Kd float64
+
+	SetPoint float64
+	DeadBand float64
+
+	OutputMin   float64
+	OutputMax   float64
+	IntegralMin float64
+	IntegralMax float64
+
+	Mode         Mode
+	ManualOutput float64
+	FeedForward  float64
+
+	Integral   float64
+	PrevError  float64
+	PrevValue  float64
+	LastUpdate time.Time
+
+	// LastOutput and LastStatus describe the most recent update.
+	LastOutput float64
+	LastStatus Status
+	// Faulted reports whether the controller is latched in a fault.
+	Faulted bool
+}
+
+// Snapshot returns the live values of the controller, all read at the same
+// instant.
+func (pid *PID) Snapshot() Snapshot {
+	pid.mu.Lock()
+	defer pid.mu.Unlock()
+	return Snapshot{
+		Kp:           pid.kp,
+		Ki:           pid.ki,
+		Kd:           pid.kd,
+		SetPoint:     pid.setPoint,
+		DeadBand:     pid.deadBand,
+		OutputMin:    pid.outputMin,
+		OutputMax:    pid.outputMax,
+		IntegralMin:  pid.integralMin,
+		IntegralMax:  pid.integralMax,
+		Mode:         pid.mode,
+		ManualOutput: pid.manualOutput,
+		FeedForward:  pid.feedForward,
+		Integral:     pid.integral,
+		PrevError:    pid.prevError,
+		PrevValue:    pid.prevValue,
+		LastUpdate:   pid.lastUpdate,
+		LastOutput:   pid.lastOutput,
+		LastStatus:   pid.lastStatus,
+		Faulted:      pid.faulted,
+	}
+}
@@ -0,0 +1,53 @@
+package pidpool_test
+
+import (
+	"sync"
+	"testing"
+
+	"github.com/ankur-anand/go-pidpool"
+)
+
+func TestSnapshot(t *testing.T) {
+	pid := pidpool.NewPID(2, 0.5, 0, 0.1)
+	pid.SetOutputLimits(-4, 4)
+	pid.SetSetPoint(10)
+	pid.UpdateDuration(8, 1)
+
+	s := pid.Snapshot()
+	if s.Kp != 2 || s.Ki != 0.5 || s.Kd != 0 || s.DeadBand != 0.1 {
+		t.Fatalf("unexpected gains in %+v", s)
+	}
+	if s.SetPoint != 10 || s.OutputMin != -4 || s.OutputMax != 4 || s.Mode != pidpool.Auto {
+		t.Fatalf("unexpected configuration in %+v", s)
+	}
+	if s.Integral != 2 || s.PrevError != 2 || s.PrevValue != 8 {
+		t.Fatalf("unexpected dynamic state in %+v", s)
+	}
+	if s.LastOutput != 4 || !s.LastStatus.Saturated || s.Faulted {
+		t.Fatalf("unexpected last update in %+v", s)
+	}
+	if st := pid.State(); !st.LastUpdate.Equal(s.LastUpdate) || st.Integral != s.Integral {
+		t.Fatalf("snapshot disagrees with State")
+	}
+}
+
+func TestSnapshot_Consistent(t *testing.T) {
+	pid := pidpool.NewP(1)
+	var wg sync.WaitGroup
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		for i := 0; i < 1000; i++ {
+			v := float64(i)
+			pid.SetSetPoint(v)
+			pid.UpdateDuration(0, 1)
+		}
+	}()
+	for i := 0; i < 1000; i++ {
+		// a P controller's output tracks the setpoint it was updated with.
+		if s := pid.Snapshot(); s.LastOutput != 0 && s.LastOutput != s.SetPoint && s.LastOutput != s.SetPoint-1 {
+			t.Fatalf("torn snapshot: setpoint %v, output %v", s.SetPoint, s.LastOutput)
+		}
+	}
+	wg.Wait()
+}
@@ -0,0 +1,125 @@
+package pidpool
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+)
+
+// SplitRangeConfig configures a SplitRange.
+type SplitRangeConfig struct {
+	// Min and Max are the range of the control signal, usually the output
+	// limits of the controller.
+	Min float64
+	Max float64
+	// LowEnd is the signal at which the low actuator is fully closed; it is
+	// fully open at Min. HighStart is the signal at which the high
+	// actuator starts to open; it is fully open at Max. LowEnd below
+	// HighStart leaves a dead zone where both are closed, LowEnd above
+	// HighStart an overlap where both act.
+	LowEnd    float64
+	HighStart float64
+	// Low and High map the opening of each actuator, a fraction in [0, 1],
+	// onto the actuator with its own scaling, limits, slew rate and
+	// quantization, e.g. OutputConfig{Scale: 100, Max: 100} for percent.
+	Low  OutputConfig
+	High OutputConfig
+}
+
+// SplitRange maps one control signal onto two actuators acting in
+// opposite directions, e.g. cooling on the lower half of the range and
+// heating on the upper half.
+type SplitRange struct {
+	mu        sync.Mutex
+	cfg       SplitRangeConfig
+	low, high *OutputStage
+	lastWrite time.Time
+}
+
+// NewSplitRange returns a split-range stage for cfg.
+func NewSplitRange(cfg SplitRangeConfig) (*SplitRange, error) {
+	if cfg.Min >= cfg.Max {
+		return nil, errors.New("min signal must be less than max signal")
+	}
+	if !(cfg.LowEnd > cfg.Min && cfg.LowEnd <= cfg.Max) {
+		return nil, errors.New("low end must be in (min, max]")
+	}
+	if !(cfg.HighStart >= cfg.Min && cfg.HighStart < cfg.Max) {
+		return nil, errors.New("high start must be in [min, max)")
+	}
+	low, err := NewOutputStage(cfg.Low)
+	if err != nil {
+		return nil, err
+	}
+	high, err := NewOutputStage(cfg.High)
+	if err != nil {
+		return nil, err
+	}
+
+	return &SplitRange{cfg: cfg, low: low, high: high}, nil
+}
+
+// Apply maps the control signal u, dt seconds after the previous call,
+// onto the low and high actuators.
+func (s *SplitRange) Apply(u, dt float64) (low, high float64) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.applyLocked(u, dt)
+}
+
+func (s *SplitRange) applyLocked(u, dt float64) (float64, float64) {
+	c := s.cfg
+	lowFrac := clamp((c.LowEnd-u)/(c.LowEnd-c.Min), 0, 1)
+	highFrac := clamp((u-c.HighStart)/(c.Max-c.HighStart), 0, 1)
+
+	return s.low.Apply(lowFrac, dt), s.high.Apply(highFrac, dt)
+}
+
+// Last returns the last values of the low and high actuators.
+func (s *SplitRange) Last() (low, high float64) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.low.Last(), s.high.Last()
+}
+
+// Reset forgets the previous values, so the slew limits do not apply to
+// the next call.
+func (s *SplitRange) Reset() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.low.Reset()
+	s.high.Reset()
+	s.lastWrite = time.Time{}
+}
+
+// Attach applies every update of pid to the stage and passes the results
+// to fn, if not nil. The returned function detaches it.
+func (s *SplitRange) Attach(pid *PID, fn func(low, high float64)) (remove func()) {
+	return pid.OnUpdate(func(ev UpdateEvent) {
+		low, high := s.Apply(ev.Output, ev.DT)
+		if fn != nil {
+			fn(low, high)
+		}
+	})
+}
+
+// Sink returns a Sink for a Runner that splits every output and writes it
+// to low and high. The slew limits use the wall time between writes. Both
+// actuators are written even when the first write fails; the errors are
+// joined.
+func (s *SplitRange) Sink(low, high Sink) Sink {
+	return SinkFunc(func(ctx context.Context, output float64) error {
+		s.mu.Lock()
+		now := time.Now()
+		dt := 0.0
+		if !s.lastWrite.IsZero() {
+			dt = now.Sub(s.lastWrite).Seconds()
+		}
+		s.lastWrite = now
+		l, h := s.applyLocked(output, dt)
+		s.mu.Unlock()
+
+		return errors.Join(low.Write(ctx, l), high.Write(ctx, h))
+	})
+}
@@ -0,0 +1,69 @@
+package pidpool_test
+
+import (
+	"context"
+	"math"
+	"testing"
+
+	"github.com/ankur-anand/go-pidpool"
+)
+
+func TestSplitRange(t *testing.T) {
+	pct := pidpool.OutputConfig{Scale: 100, Max: 100}
+	s, err := pidpool.NewSplitRange(pidpool.SplitRangeConfig{
+		Min: -100, Max: 100, LowEnd: -10, HighStart: 10, Low: pct, High: pct,
+	})
+	if err != nil {
+		t.Fatalf("NewSplitRange err: %v", err)
+	}
+
+	cases := []struct{ u, low, high float64 }{
+		{-100, 100, 0},
+		{-55, 50, 0},
+		{0, 0, 0}, // dead zone
+		{55, 0, 50},
+		{150, 0, 100},
+	}
+	for _, c := range cases {
+		low, high := s.Apply(c.u, 1)
+		if math.Abs(low-c.low) > 1e-9 || math.Abs(high-c.high) > 1e-9 {
+			t.Fatalf("u=%v: expected (%v, %v), got (%v, %v)", c.u, c.low, c.high, low, high)
+		}
+	}
+
+	overlap, err := pidpool.NewSplitRange(pidpool.SplitRangeConfig{
+		Min: 0, Max: 100, LowEnd: 60, HighStart: 40, Low: pct, High: pct,
+	})
+	if err != nil {
+		t.Fatalf("NewSplitRange err: %v", err)
+	}
+	if low, high := overlap.Apply(50, 1); math.Abs(low-1000.0/60) > 1e-9 || math.Abs(high-1000.0/60) > 1e-9 {
+		t.Fatalf("expected both actuators open in the overlap, got (%v, %v)", low, high)
+	}
+
+	if _, err := pidpool.NewSplitRange(pidpool.SplitRangeConfig{Min: 0, Max: 100, LowEnd: 0, HighStart: 50}); err == nil {
+		t.Fatalf("expected error for low end at min")
+	}
+}
+
+func TestSplitRange_Sink(t *testing.T) {
+	s, err := pidpool.NewSplitRange(pidpool.SplitRangeConfig{
+		Min: 0, Max: 100, LowEnd: 50, HighStart: 50,
+		Low:  pidpool.OutputConfig{Max: 1},
+		High: pidpool.OutputConfig{Max: 1},
+	})
+	if err != nil {
+		t.Fatalf("NewSplitRange err: %v", err)
+	}
+	var cool, heat float64
+	sink := s.Sink(
+		pidpool.SinkFunc(func(_ context.Context, v float64) error { cool = v; return nil }),
+		pidpool.SinkFunc(func(_ context.Context, v float64) error { heat = v; return nil }),
+	)
+	if err := sink.Write(context.Background(), 75); err != nil {
+		t.Fatalf("Write err: %v", err)
+	}
+	if cool != 0 || heat != 0.5 {
+		t.Fatalf("expected cool 0 and heat 0.5, got %v and %v", cool, heat)
+	}
+}
@@ -0,0 +1,172 @@
+package pidpool
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"sync"
+	"time"
+)
+
+// SensorStall describes a frozen process value: over Duration the output
+// swept OutputChange while the value moved by only ValueChange.
+type SensorStall struct {
+	Time         time.Time
+	Value        float64
+	ValueChange  float64
+	OutputChange float64
+	Duration     time.Duration
+}
+
+// StallDetectorConfig configures a StallDetector.
+type StallDetectorConfig struct {
+	// Epsilon is the largest value change over Window that still counts
+	// as frozen.
+	Epsilon float64
+	// MinOutputChange is the smallest output sweep over Window that counts
+	// as the controller actively driving the process.
+	MinOutputChange float64
+	// Window is the time span both changes are measured over, in the
+	// summed dt of the observed updates, so simulated and replayed loops
+	// are measured in their own time.
+	Window time.Duration
+
+	// SafeMode switches an attached controller to Manual at SafeOutput
+	// when a stall is detected. It is not switched back on recovery.
+	SafeMode   bool
+	SafeOutput float64
+	// Trip puts an attached controller in the fault state when a stall is
+	// detected, so it outputs its fail-safe value until ClearFault.
+	Trip bool
+
+	// OnStall is called once when a stall is detected.
+	OnStall func(SensorStall)
+	// OnRecover is called when the value moves again after a stall.
+	OnRecover func()
+}
+
+type stallSample struct {
+	// at is the summed dt up to the sample.
+	at            time.Duration
+	value, output float64
+}
+
+// StallDetector flags a stuck sensor: the process value has not changed by
+// more than Epsilon over Window while the output was actively changing. A
+// live process answers a moving output; a sensor that reads the same value
+// regardless has most likely frozen. Updates of bad quality are ignored.
+type StallDetector struct {
+	cfg StallDetectorConfig
+
+	mu      sync.Mutex
+	clock   time.Duration
+	samples []stallSample
+	stalled bool
+	stuckAt float64
+}
+
+// NewStallDetector returns a detector for the given configuration.
+func NewStallDetector(cfg StallDetectorConfig) (*StallDetector, error) {
+	if cfg.Epsilon < 0 || cfg.MinOutputChange <= 0 {
+		return nil, errors.New("epsilon must not be negative and min output change must be positive")
+	}
+	if cfg.Window <= 0 {
+		return nil, errors.New("window must be positive")
+	}
+	return &StallDetector{cfg: cfg}, nil
+}
+
+// Observe feeds an update to the detector and reports whether a stall was
+// detected on this update.
+func (d *StallDetector) Observe(ev UpdateEvent) bool {
+	if ev.Quality.IsBad() {
+		return false
+	}
+	d.mu.Lock()
+	stall, detected, recovered := d.observeLocked(ev)
+	d.mu.Unlock()
+
+	if detected && d.cfg.OnStall != nil {
+		d.cfg.OnStall(stall)
+	}
+	if recovered && d.cfg.OnRecover != nil {
+		d.cfg.OnRecover()
+	}
+	return detected
+}
+
+func (d *StallDetector) observeLocked(ev UpdateEvent) (SensorStall, bool, bool) {
+	d.clock += time.Duration(ev.DT * float64(time.Second))
+	if d.stalled {
+		if math.Abs(ev.Value-d.stuckAt) <= d.cfg.Epsilon {
+			return SensorStall{}, false, false
+		}
+		d.stalled = false
+		d.samples = d.samples[:0]
+		d.samples = append(d.samples, stallSample{d.clock, ev.Value, ev.Output})
+		return SensorStall{}, false, true
+	}
+
+	d.samples = append(d.samples, stallSample{d.clock, ev.Value, ev.Output})
+	// keep one sample at or before the start of the window.
+	for len(d.samples) > 1 && d.clock-d.samples[1].at >= d.cfg.Window {
+		d.samples = d.samples[1:]
+	}
+	span := d.clock - d.samples[0].at
+	if span < d.cfg.Window {
+		return SensorStall{}, false, false
+	}
+
+	vMin, vMax := math.Inf(1), math.Inf(-1)
+	oMin, oMax := math.Inf(1), math.Inf(-1)
+	for _, s := range d.samples {
+		vMin, vMax = math.Min(vMin, s.value), math.Max(vMax, s.value)
+		oMin, oMax = math.Min(oMin, s.output), math.Max(oMax, s.output)
+	}
+	if vMax-vMin > d.cfg.Epsilon || oMax-oMin < d.cfg.MinOutputChange {
+		return SensorStall{}, false, false
+	}
+
+	d.stalled, d.stuckAt = true, ev.Value
+	return SensorStall{
+		Time:         ev.Time,
+		Value:        ev.Value,
+		ValueChange:  vMax - vMin,
+		OutputChange: oMax - oMin,
+		Duration:     span,
+	}, true, false
+}
+
+// Stalled reports whether a stall is active.
+func (d *StallDetector) Stalled() bool {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	return d.stalled
+}
+
+// Reset forgets the observed history and clears any active stall.
+func (d *StallDetector) Reset() {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.clock = 0
+	d.samples = nil
+	d.stalled = false
+}
+
+// Attach observes every update of pid. With SafeMode set, a stall switches
+// pid to Manual at SafeOutput; with Trip set, it trips pid. The returned
+// function detaches the detector.
+func (d *StallDetector) Attach(pid *PID) (remove func()) {
+	return pid.OnUpdateWith(func(ev UpdateEvent) {
+		if !d.Observe(ev) {
+			return
+		}
+		if d.cfg.SafeMode {
+			pid.SetManualOutput(d.cfg.SafeOutput)
+			_ = pid.SetMode(Manual)
+		}
+		if d.cfg.Trip {
+			pid.Trip(fmt.Sprintf("sensor stalled at %g", ev.Value))
+		}
+	}, HookOptions{Name: "stall"})
+}
@@ -0,0 +1,101 @@
+package pidpool_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/ankur-anand/go-pidpool"
+)
+
+func TestStallDetector(t *testing.T) {
+	var stalls []pidpool.SensorStall
+	recovered := 0
+	d, err := pidpool.NewStallDetector(pidpool.StallDetectorConfig{
+		Epsilon:         0.05,
+		MinOutputChange: 5,
+		Window:          10 * time.Second,
+		OnStall:         func(s pidpool.SensorStall) { stalls = append(stalls, s) },
+		OnRecover:       func() { recovered++ },
+	})
+	if err != nil {
+		t.Fatalf("NewStallDetector err: %v", err)
+	}
+
+	t0 := time.Unix(0, 0)
+	ev := func(sec int, value, output float64) pidpool.UpdateEvent {
+		return pidpool.UpdateEvent{Time: t0.Add(time.Duration(sec) * time.Second), DT: 1, Value: value, Output: output}
+	}
+
+	// a steady loop holding a constant output is not a stall.
+	for i := 0; i <= 20; i++ {
+		if d.Observe(ev(i, 50, 30)) {
+			t.Fatalf("stall detected on a steady loop at %ds", i)
+		}
+	}
+	// the output winds up while the value stays put.
+	detectedAt := -1
+	for i := 21; i <= 40; i++ {
+		if d.Observe(ev(i, 50.01, 30+float64(i-20))) {
+			detectedAt = i
+			break
+		}
+	}
+	if detectedAt < 0 || len(stalls) != 1 || !d.Stalled() {
+		t.Fatalf("expected a stall, got %v", stalls)
+	}
+	if stalls[0].Duration < 10*time.Second || stalls[0].OutputChange < 5 {
+		t.Fatalf("unexpected stall %+v", stalls[0])
+	}
+
+	d.Observe(ev(detectedAt+1, 50.02, 60))
+	if recovered != 0 {
+		t.Fatalf("recovered within epsilon")
+	}
+	d.Observe(ev(detectedAt+2, 51, 60))
+	if recovered != 1 || d.Stalled() {
+		t.Fatalf("expected recovery")
+	}
+}
+
+func TestStallDetector_SafeMode(t *testing.T) {
+	d, err := pidpool.NewStallDetector(pidpool.StallDetectorConfig{
+		MinOutputChange: 1,
+		Window:          300 * time.Millisecond,
+		SafeMode:        true,
+		SafeOutput:      5,
+	})
+	if err != nil {
+		t.Fatalf("NewStallDetector err: %v", err)
+	}
+	pid := pidpool.NewPI(1, 1)
+	pid.SetSetPoint(10)
+	defer d.Attach(pid)()
+
+	for i := 0; i < 10 && pid.GetMode() == pidpool.Auto; i++ {
+		time.Sleep(60 * time.Millisecond)
+		pid.Update(0)
+	}
+	if pid.GetMode() != pidpool.Manual || pid.GetManualOutput() != 5 {
+		t.Fatalf("expected manual at safe output, got %v at %v", pid.GetMode(), pid.GetManualOutput())
+	}
+}
+
+func TestStallDetector_SimulatedTime(t *testing.T) {
+	d, err := pidpool.NewStallDetector(pidpool.StallDetectorConfig{
+		MinOutputChange: 1,
+		Window:          10 * time.Second,
+	})
+	if err != nil {
+		t.Fatalf("NewStallDetector err: %v", err)
+	}
+	// a simulation steps 1s at a time far faster than the wall clock.
+	pid := pidpool.NewPI(1, 1)
+	pid.SetSetPoint(10)
+	defer d.Attach(pid)()
+	for i := 0; i < 12; i++ {
+		pid.UpdateDuration(0, 1)
+	}
+	if !d.Stalled() {
+		t.Fatalf("expected a stall over 12 simulated seconds")
+	}
+}
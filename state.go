@@ -0,0 +1,242 @@
+package pidpool
+
+import (
+	"errors"
+	"math"
+	"time"
+)
+
+// State is a plain copy of a controller's configuration and internal state.
+// It can be persisted and handed back to RestoreState to resume a
+// controller exactly where it left off.
+type State struct {
+	Kp float64
+	Ki float64
+	Kd float64
+
+	SetPoint float64
+	DeadBand float64
+	// AdaptiveDeadBand sizes DeadBand from the measured noise, nil for a
+	// fixed dead-band. The noise estimate restarts on restore.
+	AdaptiveDeadBand *AdaptiveDeadBand
+
+	OutputMin   float64
+	OutputMax   float64
+	IntegralMin float64
+	IntegralMax float64
+
+	// IntegralTermLimits reports whether the integral limits were set in
+	// output units via SetIntegralTermLimits.
+	IntegralTermLimits bool
+	IntegralTermMin    float64
+	IntegralTermMax    float64
+
+	// IntegralDecay is the integrator leak time constant, zero if disabled.
+	IntegralDecay time.Duration
+
+	Mode         Mode
+	ManualOutput float64
+
+	// NegativeError holds the parameters used while the error is negative,
+	// nil unless set via SetNegativeErrorParams. NegativeErrorActive
+	// reports whether they are in effect.
+	NegativeError       *DirectionalParams
+	NegativeErrorActive bool
+
+	// Quantization snaps the output to discrete positions.
+	Quantization Quantization
+	// OutputDeadband is the smallest output move, see SetOutputDeadband.
+	OutputDeadband float64
+	// CircularRange is the wrap-around range of an angular measurement,
+	// nil for a linear one.
+	CircularRange *Limits
+	// InputRange and OutputRange are the engineering-unit spans the gains
+	// are normalized to, nil when unset.
+	InputRange  *Limits
+	OutputRange *Limits
+
+	// Discretization selects the integral and derivative methods and
+	// DerivativeState is the state of the derivative filter.
+	Discretization  Discretization
+	DerivativeState float64
+
+	// Calibration maps raw readings to measurement units.
+	Calibration Calibration
+
+	Filter MeasurementFilter
+	// FilterHistory is the filter state. Its layout depends on the filter
+	// kind and is only meaningful together with Filter.
+	FilterHistory []float64
+
+	Integral   float64
+	PrevValue  float64
+	PrevError  float64
+	LastUpdate time.Time
+	// LastOutput is the output of the most recent update, which the output
+	// dead-band and quantizer compare the next output with.
+	LastOutput float64
+
+	// Annotations are free-form metadata, see SetAnnotation.
+	Annotations map[string]string
+}
+
+// State returns a copy of the controller state.
+func (pid *PID) State() State {
+	pid.mu.Lock()
+	defer pid.mu.Unlock()
+	return pid.stateLocked()
+}
+
+func (pid *PID) stateLocked() State {
+	return State{
+		Kp:                  pid.kp,
+		Ki:                  pid.ki,
+		Kd:                  pid.kd,
+		SetPoint:            pid.setPoint,
+		DeadBand:            pid.deadBand,
+		AdaptiveDeadBand:    cloneAdaptiveDeadBand(pid.adaptiveDeadBand),
+		OutputMin:           pid.outputMin,
+		OutputMax:           pid.outputMax,
+		IntegralMin:         pid.integralMin,
+		IntegralMax:         pid.integralMax,
+		IntegralTermLimits:  pid.termLimits,
+		IntegralTermMin:     pid.termMin,
+		IntegralTermMax:     pid.termMax,
+		IntegralDecay:       pid.integralDecay,
+		Mode:                pid.mode,
+		ManualOutput:        pid.manualOutput,
+		NegativeError:       cloneDirectional(pid.negative),
+		NegativeErrorActive: pid.negativeActive,
+		Quantization:        pid.quantization,
+		OutputDeadband:      pid.outputDeadband,
+		CircularRange:       cloneLimits(pid.circular),
+		InputRange:          cloneLimits(pid.inputRange),
+		OutputRange:         cloneLimits(pid.outputRange),
+		Discretization:      pid.discretization,
+		DerivativeState:     pid.derivativeState,
+		Calibration:         pid.calibration,
+		Filter:              pid.filter,
+		FilterHistory:       append([]float64(nil), pid.filterHistory...),
+		Integral:            pid.integral,
+		PrevValue:           pid.prevValue,
+		PrevError:           pid.prevError,
+		LastUpdate:          pid.lastUpdate,
+		LastOutput:          pid.lastOutput,
+		Annotations:         cloneAnnotations(pid.annotations),
+	}
+}
+
+// Validate reports whether the state is consistent.
+func (s State) Validate() error {
+	if s.AdaptiveDeadBand != nil {
+		if err := s.AdaptiveDeadBand.Validate(); err != nil {
+			return err
+		}
+	}
+	if s.OutputMin > s.OutputMax {
+		return errors.New("min output greater than max output")
+	}
+	if s.IntegralMin > s.IntegralMax {
+		return errors.New("min integral greater than max integral")
+	}
+	if s.Mode != Auto && s.Mode != Manual {
+		return errors.New("unknown mode")
+	}
+	if s.IntegralTermLimits && s.IntegralTermMin > s.IntegralTermMax {
+		return errors.New("min integral term greater than max integral term")
+	}
+	if err := s.Filter.Validate(); err != nil {
+		return err
+	}
+	if n := len(s.FilterHistory); n > s.Filter.historyLen() ||
+		s.Filter.Kind != FilterSMA && n != 0 && n != s.Filter.historyLen() {
+		return errors.New("filter history does not match the filter")
+	}
+	if err := s.Calibration.Validate(); err != nil {
+		return err
+	}
+	if err := s.Quantization.Validate(); err != nil {
+		return err
+	}
+	if !(s.OutputDeadband >= 0) || math.IsInf(s.OutputDeadband, 0) {
+		return errors.New("output deadband must be finite and not negative")
+	}
+	if s.CircularRange != nil {
+		if err := validateCircular(*s.CircularRange); err != nil {
+			return err
+		}
+	}
+	if err := s.Discretization.Validate(); err != nil {
+		return err
+	}
+	if s.InputRange != nil {
+		if err := validateRange(*s.InputRange); err != nil {
+			return errors.New("input range: " + err.Error())
+		}
+	}
+	if s.OutputRange != nil {
+		if err := validateRange(*s.OutputRange); err != nil {
+			return errors.New("output range: " + err.Error())
+		}
+	}
+	if s.NegativeError != nil {
+		if err := s.NegativeError.Validate(); err != nil {
+			return err
+		}
+	} else if s.NegativeErrorActive {
+		return errors.New("negative error parameters active but not set")
+	}
+	if s.IntegralDecay < 0 {
+		return errors.New("integral decay must not be negative")
+	}
+
+	return nil
+}
+
+// RestoreState replaces the controller state with s. The previous value and
+// integral are restored as well, so the next update continues without an
+// output bump.
+func (pid *PID) RestoreState(s State) error {
+	if err := s.Validate(); err != nil {
+		return err
+	}
+	defer pid.notifyChange()
+	pid.mu.Lock()
+	defer pid.mu.Unlock()
+	pid.restoreLocked(s)
+
+	return nil
+}
+
+func (pid *PID) restoreLocked(s State) {
+	pid.kp, pid.ki, pid.kd = s.Kp, s.Ki, s.Kd
+	pid.setPoint = s.SetPoint
+	pid.deadBand = s.DeadBand
+	pid.setAdaptiveDeadBandLocked(s.AdaptiveDeadBand)
+	pid.outputMin, pid.outputMax = s.OutputMin, s.OutputMax
+	pid.integralMin, pid.integralMax = s.IntegralMin, s.IntegralMax
+	pid.termLimits = s.IntegralTermLimits
+	pid.termMin, pid.termMax = s.IntegralTermMin, s.IntegralTermMax
+	pid.integralDecay = s.IntegralDecay
+	pid.mode = s.Mode
+	pid.manualOutput = s.ManualOutput
+	pid.negative = cloneDirectional(s.NegativeError)
+	pid.negativeActive = s.NegativeErrorActive
+	pid.quantization = s.Quantization
+	pid.outputDeadband = s.OutputDeadband
+	pid.circular = cloneLimits(s.CircularRange)
+	pid.inputRange = cloneLimits(s.InputRange)
+	pid.outputRange = cloneLimits(s.OutputRange)
+	pid.discretization = s.Discretization
+	pid.derivativeState = s.DerivativeState
+	pid.calibration = s.Calibration
+	pid.filter = s.Filter
+	pid.filterHistory = append([]float64(nil), s.FilterHistory...)
+	pid.filterQuality = nil
+	pid.integral = s.Integral
+	pid.prevValue = s.PrevValue
+	pid.prevError = s.PrevError
+	pid.lastUpdate = s.LastUpdate
+	pid.lastOutput = s.LastOutput
+	pid.annotations = cloneAnnotations(s.Annotations)
+}
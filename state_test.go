@@ -0,0 +1,37 @@
+package pidpool_test
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/ankur-anand/go-pidpool"
+)
+
+func TestStateRestore_NoBump(t *testing.T) {
+	a := pidpool.NewPID(2, 0.5, 0.1, 0)
+	a.SetSetPoint(50)
+	for i := 0; i < 10; i++ {
+		a.UpdateDuration(float64(i*3), 0.1)
+	}
+
+	b := pidpool.NewPID(0, 0, 0, 0)
+	if err := b.RestoreState(a.State()); err != nil {
+		t.Fatalf("RestoreState err: %v", err)
+	}
+	if !reflect.DeepEqual(a.State(), b.State()) {
+		t.Fatalf("state mismatch:\n%+v\n%+v", a.State(), b.State())
+	}
+
+	if oa, ob := a.UpdateDuration(31, 0.1), b.UpdateDuration(31, 0.1); oa != ob {
+		t.Fatalf("restored controller diverged: %v != %v", oa, ob)
+	}
+}
+
+func TestRestoreState_Invalid(t *testing.T) {
+	p := pidpool.NewPID(1, 0, 0, 0)
+	s := p.State()
+	s.OutputMin, s.OutputMax = 10, 0
+	if err := p.RestoreState(s); err == nil {
+		t.Fatalf("expected error for inverted output limits")
+	}
+}
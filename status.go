@@ -0,0 +1,35 @@
+package pidpool
+
+// Status is the result of an update together with the saturation flags an
+// outer loop or an alarm needs to act on.
+type Status struct {
+	// Value is the output.
+	Value float64
+	// Saturated reports whether the output was clamped to the output
+	// limits.
+	Saturated bool
+	// WindupClamped reports whether anti-windup stopped the integral from
+	// following the error: it hit the integral limits or was frozen.
+	WindupClamped bool
+}
+
+// Status returns the status of the update.
+func (ev UpdateEvent) Status() Status {
+	return Status{
+		Value:         ev.Output,
+		Saturated:     ev.Output-ev.QuantizationError != ev.RawOutput,
+		WindupClamped: ev.IntegralClamped,
+	}
+}
+
+// UpdateStatus is Update returning the full status.
+func (pid *PID) UpdateStatus(value float64) Status {
+	return pid.updateNow(value).Status()
+}
+
+// LastStatus returns the status of the most recent update.
+func (pid *PID) LastStatus() Status {
+	pid.mu.Lock()
+	defer pid.mu.Unlock()
+	return pid.lastStatus
+}
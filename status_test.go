@@ -0,0 +1,28 @@
+package pidpool_test
+
+import (
+	"testing"
+
+	"github.com/ankur-anand/go-pidpool"
+)
+
+func TestUpdateStatus(t *testing.T) {
+	p := pidpool.NewPI(1, 1)
+	p.SetSetPoint(10)
+	_ = p.SetOutputLimits(0, 12)
+	_ = p.SetIntegralLimits(-5, 5)
+
+	if s := p.UpdateStatus(8); s.Saturated || s.WindupClamped {
+		t.Fatalf("unexpected saturation %+v", s)
+	}
+
+	// the integral keeps growing until it hits its limit of 5, and the
+	// output of 10+5 is clamped to 12.
+	for i := 0; i < 10; i++ {
+		_ = p.UpdateDuration(0, 1)
+	}
+	s := p.LastStatus()
+	if !s.Saturated || !s.WindupClamped || s.Value != 12 {
+		t.Fatalf("expected saturated and clamped at 12, got %+v", s)
+	}
+}
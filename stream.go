@@ -0,0 +1,44 @@
+package pidpool
+
+import (
+	"context"
+	"time"
+)
+
+// Stream runs the controller over a channel of samples and returns a
+// channel carrying one output per input sample. dt is derived from the
+// sample timestamps; a sample with a zero Time is stamped with the wall
+// clock on arrival.
+//
+// The output channel is closed when in is closed or ctx is done.
+func (pid *PID) Stream(ctx context.Context, in <-chan Sample) <-chan float64 {
+	out := make(chan float64)
+	go func() {
+		defer close(out)
+		for {
+			var s Sample
+			var ok bool
+			select {
+			case <-ctx.Done():
+				return
+			case s, ok = <-in:
+				if !ok {
+					return
+				}
+			}
+
+			if s.Time.IsZero() {
+				s.Time = time.Now()
+			}
+			v := pid.UpdateAt(s.Value, s.Time)
+
+			select {
+			case <-ctx.Done():
+				return
+			case out <- v:
+			}
+		}
+	}()
+
+	return out
+}
@@ -0,0 +1,59 @@
+package pidpool_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/ankur-anand/go-pidpool"
+)
+
+func TestStream_OneOutputPerSample(t *testing.T) {
+	start := time.Now()
+	p := pidpool.NewPID(1, 1, 0, 0)
+	p.SetSetPoint(10)
+	st := p.State()
+	st.LastUpdate = start
+	if err := p.RestoreState(st); err != nil {
+		t.Fatalf("RestoreState err: %v", err)
+	}
+
+	in := make(chan pidpool.Sample)
+	out := p.Stream(context.Background(), in)
+	go func() {
+		defer close(in)
+		for i := 1; i <= 3; i++ {
+			in <- pidpool.Sample{Value: 0, Time: start.Add(time.Duration(i) * time.Second)}
+		}
+	}()
+
+	var got []float64
+	for v := range out {
+		got = append(got, v)
+	}
+	want := []float64{20, 30, 40}
+	if len(got) != len(want) {
+		t.Fatalf("expected %d outputs, got %v", len(want), got)
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Fatalf("output %d: expected %v, got %v", i, want[i], got[i])
+		}
+	}
+}
+
+func TestStream_ContextCancel(t *testing.T) {
+	p := pidpool.NewPID(1, 0, 0, 0)
+	ctx, cancel := context.WithCancel(context.Background())
+	out := p.Stream(ctx, make(chan pidpool.Sample))
+	cancel()
+
+	select {
+	case _, ok := <-out:
+		if ok {
+			t.Fatalf("expected closed channel")
+		}
+	case <-time.After(time.Second):
+		t.Fatalf("stream did not stop on context cancel")
+	}
+}
@@ -0,0 +1,108 @@
+package pidpool
+
+import (
+	"errors"
+	"math"
+	"math/cmplx"
+	"time"
+)
+
+// TransferFunction is a linear process or controller model
+//
+//	Num(s) / Den(s) * exp(-DeadTime*s)
+//
+// with the polynomial coefficients in descending powers of s: Den
+// {2, 3, 1} is 2s² + 3s + 1. DeadTime is in seconds.
+type TransferFunction struct {
+	Num      []float64 `json:"num"`
+	Den      []float64 `json:"den"`
+	DeadTime float64   `json:"deadTime,omitempty"`
+}
+
+// Validate reports whether the transfer function is well formed.
+func (tf TransferFunction) Validate() error {
+	if len(trimPoly(tf.Num)) == 0 {
+		return errors.New("transfer function numerator must not be zero")
+	}
+	if len(trimPoly(tf.Den)) == 0 {
+		return errors.New("transfer function denominator must not be zero")
+	}
+	for _, c := range append(append([]float64{tf.DeadTime}, tf.Num...), tf.Den...) {
+		if math.IsNaN(c) || math.IsInf(c, 0) {
+			return errors.New("transfer function coefficients must be finite")
+		}
+	}
+	if tf.DeadTime < 0 {
+		return errors.New("dead time must not be negative")
+	}
+	return nil
+}
+
+// Eval returns the frequency response at w rad/s.
+func (tf TransferFunction) Eval(w float64) complex128 {
+	return tf.rational(w) * cmplx.Exp(complex(0, -w*tf.DeadTime))
+}
+
+// rational returns the frequency response without the dead time.
+func (tf TransferFunction) rational(w float64) complex128 {
+	s := complex(0, w)
+	return polyEval(tf.Num, s) / polyEval(tf.Den, s)
+}
+
+// Mul returns the series connection of tf and o.
+func (tf TransferFunction) Mul(o TransferFunction) TransferFunction {
+	return TransferFunction{
+		Num:      polyMul(tf.Num, o.Num),
+		Den:      polyMul(tf.Den, o.Den),
+		DeadTime: tf.DeadTime + o.DeadTime,
+	}
+}
+
+// TransferFunction returns the model as Gain / (TimeConstant*s + 1) with
+// its dead time.
+func (m FOPDT) TransferFunction() TransferFunction {
+	return TransferFunction{
+		Num:      []float64{m.Gain},
+		Den:      []float64{m.TimeConstant, 1},
+		DeadTime: m.DeadTime,
+	}
+}
+
+// TransferFunction returns the controller Kp + Ki/s + Kd*s/(Tf*s + 1) with
+// the derivative filter time constant Tf; zero gives the ideal derivative.
+func (g Gains) TransferFunction(filter time.Duration) TransferFunction {
+	tf := filter.Seconds()
+	return TransferFunction{
+		Num: trimPoly([]float64{g.Kp*tf + g.Kd, g.Kp + g.Ki*tf, g.Ki}),
+		Den: trimPoly([]float64{tf, 1, 0}),
+	}
+}
+
+// trimPoly drops the leading zero coefficients.
+func trimPoly(p []float64) []float64 {
+	for len(p) > 0 && p[0] == 0 {
+		p = p[1:]
+	}
+	return p
+}
+
+func polyEval(p []float64, s complex128) complex128 {
+	var v complex128
+	for _, c := range p {
+		v = v*s + complex(c, 0)
+	}
+	return v
+}
+
+func polyMul(a, b []float64) []float64 {
+	if len(a) == 0 || len(b) == 0 {
+		return nil
+	}
+	out := make([]float64, len(a)+len(b)-1)
+	for i, x := range a {
+		for j, y := range b {
+			out[i+j] += x * y
+		}
+	}
+	return out
+}
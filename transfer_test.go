@@ -0,0 +1,35 @@
+package pidpool_test
+
+import (
+	"math"
+	"math/cmplx"
+	"testing"
+	"time"
+
+	"github.com/ankur-anand/go-pidpool"
+)
+
+func TestTransferFunction_Eval(t *testing.T) {
+	model := pidpool.FOPDT{Gain: 2, TimeConstant: 10, DeadTime: 1}.TransferFunction()
+	g := model.Eval(0.1)
+	if math.Abs(cmplx.Abs(g)-2/math.Sqrt(2)) > 1e-12 {
+		t.Fatalf("unexpected magnitude %v", cmplx.Abs(g))
+	}
+	if want := -math.Pi/4 - 0.1; math.Abs(cmplx.Phase(g)-want) > 1e-12 {
+		t.Fatalf("unexpected phase %v, want %v", cmplx.Phase(g), want)
+	}
+
+	// the filtered PID matches its parallel form.
+	gains := pidpool.Gains{Kp: 2, Ki: 0.5, Kd: 3}
+	c := gains.TransferFunction(100 * time.Millisecond).Eval(2)
+	s := complex(0, 2)
+	want := 2 + 0.5/s + 3*s/(0.1*s+1)
+	if cmplx.Abs(c-want) > 1e-12 {
+		t.Fatalf("controller response %v, want %v", c, want)
+	}
+
+	loop := gains.TransferFunction(0).Mul(model)
+	if got, want := loop.Eval(0.3), gains.TransferFunction(0).Eval(0.3)*model.Eval(0.3); cmplx.Abs(got-want) > 1e-12 {
+		t.Fatalf("series response %v, want %v", got, want)
+	}
+}
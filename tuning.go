@@ -0,0 +1,115 @@
+package pidpool
+
+import (
+	"encoding/json"
+	"errors"
+	"io"
+	"math"
+	"os"
+	"time"
+)
+
+// UltimatePoint is the result of a relay or Ziegler-Nichols experiment:
+// the proportional gain at which the loop oscillates steadily and the
+// period of that oscillation, in seconds.
+type UltimatePoint struct {
+	Gain   float64 `json:"gain"`
+	Period float64 `json:"period"`
+}
+
+// TuningRecord is the outcome of a tuning session: the gains together with
+// the identification data they were derived from, so the gains can be
+// audited, compared and reloaded later.
+type TuningRecord struct {
+	// Time is when the tuning was done.
+	Time time.Time `json:"time"`
+	// Method names the tuning rule, e.g. "simc" or "ziegler-nichols".
+	Method string `json:"method,omitempty"`
+	Gains  Gains  `json:"gains"`
+
+	// Model and Ultimate hold the identification data, whichever the
+	// method used.
+	Model    *FOPDT         `json:"model,omitempty"`
+	Ultimate *UltimatePoint `json:"ultimate,omitempty"`
+
+	// Performance holds the indices measured or simulated with Gains.
+	Performance *PerformanceReport `json:"performance,omitempty"`
+
+	Note string `json:"note,omitempty"`
+}
+
+// Annotation keys set by TuningRecord.Apply.
+const (
+	AnnotationTuningMethod = "tuning.method"
+	AnnotationTuningTime   = "tuning.time"
+)
+
+// Validate reports whether the record can be applied.
+func (r TuningRecord) Validate() error {
+	if err := r.Gains.Validate(); err != nil {
+		return err
+	}
+	if u := r.Ultimate; u != nil && (!(u.Gain > 0) || !(u.Period > 0) || math.IsInf(u.Gain, 0) || math.IsInf(u.Period, 0)) {
+		return errors.New("ultimate gain and period must be positive")
+	}
+	return nil
+}
+
+// Apply sets the gains of pid from the record and annotates it with the
+// method and time of the tuning.
+func (r TuningRecord) Apply(pid *PID) error {
+	if err := r.Validate(); err != nil {
+		return err
+	}
+	if err := pid.SetGains(r.Gains); err != nil {
+		return err
+	}
+	if r.Method != "" {
+		if err := pid.SetAnnotation(AnnotationTuningMethod, r.Method); err != nil {
+			return err
+		}
+	}
+	if !r.Time.IsZero() {
+		return pid.SetAnnotation(AnnotationTuningTime, r.Time.UTC().Format(time.RFC3339))
+	}
+	return nil
+}
+
+// Write encodes the record as JSON.
+func (r TuningRecord) Write(w io.Writer) error {
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(r)
+}
+
+// ReadTuningRecord decodes a record written by Write.
+func ReadTuningRecord(rd io.Reader) (TuningRecord, error) {
+	var r TuningRecord
+	if err := json.NewDecoder(rd).Decode(&r); err != nil {
+		return TuningRecord{}, err
+	}
+	return r, r.Validate()
+}
+
+// LoadTuningRecord reads a record from a file.
+func LoadTuningRecord(path string) (TuningRecord, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return TuningRecord{}, err
+	}
+	defer file.Close()
+	return ReadTuningRecord(file)
+}
+
+// Save writes the record to a file.
+func (r TuningRecord) Save(path string) error {
+	file, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	if err := r.Write(file); err != nil {
+		file.Close()
+		return err
+	}
+	return file.Close()
+}
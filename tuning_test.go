@@ -0,0 +1,57 @@
+package pidpool_test
+
+import (
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/ankur-anand/go-pidpool"
+)
+
+func TestTuningRecord_SaveLoadApply(t *testing.T) {
+	model := pidpool.FOPDT{Gain: 2, TimeConstant: 30, DeadTime: 5}
+	kp, ki, kd, err := model.SIMC(0)
+	if err != nil {
+		t.Fatalf("SIMC err: %v", err)
+	}
+	rec := pidpool.TuningRecord{
+		Time:        time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC),
+		Method:      "simc",
+		Gains:       pidpool.Gains{Kp: kp, Ki: ki, Kd: kd},
+		Model:       &model,
+		Ultimate:    &pidpool.UltimatePoint{Gain: 4, Period: 18},
+		Performance: &pidpool.PerformanceReport{IAE: 12, Settled: true, SettlingTime: 90 * time.Second},
+	}
+
+	path := filepath.Join(t.TempDir(), "tuning.json")
+	if err := rec.Save(path); err != nil {
+		t.Fatalf("Save err: %v", err)
+	}
+	got, err := pidpool.LoadTuningRecord(path)
+	if err != nil {
+		t.Fatalf("LoadTuningRecord err: %v", err)
+	}
+	if !got.Time.Equal(rec.Time) || got.Gains != rec.Gains || *got.Model != model ||
+		*got.Ultimate != *rec.Ultimate || *got.Performance != *rec.Performance {
+		t.Fatalf("round trip mismatch: %+v", got)
+	}
+
+	pid := pidpool.NewPID(0, 0, 0, 0)
+	if err := got.Apply(pid); err != nil {
+		t.Fatalf("Apply err: %v", err)
+	}
+	if pid.Gains() != rec.Gains {
+		t.Fatalf("gains not applied: %+v", pid.Gains())
+	}
+	if m, _ := pid.Annotation(pidpool.AnnotationTuningMethod); m != "simc" {
+		t.Fatalf("method annotation: %q", m)
+	}
+	if ts, _ := pid.Annotation(pidpool.AnnotationTuningTime); ts != "2024-05-01T12:00:00Z" {
+		t.Fatalf("time annotation: %q", ts)
+	}
+
+	rec.Ultimate.Period = 0
+	if err := rec.Apply(pid); err == nil {
+		t.Fatalf("expected error for an invalid ultimate point")
+	}
+}
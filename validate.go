@@ -0,0 +1,177 @@
+package pidpool
+
+import (
+	"errors"
+	"fmt"
+	"math"
+)
+
+// SafetyLimits bound the closed-loop behaviour accepted by ValidateGains.
+type SafetyLimits struct {
+	// MaxOvershoot is the largest accepted overshoot of a setpoint step, as
+	// a fraction of the step. Zero selects 0.3.
+	MaxOvershoot float64
+	// MaxDecayRatio is the largest accepted ratio between successive
+	// same-sign oscillation peaks. Quarter amplitude damping is 0.25; a
+	// ratio of 1 or more is a sustained or growing oscillation. Zero
+	// selects 0.5.
+	MaxDecayRatio float64
+}
+
+func (l SafetyLimits) withDefaults() SafetyLimits {
+	if l.MaxOvershoot == 0 {
+		l.MaxOvershoot = 0.3
+	}
+	if l.MaxDecayRatio == 0 {
+		l.MaxDecayRatio = 0.5
+	}
+	return l
+}
+
+// SimulationReport summarizes a simulated closed-loop setpoint step.
+type SimulationReport struct {
+	Overshoot  float64
+	DecayRatio float64
+	// Stable is false when the response diverged.
+	Stable bool
+}
+
+// ErrUnsafeGains is returned when proposed gains are predicted to
+// oscillate beyond the safety limits.
+var ErrUnsafeGains = errors.New("gains predicted to be unsafe")
+
+// simulateStep runs a unit setpoint step against the model with a
+// controller configured from st, in Auto mode, and reports the response.
+// A response diverges when its motion speeds up towards the end of the
+// horizon or its oscillation about the final value does not decay; a
+// steady-state offset, as left by a P-only controller, is not divergence.
+func (m FOPDT) simulateStep(st State) (SimulationReport, error) {
+	if m.TimeConstant <= 0 || m.DeadTime < 0 {
+		return SimulationReport{}, errors.New("invalid model")
+	}
+	horizon := 20 * (m.TimeConstant + m.DeadTime)
+	dt := (m.TimeConstant + m.DeadTime) / 200
+	steps := int(horizon / dt)
+	delay := int(math.Round(m.DeadTime / dt))
+	a := math.Exp(-dt / m.TimeConstant)
+
+	st.SetPoint = 1
+	st.Mode = Auto
+	st.Integral, st.PrevValue, st.PrevError = 0, 0, 0
+	pid := &PID{}
+	if err := pid.RestoreState(st); err != nil {
+		return SimulationReport{}, err
+	}
+
+	outputs := make([]float64, delay+1)
+	ys := make([]float64, steps)
+	y, peak := 0.0, 0.0
+	for k := 0; k < steps; k++ {
+		pid.mu.Lock()
+		u := pid.updateInternal(y, dt).Output
+		pid.mu.Unlock()
+
+		copy(outputs, outputs[1:])
+		outputs[delay] = u
+		y = a*y + m.Gain*(1-a)*outputs[0]
+		if math.IsNaN(y) || math.Abs(y) > 1e6 {
+			return SimulationReport{Stable: false, DecayRatio: math.Inf(1)}, nil
+		}
+		peak = math.Max(peak, y-1)
+		ys[k] = y
+	}
+
+	// the response settles about the mean of its last quarter; track the
+	// extreme deviation from it of every half cycle.
+	q := steps / 4
+	center := 0.0
+	for _, v := range ys[3*q:] {
+		center += v
+	}
+	center /= float64(len(ys[3*q:]))
+	var amps []float64
+	segAmp, segSign := 0.0, 0.0
+	for _, v := range ys {
+		e := v - center
+		sign := math.Copysign(1, e)
+		if sign != segSign {
+			if segAmp > 0.01 {
+				amps = append(amps, segAmp)
+			}
+			segSign, segAmp = sign, 0
+		}
+		segAmp = math.Max(segAmp, math.Abs(e))
+	}
+	if segAmp > 0.01 {
+		amps = append(amps, segAmp)
+	}
+
+	r := SimulationReport{Overshoot: peak, Stable: true}
+	for i := 1; i+2 < len(amps); i++ {
+		r.DecayRatio = math.Max(r.DecayRatio, amps[i+2]/amps[i])
+	}
+	// a converging response moves less in the last quarter than in the one
+	// before; a diverging one moves more.
+	motion := func(from, to int) float64 {
+		var s float64
+		for i := from; i < to; i++ {
+			s += math.Abs(ys[i] - ys[i-1])
+		}
+		return s
+	}
+	late, prev := motion(3*q, steps), motion(2*q, 3*q)
+	if r.DecayRatio >= 1 || late > prev && late > 1e-6 {
+		r.Stable = false
+	}
+
+	return r, nil
+}
+
+// ValidateGains simulates a setpoint step against the model with the
+// proposed gains and returns ErrUnsafeGains, along with the report, if the
+// response overshoots or oscillates beyond the limits. The model can come
+// from IdentifyFOPDT over recently recorded data.
+func ValidateGains(m FOPDT, kp, ki, kd float64, lim SafetyLimits) (SimulationReport, error) {
+	st := NewPID(kp, ki, kd, 0).State()
+	st.IntegralMin, st.IntegralMax = math.Inf(-1), math.Inf(1)
+
+	return validateState(m, st, lim)
+}
+
+func validateState(m FOPDT, st State, lim SafetyLimits) (SimulationReport, error) {
+	r, err := m.simulateStep(st)
+	if err != nil {
+		return r, err
+	}
+
+	lim = lim.withDefaults()
+	switch {
+	case !r.Stable:
+		return r, fmt.Errorf("%w: response diverges", ErrUnsafeGains)
+	case r.Overshoot > lim.MaxOvershoot:
+		return r, fmt.Errorf("%w: overshoot %.2f exceeds %.2f", ErrUnsafeGains, r.Overshoot, lim.MaxOvershoot)
+	case r.DecayRatio > lim.MaxDecayRatio:
+		return r, fmt.Errorf("%w: decay ratio %.2f exceeds %.2f", ErrUnsafeGains, r.DecayRatio, lim.MaxDecayRatio)
+	}
+
+	return r, nil
+}
+
+// SetPIDValidated validates the proposed gains against the model with the
+// controller's own limits and applies them only if they are predicted to
+// be safe. force applies them regardless; the report is still returned.
+func (pid *PID) SetPIDValidated(m FOPDT, kp, ki, kd float64, lim SafetyLimits, force bool) (SimulationReport, error) {
+	st := pid.State()
+	st.Kp, st.Ki, st.Kd = kp, ki, kd
+	if st.IntegralTermLimits && ki != 0 {
+		st.IntegralMin, st.IntegralMax = termToAccumulator(st.IntegralTermMin, st.IntegralTermMax, ki)
+	}
+
+	r, err := validateState(m, st, lim)
+	if err != nil && !(force && errors.Is(err, ErrUnsafeGains)) {
+		return r, err
+	}
+	pid.SetPID(kp, ki, kd)
+
+	return r, nil
+}
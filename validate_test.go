@@ -0,0 +1,69 @@
+package pidpool_test
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/ankur-anand/go-pidpool"
+)
+
+func TestValidateGains(t *testing.T) {
+	m := pidpool.FOPDT{Gain: 2, TimeConstant: 5, DeadTime: 1}
+
+	kp, ki, kd, err := m.SIMC(0)
+	if err != nil {
+		t.Fatalf("SIMC err: %v", err)
+	}
+	r, err := pidpool.ValidateGains(m, kp, ki, kd, pidpool.SafetyLimits{})
+	if err != nil {
+		t.Fatalf("SIMC gains rejected: %v (%+v)", err, r)
+	}
+
+	r, err = pidpool.ValidateGains(m, 10*kp, 10*ki, 0, pidpool.SafetyLimits{})
+	if !errors.Is(err, pidpool.ErrUnsafeGains) {
+		t.Fatalf("expected aggressive gains to be rejected, got %v (%+v)", err, r)
+	}
+}
+
+func TestSetPIDValidated(t *testing.T) {
+	m := pidpool.FOPDT{Gain: 2, TimeConstant: 5, DeadTime: 1}
+	p := pidpool.NewPID(0.1, 0.01, 0, 0)
+
+	if _, err := p.SetPIDValidated(m, 20, 5, 0, pidpool.SafetyLimits{}, false); err == nil {
+		t.Fatalf("expected unsafe gains to be refused")
+	}
+	if kp, _, _ := p.GetPID(); kp != 0.1 {
+		t.Fatalf("refused gains were applied: kp %v", kp)
+	}
+
+	if _, err := p.SetPIDValidated(m, 20, 5, 0, pidpool.SafetyLimits{}, true); err != nil {
+		t.Fatalf("forced apply err: %v", err)
+	}
+	if kp, _, _ := p.GetPID(); kp != 20 {
+		t.Fatalf("forced gains not applied: kp %v", kp)
+	}
+}
+
+func TestValidateGains_Divergence(t *testing.T) {
+	m := pidpool.FOPDT{Gain: 2, TimeConstant: 5, DeadTime: 1}
+
+	// P-only settles well short of the setpoint; an offset is not divergence.
+	r, err := pidpool.ValidateGains(m, 0.2, 0, 0, pidpool.SafetyLimits{})
+	if err != nil || !r.Stable {
+		t.Fatalf("expected a P-only offset to be accepted, got %v (%+v)", err, r)
+	}
+
+	// the wrong sign runs away without oscillating.
+	r, err = pidpool.ValidateGains(m, -1, -0.2, 0, pidpool.SafetyLimits{})
+	if !errors.Is(err, pidpool.ErrUnsafeGains) || r.Stable {
+		t.Fatalf("expected a runaway response to be rejected, got %v (%+v)", err, r)
+	}
+
+	// a controller in Manual mode is still validated in closed loop.
+	kp, ki, kd, _ := m.SIMC(0)
+	p := pidpool.NewPID(0.1, 0.01, 0, 0)
+	p.SetMode(pidpool.Manual)
+	if r, err := p.SetPIDValidated(m, kp, ki, kd, pidpool.SafetyLimits{}, false); err != nil {
+		t.Fatalf("expected safe gains accepted in Manual mode, got %v (%+v)", err, r)
+	}
+}
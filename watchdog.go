@@ -0,0 +1,198 @@
+package pidpool
+
+import (
+	"errors"
+	"math"
+	"sync"
+	"time"
+)
+
+// TripKind classifies a watchdog trip.
+type TripKind int
+
+const (
+	// TripOscillation is a sustained oscillation whose amplitude does not
+	// decay.
+	TripOscillation TripKind = iota
+	// TripDivergence is an oscillation whose amplitude keeps growing.
+	TripDivergence
+)
+
+// String implements fmt.Stringer.
+func (k TripKind) String() string {
+	if k == TripDivergence {
+		return "divergence"
+	}
+	return "oscillation"
+}
+
+// WatchdogTrip describes why a watchdog tripped.
+type WatchdogTrip struct {
+	Time time.Time
+	Kind TripKind
+	// Amplitude is the error peak of the last half-cycle.
+	Amplitude float64
+	// Period is the mean oscillation period, in the summed dt of the
+	// observed updates.
+	Period time.Duration
+}
+
+// WatchdogConfig configures a Watchdog. Zero fields select the defaults.
+type WatchdogConfig struct {
+	// MinAmplitude is the smallest error peak that counts; smaller swings
+	// are treated as noise.
+	MinAmplitude float64
+	// Cycles is the number of full oscillation cycles that must be
+	// observed before tripping. Defaults to 3.
+	Cycles int
+	// MaxDecay is the ratio of successive peaks at or above which the
+	// oscillation counts as sustained. Defaults to 0.8.
+	MaxDecay float64
+	// GrowthRatio is the ratio of successive peaks above which the
+	// oscillation counts as diverging. Defaults to 1.2.
+	GrowthRatio float64
+
+	// SafeMode switches an attached controller to Manual at SafeOutput
+	// when the watchdog trips.
+	SafeMode   bool
+	SafeOutput float64
+
+	// OnTrip is called once when the watchdog trips.
+	OnTrip func(WatchdogTrip)
+}
+
+// Watchdog monitors the control error for sustained oscillation or
+// divergence, so a badly tuned loop cannot silently shake hardware apart.
+// It tracks the error peaks of successive half-cycles; after it trips it
+// stays tripped until Reset.
+type Watchdog struct {
+	cfg WatchdogConfig
+
+	mu sync.Mutex
+	// clock is the summed dt of the observed updates; half-cycle starts
+	// are taken from it.
+	clock   time.Duration
+	sign    int
+	peak    float64
+	start   time.Duration
+	peaks   []float64
+	starts  []time.Duration
+	tripped bool
+}
+
+// NewWatchdog returns a watchdog for the given configuration.
+func NewWatchdog(cfg WatchdogConfig) (*Watchdog, error) {
+	if cfg.MinAmplitude < 0 || cfg.Cycles < 0 || cfg.MaxDecay < 0 || cfg.GrowthRatio < 0 {
+		return nil, errors.New("watchdog parameters must not be negative")
+	}
+	if cfg.Cycles == 0 {
+		cfg.Cycles = 3
+	}
+	if cfg.MaxDecay == 0 {
+		cfg.MaxDecay = 0.8
+	}
+	if cfg.GrowthRatio == 0 {
+		cfg.GrowthRatio = 1.2
+	}
+	if cfg.GrowthRatio <= 1 {
+		return nil, errors.New("growth ratio must be greater than 1")
+	}
+	return &Watchdog{cfg: cfg}, nil
+}
+
+// Observe feeds an update to the watchdog and reports whether it tripped
+// on this update.
+func (w *Watchdog) Observe(ev UpdateEvent) bool {
+	w.mu.Lock()
+	trip, ok := w.observeLocked(ev)
+	w.mu.Unlock()
+
+	if ok && w.cfg.OnTrip != nil {
+		w.cfg.OnTrip(trip)
+	}
+	return ok
+}
+
+func (w *Watchdog) observeLocked(ev UpdateEvent) (WatchdogTrip, bool) {
+	if w.tripped {
+		return WatchdogTrip{}, false
+	}
+	w.clock += time.Duration(ev.DT * float64(time.Second))
+
+	e := ev.Error
+	sign := 0
+	switch {
+	case e > w.cfg.MinAmplitude:
+		sign = 1
+	case e < -w.cfg.MinAmplitude:
+		sign = -1
+	}
+	if sign != 0 && sign != w.sign {
+		if w.sign != 0 {
+			w.peaks = append(w.peaks, w.peak)
+			w.starts = append(w.starts, w.start)
+		}
+		w.sign, w.peak, w.start = sign, 0, w.clock
+	}
+	w.peak = math.Max(w.peak, math.Abs(e))
+
+	// keep the half-cycles of the configured number of cycles.
+	need := 2 * w.cfg.Cycles
+	if len(w.peaks) > need {
+		w.peaks = w.peaks[len(w.peaks)-need:]
+		w.starts = w.starts[len(w.starts)-need:]
+	}
+	if len(w.peaks) < need {
+		return WatchdogTrip{}, false
+	}
+
+	sustained, growing := true, true
+	for i := 1; i < len(w.peaks); i++ {
+		ratio := w.peaks[i] / w.peaks[i-1]
+		sustained = sustained && ratio >= w.cfg.MaxDecay
+		growing = growing && ratio > w.cfg.GrowthRatio
+	}
+	if !sustained {
+		return WatchdogTrip{}, false
+	}
+
+	w.tripped = true
+	trip := WatchdogTrip{
+		Time:      ev.Time,
+		Kind:      TripOscillation,
+		Amplitude: w.peaks[len(w.peaks)-1],
+		Period:    (w.clock - w.starts[0]) / time.Duration(w.cfg.Cycles),
+	}
+	if growing {
+		trip.Kind = TripDivergence
+	}
+
+	return trip, true
+}
+
+// Tripped reports whether the watchdog has tripped.
+func (w *Watchdog) Tripped() bool {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return w.tripped
+}
+
+// Reset clears the error history and re-arms the watchdog.
+func (w *Watchdog) Reset() {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	w.clock, w.sign, w.peak = 0, 0, 0
+	w.peaks, w.starts = nil, nil
+	w.tripped = false
+}
+
+// Attach observes every update of pid. With SafeMode set, a trip switches
+// pid to Manual at SafeOutput. The returned function detaches the watchdog.
+func (w *Watchdog) Attach(pid *PID) (remove func()) {
+	return pid.OnUpdateWith(func(ev UpdateEvent) {
+		if w.Observe(ev) && w.cfg.SafeMode {
+			pid.SetManualOutput(w.cfg.SafeOutput)
+			_ = pid.SetMode(Manual)
+		}
+	}, HookOptions{Name: "watchdog"})
+}
@@ -0,0 +1,77 @@
+package pidpool_test
+
+import (
+	"math"
+	"testing"
+	"time"
+
+	"github.com/ankur-anand/go-pidpool"
+)
+
+// oscillate feeds the watchdog an error of amplitude a*g^t and period 10s,
+// sampled every second, and returns the trips.
+func oscillate(t *testing.T, w *pidpool.Watchdog, a, g float64, seconds int) []time.Time {
+	t.Helper()
+	var trips []time.Time
+	t0 := time.Unix(0, 0)
+	for s := 0; s < seconds; s++ {
+		e := a * math.Pow(g, float64(s)) * math.Sin(2*math.Pi*float64(s)/10+0.1)
+		if w.Observe(pidpool.UpdateEvent{Time: t0.Add(time.Duration(s) * time.Second), DT: 1, Error: e}) {
+			trips = append(trips, t0.Add(time.Duration(s)*time.Second))
+		}
+	}
+	return trips
+}
+
+func TestWatchdog(t *testing.T) {
+	var got []pidpool.WatchdogTrip
+	cfg := pidpool.WatchdogConfig{
+		MinAmplitude: 0.5,
+		OnTrip:       func(tr pidpool.WatchdogTrip) { got = append(got, tr) },
+	}
+
+	w, _ := pidpool.NewWatchdog(cfg)
+	if trips := oscillate(t, w, 5, 0.85, 100); len(trips) != 0 {
+		t.Fatalf("decaying oscillation must not trip, tripped at %v", trips)
+	}
+
+	w, _ = pidpool.NewWatchdog(cfg)
+	if trips := oscillate(t, w, 5, 1, 100); len(trips) != 1 || !w.Tripped() {
+		t.Fatalf("expected one trip for sustained oscillation, got %v", trips)
+	}
+	if tr := got[0]; tr.Kind != pidpool.TripOscillation || tr.Period != 10*time.Second {
+		t.Fatalf("unexpected trip %+v", tr)
+	}
+
+	w, _ = pidpool.NewWatchdog(cfg)
+	oscillate(t, w, 1, 1.05, 100)
+	if tr := got[len(got)-1]; tr.Kind != pidpool.TripDivergence {
+		t.Fatalf("expected divergence, got %+v", tr)
+	}
+}
+
+func TestWatchdog_SafeMode(t *testing.T) {
+	p := pidpool.NewP(1)
+	w, _ := pidpool.NewWatchdog(pidpool.WatchdogConfig{Cycles: 1, SafeMode: true, SafeOutput: 7})
+	w.Attach(p)
+	for _, v := range []float64{5, -5, 5, -5, 5} {
+		p.UpdateDuration(v, 1)
+	}
+	if p.GetMode() != pidpool.Manual || p.UpdateDuration(0, 1) != 7 {
+		t.Fatalf("expected safe mode at 7, mode %v", p.GetMode())
+	}
+}
+
+func TestWatchdog_SimulatedPeriod(t *testing.T) {
+	var trip pidpool.WatchdogTrip
+	w, _ := pidpool.NewWatchdog(pidpool.WatchdogConfig{Cycles: 1, OnTrip: func(tr pidpool.WatchdogTrip) { trip = tr }})
+	p := pidpool.NewP(1)
+	defer w.Attach(p)()
+	// steps of 2 simulated seconds, run back to back.
+	for _, v := range []float64{5, -5, 5} {
+		p.UpdateDuration(v, 2)
+	}
+	if !w.Tripped() || trip.Period != 4*time.Second {
+		t.Fatalf("expected a 4s period in simulated time, got %+v", trip)
+	}
+}